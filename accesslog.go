@@ -0,0 +1,245 @@
+package lrpc
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"github.com/lazygophers/utils/json"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+type AccessLogFormat string
+
+const (
+	AccessLogFormatJson     AccessLogFormat = "json"
+	AccessLogFormatCombined AccessLogFormat = "combined"
+)
+
+const accessLogSampleKey = "lrpc_access_log_sample"
+
+type AccessLog struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query,omitempty"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Latency   int64     `json:"latency_ms"`
+	Bytes     int       `json:"bytes"`
+	Ip        string    `json:"ip"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	TraceId   string    `json:"trace_id,omitempty"`
+	RequestId string    `json:"request_id,omitempty"`
+	Body      string    `json:"body,omitempty"`
+}
+
+type AccessLogConfig struct {
+	// 默认 json
+	Format AccessLogFormat
+
+	// 为空时输出到 log.Info
+	Writer io.Writer
+
+	// 采样率，(0, 1]，默认 1，可以通过 RouteWithAccessLogSample 对单个路由设置
+	Sample float64
+
+	// 返回 true 时不记录
+	Skip func(ctx *Ctx) bool
+
+	// 为空时使用 Ctx.UserId
+	User func(ctx *Ctx) string
+
+	// 为空时使用 Ctx.TenantId
+	Tenant func(ctx *Ctx) string
+
+	// 是否记录请求体，只记录 json 和文本
+	LogBody bool
+
+	// 请求体的最大记录长度，默认 1024，json 中的敏感字段通过 middleware/redact 脱敏
+	MaxBodySize int
+}
+
+func (c *AccessLogConfig) apply() {
+	if c.Format == "" {
+		c.Format = AccessLogFormatJson
+	}
+
+	if c.Sample <= 0 || c.Sample > 1 {
+		c.Sample = 1
+	}
+
+	if c.MaxBodySize == 0 {
+		c.MaxBodySize = 1024
+	}
+}
+
+func (c *AccessLogConfig) body(ctx *Ctx) string {
+	body := ctx.Body()
+	if len(body) == 0 {
+		return ""
+	}
+
+	contentType := ctx.Header(HeaderContentType)
+	if strings.Contains(contentType, "json") {
+		var v any
+		err := json.Unmarshal(body, &v)
+		if err == nil {
+			buffer, err := json.Marshal(redact.Any(v))
+			if err == nil {
+				body = buffer
+			}
+		}
+	} else if !strings.HasPrefix(contentType, "text/") {
+		return ""
+	}
+
+	if len(body) > c.MaxBodySize {
+		return string(body[:c.MaxBodySize]) + "..."
+	}
+
+	return string(body)
+}
+
+func (c *AccessLogConfig) sample(ctx *Ctx) bool {
+	rate := c.Sample
+	if v, ok := ctx.GetLocal(accessLogSampleKey).(float64); ok {
+		rate = v
+	}
+
+	if rate <= 0 {
+		return false
+	}
+
+	return rate >= 1 || rand.Float64() < rate
+}
+
+func (c *AccessLogConfig) format(l *AccessLog) string {
+	switch c.Format {
+	case AccessLogFormatCombined:
+		uri := l.Path
+		if l.Query != "" {
+			uri += "?" + l.Query
+		}
+
+		user := l.User
+		if user == "" {
+			user = "-"
+		}
+
+		return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %d "%s" "%s" %dms %s`,
+			l.Ip, user, l.Time.Format("02/Jan/2006:15:04:05 -0700"), l.Method, uri, l.Proto,
+			l.Status, l.Bytes, l.Referer, l.UserAgent, l.Latency, l.TraceId)
+	default:
+		buffer, err := json.Marshal(l)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+		return string(buffer)
+	}
+}
+
+// AccessLogger 记录访问日志，需要通过 App.UseAfter 注册
+func AccessLogger(configs ...*AccessLogConfig) HandlerFunc {
+	// 复制一份，不修改调用方的配置
+	c := &AccessLogConfig{}
+	if len(configs) > 0 {
+		x := *configs[0]
+		c = &x
+	}
+	c.apply()
+
+	// Writer 在请求之间共用
+	var lock sync.Mutex
+
+	return func(ctx *Ctx) error {
+		if c.Skip != nil && c.Skip(ctx) {
+			return nil
+		}
+
+		if !c.sample(ctx) {
+			return nil
+		}
+
+		rc := ctx.Context()
+
+		l := &AccessLog{
+			Time:      rc.Time(),
+			Method:    ctx.Method(),
+			Path:      ctx.Path(),
+			Query:     string(rc.QueryArgs().QueryString()),
+			Proto:     string(rc.Request.Header.Protocol()),
+			Status:    rc.Response.StatusCode(),
+			Ip:        rc.RemoteIP().String(),
+			UserAgent: string(rc.UserAgent()),
+			Referer:   string(rc.Referer()),
+			TraceId:   ctx.TranceId(),
+			RequestId: ctx.RequestId(),
+		}
+
+		// 流式的响应(例如静态文件)读取 Body 会将整个流读入内存，使用 Content-Length
+		if ctx.IsBodyStream() {
+			l.Bytes = rc.Response.Header.ContentLength()
+			if l.Bytes < 0 {
+				l.Bytes = 0
+			}
+		} else {
+			l.Bytes = len(rc.Response.Body())
+		}
+
+		// 未经过 fasthttp.Server 的请求(例如测试)没有开始时间
+		if !l.Time.IsZero() {
+			l.Latency = time.Since(l.Time).Milliseconds()
+		}
+
+		if c.User != nil {
+			l.User = c.User(ctx)
+		} else {
+			l.User = ctx.UserId()
+		}
+
+		if c.Tenant != nil {
+			l.Tenant = c.Tenant(ctx)
+		} else {
+			l.Tenant = ctx.TenantId()
+		}
+
+		if c.LogBody {
+			l.Body = c.body(ctx)
+		}
+
+		line := c.format(l)
+		if c.Writer == nil {
+			log.Info(line)
+			return nil
+		}
+
+		lock.Lock()
+		_, err := c.Writer.Write([]byte(line + "\n"))
+		lock.Unlock()
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+
+		return nil
+	}
+}
+
+// RouteWithAccessLogSample 设置单个路由访问日志的采样率，为 0 时不记录
+func RouteWithAccessLogSample(rate float64) RouteOption {
+	return RouteWithMergeExtra(map[string]any{
+		accessLogSampleKey: rate,
+	})
+}
+
+// RouteWithoutAccessLog 关闭单个路由的访问日志
+func RouteWithoutAccessLog() RouteOption {
+	return RouteWithAccessLogSample(0)
+}
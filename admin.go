@@ -0,0 +1,257 @@
+package lrpc
+
+import (
+	"errors"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/utils/json"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type AdminConfig struct {
+	// 路由前缀，默认 /admin
+	Prefix string
+
+	// 返回 false 时拒绝访问，必须设置，管理接口可以修改运行时的状态
+	Auth func(ctx *Ctx) bool
+
+	// 健康检查，名字 -> 检查函数，任意一项失败时 /health 返回 503
+	HealthChecks map[string]func() error
+
+	// 通过 /config 输出的配置，敏感字段会被脱敏
+	Config any
+
+	// 可以通过 /cache/invalidate 按命名空间失效的缓存，名字 -> 缓存
+	Caches map[string]cache.Cache
+
+	// 通过 /stats 输出的容量等统计信息，名字 -> 统计函数，例如 db.Client.TableStats
+	//
+	//	Stats: map[string]func() (any, error){
+	//		"db": func() (any, error) { return cli.TableStats() },
+	//	}
+	Stats map[string]func() (any, error)
+}
+
+func (c *AdminConfig) apply() {
+	if c.Prefix == "" {
+		c.Prefix = "/admin"
+	}
+	c.Prefix = strings.TrimSuffix(c.Prefix, "/")
+
+	if c.Auth == nil {
+		panic("admin auth is required")
+	}
+}
+
+var logLevels = []log.Level{
+	log.TraceLevel,
+	log.DebugLevel,
+	log.InfoLevel,
+	log.WarnLevel,
+	log.ErrorLevel,
+	log.FatalLevel,
+	log.PanicLevel,
+}
+
+func parseLogLevel(s string) (log.Level, bool) {
+	s = strings.ToLower(s)
+	if s == "warning" {
+		s = "warn"
+	}
+
+	for _, level := range logLevels {
+		if level.String() == s {
+			return level, true
+		}
+	}
+
+	return 0, false
+}
+
+type HealthResult struct {
+	Healthy     bool              `json:"healthy"`
+	Maintenance bool              `json:"maintenance"`
+	Checks      map[string]string `json:"checks,omitempty"`
+}
+
+func (p *App) health(c *AdminConfig) *HealthResult {
+	res := &HealthResult{
+		Healthy:     true,
+		Maintenance: p.IsMaintenance(),
+		Checks:      make(map[string]string, len(c.HealthChecks)),
+	}
+
+	for name, check := range c.HealthChecks {
+		err := check()
+		if err != nil {
+			log.Errorf("health check %s err:%v", name, err)
+			res.Healthy = false
+			res.Checks[name] = err.Error()
+			continue
+		}
+		res.Checks[name] = "ok"
+	}
+
+	// 组件启动完成前以及停止过程中不接收流量
+	if !p.IsReady() {
+		res.Healthy = false
+		res.Checks["ready"] = "not ready"
+	}
+
+	return res
+}
+
+// 先转换为 map 再脱敏，字段名以 json 序列化后的为准
+func redactConfig(o any) (map[string]any, error) {
+	if o == nil {
+		return map[string]any{}, nil
+	}
+
+	buf, err := json.Marshal(o)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	var m map[string]any
+	err = json.Unmarshal(buf, &m)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return redact.Map(m), nil
+}
+
+// EnableAdmin 注册运行时管理的接口，包括日志级别、维护模式、健康检查、配置查看以及缓存失效，没有设置 Auth 时 panic
+//
+//	GET  {prefix}/log/level
+//	POST {prefix}/log/level?level=debug
+//	GET  {prefix}/maintenance
+//	POST {prefix}/maintenance?enable=true
+//	GET  {prefix}/health
+//	GET  {prefix}/config
+//	GET  {prefix}/stats
+//	POST {prefix}/cache/invalidate?cache=name&namespace=user:
+func (p *App) EnableAdmin(configs ...*AdminConfig) {
+	c := &AdminConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	routes := []*Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/log/level",
+			Handler: func(ctx *Ctx) error {
+				return ctx.SendJson(map[string]string{"level": log.GetLevel().String()})
+			},
+		},
+		{
+			Method: http.MethodPost,
+			Path:   "/log/level",
+			Handler: func(ctx *Ctx) error {
+				level, ok := parseLogLevel(ctx.Query("level"))
+				if !ok {
+					ctx.SendStatus(fasthttp.StatusBadRequest)
+					return nil
+				}
+
+				log.SetLevel(level)
+				log.Warnf("log level:%s", level)
+
+				return ctx.SendJson(map[string]string{"level": level.String()})
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/health",
+			Handler: func(ctx *Ctx) error {
+				res := p.health(c)
+				if !res.Healthy {
+					ctx.SendStatus(fasthttp.StatusServiceUnavailable)
+				}
+				return ctx.SendJson(res)
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/config",
+			Handler: func(ctx *Ctx) error {
+				m, err := redactConfig(c.Config)
+				if err != nil {
+					log.Errorf("err:%v", err)
+					return err
+				}
+				return ctx.SendJson(m)
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/stats",
+			Handler: func(ctx *Ctx) error {
+				m := make(map[string]any, len(c.Stats))
+				for name, stat := range c.Stats {
+					v, err := stat()
+					if err != nil {
+						log.Errorf("stats %s err:%v", name, err)
+						return err
+					}
+					m[name] = v
+				}
+				return ctx.SendJson(m)
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/cache",
+			Handler: func(ctx *Ctx) error {
+				names := make([]string, 0, len(c.Caches))
+				for name := range c.Caches {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return ctx.SendJson(names)
+			},
+		},
+		{
+			// 不允许空的命名空间，避免误删整个缓存
+			Method: http.MethodPost,
+			Path:   "/cache/invalidate",
+			Handler: func(ctx *Ctx) error {
+				cc, ok := c.Caches[ctx.Query("cache")]
+				namespace := ctx.Query("namespace")
+				if !ok || namespace == "" {
+					ctx.SendStatus(fasthttp.StatusBadRequest)
+					return nil
+				}
+
+				// key 很多时分多次删除，incomplete 为 true 时需要再次调用
+				count, err := cc.Namespace(namespace).InvalidateNamespace()
+				incomplete := errors.Is(err, cache.ErrDelPrefixIncomplete)
+				if err != nil && !incomplete {
+					log.Errorf("err:%v", err)
+					return err
+				}
+
+				log.Warnf("invalidate cache %s namespace %s, count:%d, incomplete:%v", ctx.Query("cache"), namespace, count, incomplete)
+
+				return ctx.SendJson(map[string]any{
+					"count":      count,
+					"incomplete": incomplete,
+				})
+			},
+		},
+	}
+	routes = append(routes, p.maintenanceRoutes()...)
+
+	// 维护期间管理接口依旧可用
+	p.AllowInMaintenance(c.Prefix)
+
+	p.AddRoutes(routes, RouteWithPrefix(c.Prefix), RouteWithBefore(authHandler(c.Auth)), RouteWithoutAccessLog())
+}
@@ -3,6 +3,7 @@ package lrpc
 import (
 	"github.com/valyala/fasthttp"
 	"sync"
+	"sync/atomic"
 )
 
 type App struct {
@@ -12,14 +13,39 @@ type App struct {
 
 	routes map[string]*SearchTree[HandlerFunc]
 
+	// 全局的中间件，before 在路由匹配之前执行，after 在请求处理完成之后执行
+	before, after []HandlerFunc
+
 	ctxPool sync.Pool
 
 	hook *Hooks
+
+	crashCount atomic.Int64
+
+	maintenance     atomic.Bool
+	maintenanceLock sync.RWMutex
+
+	// method + path -> *ResponseCacheConfig，用于按路由失效
+	responseCaches sync.Map
+
+	features atomic.Pointer[featureSet]
+
+	// path -> 自动注册的预检路由合并后的跨域配置，为 nil 时表示用户注册了 OPTIONS 路由
+	preflights map[string]*CorsConfig
+
+	// method -> 以流的方式读取请求体的路由
+	streamRoutes map[string]*SearchTree[bool]
+
+	// 通过 Run 注册的组件，notReady 在组件启动完成前以及开始停止后为 true
+	components []*Component
+	notReady   atomic.Bool
 }
 
 func NewApp(c ...*Config) *App {
 	p := &App{
-		routes: make(map[string]*SearchTree[HandlerFunc]),
+		routes:       make(map[string]*SearchTree[HandlerFunc]),
+		preflights:   make(map[string]*CorsConfig),
+		streamRoutes: make(map[string]*SearchTree[bool]),
 		ctxPool: sync.Pool{
 			New: func() any {
 				return newCtx()
@@ -35,5 +61,10 @@ func NewApp(c ...*Config) *App {
 	p.initConfig()
 	p.initServer()
 
+	p.maintenance.Store(p.maintenanceConfig().Enable)
+	if p.c.Features != nil {
+		p.features.Store(newFeatureSet(p.c.Features))
+	}
+
 	return p
 }
@@ -0,0 +1,232 @@
+package lrpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils"
+	"github.com/lazygophers/utils/stringx"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindQuery 将 query 参数解析到结构体中，参数名依次取 query、json 标签，都没有时为字段名的 snake_case
+// 同名参数出现多次或者以逗号分隔时可以解析为切片，缺失时使用 default 标签，之后按 validate 标签校验
+//
+//	type ListReq struct {
+//		Page   int      `query:"page" default:"1" validate:"min=1"`
+//		Status string   `query:"status" validate:"omitempty,oneof=open closed"`
+//		Ids    []uint64 `query:"id"`
+//	}
+func (p *Ctx) BindQuery(o any) error {
+	args := p.ctx.QueryArgs()
+	return bindValues(o, "query", func(name string) []string {
+		var values []string
+		for _, v := range args.PeekMulti(name) {
+			values = append(values, string(v))
+		}
+		return values
+	})
+}
+
+// BindPath 将路由中的参数（例如 /user/:id）解析到结构体中，规则与 BindQuery 相同，标签为 path
+func (p *Ctx) BindPath(o any) error {
+	return bindValues(o, "path", func(name string) []string {
+		v, ok := p.params[name]
+		if !ok {
+			return nil
+		}
+		return []string{v}
+	})
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 所有字段的错误合并为一个 ErrInvalidParam，字段名与原因放在 Fields 中
+func bindValues(o any, tag string, lookup func(name string) []string) error {
+	rv := reflect.ValueOf(o)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic("bind target must be a pointer to struct")
+	}
+
+	fieldErrs := make(map[string]string)
+	names := make(map[string]string)
+	bindStruct(rv.Elem(), tag, lookup, names, fieldErrs)
+
+	if len(fieldErrs) == 0 {
+		err := utils.Validate(o)
+		if err != nil {
+			var ve validator.ValidationErrors
+			if !errors.As(err, &ve) {
+				return err
+			}
+
+			for _, fe := range ve {
+				name, ok := names[fe.StructField()]
+				if !ok {
+					name = fe.Field()
+				}
+
+				if fe.Param() != "" {
+					fieldErrs[name] = fe.Tag() + "=" + fe.Param()
+				} else {
+					fieldErrs[name] = fe.Tag()
+				}
+			}
+		}
+	}
+
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fieldErrs))
+	for k := range fieldErrs {
+		keys = append(keys, k)
+	}
+	// 保证消息稳定
+	sort.Strings(keys)
+
+	x := xerror.NewInvalidParam("invalid param: ", strings.Join(keys, ", "))
+	for _, k := range keys {
+		x.WithField(k, fieldErrs[k])
+	}
+
+	return x
+}
+
+func bindStruct(rv reflect.Value, tag string, lookup func(name string) []string, names, fieldErrs map[string]string) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			bindStruct(rv.Field(i), tag, lookup, names, fieldErrs)
+			continue
+		}
+
+		name := bindName(field, tag)
+		if name == "" {
+			continue
+		}
+		names[field.Name] = name
+
+		values := lookup(name)
+		if len(values) == 0 {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			values = []string{def}
+		}
+
+		err := setField(rv.Field(i), values)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			fieldErrs[name] = err.Error()
+		}
+	}
+}
+
+func bindName(field reflect.StructField, tag string) string {
+	name := field.Tag.Get(tag)
+	if name == "" {
+		name = strings.Split(field.Tag.Get("json"), ",")[0]
+	}
+
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return stringx.Camel2Snake(field.Name)
+	default:
+		return name
+	}
+}
+
+func setField(fv reflect.Value, values []string) error {
+	if fv.Kind() == reflect.Ptr {
+		v := reflect.New(fv.Type().Elem())
+		err := setField(v.Elem(), values)
+		if err != nil {
+			return err
+		}
+		fv.Set(v)
+		return nil
+	}
+
+	if fv.Kind() == reflect.Slice {
+		var list []string
+		for _, v := range values {
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(fv.Type(), len(list), len(list))
+		for i, s := range list {
+			err := setValue(slice.Index(i), s)
+			if err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	return setValue(fv, values[0])
+}
+
+func setValue(fv reflect.Value, s string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", s)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid int %q", s)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid uint %q", s)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float %q", s)
+		}
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
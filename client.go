@@ -6,21 +6,31 @@ import (
 	"github.com/lazygophers/lrpc/middleware/xerror"
 	"github.com/valyala/fasthttp"
 	"google.golang.org/protobuf/proto"
+	"time"
 )
 
 var DiscoveryClient = func(c *core.ServiceDiscoveryClient) (*fasthttp.HostClient, *fasthttp.Request) {
 	panic("you should registe with discovery github.com/lazygophers/lrpc/middleware/service_discovery")
 }
 
+// Call 使用 protobuf 调用，其他编码见 CallOptions.Codec
 func Call(ctx *Ctx, c *core.ServiceDiscoveryClient, req proto.Message, rsp proto.Message) error {
+	return call(ctx, c, req, rsp, ProtoCodec)
+}
+
+func call(ctx *Ctx, c *core.ServiceDiscoveryClient, req proto.Message, rsp proto.Message, codec Codec) error {
 	var response fasthttp.Response
 	client, request := DiscoveryClient(c)
 
-	request.Header.Set(HeaderContentType, MIMEProtobuf)
+	request.Header.Set(HeaderContentType, codec.ContentType())
+	request.Header.Set(HeaderAccept, codec.ContentType())
 	request.Header.Set(HeaderTrance, ctx.TranceId())
+	if ctx.RequestId() != "" {
+		request.Header.Set(HeaderRequestId, ctx.RequestId())
+	}
 
 	if req != nil {
-		buffer, err := proto.Marshal(req)
+		buffer, err := codec.Marshal(req)
 		if err != nil {
 			log.Errorf("err:%v", err)
 			return err
@@ -34,18 +44,35 @@ func Call(ctx *Ctx, c *core.ServiceDiscoveryClient, req proto.Message, rsp proto
 		return err
 	}
 
+	retryAfter := parseRetryAfter(response.Header.Peek(HeaderRetryAfter))
+
 	log.Info(response.Body())
 	log.Info(string(response.Header.ContentType()))
 
+	// 服务端不支持请求的编码时按响应的 Content-Type 解析
+	rspCodec := GetCodec(string(response.Header.ContentType()))
+	if rspCodec == nil {
+		rspCodec = codec
+	}
+
 	baseResp := &core.BaseResponse{}
-	err = proto.Unmarshal(response.Body(), baseResp)
+	err = rspCodec.Unmarshal(response.Body(), baseResp)
 	if err != nil {
 		log.Errorf("err:%v", err)
+
+		// 限流、维护等由框架直接返回的响应
+		switch response.StatusCode() {
+		case fasthttp.StatusTooManyRequests:
+			return xerror.NewRateLimited(retryAfter)
+		case fasthttp.StatusServiceUnavailable:
+			return xerror.NewUnavailable(retryAfter)
+		}
+
 		return err
 	}
 
 	if baseResp.Code != 0 {
-		return xerror.NewErrorWithMsg(baseResp.Code, baseResp.Message)
+		return xerror.NewErrorWithMsg(baseResp.Code, baseResp.Message).WithRetryAfter(retryAfter)
 	}
 
 	if rsp != nil {
@@ -58,3 +85,67 @@ func Call(ctx *Ctx, c *core.ServiceDiscoveryClient, req proto.Message, rsp proto
 
 	return nil
 }
+
+// CallHook 每次调用(包括重试)结束后执行，可用于链路追踪、监控
+type CallHook func(ctx *Ctx, c *core.ServiceDiscoveryClient, err error, cost time.Duration)
+
+type CallOptions struct {
+	// 网络错误时的重试次数，业务错误(xerror)只有 xerror.IsRetryable 的会重试
+	Retry int
+
+	RetryInterval time.Duration
+
+	// 服务端返回的 Retry-After 大于 RetryInterval 时使用 Retry-After，超过 MaxRetryAfter 时不再重试，为 0 时不限制
+	MaxRetryAfter time.Duration
+
+	// 请求与响应的编码，需要能处理 proto.Message，默认 ProtoCodec
+	Codec Codec
+
+	Hooks []CallHook
+}
+
+func CallWithOptions(ctx *Ctx, c *core.ServiceDiscoveryClient, req proto.Message, rsp proto.Message, opts *CallOptions) (err error) {
+	if opts == nil {
+		return Call(ctx, c, req, rsp)
+	}
+
+	codec := opts.Codec
+	if codec == nil {
+		codec = ProtoCodec
+	}
+
+	wait := opts.RetryInterval
+	for i := 0; i <= opts.Retry; i++ {
+		if i > 0 && wait > 0 {
+			time.Sleep(wait)
+		}
+
+		start := time.Now()
+		err = call(ctx, c, req, rsp, codec)
+		for _, hook := range opts.Hooks {
+			hook(ctx, c, err, time.Since(start))
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := xerror.GetCode(err); ok && !xerror.IsRetryable(err) {
+			return err
+		}
+
+		wait = opts.RetryInterval
+		if retryAfter, ok := xerror.GetRetryAfter(err); ok {
+			if opts.MaxRetryAfter > 0 && retryAfter > opts.MaxRetryAfter {
+				return err
+			}
+			if retryAfter > wait {
+				wait = retryAfter
+			}
+		}
+
+		log.Warnf("call %s%s failed, retry:%d, err:%v", c.ServiceName, c.ServicePath, i, err)
+	}
+
+	return err
+}
@@ -0,0 +1,152 @@
+// protoc-gen-lrpc 根据 proto 中的 service 生成 lrpc 的路由注册、handler 接口以及客户端
+//
+//	protoc --go_out=. --lrpc_out=. xxx.proto
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/lazygophers/utils/stringx"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+	"strings"
+)
+
+const version = "0.1.0"
+
+const (
+	lrpcPackage = protogen.GoImportPath("github.com/lazygophers/lrpc")
+	corePackage = protogen.GoImportPath("github.com/lazygophers/lrpc/middleware/core")
+	httpPackage = protogen.GoImportPath("net/http")
+)
+
+func main() {
+	var flags flag.FlagSet
+	pathPrefix := flags.String("path_prefix", "", "prefix of generated route path")
+
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		generate(gen, *pathPrefix)
+		return nil
+	})
+}
+
+func generate(gen *protogen.Plugin, pathPrefix string) {
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+	for _, f := range gen.Files {
+		if !f.Generate || len(f.Services) == 0 {
+			continue
+		}
+
+		generateFile(gen, f, pathPrefix)
+	}
+}
+
+func routePath(prefix string, service *protogen.Service, method *protogen.Method) string {
+	return fmt.Sprintf("%s/%s/%s",
+		strings.TrimSuffix(prefix, "/"),
+		stringx.ToSnake(service.GoName),
+		stringx.ToSnake(method.GoName))
+}
+
+func generateFile(gen *protogen.Plugin, file *protogen.File, prefix string) {
+	g := gen.NewGeneratedFile(file.GeneratedFilenamePrefix+"_lrpc.pb.go", file.GoImportPath)
+
+	g.P("// Code generated by protoc-gen-lrpc. DO NOT EDIT.")
+	g.P("// versions:")
+	g.P("// - protoc-gen-lrpc v", version)
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	for _, service := range file.Services {
+		generateService(g, service, prefix)
+	}
+}
+
+func generateService(g *protogen.GeneratedFile, service *protogen.Service, prefix string) {
+	var methods []*protogen.Method
+	for _, method := range service.Methods {
+		if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+			continue
+		}
+		methods = append(methods, method)
+	}
+
+	serverName := service.GoName + "Server"
+	clientName := service.GoName + "Client"
+
+	// 路由
+	g.P("const (")
+	for _, method := range methods {
+		g.P(service.GoName, "_", method.GoName, "_Path = ", fmt.Sprintf("%q", routePath(prefix, service, method)))
+	}
+	g.P(")")
+	g.P()
+
+	// 服务端
+	g.P("type ", serverName, " interface {")
+	for _, method := range methods {
+		g.P(method.Comments.Leading, method.GoName, "(ctx *", g.QualifiedGoIdent(lrpcPackage.Ident("Ctx")), ", req *", g.QualifiedGoIdent(method.Input.GoIdent), ") (*", g.QualifiedGoIdent(method.Output.GoIdent), ", error)")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("func Register", serverName, "(app *", lrpcPackage.Ident("App"), ", srv ", serverName, ", opts ...", lrpcPackage.Ident("RouteOption"), ") {")
+	g.P("app.AddRoutes([]*", lrpcPackage.Ident("Route"), "{")
+	for _, method := range methods {
+		g.P("{")
+		g.P("Method: ", httpPackage.Ident("MethodPost"), ",")
+		g.P("Path: ", service.GoName, "_", method.GoName, "_Path,")
+		g.P("Handler: app.ToHandlerFunc(func(ctx *", lrpcPackage.Ident("Ctx"), ", req *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error) {")
+		g.P("// 请求实现了 Validate 时(例如 protoc-gen-validate)先进行校验")
+		g.P("if v, ok := any(req).(interface{ Validate() error }); ok {")
+		g.P("if err := v.Validate(); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("}")
+		g.P("return srv.", method.GoName, "(ctx, req)")
+		g.P("}),")
+		g.P("},")
+	}
+	g.P("}, opts...)")
+	g.P("}")
+	g.P()
+
+	// 客户端
+	g.P("type ", clientName, " struct {")
+	g.P("serviceName string")
+	g.P("opts *", lrpcPackage.Ident("CallOptions"))
+	g.P("}")
+	g.P()
+
+	g.P("func New", clientName, "(serviceName string, opts ...*", lrpcPackage.Ident("CallOptions"), ") *", clientName, " {")
+	g.P("p := &", clientName, "{")
+	g.P("serviceName: serviceName,")
+	g.P("}")
+	g.P("if len(opts) > 0 {")
+	g.P("p.opts = opts[0]")
+	g.P("}")
+	g.P("return p")
+	g.P("}")
+	g.P()
+
+	for _, method := range methods {
+		g.P(method.Comments.Leading, "func (p *", clientName, ") ", method.GoName, "(ctx *", lrpcPackage.Ident("Ctx"), ", req *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error) {")
+		g.P("rsp := &", method.Output.GoIdent, "{}")
+		g.P("err := ", lrpcPackage.Ident("CallWithOptions"), "(ctx, &", corePackage.Ident("ServiceDiscoveryClient"), "{")
+		g.P("ServiceName: p.serviceName,")
+		g.P("ServicePath: ", service.GoName, "_", method.GoName, "_Path,")
+		g.P("Method: ", httpPackage.Ident("MethodPost"), ",")
+		g.P("}, req, rsp, p.opts)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return rsp, nil")
+		g.P("}")
+		g.P()
+	}
+}
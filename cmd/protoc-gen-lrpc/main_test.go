@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"github.com/lazygophers/lrpc/cmd/protoc-gen-lrpc/testdata/greeter"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+	"os"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// testdata/greeter 中的 greeter.pb.go 由 protoc-gen-go 生成，greeter_lrpc.pb.go 为期望的输出
+// 测试引用了 greeter，期望的输出需要能够编译通过
+func TestGenerate(t *testing.T) {
+	const golden = "testdata/greeter/greeter_lrpc.pb.go"
+
+	file := protodesc.ToFileDescriptorProto(greeter.File_greeter_proto)
+	gen, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      []*descriptorpb.FileDescriptorProto{file},
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	generate(gen, "/api")
+
+	rsp := gen.Response()
+	if rsp.Error != nil || len(rsp.File) != 1 {
+		t.Fatalf("err:%s, files:%d", rsp.GetError(), len(rsp.File))
+	}
+
+	content := rsp.File[0].GetContent()
+	if *update {
+		err = os.WriteFile(golden, []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if content != string(want) {
+		t.Errorf("output differs from %s, run go test -update to regenerate\n%s", golden, content)
+	}
+}
@@ -0,0 +1,94 @@
+// Code generated by protoc-gen-lrpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-lrpc v0.1.0
+// source: greeter.proto
+
+package greeter
+
+import (
+	lrpc "github.com/lazygophers/lrpc"
+	core "github.com/lazygophers/lrpc/middleware/core"
+	http "net/http"
+)
+
+const (
+	Greeter_SayHello_Path      = "/api/greeter/say_hello"
+	Greeter_SayHelloAgain_Path = "/api/greeter/say_hello_again"
+)
+
+type GreeterServer interface {
+	SayHello(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error)
+	SayHelloAgain(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error)
+}
+
+func RegisterGreeterServer(app *lrpc.App, srv GreeterServer, opts ...lrpc.RouteOption) {
+	app.AddRoutes([]*lrpc.Route{
+		{
+			Method: http.MethodPost,
+			Path:   Greeter_SayHello_Path,
+			Handler: app.ToHandlerFunc(func(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error) {
+				// 请求实现了 Validate 时(例如 protoc-gen-validate)先进行校验
+				if v, ok := any(req).(interface{ Validate() error }); ok {
+					if err := v.Validate(); err != nil {
+						return nil, err
+					}
+				}
+				return srv.SayHello(ctx, req)
+			}),
+		},
+		{
+			Method: http.MethodPost,
+			Path:   Greeter_SayHelloAgain_Path,
+			Handler: app.ToHandlerFunc(func(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error) {
+				// 请求实现了 Validate 时(例如 protoc-gen-validate)先进行校验
+				if v, ok := any(req).(interface{ Validate() error }); ok {
+					if err := v.Validate(); err != nil {
+						return nil, err
+					}
+				}
+				return srv.SayHelloAgain(ctx, req)
+			}),
+		},
+	}, opts...)
+}
+
+type GreeterClient struct {
+	serviceName string
+	opts        *lrpc.CallOptions
+}
+
+func NewGreeterClient(serviceName string, opts ...*lrpc.CallOptions) *GreeterClient {
+	p := &GreeterClient{
+		serviceName: serviceName,
+	}
+	if len(opts) > 0 {
+		p.opts = opts[0]
+	}
+	return p
+}
+
+func (p *GreeterClient) SayHello(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error) {
+	rsp := &HelloReply{}
+	err := lrpc.CallWithOptions(ctx, &core.ServiceDiscoveryClient{
+		ServiceName: p.serviceName,
+		ServicePath: Greeter_SayHello_Path,
+		Method:      http.MethodPost,
+	}, req, rsp, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (p *GreeterClient) SayHelloAgain(ctx *lrpc.Ctx, req *HelloRequest) (*HelloReply, error) {
+	rsp := &HelloReply{}
+	err := lrpc.CallWithOptions(ctx, &core.ServiceDiscoveryClient{
+		ServiceName: p.serviceName,
+		ServicePath: Greeter_SayHelloAgain_Path,
+		Method:      http.MethodPost,
+	}, req, rsp, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
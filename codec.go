@@ -0,0 +1,175 @@
+package lrpc
+
+import (
+	"errors"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/lazygophers/utils/json"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"strings"
+)
+
+// Codec 请求与响应的序列化方式，请求按 Content-Type 选择，响应按 Accept 协商
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var ErrNotProtoMessage = errors.New("not proto message")
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return MIMEJson
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string {
+	return MIMEProtobuf
+}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string {
+	return MIMEMsgpack
+}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) ContentType() string {
+	return MIMECbor
+}
+
+func (cborCodec) Marshal(v any) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborCodec) Unmarshal(data []byte, v any) error {
+	return cbor.Unmarshal(data, v)
+}
+
+var (
+	JsonCodec    Codec = jsonCodec{}
+	ProtoCodec   Codec = protoCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	CborCodec    Codec = cborCodec{}
+)
+
+// 只在启动前注册，运行时只读
+var codecs = map[string]Codec{
+	MIMEJson:     JsonCodec,
+	MIMEProtobuf: ProtoCodec,
+	MIMEMsgpack:  MsgpackCodec,
+	MIMECbor:     CborCodec,
+}
+
+// RegisterCodec 注册或覆盖某个 Content-Type 的 Codec，需要在服务启动前调用
+func RegisterCodec(c Codec) {
+	codecs[c.ContentType()] = c
+}
+
+// GetCodec 根据 Content-Type 获取 Codec，不存在时返回 nil
+func GetCodec(contentType string) Codec {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	return codecs[strings.ToLower(strings.TrimSpace(contentType))]
+}
+
+func canEncode(c Codec, v any) bool {
+	if c != ProtoCodec {
+		return true
+	}
+
+	_, ok := v.(proto.Message)
+	return ok
+}
+
+// 不能处理的类型(例如 protobuf 遇到非 proto.Message)回退到 json
+func codecFor(contentType string, v any) Codec {
+	c := GetCodec(contentType)
+	if c == nil || !canEncode(c, v) {
+		return JsonCodec
+	}
+
+	return c
+}
+
+// 按 Accept 选择 Codec，q 值大的优先，相同时按出现的顺序，没有可用的 Codec 时返回 nil
+func acceptCodec(accept string, v any) Codec {
+	var best Codec
+	var bestQ float64
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(item, ";")
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+
+			var err error
+			q, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				q = 0
+			}
+		}
+
+		if q <= bestQ {
+			continue
+		}
+
+		c := GetCodec(mediaType)
+		if c == nil || !canEncode(c, v) {
+			continue
+		}
+
+		best, bestQ = c, q
+	}
+
+	return best
+}
+
+// 响应按 Accept 协商，没有可用的 Codec 时与请求的 Content-Type 保持一致
+func responseCodec(accept, contentType string, v any) Codec {
+	c := acceptCodec(accept, v)
+	if c == nil {
+		return codecFor(contentType, v)
+	}
+
+	return c
+}
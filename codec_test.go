@@ -0,0 +1,185 @@
+package lrpc_test
+
+import (
+	"github.com/lazygophers/lrpc"
+	"github.com/lazygophers/lrpc/middleware/core"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"net"
+	"testing"
+)
+
+type codecItem struct {
+	Id   int64    `json:"id" msgpack:"id" cbor:"id"`
+	Name string   `json:"name" msgpack:"name" cbor:"name"`
+	Tags []string `json:"tags" msgpack:"tags" cbor:"tags"`
+}
+
+func TestCodec(t *testing.T) {
+	in := &codecItem{Id: 1, Name: "alice", Tags: []string{"a", "b"}}
+	for _, c := range []lrpc.Codec{lrpc.JsonCodec, lrpc.MsgpackCodec, lrpc.CborCodec} {
+		buffer, err := c.Marshal(in)
+		if err != nil {
+			t.Fatalf("%s: err:%v", c.ContentType(), err)
+		}
+
+		var out codecItem
+		err = c.Unmarshal(buffer, &out)
+		if err != nil || out.Id != in.Id || out.Name != in.Name || len(out.Tags) != 2 {
+			t.Errorf("%s: out:%+v, err:%v", c.ContentType(), out, err)
+		}
+
+		if lrpc.GetCodec(c.ContentType()+"; charset=utf-8") != c {
+			t.Errorf("%s: codec not found", c.ContentType())
+		}
+	}
+
+	buffer, err := lrpc.ProtoCodec.Marshal(&core.Paginate{Limit: 10, Total: 3})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	var page core.Paginate
+	err = lrpc.ProtoCodec.Unmarshal(buffer, &page)
+	if err != nil || page.Limit != 10 || page.Total != 3 {
+		t.Errorf("page:%v, err:%v", &page, err)
+	}
+
+	_, err = lrpc.ProtoCodec.Marshal(in)
+	if err != lrpc.ErrNotProtoMessage {
+		t.Errorf("err:%v", err)
+	}
+}
+
+func TestCodecNegotiation(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Post("/item", func(ctx *lrpc.Ctx) error {
+		return ctx.SendJson(&codecItem{Id: 1, Name: "alice"})
+	})
+
+	call := func(contentType, accept string) string {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodPost)
+		c.Request.SetRequestURI("/item")
+		if contentType != "" {
+			c.Request.Header.Set(lrpc.HeaderContentType, contentType)
+		}
+		if accept != "" {
+			c.Request.Header.Set(lrpc.HeaderAccept, accept)
+		}
+		app.Handler(&c)
+
+		var out codecItem
+		codec := lrpc.GetCodec(string(c.Response.Header.ContentType()))
+		if codec == nil || codec.Unmarshal(c.Response.Body(), &out) != nil || out.Name != "alice" {
+			t.Errorf("content type:%s, body:%q", c.Response.Header.ContentType(), c.Response.Body())
+		}
+		return string(c.Response.Header.ContentType())
+	}
+
+	for _, tc := range []struct {
+		contentType, accept, want string
+	}{
+		{"", "", lrpc.MIMEJson},
+		{lrpc.MIMEMsgpack, "", lrpc.MIMEMsgpack},
+		{lrpc.MIMEJson, lrpc.MIMECbor, lrpc.MIMECbor},
+		{"", "application/cbor;q=0.5, application/msgpack", lrpc.MIMEMsgpack},
+		{"", "application/msgpack;q=0, application/cbor;q=0.1", lrpc.MIMECbor},
+		// 没有可用的 Codec 时与请求保持一致
+		{lrpc.MIMECbor, "text/html, */*", lrpc.MIMECbor},
+		// protobuf 不能处理非 proto.Message
+		{lrpc.MIMEMsgpack, lrpc.MIMEProtobuf, lrpc.MIMEMsgpack},
+	} {
+		if got := call(tc.contentType, tc.accept); got != tc.want {
+			t.Errorf("content type:%q, accept:%q, got:%s, want:%s", tc.contentType, tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestCallCodec(t *testing.T) {
+	var contentTypes []string
+
+	app := lrpc.NewApp()
+	app.Post("/page", app.ToHandlerFunc(func(ctx *lrpc.Ctx, req *core.Paginate) (*core.Paginate, error) {
+		contentTypes = append(contentTypes, ctx.Header(lrpc.HeaderContentType))
+		return &core.Paginate{Limit: req.Limit, Total: req.Limit * 2}, nil
+	}))
+
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		_ = fasthttp.Serve(ln, app.Handler)
+	}()
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	discovery := lrpc.DiscoveryClient
+	lrpc.DiscoveryClient = func(c *core.ServiceDiscoveryClient) (*fasthttp.HostClient, *fasthttp.Request) {
+		request := &fasthttp.Request{}
+		request.SetRequestURI("http://test" + c.ServicePath)
+		request.Header.SetMethod(fasthttp.MethodPost)
+		return &fasthttp.HostClient{
+			Addr: "test",
+			Dial: func(addr string) (net.Conn, error) {
+				return ln.Dial()
+			},
+		}, request
+	}
+	t.Cleanup(func() {
+		lrpc.DiscoveryClient = discovery
+	})
+
+	var c fasthttp.RequestCtx
+	ctx := app.AcquireCtx(&c)
+	defer app.ReleaseCtx(ctx)
+
+	client := &core.ServiceDiscoveryClient{ServiceName: "test", ServicePath: "/page"}
+
+	var rsp core.Paginate
+	err := lrpc.Call(ctx, client, &core.Paginate{Limit: 5}, &rsp)
+	if err != nil || rsp.Total != 10 {
+		t.Fatalf("rsp:%v, err:%v", &rsp, err)
+	}
+
+	rsp.Reset()
+	err = lrpc.CallWithOptions(ctx, client, &core.Paginate{Limit: 7}, &rsp, &lrpc.CallOptions{
+		Codec: lrpc.JsonCodec,
+	})
+	if err != nil || rsp.Total != 14 {
+		t.Fatalf("rsp:%v, err:%v", &rsp, err)
+	}
+
+	if len(contentTypes) != 2 || contentTypes[0] != lrpc.MIMEProtobuf || contentTypes[1] != lrpc.MIMEJson {
+		t.Errorf("content types:%v", contentTypes)
+	}
+}
+
+func BenchmarkCodec(b *testing.B) {
+	item := &codecItem{Id: 1, Name: "alice", Tags: []string{"a", "b", "c"}}
+	page := &core.Paginate{Offset: 20, Limit: 10, Total: 1000}
+
+	for _, tc := range []struct {
+		codec lrpc.Codec
+		in    any
+		out   func() any
+	}{
+		{lrpc.JsonCodec, item, func() any { return &codecItem{} }},
+		{lrpc.MsgpackCodec, item, func() any { return &codecItem{} }},
+		{lrpc.CborCodec, item, func() any { return &codecItem{} }},
+		{lrpc.ProtoCodec, page, func() any { return &core.Paginate{} }},
+	} {
+		b.Run(tc.codec.ContentType(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buffer, err := tc.codec.Marshal(tc.in)
+				if err != nil {
+					b.Fatalf("err:%v", err)
+				}
+
+				err = tc.codec.Unmarshal(buffer, tc.out())
+				if err != nil {
+					b.Fatalf("err:%v", err)
+				}
+			}
+		})
+	}
+}
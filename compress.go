@@ -0,0 +1,293 @@
+package lrpc
+
+import (
+	"bytes"
+	"errors"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/valyala/fasthttp"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	HeaderAcceptEncoding  = "Accept-Encoding"
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderVary            = "Vary"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingZstd    = "zstd"
+)
+
+type CompressConfig struct {
+	// 压缩等级，对应 fasthttp.CompressDefaultCompression 等
+	Level int
+
+	// 小于该大小的响应不压缩，默认 1024
+	MinSize int
+
+	// 允许压缩的 Content-Type 前缀，为空时使用默认值
+	ContentTypes []string
+
+	// 编码的优先级，默认 zstd > gzip > deflate
+	Encodings []string
+
+	// 请求体解压之后的最大大小，默认 32M
+	MaxDecompressSize int
+}
+
+var defaultCompressContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	MIMEProtobuf,
+}
+
+func (c *CompressConfig) apply() {
+	if c.Level == 0 {
+		c.Level = fasthttp.CompressDefaultCompression
+	}
+
+	if c.MinSize == 0 {
+		c.MinSize = 1024
+	}
+
+	if len(c.ContentTypes) == 0 {
+		c.ContentTypes = defaultCompressContentTypes
+	}
+
+	if len(c.Encodings) == 0 {
+		c.Encodings = []string{EncodingZstd, EncodingGzip, EncodingDeflate}
+	}
+
+	if c.MaxDecompressSize == 0 {
+		c.MaxDecompressSize = 32 * 1024 * 1024
+	}
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+)
+
+// zstd 的 EncodeAll 是并发安全的，全局共用一个即可
+func initZstd() {
+	zstdOnce.Do(func() {
+		var err error
+		zstdEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			log.Panicf("err:%v", err)
+		}
+	})
+}
+
+func acceptEncodings(header string) map[string]bool {
+	accept := make(map[string]bool)
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		encoding, params, _ := strings.Cut(item, ";")
+		params = strings.ReplaceAll(params, " ", "")
+		if params == "q=0" || params == "q=0.0" {
+			continue
+		}
+
+		accept[strings.ToLower(strings.TrimSpace(encoding))] = true
+	}
+	return accept
+}
+
+func (c *CompressConfig) allowContentType(contentType string) bool {
+	for _, prefix := range c.ContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CompressConfig) compress(encoding string, body []byte) []byte {
+	switch encoding {
+	case EncodingGzip:
+		return fasthttp.AppendGzipBytesLevel(nil, body, c.Level)
+	case EncodingDeflate:
+		return fasthttp.AppendDeflateBytesLevel(nil, body, c.Level)
+	case EncodingZstd:
+		initZstd()
+		return zstdEncoder.EncodeAll(body, make([]byte, 0, len(body)/2))
+	default:
+		return nil
+	}
+}
+
+// Compress 根据 Accept-Encoding 压缩响应，需要通过 App.UseAfter 注册
+func Compress(configs ...*CompressConfig) HandlerFunc {
+	// 复制一份，不修改调用方的配置
+	c := &CompressConfig{}
+	if len(configs) > 0 {
+		x := *configs[0]
+		c = &x
+	}
+	c.apply()
+
+	return func(ctx *Ctx) error {
+		resp := &ctx.Context().Response
+
+		if ctx.IsBodyStream() || len(resp.Header.Peek(HeaderContentEncoding)) > 0 {
+			return nil
+		}
+
+		if ctx.Method() == fasthttp.MethodHead {
+			return nil
+		}
+
+		switch resp.StatusCode() {
+		case fasthttp.StatusNoContent, fasthttp.StatusNotModified:
+			return nil
+		}
+
+		body := resp.Body()
+		if len(body) < c.MinSize {
+			return nil
+		}
+
+		if !c.allowContentType(string(resp.Header.ContentType())) {
+			return nil
+		}
+
+		resp.Header.Add(HeaderVary, HeaderAcceptEncoding)
+
+		accept := acceptEncodings(ctx.Header(HeaderAcceptEncoding))
+		for _, encoding := range c.Encodings {
+			if !accept[encoding] {
+				continue
+			}
+
+			buf := c.compress(encoding, body)
+			if len(buf) == 0 || len(buf) >= len(body) {
+				return nil
+			}
+
+			resp.SetBodyRaw(buf)
+			ctx.SetHeader(HeaderContentEncoding, encoding)
+			return nil
+		}
+
+		return nil
+	}
+}
+
+var errDecompressTooLarge = errors.New("decompressed body too large")
+
+// 流式解压，zstd 的窗口大小同样受 maxSize 限制，避免解压之前就分配过大的内存
+func (c *CompressConfig) decompressReader(encoding string, body []byte, zstdPool *sync.Pool) (io.Reader, func(), error) {
+	switch encoding {
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, func() { _ = r.Close() }, nil
+
+	case EncodingDeflate:
+		// 与 fasthttp 一致，deflate 为 zlib 格式
+		r, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		return r, func() { _ = r.Close() }, nil
+
+	case EncodingZstd:
+		var d *zstd.Decoder
+		if v := zstdPool.Get(); v != nil {
+			d = v.(*zstd.Decoder)
+		} else {
+			var err error
+			d, err = zstd.NewReader(nil,
+				zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderLowmem(true),
+				zstd.WithDecoderMaxMemory(uint64(c.MaxDecompressSize)))
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		err := d.Reset(bytes.NewReader(body))
+		if err != nil {
+			d.Close()
+			return nil, nil, err
+		}
+		return d, func() {
+			_ = d.Reset(nil)
+			zstdPool.Put(d)
+		}, nil
+
+	default:
+		return nil, nil, nil
+	}
+}
+
+// Decompress 解压带有 Content-Encoding 的请求体，需要通过 App.Use 注册
+// 解压后超过 MaxDecompressSize 时立即停止并返回 413
+func Decompress(configs ...*CompressConfig) HandlerFunc {
+	// 复制一份，不修改调用方的配置
+	c := &CompressConfig{}
+	if len(configs) > 0 {
+		x := *configs[0]
+		c = &x
+	}
+	c.apply()
+
+	var zstdPool sync.Pool
+
+	return func(ctx *Ctx) error {
+		req := &ctx.Context().Request
+
+		encoding := strings.ToLower(strings.TrimSpace(string(req.Header.Peek(HeaderContentEncoding))))
+		if encoding == "" || encoding == "identity" {
+			return nil
+		}
+
+		r, release, err := c.decompressReader(encoding, req.Body(), &zstdPool)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return xerror.NewInvalidParam("invalid compressed body")
+		}
+		if r == nil {
+			return xerror.NewInvalidParam("unsupported content encoding ", encoding)
+		}
+		defer release()
+
+		body, err := io.ReadAll(io.LimitReader(r, int64(c.MaxDecompressSize)+1))
+		if err != nil {
+			if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+				err = errDecompressTooLarge
+			} else {
+				log.Errorf("err:%v", err)
+				return xerror.NewInvalidParam("invalid compressed body")
+			}
+		}
+
+		if err != nil || len(body) > c.MaxDecompressSize {
+			log.Errorf("err:%v", errDecompressTooLarge)
+			ctx.SendStatus(fasthttp.StatusRequestEntityTooLarge)
+			return xerror.NewInvalidParam(errDecompressTooLarge)
+		}
+
+		req.SetBodyRaw(body)
+		req.Header.Del(HeaderContentEncoding)
+
+		return nil
+	}
+}
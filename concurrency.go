@@ -0,0 +1,105 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"sync/atomic"
+	"time"
+)
+
+// ConcurrencyConfig 限制单个路由同时处理的请求数，避免慢接口占满全部的处理能力
+type ConcurrencyConfig struct {
+	// 同时处理的请求数，为 0 时不限制
+	Limit int
+
+	// 超过 Limit 时排队等待的最长时间，为 0 时直接返回 503
+	QueueTimeout time.Duration
+
+	// 排队的最大请求数，为 0 时只受 QueueTimeout 限制
+	MaxQueue int
+
+	// 返回 503 时的 Retry-After，默认 1 秒
+	RetryAfter time.Duration
+
+	// 用于单个路由关闭全局配置
+	Disable bool
+}
+
+func (c *ConcurrencyConfig) apply() {
+	if c.RetryAfter <= 0 {
+		c.RetryAfter = time.Second
+	}
+}
+
+// 每个路由单独的信号量，超时返回的请求在后台执行完成之前依旧占用
+func (p *App) concurrencyHandler(handler HandlerFunc, r *Route, c *ConcurrencyConfig) HandlerFunc {
+	c.apply()
+
+	sem := make(chan struct{}, c.Limit)
+	var queued atomic.Int64
+
+	reject := func(ctx *Ctx) error {
+		log.Warnf("concurrency limit exceeded, method:%s, path:%s, limit:%d", r.Method, r.Path, c.Limit)
+		return xerror.NewUnavailable(c.RetryAfter)
+	}
+
+	return func(ctx *Ctx) error {
+		select {
+		case sem <- struct{}{}:
+		default:
+			if c.QueueTimeout <= 0 {
+				return reject(ctx)
+			}
+
+			n := queued.Add(1)
+			defer queued.Add(-1)
+			if c.MaxQueue > 0 && n > int64(c.MaxQueue) {
+				return reject(ctx)
+			}
+
+			timer := time.NewTimer(c.QueueTimeout)
+			select {
+			case sem <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				return reject(ctx)
+			}
+		}
+		defer func() {
+			<-sem
+		}()
+
+		return handler(ctx)
+	}
+}
+
+func (p *App) concurrencyConfig(r *Route) *ConcurrencyConfig {
+	c := r.Concurrency
+	if c == nil {
+		c = p.c.Concurrency
+	}
+
+	if c == nil || c.Disable || c.Limit <= 0 {
+		return nil
+	}
+
+	// 全局配置会被多个路由共用，复制一份避免 apply 时并发修改
+	cc := *c
+	return &cc
+}
+
+// RouteWithConcurrency 单个路由的并发限制，例如报表导出等慢接口
+func RouteWithConcurrency(c *ConcurrencyConfig) RouteOption {
+	return func(r *Route) {
+		r.Concurrency = c
+	}
+}
+
+// RouteWithoutConcurrency 关闭单个路由的并发限制
+func RouteWithoutConcurrency() RouteOption {
+	return func(r *Route) {
+		r.Concurrency = &ConcurrencyConfig{
+			Disable: true,
+		}
+	}
+}
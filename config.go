@@ -6,6 +6,7 @@ import (
 	"github.com/lazygophers/lrpc/middleware/core"
 	"github.com/lazygophers/lrpc/middleware/xerror"
 	"reflect"
+	"time"
 )
 
 type ListenData struct {
@@ -22,6 +23,50 @@ type Config struct {
 	// 用于统一的封包、权限等处理
 	AfterHandlerFuncWithRef func(ctx *Ctx, data reflect.Value, err error)
 	AfterHandlerFunc        func(ctx *Ctx, err error)
+
+	// handler panic 时的上报，为空时只记录日志
+	CrashSink CrashSink
+
+	// 单个请求的处理超时时间，超时后直接返回 503，为 0 时不限制
+	HandlerTimeout time.Duration
+
+	// 读取完整请求(包括 header 与 body)的超时时间，用于防止慢速客户端长期占用连接，为 0 时不限制
+	ReadTimeout time.Duration
+	// 写入响应的超时时间，为 0 时不限制
+	WriteTimeout time.Duration
+	// keep-alive 连接的空闲时间，为 0 时使用 ReadTimeout
+	IdleTimeout time.Duration
+
+	// 请求体的最大大小，为 0 时使用 fasthttp 的默认值(4M)
+	MaxRequestBodySize int
+	// 单个 IP 的最大连接数，为 0 时不限制
+	MaxConnsPerIP int
+
+	// 全局合并并发的相同 GET 请求，为空时不启用，可以通过 RouteWithDedup/RouteWithoutDedup 对单个路由设置
+	Dedup *DedupConfig
+
+	// 全局限流，每个路由单独计数，为空时不启用，可以通过 RouteWithRateLimit/RouteWithoutRateLimit 对单个路由设置
+	RateLimit *RateLimitConfig
+
+	// 全局的并发限制，每个路由单独计数，为空时不启用，可以通过 RouteWithConcurrency/RouteWithoutConcurrency 对单个路由设置
+	Concurrency *ConcurrencyConfig
+
+	// 维护模式，可以通过 App.SetMaintenance 在运行时切换
+	Maintenance *MaintenanceConfig
+
+	// 功能开关，可以通过 App.SetFeatures 在运行时替换
+	Features *FeatureConfig
+
+	// 以 core.Response 的统一格式返回数据与错误，可以通过 RouteWithoutEnvelope 对单个路由关闭
+	Envelope bool
+
+	// 为空时不启用 TLS
+	TLS *TLSConfig
+
+	// App.Run 等待所有组件就绪的超时时间，默认 30s
+	ReadyTimeout time.Duration
+	// App.Run 退出时单个组件停止的超时时间，默认 10s
+	StopTimeout time.Duration
 }
 
 var defaultOnError = func(ctx *Ctx, err error) {
@@ -53,13 +98,27 @@ var defaultConfig = &Config{
 	AfterHandlerFuncWithRef: defaultAfterHandlerFuncWithDef,
 }
 
+// 复制一份再填充默认值，运行时的修改不会影响调用方的配置以及其他的 App
 func (p *App) initConfig() {
-	if p.c == nil {
-		p.c = defaultConfig
+	c := *defaultConfig
+	if p.c != nil {
+		c = *p.c
 	}
+	p.c = &c
+
+	// AllowInMaintenance 会修改允许的路径
+	m := MaintenanceConfig{}
+	if p.c.Maintenance != nil {
+		m = *p.c.Maintenance
+	}
+	p.c.Maintenance = &m
 
 	if p.c.OnError == nil {
-		p.c.OnError = defaultOnError
+		if p.c.Envelope {
+			p.c.OnError = envelopeOnError
+		} else {
+			p.c.OnError = defaultOnError
+		}
 	}
 
 	if p.c.AfterHandlerFuncWithRef == nil {
@@ -0,0 +1,190 @@
+package lrpc
+
+import (
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderOrigin                        = "Origin"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+)
+
+type CorsConfig struct {
+	// 允许的来源，支持 * 以及 https://*.example.com 形式的通配符
+	AllowOrigins []string
+
+	// 自定义来源校验，例如使用正则，返回 true 时允许
+	AllowOriginFunc func(origin string) bool
+
+	// 默认 GET,POST,PUT,PATCH,DELETE,HEAD
+	AllowMethods []string
+
+	// 为空时使用预检请求中的 Access-Control-Request-Headers
+	AllowHeaders []string
+
+	ExposeHeaders []string
+
+	AllowCredentials bool
+
+	MaxAge time.Duration
+}
+
+func (c *CorsConfig) apply() {
+	if len(c.AllowOrigins) == 0 && c.AllowOriginFunc == nil {
+		c.AllowOrigins = []string{"*"}
+	}
+
+	if len(c.AllowMethods) == 0 {
+		c.AllowMethods = []string{
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+			http.MethodHead,
+		}
+	}
+}
+
+type cors struct {
+	c *CorsConfig
+
+	allowAll bool
+	origins  map[string]bool
+	patterns []*regexp.Regexp
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+func newCors(c *CorsConfig) *cors {
+	c.apply()
+
+	p := &cors{
+		c:             c,
+		origins:       make(map[string]bool),
+		allowMethods:  strings.Join(c.AllowMethods, ","),
+		allowHeaders:  strings.Join(c.AllowHeaders, ","),
+		exposeHeaders: strings.Join(c.ExposeHeaders, ","),
+	}
+
+	if c.MaxAge > 0 {
+		p.maxAge = strconv.FormatInt(int64(c.MaxAge.Seconds()), 10)
+	}
+
+	for _, origin := range c.AllowOrigins {
+		switch {
+		case origin == "*":
+			p.allowAll = true
+		case strings.Contains(origin, "*"):
+			p.patterns = append(p.patterns, regexp.MustCompile("^"+strings.ReplaceAll(regexp.QuoteMeta(origin), `\*`, `[^/]*`)+"$"))
+		default:
+			p.origins[strings.ToLower(origin)] = true
+		}
+	}
+
+	return p
+}
+
+func (p *cors) allowOrigin(origin string) bool {
+	if p.allowAll || p.origins[strings.ToLower(origin)] {
+		return true
+	}
+
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	if p.c.AllowOriginFunc != nil {
+		return p.c.AllowOriginFunc(origin)
+	}
+
+	return false
+}
+
+func (p *cors) handle(ctx *Ctx) error {
+	origin := ctx.Header(HeaderOrigin)
+	if origin == "" {
+		return nil
+	}
+
+	ctx.Context().Response.Header.Add(HeaderVary, HeaderOrigin)
+
+	preflight := ctx.Method() == http.MethodOptions && ctx.Header(HeaderAccessControlRequestMethod) != ""
+
+	if !p.allowOrigin(origin) {
+		if preflight {
+			ctx.SendStatus(fasthttp.StatusForbidden)
+			ctx.Abort()
+		}
+		return nil
+	}
+
+	if p.allowAll && !p.c.AllowCredentials {
+		ctx.SetHeader(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		ctx.SetHeader(HeaderAccessControlAllowOrigin, origin)
+	}
+
+	if p.c.AllowCredentials {
+		ctx.SetHeader(HeaderAccessControlAllowCredentials, "true")
+	}
+
+	if !preflight {
+		if p.exposeHeaders != "" {
+			ctx.SetHeader(HeaderAccessControlExposeHeaders, p.exposeHeaders)
+		}
+		return nil
+	}
+
+	ctx.SetHeader(HeaderAccessControlAllowMethods, p.allowMethods)
+
+	if p.allowHeaders != "" {
+		ctx.SetHeader(HeaderAccessControlAllowHeaders, p.allowHeaders)
+	} else if h := ctx.Header(HeaderAccessControlRequestHeaders); h != "" {
+		ctx.SetHeader(HeaderAccessControlAllowHeaders, h)
+	}
+
+	if p.maxAge != "" {
+		ctx.SetHeader(HeaderAccessControlMaxAge, p.maxAge)
+	}
+
+	ctx.SendStatus(fasthttp.StatusNoContent)
+	ctx.Abort()
+
+	return nil
+}
+
+// Cors 跨域处理，通过 App.Use 注册时对全部路由生效，预检请求会直接返回
+func Cors(configs ...*CorsConfig) HandlerFunc {
+	c := &CorsConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+
+	return newCors(c).handle
+}
+
+// RouteWithCors 为单个路由(或通过 AddRoutes 为一组路由)配置跨域，会同时注册对应路径的 OPTIONS 路由
+// 已经注册了 OPTIONS 路由时不会覆盖，同一路径下多个路由允许的方法会合并
+func RouteWithCors(c *CorsConfig) RouteOption {
+	return func(r *Route) {
+		r.Cors = c
+	}
+}
@@ -2,11 +2,10 @@ package lrpc
 
 import (
 	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/core"
 	"github.com/lazygophers/utils"
-	"github.com/lazygophers/utils/json"
 	"github.com/valyala/fasthttp"
-	"google.golang.org/protobuf/proto"
-	"strings"
+	"sync"
 )
 
 type Ctx struct {
@@ -14,7 +13,21 @@ type Ctx struct {
 
 	tranceId string
 
+	requestId string
+
 	params map[string]string
+
+	aborted bool
+
+	// 处理超时后 handler 仍在后台执行，此时不能放回池中复用
+	detached bool
+
+	// 请求内的缓存，第一次调用 Memo 时创建
+	memo     *memoStore
+	memoOnce sync.Once
+
+	// 第一次调用 Logger 时创建，设置用户、租户后重新创建
+	logger *log.Logger
 }
 
 func newCtx() *Ctx {
@@ -25,6 +38,13 @@ func newCtx() *Ctx {
 
 func (p *Ctx) Reset() {
 	p.ctx = nil
+	p.tranceId = ""
+	p.requestId = ""
+	p.aborted = false
+	p.detached = false
+	p.memo = nil
+	p.memoOnce = sync.Once{}
+	p.logger = nil
 	if len(p.params) > 0 {
 		p.params = make(map[string]string)
 	}
@@ -34,6 +54,15 @@ func (p *Ctx) Context() *fasthttp.RequestCtx {
 	return p.ctx
 }
 
+// Abort 终止后续 handler 的执行，用于中间件直接返回响应的场景
+func (p *Ctx) Abort() {
+	p.aborted = true
+}
+
+func (p *Ctx) IsAborted() bool {
+	return p.aborted
+}
+
 func (p *Ctx) setParam(params map[string]string) {
 	p.params = params
 }
@@ -90,29 +119,19 @@ func (p *Ctx) SendStatus(status int) {
 	p.ctx.SetStatusCode(status)
 }
 
+// SendJson 按请求的 Accept 选择 Codec 序列化响应，没有可用的 Codec 时使用请求的 Content-Type，默认 json
 func (p *Ctx) SendJson(o any) error {
-	contentType := p.Header(HeaderContentType)
-	if strings.Contains(contentType, MIMEProtobuf) {
-		if v, ok := o.(proto.Message); ok {
-			p.SetHeader(HeaderContentType, MIMEProtobuf)
-			buffer, err := proto.Marshal(v)
-			if err != nil {
-				log.Errorf("err:%v", err)
-				return err
-			}
-
-			p.ctx.SetBody(buffer)
-			return nil
-		}
-	}
+	c := responseCodec(p.Header(HeaderAccept), p.Header(HeaderContentType), o)
 
-	p.SetHeader(HeaderContentType, MIMEJson)
-	buffer, err := json.Marshal(o)
+	buffer, err := c.Marshal(o)
 	if err != nil {
+		log.Errorf("err:%v", err)
 		return err
 	}
 
-	p.ctx.SetBody(buffer)
+	p.SetHeader(HeaderContentType, c.ContentType())
+	// buffer 是新分配的，不需要再拷贝一次
+	p.ctx.Response.SetBodyRaw(buffer)
 	return nil
 }
 
@@ -128,26 +147,7 @@ func (p *Ctx) BodyParser(o any) (err error) {
 		return nil
 	}
 
-	contentType := p.Header(HeaderContentType)
-	if strings.Contains(contentType, MIMEProtobuf) {
-		if v, ok := o.(proto.Message); ok {
-			err = proto.Unmarshal(body, v)
-			if err != nil {
-				log.Errorf("err:%v", err)
-				return err
-			}
-
-			err = utils.Validate(v)
-			if err != nil {
-				log.Errorf("err:%v", err)
-				return err
-			}
-
-			return nil
-		}
-	}
-
-	err = json.Unmarshal(body, o)
+	err = codecFor(p.Header(HeaderContentType), o).Unmarshal(body, o)
 	if err != nil {
 		log.Errorf("err:%v", err)
 		return err
@@ -174,6 +174,7 @@ func (p *Ctx) SetTranceId(tranceId ...string) {
 	} else {
 		p.tranceId = log.GenTraceId()
 	}
+	p.logger = nil
 }
 
 func (p *Ctx) init() {
@@ -184,6 +185,9 @@ func (p *Ctx) init() {
 	if p.tranceId == "" {
 		p.tranceId = log.GetTrace()
 	}
+
+	// 通过 Context() 传递到其他模块时可以取到请求的日志
+	p.ctx.SetUserValue(core.LoggerKey{}, p)
 }
 
 func (p *App) AcquireCtx(ctx *fasthttp.RequestCtx) *Ctx {
@@ -201,6 +205,10 @@ func (p *App) AcquireCtx(ctx *fasthttp.RequestCtx) *Ctx {
 }
 
 func (p *App) ReleaseCtx(ctx *Ctx) {
+	if ctx.detached {
+		return
+	}
+
 	ctx.Reset()
 	p.ctxPool.Put(ctx)
 }
@@ -0,0 +1,40 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"strings"
+)
+
+// Logger 返回带有请求 ID、trace、路由以及用户、租户的日志，用于关联同一个请求的日志
+// Context() 可以作为 context.Context 传给 db 的 Scoop.WithContext，sql 日志会带上相同的信息
+func (p *Ctx) Logger() *log.Logger {
+	if p.logger == nil {
+		// 直接调用 Logger 的方法，比包级别的函数少一层
+		p.logger = log.Clone().SetCallerDepth(3).SetPrefixMsg(p.logPrefix())
+	}
+	return p.logger
+}
+
+func (p *Ctx) logPrefix() string {
+	var b strings.Builder
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(value)
+	}
+
+	add("request_id", p.requestId)
+	add("trace_id", p.tranceId)
+	add("route", p.Method()+" "+p.Path())
+	// 只输出用户 ID，避免把整个用户信息写入日志
+	add("user", p.UserId())
+	add("tenant", p.TenantId())
+
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package lrpc
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const HeaderAcceptLanguage = "Accept-Language"
+
+// 使用私有类型作为 key，避免与 SetLocal 的字符串 key 冲突
+type ctxValueKey int
+
+const (
+	ctxUserKey ctxValueKey = iota
+	ctxTenantKey
+	ctxLocaleKey
+	ctxDeadlineKey
+	ctxFeatureKey
+)
+
+// SetUser 一般由鉴权中间件写入当前登录的用户
+func (p *Ctx) SetUser(user any) {
+	p.ctx.SetUserValue(ctxUserKey, user)
+	p.logger = nil
+}
+
+func (p *Ctx) User() any {
+	return p.ctx.UserValue(ctxUserKey)
+}
+
+// UserIdentifier SetUser 写入的用户为结构体时实现，返回稳定的用户 ID
+type UserIdentifier interface {
+	UserId() string
+}
+
+// UserId 返回 SetUser 写入的用户的 ID，用于日志、限流、灰度以及缓存的 key
+// 只支持简单的类型与 UserIdentifier，避免把整个用户信息写入日志或者 key，不支持时返回空
+func (p *Ctx) UserId() string {
+	switch x := p.User().(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case UserIdentifier:
+		return x.UserId()
+	default:
+		return ""
+	}
+}
+
+// CtxUser 按类型获取 SetUser 写入的用户，类型不匹配时返回 false
+func CtxUser[T any](ctx *Ctx) (T, bool) {
+	user, ok := ctx.User().(T)
+	return user, ok
+}
+
+func (p *Ctx) SetTenantId(tenantId string) {
+	p.ctx.SetUserValue(ctxTenantKey, tenantId)
+	p.logger = nil
+}
+
+func (p *Ctx) TenantId() string {
+	tenantId, _ := p.ctx.UserValue(ctxTenantKey).(string)
+	return tenantId
+}
+
+func (p *Ctx) SetLocale(locale string) {
+	p.ctx.SetUserValue(ctxLocaleKey, locale)
+}
+
+// Locale 未设置时使用 Accept-Language 中的第一个语言
+func (p *Ctx) Locale() string {
+	if locale, ok := p.ctx.UserValue(ctxLocaleKey).(string); ok {
+		return locale
+	}
+
+	locale, _, _ := strings.Cut(p.Header(HeaderAcceptLanguage), ",")
+	locale, _, _ = strings.Cut(locale, ";")
+	return strings.TrimSpace(locale)
+}
+
+func (p *Ctx) SetDeadline(deadline time.Time) {
+	p.ctx.SetUserValue(ctxDeadlineKey, deadline)
+}
+
+// Deadline 请求处理的截止时间，配置了超时时会自动设置
+func (p *Ctx) Deadline() (time.Time, bool) {
+	deadline, ok := p.ctx.UserValue(ctxDeadlineKey).(time.Time)
+	return deadline, ok
+}
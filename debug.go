@@ -0,0 +1,270 @@
+package lrpc
+
+import (
+	"fmt"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/app"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const MIMEOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+type DebugConfig struct {
+	// 路由前缀，默认 /debug
+	Prefix string
+
+	// 返回 false 时拒绝访问，为空时只允许回环地址访问，允许内网等更大范围访问时需要显式设置
+	Auth func(ctx *Ctx) bool
+
+	// 是否开启 pprof，会注册 Prefix/pprof 下的路由
+	EnablePprof bool
+}
+
+func (c *DebugConfig) apply() {
+	if c.Prefix == "" {
+		c.Prefix = "/debug"
+	}
+	c.Prefix = strings.TrimSuffix(c.Prefix, "/")
+
+	if c.Auth == nil {
+		c.Auth = localAuth
+	}
+}
+
+// 只允许回环地址访问，同一内网中的其他机器也可能不可信
+func localAuth(ctx *Ctx) bool {
+	return ctx.Context().RemoteIP().IsLoopback()
+}
+
+// 鉴权失败时返回 403 并终止后续的处理
+func authHandler(fn func(ctx *Ctx) bool) HandlerFunc {
+	return func(ctx *Ctx) error {
+		if fn(ctx) {
+			return nil
+		}
+
+		ctx.SendStatus(fasthttp.StatusForbidden)
+		ctx.Abort()
+		return nil
+	}
+}
+
+type BuildInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Branch    string `json:"branch,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	GoOS      string `json:"go_os"`
+	GoArch    string `json:"go_arch"`
+}
+
+// 优先使用编译时注入的信息，缺失时从 debug.ReadBuildInfo 中读取
+func getBuildInfo() *BuildInfo {
+	info := &BuildInfo{
+		Name:      app.Name,
+		Version:   app.Version,
+		Commit:    app.Commit,
+		Branch:    app.Branch,
+		BuildDate: app.BuildDate,
+		GoVersion: runtime.Version(),
+		GoOS:      runtime.GOOS,
+		GoArch:    runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" {
+			info.Version = bi.Main.Version
+		}
+
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			}
+		}
+	}
+
+	return info
+}
+
+type RuntimeStats struct {
+	Uptime       int64   `json:"uptime_seconds"`
+	Goroutines   int     `json:"goroutines"`
+	NumCPU       int     `json:"num_cpu"`
+	HeapAlloc    uint64  `json:"heap_alloc_bytes"`
+	HeapInuse    uint64  `json:"heap_inuse_bytes"`
+	HeapObjects  uint64  `json:"heap_objects"`
+	Sys          uint64  `json:"sys_bytes"`
+	NumGC        uint32  `json:"num_gc"`
+	PauseTotal   float64 `json:"gc_pause_total_seconds"`
+	LastGC       int64   `json:"last_gc_unix,omitempty"`
+	CrashCount   int64   `json:"crash_count"`
+	TotalAlloc   uint64  `json:"total_alloc_bytes"`
+	MallocsTotal uint64  `json:"mallocs_total"`
+}
+
+var startAt = time.Now()
+
+func (p *App) runtimeStats() *RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	stats := &RuntimeStats{
+		Uptime:       int64(time.Since(startAt).Seconds()),
+		Goroutines:   runtime.NumGoroutine(),
+		NumCPU:       runtime.NumCPU(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapObjects:  m.HeapObjects,
+		Sys:          m.Sys,
+		NumGC:        m.NumGC,
+		PauseTotal:   time.Duration(m.PauseTotalNs).Seconds(),
+		CrashCount:   p.CrashCount(),
+		TotalAlloc:   m.TotalAlloc,
+		MallocsTotal: m.Mallocs,
+	}
+
+	if m.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(m.LastGC)).Unix()
+	}
+
+	return stats
+}
+
+func (p *App) openMetrics() string {
+	info := getBuildInfo()
+	stats := p.runtimeStats()
+
+	var b strings.Builder
+	gauge := func(name, help string, value any) {
+		b.WriteString(fmt.Sprintf("# TYPE %s gauge\n# HELP %s %s\n%s %v\n", name, name, help, name, value))
+	}
+	counter := func(name, help string, value any) {
+		b.WriteString(fmt.Sprintf("# TYPE %s counter\n# HELP %s %s\n%s_total %v\n", name, name, help, name, value))
+	}
+
+	b.WriteString("# TYPE lrpc_build info\n# HELP lrpc_build Build information.\n")
+	b.WriteString(fmt.Sprintf("lrpc_build_info{name=%q,version=%q,commit=%q,go_version=%q} 1\n",
+		info.Name, info.Version, info.Commit, info.GoVersion))
+
+	gauge("process_uptime_seconds", "Seconds since the process started.", stats.Uptime)
+	gauge("go_goroutines", "Number of goroutines that currently exist.", stats.Goroutines)
+	gauge("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", stats.HeapAlloc)
+	gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", stats.HeapInuse)
+	gauge("go_memstats_heap_objects", "Number of allocated objects.", stats.HeapObjects)
+	gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", stats.Sys)
+	counter("go_gc_cycles", "Number of completed GC cycles.", stats.NumGC)
+	counter("go_gc_pause_seconds", "Total GC pause time.", stats.PauseTotal)
+	counter("lrpc_crash", "Number of recovered panics.", stats.CrashCount)
+
+	occurrences := xerror.Occurrences()
+	if len(occurrences) > 0 {
+		b.WriteString("# TYPE lrpc_error_occurrences counter\n# HELP lrpc_error_occurrences Number of errors by fingerprint.\n")
+		for _, o := range occurrences {
+			b.WriteString(fmt.Sprintf("lrpc_error_occurrences_total{fingerprint=%q,code=\"%d\"} %d\n", o.Fingerprint, o.Code, o.Count))
+		}
+
+		b.WriteString("# TYPE lrpc_error_suppressed_logs counter\n# HELP lrpc_error_suppressed_logs Number of error logs dropped by throttling.\n")
+		for _, o := range occurrences {
+			b.WriteString(fmt.Sprintf("lrpc_error_suppressed_logs_total{fingerprint=%q,code=\"%d\"} %d\n", o.Fingerprint, o.Code, o.Suppressed))
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	return b.String()
+}
+
+func pprofHandler(h http.HandlerFunc) HandlerFunc {
+	handler := fasthttpadaptor.NewFastHTTPHandlerFunc(h)
+	return func(ctx *Ctx) error {
+		handler(ctx.Context())
+		return nil
+	}
+}
+
+// EnableDebug 注册构建信息、运行时状态、OpenMetrics 以及可选的 pprof 路由
+func (p *App) EnableDebug(configs ...*DebugConfig) {
+	c := &DebugConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	routes := []*Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/build",
+			Handler: func(ctx *Ctx) error {
+				return ctx.SendJson(getBuildInfo())
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/runtime",
+			Handler: func(ctx *Ctx) error {
+				return ctx.SendJson(p.runtimeStats())
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/metrics",
+			Handler: func(ctx *Ctx) error {
+				ctx.Context().SetContentType(MIMEOpenMetrics)
+				ctx.SendString(p.openMetrics())
+				return nil
+			},
+		},
+	}
+	routes = append(routes, p.maintenanceRoutes()...)
+
+	if c.EnablePprof {
+		index := pprofHandler(pprof.Index)
+		routes = append(routes,
+			// 路由不区分结尾的 /，首页中的链接为相对路径，需要跳转到以 / 结尾的地址
+			&Route{
+				Method: http.MethodGet,
+				Path:   "/pprof",
+				Handler: func(ctx *Ctx) error {
+					if !strings.HasSuffix(ctx.Path(), "/") {
+						ctx.Context().Redirect(c.Prefix+"/pprof/", fasthttp.StatusMovedPermanently)
+						return nil
+					}
+					return index(ctx)
+				},
+			},
+			&Route{Method: http.MethodGet, Path: "/pprof/cmdline", Handler: pprofHandler(pprof.Cmdline)},
+			&Route{Method: http.MethodGet, Path: "/pprof/profile", Handler: pprofHandler(pprof.Profile)},
+			&Route{Method: http.MethodGet, Path: "/pprof/symbol", Handler: pprofHandler(pprof.Symbol)},
+			&Route{Method: http.MethodPost, Path: "/pprof/symbol", Handler: pprofHandler(pprof.Symbol)},
+			&Route{Method: http.MethodGet, Path: "/pprof/trace", Handler: pprofHandler(pprof.Trace)},
+			&Route{
+				Method: http.MethodGet,
+				Path:   "/pprof/:name",
+				Handler: func(ctx *Ctx) error {
+					return pprofHandler(pprof.Handler(ctx.Parame("name")).ServeHTTP)(ctx)
+				},
+			},
+		)
+	}
+
+	// 维护期间调试接口依旧可用，否则无法关闭维护模式
+	p.AllowInMaintenance(c.Prefix)
+
+	p.AddRoutes(routes, RouteWithPrefix(c.Prefix), RouteWithBefore(authHandler(c.Auth)), RouteWithoutAccessLog())
+}
@@ -0,0 +1,131 @@
+package lrpc
+
+import (
+	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"strings"
+)
+
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderCookie        = "Cookie"
+)
+
+type DedupConfig struct {
+	// 参与计算 key 的请求头，用于区分不同的用户，默认 Authorization、Cookie
+	KeyHeaders []string
+
+	// 自定义 key，为空时使用 method + path + query + Content-Type + Accept + KeyHeaders
+	// 响应的编码由 Content-Type、Accept 决定，不同编码的请求不能合并
+	Key func(ctx *Ctx) string
+
+	// 用于单个路由关闭全局配置
+	Disable bool
+}
+
+func (c *DedupConfig) apply() {
+	if len(c.KeyHeaders) == 0 {
+		c.KeyHeaders = []string{HeaderAuthorization, HeaderCookie}
+	}
+}
+
+func (c *DedupConfig) key(ctx *Ctx) string {
+	if c.Key != nil {
+		return c.Key(ctx)
+	}
+
+	var b strings.Builder
+	b.WriteString(ctx.Method())
+	b.WriteByte(' ')
+	b.WriteString(ctx.Path())
+	b.WriteByte('?')
+	b.Write(ctx.Context().QueryArgs().QueryString())
+	for _, header := range append([]string{HeaderContentType, HeaderAccept}, c.KeyHeaders...) {
+		b.WriteByte('\n')
+		b.WriteString(ctx.Header(header))
+	}
+
+	return b.String()
+}
+
+// 合并并发的相同请求，只执行一次 handler，其余请求复制其响应
+func (p *App) dedupHandler(handler HandlerFunc, c *DedupConfig) HandlerFunc {
+	c.apply()
+
+	var group singleflight.Group
+
+	return func(ctx *Ctx) error {
+		rc := ctx.Context()
+
+		var leader bool
+		v, _, _ := group.Do(c.key(ctx), func() (interface{}, error) {
+			leader = true
+
+			err := handler(ctx)
+			if err != nil {
+				p.onError(ctx, err)
+			}
+
+			resp := &fasthttp.Response{}
+			rc.Response.CopyTo(resp)
+			return resp, nil
+		})
+
+		if leader {
+			return nil
+		}
+
+		// 只有执行 handler 的请求需要保留自己的 trace 等信息
+		trace := string(rc.Response.Header.Peek(HeaderTrance))
+		requestId := string(rc.Response.Header.Peek(HeaderRequestId))
+
+		v.(*fasthttp.Response).CopyTo(&rc.Response)
+
+		if trace != "" {
+			ctx.SetHeader(HeaderTrance, trace)
+		}
+		if requestId != "" {
+			ctx.SetHeader(HeaderRequestId, requestId)
+		}
+
+		return nil
+	}
+}
+
+func (p *App) dedupConfig(r *Route) *DedupConfig {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil
+	}
+
+	c := r.Dedup
+	if c == nil {
+		c = p.c.Dedup
+	}
+
+	if c == nil || c.Disable {
+		return nil
+	}
+
+	return c
+}
+
+// RouteWithDedup 合并单个 GET 路由的并发相同请求，适用于幂等且开销较大的读接口
+func RouteWithDedup(c ...*DedupConfig) RouteOption {
+	return func(r *Route) {
+		if len(c) > 0 {
+			r.Dedup = c[0]
+		} else {
+			r.Dedup = &DedupConfig{}
+		}
+	}
+}
+
+// RouteWithoutDedup 关闭单个路由的请求合并
+func RouteWithoutDedup() RouteOption {
+	return func(r *Route) {
+		r.Dedup = &DedupConfig{
+			Disable: true,
+		}
+	}
+}
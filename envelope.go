@@ -0,0 +1,73 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/core"
+	"reflect"
+)
+
+const envelopeDisabledKey = "lrpc_envelope_disabled"
+
+// RouteWithoutEnvelope 开启 Config.Envelope 时，单个路由直接返回数据，用于回调等需要原始格式的接口
+func RouteWithoutEnvelope() RouteOption {
+	return RouteWithMergeExtra(map[string]any{
+		envelopeDisabledKey: true,
+	})
+}
+
+func (p *Ctx) envelopeDisabled() bool {
+	disabled, _ := p.GetLocal(envelopeDisabledKey).(bool)
+	return disabled
+}
+
+// 没有使用 RequestId 中间件时使用 trace
+func (p *Ctx) envelopeRequestId() string {
+	if p.requestId != "" {
+		return p.requestId
+	}
+	return log.GetTrace()
+}
+
+// SendEnvelope 以 core.Response 的格式返回，err 不为空时按 xerror 的错误码返回错误
+func (p *Ctx) SendEnvelope(data any, err error) error {
+	var rsp *core.Response
+	if err != nil {
+		rsp = core.NewErrorResponse(err)
+	} else {
+		rsp = core.NewResponse(data)
+	}
+
+	return p.SendJson(rsp.WithRequestId(p.envelopeRequestId()))
+}
+
+var envelopeOnError = func(ctx *Ctx, err error) {
+	if ctx.envelopeDisabled() {
+		defaultOnError(ctx, err)
+		return
+	}
+
+	err = ctx.SendEnvelope(nil, err)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return
+	}
+}
+
+func (p *App) sendEnvelope(ctx *Ctx, data reflect.Value) {
+	var di any
+	if data.IsValid() {
+		di = data.Interface()
+	}
+
+	var err error
+	if ctx.envelopeDisabled() {
+		err = ctx.SendJson(di)
+	} else {
+		err = ctx.SendEnvelope(di, nil)
+	}
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.onError(ctx, err)
+		return
+	}
+}
@@ -0,0 +1,112 @@
+package lrpc
+
+import (
+	"bytes"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfModifiedSince = "If-Modified-Since"
+)
+
+// SetETag 设置响应的 ETag，没有引号时自动加上
+func (p *Ctx) SetETag(etag string, weak ...bool) {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+
+	if len(weak) > 0 && weak[0] && !strings.HasPrefix(etag, "W/") {
+		etag = "W/" + etag
+	}
+
+	p.SetHeader(HeaderETag, etag)
+}
+
+func (p *Ctx) SetLastModified(t time.Time) {
+	p.SetHeader(HeaderLastModified, t.UTC().Format(http.TimeFormat))
+}
+
+// NotModified 根据已设置的 ETag、Last-Modified 判断客户端的缓存是否可用，可用时返回 304 并清空响应体
+// 有 If-None-Match 时忽略 If-Modified-Since，只对 GET、HEAD 生效
+//
+//	ctx.SetETag(version)
+//	if ctx.NotModified() {
+//		return nil
+//	}
+func (p *Ctx) NotModified() bool {
+	if p.Method() != fasthttp.MethodGet && p.Method() != fasthttp.MethodHead {
+		return false
+	}
+
+	resp := &p.ctx.Response
+
+	notModified := false
+	if inm := p.Header(HeaderIfNoneMatch); inm != "" {
+		etag := string(resp.Header.Peek(HeaderETag))
+		notModified = etag != "" && matchETag(inm, etag)
+	} else if ims := p.Header(HeaderIfModifiedSince); ims != "" {
+		lastModified, err := http.ParseTime(string(resp.Header.Peek(HeaderLastModified)))
+		if err == nil {
+			since, err := http.ParseTime(ims)
+			notModified = err == nil && !lastModified.After(since)
+		}
+	}
+
+	if !notModified {
+		return false
+	}
+
+	resp.ResetBody()
+	resp.SetStatusCode(fasthttp.StatusNotModified)
+	return true
+}
+
+type ETagConfig struct {
+	// 使用弱 ETag，响应体会被压缩等处理时建议开启
+	Weak bool
+}
+
+// ETag 对 200 的 GET 响应体计算 ETag，并处理 If-None-Match，需要在 Compress 之前执行
+// 一般通过 RouteWithAfter 对需要的路由开启，handler 自己设置了 ETag 时只做比较
+func ETag(configs ...*ETagConfig) HandlerFunc {
+	c := &ETagConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+
+	return func(ctx *Ctx) error {
+		if ctx.Method() != fasthttp.MethodGet && ctx.Method() != fasthttp.MethodHead {
+			return nil
+		}
+
+		resp := &ctx.Context().Response
+		if resp.StatusCode() != fasthttp.StatusOK || ctx.IsBodyStream() {
+			return nil
+		}
+
+		if len(resp.Header.Peek(HeaderETag)) == 0 {
+			body := resp.Body()
+
+			h := fnv.New64a()
+			_, _ = h.Write(body)
+
+			var b bytes.Buffer
+			b.WriteString(strconv.FormatInt(int64(len(body)), 16))
+			b.WriteByte('-')
+			b.WriteString(strconv.FormatUint(h.Sum64(), 16))
+
+			ctx.SetETag(b.String(), c.Weak)
+		}
+
+		ctx.NotModified()
+
+		return nil
+	}
+}
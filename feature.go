@@ -0,0 +1,162 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"hash/fnv"
+)
+
+// FeatureFlag 功能开关，按 白名单 -> 百分比 的顺序判断
+type FeatureFlag struct {
+	Name string `json:"name" yaml:"name"`
+
+	// 总开关，关闭时所有请求都不生效
+	Enable bool `json:"enable" yaml:"enable"`
+
+	// 灰度的百分比(0-100)，按 用户 或 租户 分桶，同一个用户的结果保持不变
+	Percent float64 `json:"percent" yaml:"percent"`
+
+	// 直接生效的用户、租户
+	Users   []string `json:"users,omitempty" yaml:"users,omitempty"`
+	Tenants []string `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+}
+
+type FeatureConfig struct {
+	Flags []*FeatureFlag `json:"flags" yaml:"flags"`
+
+	// 用于定向以及分桶的用户标识，为空时使用 ctx.UserId()
+	UserId func(ctx *Ctx) string `json:"-" yaml:"-"`
+}
+
+func (c *FeatureConfig) apply() {
+	if c.UserId == nil {
+		c.UserId = (*Ctx).UserId
+	}
+}
+
+type featureSet struct {
+	flags  map[string]*FeatureFlag
+	userId func(ctx *Ctx) string
+}
+
+func newFeatureSet(c *FeatureConfig) *featureSet {
+	c.apply()
+
+	p := &featureSet{
+		flags:  make(map[string]*FeatureFlag, len(c.Flags)),
+		userId: c.UserId,
+	}
+	for _, flag := range c.Flags {
+		p.flags[flag.Name] = flag
+	}
+	return p
+}
+
+// 同一个请求中保持同一份配置，结果在第一次判断时缓存
+type featureEval struct {
+	set     *featureSet
+	results map[string]bool
+}
+
+func featureBucket(name, key string) float64 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func (p *featureEval) eval(ctx *Ctx, name string) bool {
+	flag, ok := p.set.flags[name]
+	if !ok || !flag.Enable {
+		return false
+	}
+
+	userId := p.set.userId(ctx)
+	if userId != "" {
+		for _, user := range flag.Users {
+			if user == userId {
+				return true
+			}
+		}
+	}
+
+	tenantId := ctx.TenantId()
+	if tenantId != "" {
+		for _, tenant := range flag.Tenants {
+			if tenant == tenantId {
+				return true
+			}
+		}
+	}
+
+	if flag.Percent >= 100 {
+		return true
+	}
+	if flag.Percent <= 0 {
+		return false
+	}
+
+	// 未登录的请求没有稳定的标识，只在全量时生效
+	key := userId
+	if key == "" {
+		key = tenantId
+	}
+	if key == "" {
+		return false
+	}
+
+	return featureBucket(name, key) < flag.Percent
+}
+
+// SetFeatures 运行时替换功能开关，可以在配置中心的 OnChanged 中调用，已经在处理中的请求不受影响
+func (p *App) SetFeatures(c *FeatureConfig) {
+	if c == nil {
+		p.features.Store(nil)
+		return
+	}
+
+	p.features.Store(newFeatureSet(c))
+	log.Infof("feature flags updated, count:%d", len(c.Flags))
+}
+
+func (p *App) bindFeatures(ctx *Ctx) {
+	set := p.features.Load()
+	if set == nil {
+		return
+	}
+
+	ctx.ctx.SetUserValue(ctxFeatureKey, &featureEval{
+		set:     set,
+		results: make(map[string]bool),
+	})
+}
+
+// Feature 判断当前请求是否开启了功能，未配置的功能返回 false
+func (p *Ctx) Feature(name string) bool {
+	e, ok := p.ctx.UserValue(ctxFeatureKey).(*featureEval)
+	if !ok {
+		return false
+	}
+
+	if v, ok := e.results[name]; ok {
+		return v
+	}
+
+	v := e.eval(p, name)
+	e.results[name] = v
+	return v
+}
+
+// Features 当前请求所有功能的开启状态，用于下发给前端或者模板
+func (p *Ctx) Features() map[string]bool {
+	e, ok := p.ctx.UserValue(ctxFeatureKey).(*featureEval)
+	if !ok {
+		return map[string]bool{}
+	}
+
+	m := make(map[string]bool, len(e.set.flags))
+	for name := range e.set.flags {
+		m[name] = p.Feature(name)
+	}
+	return m
+}
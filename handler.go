@@ -4,10 +4,12 @@ import (
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/lrpc/middleware/core"
 	"github.com/lazygophers/utils"
+	"github.com/valyala/fasthttp"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 	"net/http"
 	"reflect"
+	"slices"
 )
 
 type BaseResponse struct {
@@ -24,6 +26,8 @@ func (p *App) onError(ctx *Ctx, err error) {
 		return
 	}
 
+	setRetryAfter(ctx, err)
+
 	if p.c.OnError == nil {
 		return
 	}
@@ -45,7 +49,13 @@ func (p *App) afterHandlerWithRef(ctx *Ctx, data reflect.Value, err error) {
 		}
 	}
 
-	if !ctx.BodyEmpty() || ctx.IsBodyStream() {
+	// handler 已经写入了响应
+	if len(ctx.ctx.Response.Body()) > 0 || ctx.IsBodyStream() {
+		return
+	}
+
+	if p.c.Envelope {
+		p.sendEnvelope(ctx, data)
 		return
 	}
 
@@ -277,11 +287,25 @@ func MergeHandler(handlers ...HandlerFunc) HandlerFunc {
 			if err != nil {
 				return err
 			}
+
+			if ctx.IsAborted() {
+				return nil
+			}
 		}
 		return nil
 	}
 }
 
+// Use 注册全局中间件，在路由匹配之前执行
+func (p *App) Use(handlers ...HandlerFunc) {
+	p.before = append(p.before, handlers...)
+}
+
+// UseAfter 注册全局中间件，在请求处理完成(包括错误处理)之后执行
+func (p *App) UseAfter(handlers ...HandlerFunc) {
+	p.after = append(p.after, handlers...)
+}
+
 func (p *App) handlerExtr(extr map[string]any) HandlerFunc {
 	return func(ctx *Ctx) error {
 		for k, v := range extr {
@@ -313,11 +337,99 @@ func (p *App) AddRoute(r *Route, opts ...RouteOption) {
 		handlers = append(handlers, p.handlerExtr(r.Extra))
 	}
 
+	if r.Method == http.MethodOptions && !r.preflight {
+		p.preflights[r.Path] = nil
+	}
+
+	if r.Cors != nil {
+		handlers = append(handlers, Cors(r.Cors))
+
+		if r.Method != http.MethodOptions {
+			p.addPreflight(r.Path, r.Cors)
+		}
+	}
+
+	if r.ClientCert != nil {
+		handlers = append(handlers, p.clientCertHandler(r.ClientCert))
+	}
+
+	if r.StreamBody {
+		if _, ok := p.streamRoutes[r.Method]; !ok {
+			p.streamRoutes[r.Method] = NewSearchTree[bool]()
+		}
+		p.streamRoutes[r.Method].Add(r.Path, true)
+	} else if r.MaxBodySize > 0 {
+		handlers = append(handlers, p.maxBodySizeHandler(r.MaxBodySize))
+	}
+
 	handlers = append(handlers, r.Before...)
 	handlers = append(handlers, r.Handler)
 	handlers = append(handlers, r.After...)
 
-	p.routes[r.Method].Add(r.Path, MergeHandler(handlers...))
+	handler := MergeHandler(handlers...)
+
+	if c := p.dedupConfig(r); c != nil {
+		handler = p.dedupHandler(handler, c)
+	}
+
+	if c := p.responseCacheConfig(r); c != nil {
+		handler = p.responseCacheHandler(handler, r, c)
+	}
+
+	// 命中缓存与合并的请求不占用并发
+	if c := p.concurrencyConfig(r); c != nil {
+		handler = p.concurrencyHandler(handler, r, c)
+	}
+
+	// 在合并请求之外，被合并的请求同样计数
+	if c := p.rateLimitConfig(r); c != nil {
+		handler = p.rateLimitHandler(handler, r, c)
+	}
+
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = p.c.HandlerTimeout
+	}
+
+	if timeout > 0 {
+		handler = p.timeoutHandler(handler, timeout)
+	}
+
+	p.routes[r.Method].Add(r.Path, handler)
+}
+
+// 注册跨域的预检路由，已经有用户注册的 OPTIONS 路由时跳过，同一路径下多个路由允许的方法合并
+func (p *App) addPreflight(path string, c *CorsConfig) {
+	merged, ok := p.preflights[path]
+	if ok && merged == nil {
+		return
+	}
+
+	if merged == nil {
+		cc := *c
+		merged = &cc
+	} else {
+		cc := *merged
+		cc.AllowMethods = append([]string(nil), merged.AllowMethods...)
+		for _, method := range c.AllowMethods {
+			if !slices.Contains(cc.AllowMethods, method) {
+				cc.AllowMethods = append(cc.AllowMethods, method)
+			}
+		}
+		merged = &cc
+	}
+	p.preflights[path] = merged
+
+	p.AddRoute(&Route{
+		Method: http.MethodOptions,
+		Path:   path,
+		Handler: func(ctx *Ctx) error {
+			ctx.SendStatus(fasthttp.StatusNoContent)
+			return nil
+		},
+		Before:    []HandlerFunc{Cors(merged)},
+		preflight: true,
+	})
 }
 
 func (p *App) AddRoutes(rs []*Route, opts ...RouteOption) {
@@ -2,10 +2,14 @@ package lrpc
 
 const (
 	HeaderContentType = "Content-Type"
+	HeaderAccept      = "Accept"
 	HeaderTrance      = "X-Trance"
+	HeaderRequestId   = "X-Request-ID"
 )
 
 const (
 	MIMEJson     = "application/json"
 	MIMEProtobuf = "application/protobuf"
+	MIMEMsgpack  = "application/msgpack"
+	MIMECbor     = "application/cbor"
 )
@@ -0,0 +1,156 @@
+package lrpc
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"io"
+	"time"
+)
+
+// Component 随 App.Run 启动、停止的组件，例如 db、cache 的客户端以及定时任务
+type Component struct {
+	Name string
+
+	// 可以为空
+	Start func() error
+
+	// 返回 nil 时表示已经就绪，启动后重试直到 Config.ReadyTimeout，可以为空
+	Ready func() error
+
+	// 可以为空
+	Stop func() error
+}
+
+// CloserComponent 用于只需要在退出时关闭的客户端，例如 db.Client、cache.Cache
+func CloserComponent(name string, c io.Closer) *Component {
+	return &Component{
+		Name: name,
+		Stop: c.Close,
+	}
+}
+
+// Register 注册组件，按注册的顺序启动，按相反的顺序停止，需要在 Run 之前调用
+func (p *App) Register(components ...*Component) {
+	p.components = append(p.components, components...)
+}
+
+// IsReady 通过 Run 启动时，组件全部就绪之后、开始停止之前为 true；没有通过 Run 启动时总是为 true
+func (p *App) IsReady() bool {
+	return !p.notReady.Load()
+}
+
+// Run 按注册的顺序启动组件并等待就绪后开始监听，进程退出时先停止监听，再按相反的顺序停止组件
+// 启动过程中失败时停止已经启动的组件并返回错误
+func (p *App) Run(port int, handlers ...ListenHandler) error {
+	// 组件启动、停止期间的 panic 同样上报
+	defer xerror.OnPanic(p.reportPanic)()
+
+	p.notReady.Store(true)
+
+	started, err := p.startComponents()
+	if err != nil {
+		p.stopComponents(started)
+		return err
+	}
+
+	err = p.waitReady()
+	if err != nil {
+		p.stopComponents(started)
+		return err
+	}
+
+	p.notReady.Store(false)
+	log.Infof("all components ready")
+
+	err = p.listenAndServe(port, handlers...)
+
+	p.notReady.Store(true)
+	p.stopComponents(started)
+
+	return err
+}
+
+func (p *App) startComponents() ([]*Component, error) {
+	started := make([]*Component, 0, len(p.components))
+	for _, c := range p.components {
+		if c.Start != nil {
+			start := time.Now()
+			err := c.Start()
+			if err != nil {
+				log.Errorf("start %s err:%v", c.Name, err)
+				return started, fmt.Errorf("start %s: %w", c.Name, err)
+			}
+			log.Infof("component %s started in %s", c.Name, time.Since(start))
+		}
+
+		started = append(started, c)
+	}
+
+	return started, nil
+}
+
+// 依次等待每个组件就绪，共用一个超时时间
+func (p *App) waitReady() error {
+	timeout := p.c.ReadyTimeout
+	if timeout == 0 {
+		timeout = time.Second * 30
+	}
+	deadline := time.Now().Add(timeout)
+
+	for _, c := range p.components {
+		if c.Ready == nil {
+			continue
+		}
+
+		for {
+			err := c.Ready()
+			if err == nil {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				log.Errorf("component %s not ready err:%v", c.Name, err)
+				return fmt.Errorf("component %s not ready: %w", c.Name, err)
+			}
+
+			log.Warnf("waiting for %s, err:%v", c.Name, err)
+			time.Sleep(time.Millisecond * 500)
+		}
+	}
+
+	return nil
+}
+
+// 按启动相反的顺序停止，单个组件停止失败或者超时不影响其他组件
+func (p *App) stopComponents(started []*Component) {
+	timeout := p.c.StopTimeout
+	if timeout == 0 {
+		timeout = time.Second * 10
+	}
+
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.Stop()
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Errorf("stop %s err:%v", c.Name, err)
+				continue
+			}
+			log.Infof("component %s stopped", c.Name)
+
+		case <-time.After(timeout):
+			log.Errorf("stop %s err:%v", c.Name, errors.New("timeout"))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package lrpc
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/core"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/json"
+	"github.com/valyala/fasthttp"
+	"io"
+	"runtime/debug"
+	"time"
+)
+
+func (p *App) maxBodySizeHandler(size int) HandlerFunc {
+	return func(ctx *Ctx) error {
+		if len(ctx.Body()) <= size {
+			return nil
+		}
+
+		log.Warnf("request body too large, path:%s, size:%d, limit:%d", ctx.Path(), len(ctx.Body()), size)
+		ctx.SendStatus(fasthttp.StatusRequestEntityTooLarge)
+		return xerror.NewInvalidParam(fmt.Sprintf("request body too large, limit %d", size))
+	}
+}
+
+// server 开启了 StreamRequestBody，长度已知的请求体已经按 Config.MaxRequestBodySize 读入内存，超过时为流
+// 除 StreamBody 的路由外，需要在这里按限制读取，与未开启时的行为保持一致
+func (p *App) readBody(ctx *Ctx) error {
+	stream := ctx.ctx.RequestBodyStream()
+	if stream == nil {
+		return nil
+	}
+
+	if tree := p.streamRoutes[ctx.Method()]; tree != nil {
+		if _, ok := tree.Search(ctx.Path()); ok {
+			return nil
+		}
+	}
+
+	limit := p.c.MaxRequestBodySize
+	if limit <= 0 {
+		limit = fasthttp.DefaultMaxRequestBodySize
+	}
+
+	size := ctx.ctx.Request.Header.ContentLength()
+	if size >= 0 && size <= limit {
+		return nil
+	}
+
+	// 分块传输时长度未知
+	var body []byte
+	if size < 0 {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		size = len(body)
+	}
+
+	if size > limit {
+		log.Warnf("request body too large, path:%s, size:%d, limit:%d", ctx.Path(), size, limit)
+		ctx.SendStatus(fasthttp.StatusRequestEntityTooLarge)
+		return xerror.NewInvalidParam(fmt.Sprintf("request body too large, limit %d", limit))
+	}
+
+	ctx.ctx.Request.SetBody(body)
+
+	return nil
+}
+
+// 超过处理时间的请求直接返回 503，handler 会在后台继续执行完成
+func (p *App) timeoutHandler(handler HandlerFunc, timeout time.Duration) HandlerFunc {
+	return func(ctx *Ctx) error {
+		ctx.SetDeadline(time.Now().Add(timeout))
+
+		done := make(chan error, 1)
+
+		traceId := log.GetTrace()
+		go func() {
+			log.SetTrace(traceId)
+			defer log.DelTrace()
+
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("panic:%v, method:%s, path:%s", r, ctx.Method(), ctx.Path())
+					log.Error(string(debug.Stack()))
+					// 与其他 panic 一样计数并上报到 CrashSink
+					p.reportPanic(xerror.FromPanic(r, "method", ctx.Method(), "path", ctx.Path()))
+					done <- xerror.New(xerror.ErrSystemError)
+				}
+			}()
+
+			done <- handler(ctx)
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case err := <-done:
+			return err
+
+		case <-timer.C:
+			log.Warnf("handler timeout, method:%s, path:%s, timeout:%s", ctx.Method(), ctx.Path(), timeout)
+
+			ctx.detached = true
+
+			buffer, err := json.Marshal(&core.BaseResponse{
+				Code:    xerror.ErrTimeout,
+				Message: "Timeout",
+				Hint:    ctx.TranceId(),
+			})
+			if err != nil {
+				log.Errorf("err:%v", err)
+			}
+
+			resp := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(resp)
+
+			resp.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			resp.Header.SetContentType(MIMEJson)
+			resp.SetBody(buffer)
+
+			// 之后对 ctx 的任何修改都会被忽略
+			ctx.Context().TimeoutErrorWithResponse(resp)
+
+			return nil
+		}
+	}
+}
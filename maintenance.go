@@ -0,0 +1,106 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MaintenanceConfig struct {
+	// 维护期间依旧可以访问的路径前缀，按路径的分段匹配，例如健康检查、管理接口，EnableDebug 的前缀会自动加入
+	Allow []string
+
+	// 启动时是否处于维护状态
+	Enable bool
+
+	// 返回给客户端的 Retry-After，为 0 时不设置
+	RetryAfter time.Duration
+}
+
+// 在 initConfig 中复制，不会为空
+func (p *App) maintenanceConfig() *MaintenanceConfig {
+	return p.c.Maintenance
+}
+
+// SetMaintenance 运行时切换维护状态，可以在配置中心的 OnChanged 中调用
+func (p *App) SetMaintenance(enable bool) {
+	if p.maintenance.Swap(enable) != enable {
+		log.Warnf("maintenance mode:%v", enable)
+	}
+}
+
+func (p *App) IsMaintenance() bool {
+	return p.maintenance.Load()
+}
+
+// AllowInMaintenance 追加维护期间允许访问的路径前缀
+func (p *App) AllowInMaintenance(prefixes ...string) {
+	p.maintenanceLock.Lock()
+	defer p.maintenanceLock.Unlock()
+
+	c := p.maintenanceConfig()
+	c.Allow = append(append([]string(nil), c.Allow...), prefixes...)
+}
+
+func (p *App) allowInMaintenance(path string) bool {
+	p.maintenanceLock.RLock()
+	defer p.maintenanceLock.RUnlock()
+
+	for _, prefix := range p.maintenanceConfig().Allow {
+		if hasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 按路径的分段匹配，/admin 不会匹配 /administrator
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// 维护期间除白名单外的请求直接返回 503，不会执行全局中间件
+func (p *App) checkMaintenance(ctx *Ctx) error {
+	if !p.maintenance.Load() || p.allowInMaintenance(ctx.Path()) {
+		return nil
+	}
+
+	// 没有设置 RetryAfter 时同样返回 503
+	ctx.SendStatus(fasthttp.StatusServiceUnavailable)
+	return xerror.NewUnavailable(p.maintenanceConfig().RetryAfter)
+}
+
+// 查询与切换维护状态的接口，由 EnableDebug 与 EnableAdmin 注册
+func (p *App) maintenanceRoutes() []*Route {
+	return []*Route{
+		{
+			Method: http.MethodGet,
+			Path:   "/maintenance",
+			Handler: func(ctx *Ctx) error {
+				return ctx.SendJson(map[string]bool{"enable": p.IsMaintenance()})
+			},
+		},
+		{
+			// ?enable=true/false
+			Method: http.MethodPost,
+			Path:   "/maintenance",
+			Handler: func(ctx *Ctx) error {
+				enable, err := strconv.ParseBool(ctx.Query("enable"))
+				if err != nil {
+					log.Errorf("err:%v", err)
+					ctx.SendStatus(fasthttp.StatusBadRequest)
+					return nil
+				}
+
+				p.SetMaintenance(enable)
+				return ctx.SendJson(map[string]bool{"enable": enable})
+			},
+		},
+	}
+}
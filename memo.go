@@ -0,0 +1,96 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"sync"
+)
+
+type memoEntry struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+type memoStore struct {
+	lock    sync.Mutex
+	entries map[string]*memoEntry
+}
+
+// Memo 在当前请求内缓存 loader 的结果，中间件与 handler 之间共享，请求结束后丢弃
+// 同一个 key 并发调用时只执行一次 loader，失败以及 panic 的结果不缓存
+func (p *Ctx) Memo(key string, loader func() (any, error)) (any, error) {
+	p.memoOnce.Do(func() {
+		p.memo = &memoStore{
+			entries: map[string]*memoEntry{},
+		}
+	})
+
+	p.memo.lock.Lock()
+	e, ok := p.memo.entries[key]
+	if ok {
+		p.memo.lock.Unlock()
+		<-e.done
+		return e.value, e.err
+	}
+
+	e = &memoEntry{
+		done: make(chan struct{}),
+	}
+	p.memo.entries[key] = e
+	p.memo.lock.Unlock()
+
+	// loader panic 时等待中的调用返回错误，panic 继续向上传递
+	finished := false
+	defer func() {
+		if !finished {
+			e.err = xerror.New(xerror.ErrSystemError)
+		}
+		if e.err != nil {
+			p.memo.lock.Lock()
+			delete(p.memo.entries, key)
+			p.memo.lock.Unlock()
+		}
+		close(e.done)
+	}()
+
+	e.value, e.err = loader()
+	finished = true
+
+	return e.value, e.err
+}
+
+// MemoForget 删除缓存的结果，用于请求中修改了对应的数据
+func (p *Ctx) MemoForget(keys ...string) {
+	if p.memo == nil {
+		return
+	}
+
+	p.memo.lock.Lock()
+	for _, key := range keys {
+		delete(p.memo.entries, key)
+	}
+	p.memo.lock.Unlock()
+}
+
+// CtxMemo 按类型使用 Ctx.Memo，缓存的值类型不匹配时重新加载
+func CtxMemo[T any](ctx *Ctx, key string, loader func() (T, error)) (T, error) {
+	value, err := ctx.Memo(key, func() (any, error) {
+		return loader()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if value == nil {
+		var zero T
+		return zero, nil
+	}
+
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	ctx.MemoForget(key)
+	return loader()
+}
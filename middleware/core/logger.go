@@ -0,0 +1,25 @@
+package core
+
+import (
+	"context"
+	"github.com/lazygophers/log"
+)
+
+// LoggerKey 请求的日志在 context 中的 key，值为 *log.Logger 或者实现了 Logger() *log.Logger 的类型，例如 lrpc.Ctx
+type LoggerKey struct{}
+
+func ContextWithLogger(ctx context.Context, logger *log.Logger) context.Context {
+	return context.WithValue(ctx, LoggerKey{}, logger)
+}
+
+// LoggerFromContext 没有设置时返回 nil
+func LoggerFromContext(ctx context.Context) *log.Logger {
+	switch x := ctx.Value(LoggerKey{}).(type) {
+	case *log.Logger:
+		return x
+	case interface{ Logger() *log.Logger }:
+		return x.Logger()
+	default:
+		return nil
+	}
+}
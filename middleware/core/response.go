@@ -0,0 +1,63 @@
+package core
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+)
+
+// Response 统一的响应格式，成功时 code 为 0
+type Response struct {
+	Code       int32     `json:"code"`
+	Message    string    `json:"message,omitempty"`
+	Data       any       `json:"data,omitempty"`
+	RequestId  string    `json:"request_id,omitempty"`
+	Pagination *Paginate `json:"pagination,omitempty"`
+}
+
+// Paginated 列表接口的返回实现后，分页信息会提升到 Response.Pagination
+type Paginated interface {
+	GetPaginate() *Paginate
+}
+
+// NewResponse 包装成功的返回
+func NewResponse(data any) *Response {
+	rsp := &Response{
+		Data: data,
+	}
+
+	if x, ok := data.(Paginated); ok {
+		rsp.Pagination = x.GetPaginate()
+	}
+
+	return rsp
+}
+
+// NewPageResponse 包装列表的返回，用于 data 本身不包含分页信息的场景
+func NewPageResponse(data any, page *Paginate) *Response {
+	return &Response{
+		Data:       data,
+		Pagination: page,
+	}
+}
+
+// NewErrorResponse 按 xerror 的错误码包装错误，其他错误的错误码为 xerror.ErrSystemError
+func NewErrorResponse(err error) *Response {
+	var x *xerror.Error
+	if !errors.As(err, &x) || x == nil {
+		return &Response{
+			Code:    xerror.ErrSystemError,
+			Message: err.Error(),
+		}
+	}
+
+	return &Response{
+		Code:    x.Code,
+		Message: x.Msg,
+	}
+}
+
+// WithRequestId 设置请求 ID，一般由框架统一设置
+func (p *Response) WithRequestId(requestId string) *Response {
+	p.RequestId = requestId
+	return p
+}
@@ -0,0 +1,85 @@
+package eventbus
+
+import (
+	"errors"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/json"
+)
+
+var ErrTransportNotSupport = errors.New("event bus transport not support")
+
+type Bus struct {
+	transport Transport
+}
+
+func NewBus(transport Transport) *Bus {
+	return &Bus{
+		transport: transport,
+	}
+}
+
+func New(c *Config) (*Bus, error) {
+	c.apply()
+
+	switch c.Type {
+	case "local":
+		return NewBus(NewLocal()), nil
+
+	case "cache":
+		if c.Cache == nil {
+			return nil, errors.New("cache is required")
+		}
+		return NewBus(NewCache(c.Cache)), nil
+
+	default:
+		return nil, ErrTransportNotSupport
+	}
+}
+
+var defaultBus = NewBus(NewLocal())
+
+// SetDefault 替换 Publish/Subscribe 使用的默认事件总线，默认为进程内分发
+func SetDefault(bus *Bus) {
+	defaultBus = bus
+}
+
+func PublishTo[T any](bus *Bus, topic string, ev T) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	err = bus.transport.Publish(topic, payload)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// SubscribeTo handler 返回的错误只会记录日志
+func SubscribeTo[T any](bus *Bus, topic string, handler func(ev T) error) error {
+	return bus.transport.Subscribe(topic, func(payload []byte) {
+		var ev T
+		err := json.Unmarshal(payload, &ev)
+		if err != nil {
+			log.Errorf("topic:%s, err:%v", topic, err)
+			return
+		}
+
+		err = handler(ev)
+		if err != nil {
+			log.Errorf("topic:%s, err:%v", topic, err)
+		}
+	})
+}
+
+func Publish[T any](topic string, ev T) error {
+	return PublishTo(defaultBus, topic, ev)
+}
+
+func Subscribe[T any](topic string, handler func(ev T) error) error {
+	return SubscribeTo(defaultBus, topic, handler)
+}
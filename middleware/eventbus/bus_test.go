@@ -0,0 +1,48 @@
+package eventbus_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/eventbus"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"testing"
+	"time"
+)
+
+type userCreated struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestBus(t *testing.T) {
+	for _, c := range []*eventbus.Config{
+		{},
+		{Type: "cache", Cache: cache.NewMem()},
+	} {
+		bus, err := eventbus.New(c)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		got := make(chan *userCreated, 1)
+		err = eventbus.SubscribeTo(bus, "user.created", func(ev *userCreated) error {
+			got <- ev
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		err = eventbus.PublishTo(bus, "user.created", &userCreated{Id: 1, Name: "a"})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		select {
+		case ev := <-got:
+			if ev.Id != 1 || ev.Name != "a" {
+				t.Fatalf("unexpected event: %+v", ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: event not received", c.Type)
+		}
+	}
+}
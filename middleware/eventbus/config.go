@@ -0,0 +1,21 @@
+package eventbus
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+)
+
+type Config struct {
+	// Event bus transport, support local, cache, default local
+	// local: dispatch in process
+	// cache: publish/subscribe of the cache middleware, Cache is required
+	Type string `yaml:"type"`
+
+	// Cache used by the cache transport
+	Cache cache.BaseCache `yaml:"-"`
+}
+
+func (c *Config) apply() {
+	if c.Type == "" {
+		c.Type = "local"
+	}
+}
@@ -0,0 +1,70 @@
+package eventbus
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/utils/routine"
+	"sync"
+)
+
+type Transport interface {
+	Publish(topic string, payload []byte) error
+	Subscribe(topic string, handler func(payload []byte)) error
+}
+
+// Local 进程内分发，每个订阅者在独立的 goroutine 中处理
+type Local struct {
+	lock     sync.RWMutex
+	handlers map[string][]func(payload []byte)
+}
+
+func NewLocal() *Local {
+	return &Local{
+		handlers: make(map[string][]func(payload []byte)),
+	}
+}
+
+func (p *Local) Publish(topic string, payload []byte) error {
+	p.lock.RLock()
+	handlers := p.handlers[topic]
+	p.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler := handler
+		routine.GoWithRecover(func() error {
+			handler(payload)
+			return nil
+		})
+	}
+
+	return nil
+}
+
+func (p *Local) Subscribe(topic string, handler func(payload []byte)) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.handlers[topic] = append(p.handlers[topic], handler)
+
+	return nil
+}
+
+// Cache 基于缓存的发布订阅，不保证送达
+type Cache struct {
+	cache cache.BaseCache
+}
+
+func NewCache(c cache.BaseCache) *Cache {
+	return &Cache{
+		cache: c,
+	}
+}
+
+func (p *Cache) Publish(topic string, payload []byte) error {
+	return p.cache.Publish(topic, string(payload))
+}
+
+func (p *Cache) Subscribe(topic string, handler func(payload []byte)) error {
+	return p.cache.Subscribe(topic, func(message string) {
+		handler([]byte(message))
+	})
+}
@@ -0,0 +1,40 @@
+package idgen
+
+import (
+	"github.com/lazygophers/log"
+	"sync/atomic"
+)
+
+var defaultSnowflake atomic.Pointer[Snowflake]
+
+func init() {
+	s, _ := NewSnowflake(0)
+	defaultSnowflake.Store(s)
+}
+
+// SetWorkerId 设置默认生成器的 worker，多实例部署时需要保证唯一，一般使用 LeaseWorkerId 分配
+func SetWorkerId(workerId int64) error {
+	s, err := NewSnowflake(workerId)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	defaultSnowflake.Store(s)
+	return nil
+}
+
+// SetWorkerLease 使用租约的 worker id 作为默认生成器，租约失效后 NextIdE 返回 ErrLeaseLost，NextId 会 panic
+func SetWorkerLease(lease *WorkerLease) {
+	defaultSnowflake.Store(lease.Snowflake())
+}
+
+// NextId 使用默认的 Snowflake 生成 id
+func NextId() int64 {
+	return defaultSnowflake.Load().Next()
+}
+
+// NextIdE 同 NextId，租约失效时返回 ErrLeaseLost 而不是 panic
+func NextIdE() (int64, error) {
+	return defaultSnowflake.Load().NextE()
+}
@@ -0,0 +1,177 @@
+package idgen_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/lrpc/middleware/idgen"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSnowflake(t *testing.T) {
+	s, err := idgen.NewSnowflake(3)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	last := int64(0)
+	for i := 0; i < 10000; i++ {
+		id := s.Next()
+		if id <= last {
+			t.Fatalf("id not increasing, last:%d, id:%d", last, id)
+		}
+		last = id
+	}
+
+	if d := time.Since(idgen.SnowflakeTime(last)); d < 0 || d > time.Second {
+		t.Errorf("unexpected time:%v", idgen.SnowflakeTime(last))
+	}
+
+	_, err = idgen.NewSnowflake(idgen.MaxWorkerId + 1)
+	if err != idgen.ErrInvalidWorkerId {
+		t.Errorf("err:%v", err)
+	}
+}
+
+func TestULID(t *testing.T) {
+	last := ""
+	for i := 0; i < 1000; i++ {
+		id := idgen.NewULID()
+		if len(id) != 26 || id <= last {
+			t.Fatalf("invalid ulid, last:%s, id:%s", last, id)
+		}
+		last = id
+	}
+
+	if len(idgen.NewUUIDv7()) != 36 {
+		t.Errorf("invalid uuid")
+	}
+}
+
+func TestLeaseWorkerId(t *testing.T) {
+	c := cache.NewMem()
+
+	a, err := idgen.LeaseWorkerId(c)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	b, err := idgen.LeaseWorkerId(c)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if a.WorkerId() == b.WorkerId() {
+		t.Fatalf("duplicate worker id:%d", a.WorkerId())
+	}
+
+	_ = a.Release()
+	_ = b.Release()
+}
+
+type idModel struct {
+	Id   int64 `gorm:"primaryKey"`
+	Name string
+}
+
+func TestFillId(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address:     t.TempDir(),
+		Name:        "idgen",
+		IdGenerator: idgen.NextIdE,
+	}, &idModel{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	m := &idModel{Name: "a"}
+	err = cli.NewScoop().Create(m).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if m.Id < 1<<22 {
+		t.Errorf("id not filled:%d", m.Id)
+	}
+
+	ms := []*idModel{{Name: "b"}, {Id: 7, Name: "c"}}
+	err = cli.NewScoop().Create(&ms).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if ms[0].Id < 1<<22 || ms[1].Id != 7 {
+		t.Errorf("unexpected ids:%d, %d", ms[0].Id, ms[1].Id)
+	}
+}
+
+func TestWorkerLeaseOwner(t *testing.T) {
+	c := cache.NewMem()
+
+	var lost atomic.Bool
+	a, err := idgen.LeaseWorkerId(c, &idgen.LeaseConfig{
+		TTL: time.Millisecond * 60,
+		OnLost: func(workerId int64) {
+			lost.Store(true)
+		},
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	s := a.Snowflake()
+	s.Next()
+
+	// 模拟暂停超过 TTL 后被其他实例占用
+	key := fmt.Sprintf("idgen:worker:%d", a.WorkerId())
+	err = c.SetEx(key, "other", time.Minute)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+
+	if !lost.Load() || a.Valid() {
+		t.Fatal("lease should be lost")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != idgen.ErrLeaseLost {
+				t.Errorf("recover:%v", r)
+			}
+		}()
+		s.Next()
+	}()
+
+	if _, err = s.NextE(); err != idgen.ErrLeaseLost {
+		t.Errorf("err:%v", err)
+	}
+
+	// 写入数据库时中止创建，而不是在回调中 panic
+	cli, err := db.New(&db.Config{
+		Address:     t.TempDir(),
+		Name:        "idgen",
+		IdGenerator: s.NextE,
+	}, &idModel{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	err = cli.NewScoop().Create(&idModel{Name: "a"}).Error
+	if !errors.Is(err, idgen.ErrLeaseLost) {
+		t.Errorf("err:%v", err)
+	}
+	var count int64
+	cli.Database().Model(&idModel{}).Count(&count)
+	if count != 0 {
+		t.Errorf("count:%d", count)
+	}
+
+	// 不能删除其他实例的租约
+	_ = a.Release()
+	value, err := c.Get(key)
+	if err != nil || value != "other" {
+		t.Errorf("value:%s, err:%v", value, err)
+	}
+}
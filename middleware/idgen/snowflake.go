@@ -0,0 +1,92 @@
+package idgen
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	workerBits   = 10
+	sequenceBits = 12
+
+	MaxWorkerId = 1<<workerBits - 1
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// 2024-01-01 00:00:00 UTC，41 位毫秒可以使用约 69 年
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+var ErrInvalidWorkerId = errors.New("invalid worker id")
+
+// Snowflake 生成按时间递增的 int64，41 位毫秒 + 10 位 worker + 12 位序号，单个 worker 每毫秒最多 4096 个
+type Snowflake struct {
+	lock sync.Mutex
+
+	workerId int64
+	lastMs   int64
+	sequence int64
+
+	// 通过 WorkerLease.Snowflake 创建时不为空
+	lease *WorkerLease
+}
+
+func NewSnowflake(workerId int64) (*Snowflake, error) {
+	if workerId < 0 || workerId > MaxWorkerId {
+		return nil, ErrInvalidWorkerId
+	}
+
+	return &Snowflake{
+		workerId: workerId,
+	}, nil
+}
+
+func (p *Snowflake) WorkerId() int64 {
+	return p.workerId
+}
+
+// Next 同 NextE，租约失效后 panic ErrLeaseLost
+func (p *Snowflake) Next() int64 {
+	id, err := p.NextE()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// NextE 时钟回拨时沿用上一次的时间，序号用完后等待下一毫秒，保证不重复
+// 租约失效后返回 ErrLeaseLost，避免与占用了同一个 worker id 的实例生成重复的 id
+func (p *Snowflake) NextE() (int64, error) {
+	if p.lease != nil && !p.lease.Valid() {
+		return 0, ErrLeaseLost
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now().UnixMilli() - epoch
+	if now < p.lastMs {
+		now = p.lastMs
+	}
+
+	if now == p.lastMs {
+		p.sequence = (p.sequence + 1) & maxSequence
+		if p.sequence == 0 {
+			for now <= p.lastMs {
+				time.Sleep(time.Millisecond / 10)
+				now = time.Now().UnixMilli() - epoch
+			}
+		}
+	} else {
+		p.sequence = 0
+	}
+
+	p.lastMs = now
+
+	return now<<(workerBits+sequenceBits) | p.workerId<<sequenceBits | p.sequence, nil
+}
+
+// SnowflakeTime 解析 id 中的生成时间
+func SnowflakeTime(id int64) time.Time {
+	return time.UnixMilli(id>>(workerBits+sequenceBits) + epoch)
+}
@@ -0,0 +1,70 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// 同一毫秒内在上一个的随机部分上加一，保证单进程内严格递增
+type ulidGenerator struct {
+	lock   sync.Mutex
+	lastMs uint64
+	// 80 位随机数，hi 16 位 + lo 64 位
+	hi uint16
+	lo uint64
+}
+
+var ulidGen ulidGenerator
+
+func (p *ulidGenerator) next() [16]byte {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= p.lastMs {
+		ms = p.lastMs
+		p.lo++
+		if p.lo == 0 {
+			p.hi++
+		}
+	} else {
+		var b [10]byte
+		_, _ = rand.Read(b[:])
+		p.hi = binary.BigEndian.Uint16(b[:2])
+		p.lo = binary.BigEndian.Uint64(b[2:])
+		p.lastMs = ms
+	}
+
+	var id [16]byte
+	id[0] = byte(ms >> 40)
+	id[1] = byte(ms >> 32)
+	id[2] = byte(ms >> 24)
+	id[3] = byte(ms >> 16)
+	id[4] = byte(ms >> 8)
+	id[5] = byte(ms)
+	binary.BigEndian.PutUint16(id[6:8], p.hi)
+	binary.BigEndian.PutUint64(id[8:], p.lo)
+
+	return id
+}
+
+// NewULID 26 位 Crockford base32，按时间排序，字典序与生成顺序一致
+func NewULID() string {
+	id := ulidGen.next()
+
+	// 128 位从高位开始每 5 位一个字符，首个字符只有 3 位
+	var dst [26]byte
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	for i := 25; i >= 0; i-- {
+		dst[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(dst[:])
+}
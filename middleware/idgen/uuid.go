@@ -0,0 +1,17 @@
+package idgen
+
+import (
+	"github.com/google/uuid"
+	"github.com/lazygophers/log"
+)
+
+// NewUUIDv7 按时间排序的 uuid，适合作为数据库的字符串主键
+func NewUUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return uuid.NewString()
+	}
+
+	return id.String()
+}
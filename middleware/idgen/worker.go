@@ -0,0 +1,260 @@
+package idgen
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"github.com/lazygophers/utils/routine"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNoWorkerId = errors.New("no worker id available")
+	// ErrLeaseLost 租约丢失后 worker id 可能已经被其他实例占用，继续生成会产生重复的 id
+	ErrLeaseLost = errors.New("worker id lease lost")
+)
+
+const (
+	leaseRenewScript   = "idgen:lease_renew"
+	leaseReleaseScript = "idgen:lease_release"
+)
+
+// 只有持有租约的实例才能续期、释放，避免暂停超过 TTL 后续期或者删除其他实例的租约
+func init() {
+	cache.RegisterScript(&cache.Script{
+		Name: leaseRenewScript,
+		Lua: `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end
+return 0`,
+		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) {
+			ms, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			ok, err := leaseOwned(tx, keys[0], args[0])
+			if err != nil || !ok {
+				return int64(0), err
+			}
+
+			return int64(1), tx.SetEx(keys[0], args[0], time.Duration(ms)*time.Millisecond)
+		},
+	})
+
+	cache.RegisterScript(&cache.Script{
+		Name: leaseReleaseScript,
+		Lua: `
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`,
+		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) {
+			ok, err := leaseOwned(tx, keys[0], args[0])
+			if err != nil || !ok {
+				return int64(0), err
+			}
+
+			return int64(1), tx.Del(keys[0])
+		},
+	})
+}
+
+func leaseOwned(tx cache.ScriptTx, key, token string) (bool, error) {
+	value, err := tx.Get(key)
+	if err != nil {
+		if errors.Is(err, cache.NotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return value == token, nil
+}
+
+type LeaseConfig struct {
+	// 租约的有效期，会在 1/3 时续期，默认 30s
+	TTL time.Duration
+
+	// 租约丢失时回调，之后通过 WorkerLease.Snowflake 生成 id 会 panic
+	OnLost func(workerId int64)
+}
+
+func (c *LeaseConfig) apply() {
+	if c.TTL == 0 {
+		c.TTL = time.Second * 30
+	}
+}
+
+// WorkerLease 通过 cache 或者数据库锁独占一个 worker id
+type WorkerLease struct {
+	workerId int64
+
+	// 最后一次续期成功的时间，ttl 为 0 时不检查
+	ttl       time.Duration
+	renewedAt atomic.Int64
+	lost      atomic.Bool
+
+	release  func() error
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func (p *WorkerLease) WorkerId() int64 {
+	return p.workerId
+}
+
+// Valid 租约丢失或者超过 TTL 没有续期成功后为 false，此时不能再使用这个 worker id 生成 id
+func (p *WorkerLease) Valid() bool {
+	if p.lost.Load() {
+		return false
+	}
+
+	return p.ttl == 0 || time.Since(time.Unix(0, p.renewedAt.Load())) < p.ttl
+}
+
+// Snowflake 使用租约的 worker id 生成 id，租约失效后 Next 会 panic
+func (p *WorkerLease) Snowflake() *Snowflake {
+	return &Snowflake{
+		workerId: p.workerId,
+		lease:    p,
+	}
+}
+
+func (p *WorkerLease) markLost(onLost func(workerId int64)) {
+	if !p.lost.CompareAndSwap(false, true) {
+		return
+	}
+
+	log.Errorf("worker id %d lease lost", p.workerId)
+	if onLost != nil {
+		onLost(p.workerId)
+	}
+}
+
+// Release 释放 worker id，进程退出前调用，未调用时等待租约过期
+func (p *WorkerLease) Release() error {
+	var err error
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		err = p.release()
+	})
+	return err
+}
+
+// 同一个进程多次租用时也需要区分
+func leaseOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+}
+
+// LeaseWorkerId 基于 cache 的 SetNx 抢占一个空闲的 worker id，并定期续期
+func LeaseWorkerId(c cache.Cache, configs ...*LeaseConfig) (*WorkerLease, error) {
+	cfg := &LeaseConfig{}
+	if len(configs) > 0 {
+		cfg = configs[0]
+	}
+	cfg.apply()
+
+	owner := leaseOwner()
+	for id := int64(0); id <= MaxWorkerId; id++ {
+		key := fmt.Sprintf("idgen:worker:%d", id)
+
+		ok, err := c.SetNxWithTimeout(key, owner, cfg.TTL)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		lease := &WorkerLease{
+			workerId: id,
+			ttl:      cfg.TTL,
+			stop:     make(chan struct{}),
+			release: func() error {
+				_, err := c.Script(leaseReleaseScript).Run([]string{key}, owner)
+				return err
+			},
+		}
+		lease.renewedAt.Store(time.Now().UnixNano())
+
+		routine.GoWithRecover(func() error {
+			ticker := time.NewTicker(cfg.TTL / 3)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-lease.stop:
+					return nil
+				case <-ticker.C:
+					start := time.Now()
+					n, err := c.Script(leaseRenewScript).Int64([]string{key}, owner, cfg.TTL.Milliseconds())
+					if err != nil {
+						log.Errorf("err:%v", err)
+						// 一直续期失败时租约会在 TTL 后过期，由 Valid 判断
+						if !lease.Valid() {
+							lease.markLost(cfg.OnLost)
+							return nil
+						}
+						continue
+					}
+
+					if n == 0 {
+						lease.markLost(cfg.OnLost)
+						return nil
+					}
+					lease.renewedAt.Store(start.UnixNano())
+				}
+			}
+		})
+
+		log.Infof("lease worker id %d", id)
+
+		return lease, nil
+	}
+
+	return nil, ErrNoWorkerId
+}
+
+// LeaseWorkerIdFromDb 基于数据库的会话级锁抢占 worker id，连接断开后自动释放，只支持 mysql、postgres
+func LeaseWorkerIdFromDb(cli *db.Client, configs ...*LeaseConfig) (*WorkerLease, error) {
+	cfg := &LeaseConfig{}
+	if len(configs) > 0 {
+		cfg = configs[0]
+	}
+	cfg.apply()
+
+	for id := int64(0); id <= MaxWorkerId; id++ {
+		lease := &WorkerLease{
+			workerId: id,
+			stop:     make(chan struct{}),
+		}
+
+		leader, ok, err := cli.TryAcquire(fmt.Sprintf("idgen:worker:%d", id), &db.LeaderConfig{
+			KeepAlive: cfg.TTL / 3,
+			OnLost: func(name string) {
+				lease.markLost(cfg.OnLost)
+			},
+		})
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		log.Infof("lease worker id %d", id)
+
+		lease.release = leader.Release
+		return lease, nil
+	}
+
+	return nil, ErrNoWorkerId
+}
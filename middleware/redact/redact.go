@@ -0,0 +1,281 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask 替换敏感内容后的值
+const Mask = "***"
+
+var (
+	lock   sync.RWMutex
+	fields = map[string]bool{
+		"password": true,
+		"passwd":   true,
+		"secret":   true,
+		"token":    true,
+		"email":    true,
+	}
+)
+
+// SetFields 替换需要脱敏的字段，不区分大小写
+func SetFields(names ...string) {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[strings.ToLower(name)] = true
+	}
+
+	lock.Lock()
+	fields = m
+	lock.Unlock()
+}
+
+// AddFields 追加需要脱敏的字段
+func AddFields(names ...string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := make(map[string]bool, len(fields)+len(names))
+	for k := range fields {
+		m[k] = true
+	}
+	for _, name := range names {
+		m[strings.ToLower(name)] = true
+	}
+	fields = m
+}
+
+// IsSensitive 字段名等于或者以 _ 分隔包含敏感字段时返回 true，例如 access_token、user_email
+func IsSensitive(name string) bool {
+	name = strings.ToLower(strings.Trim(name, "`\"'[]"))
+	if name == "" {
+		return false
+	}
+
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if fields[name] {
+		return true
+	}
+
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	}) {
+		if fields[part] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value 字段敏感时返回 Mask
+func Value(name string, value any) any {
+	if IsSensitive(name) {
+		return Mask
+	}
+	return value
+}
+
+// Map 返回脱敏后的副本，会处理嵌套的 map 和切片，用于记录请求、文档等
+func Map(m map[string]any) map[string]any {
+	if m == nil {
+		return nil
+	}
+
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if IsSensitive(k) {
+			out[k] = Mask
+			continue
+		}
+		out[k] = Any(v)
+	}
+	return out
+}
+
+// Any 与 Map 相同，用于顶层不是 map 的值，例如 json 数组
+func Any(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		return Map(x)
+	case []any:
+		list := make([]any, len(x))
+		for i, item := range x {
+			list[i] = Any(item)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// Key 脱敏缓存的 key，以 : 分隔，敏感字段后面的一段会被替换，例如 session:token:abc 变为 session:token:***
+func Key(key string) string {
+	parts := strings.Split(key, ":")
+	changed := false
+	for i := 0; i+1 < len(parts); i++ {
+		if IsSensitive(parts[i]) {
+			parts[i+1] = Mask
+			changed = true
+			i++
+		}
+	}
+
+	if !changed {
+		return key
+	}
+	return strings.Join(parts, ":")
+}
+
+const sqlValue = `'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.)*"|-?\d+(?:\.\d+)?`
+
+var (
+	sqlCompare = regexp.MustCompile("(?i)([`\"]?\\w+[`\"]?)(\\s*(?:=|!=|<>|>=|<=|>|<|\\s+NOT\\s+LIKE\\s+|\\s+LIKE\\s+)\\s*)(" + sqlValue + ")")
+	sqlIn      = regexp.MustCompile("(?i)([`\"]?\\w+[`\"]?)(\\s+(?:NOT\\s+)?IN\\s*)\\(([^)]*)\\)")
+	sqlInsert  = regexp.MustCompile("(?is)^(\\s*(?:INSERT|REPLACE)\\b[^(]*?\\()([^)]*)(\\)\\s*VALUES\\s*)")
+)
+
+// SQL 脱敏日志中的 sql，处理比较、IN 以及 INSERT 的 VALUES，只用于日志，不保证结果可以执行
+func SQL(sql string) string {
+	sql = replaceSubmatch(sql, sqlCompare, func(groups []string) string {
+		if !IsSensitive(groups[1]) {
+			return groups[0]
+		}
+		return groups[1] + groups[2] + `"` + Mask + `"`
+	})
+
+	sql = replaceSubmatch(sql, sqlIn, func(groups []string) string {
+		if !IsSensitive(groups[1]) {
+			return groups[0]
+		}
+		return groups[1] + groups[2] + "(" + Mask + ")"
+	})
+
+	return redactInsert(sql)
+}
+
+func replaceSubmatch(s string, re *regexp.Regexp, fn func(groups []string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		groups := make([]string, len(m)/2)
+		for i := range groups {
+			if m[2*i] >= 0 {
+				groups[i] = s[m[2*i]:m[2*i+1]]
+			}
+		}
+
+		b.WriteString(s[last:m[0]])
+		b.WriteString(fn(groups))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+func redactInsert(sql string) string {
+	m := sqlInsert.FindStringSubmatchIndex(sql)
+	if m == nil {
+		return sql
+	}
+
+	columns := strings.Split(sql[m[4]:m[5]], ",")
+	sensitive := make([]bool, len(columns))
+	found := false
+	for i, c := range columns {
+		sensitive[i] = IsSensitive(strings.TrimSpace(c))
+		found = found || sensitive[i]
+	}
+	if !found {
+		return sql
+	}
+
+	var b strings.Builder
+	b.WriteString(sql[:m[1]])
+
+	rest := sql[m[1]:]
+	for {
+		rest = strings.TrimLeft(rest, " \t\n")
+		if !strings.HasPrefix(rest, "(") {
+			break
+		}
+
+		values, n := splitTuple(rest)
+		if n < 0 {
+			break
+		}
+
+		b.WriteString("(")
+		for i, v := range values {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if i < len(sensitive) && sensitive[i] {
+				b.WriteString(`"` + Mask + `"`)
+			} else {
+				b.WriteString(v)
+			}
+		}
+		b.WriteString(")")
+		rest = rest[n:]
+
+		trimmed := strings.TrimLeft(rest, " \t\n")
+		if !strings.HasPrefix(trimmed, ",") {
+			break
+		}
+		b.WriteString(",")
+		rest = trimmed[1:]
+	}
+	b.WriteString(rest)
+
+	return b.String()
+}
+
+// 拆分以 ( 开头的一组值，返回各个值以及消耗的长度，括号不匹配时返回 -1
+func splitTuple(s string) ([]string, int) {
+	var values []string
+	depth := 0
+	start := 1
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				values = append(values, s[start:i])
+				return values, i + 1
+			}
+		case ',':
+			if depth == 1 {
+				values = append(values, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return nil, -1
+}
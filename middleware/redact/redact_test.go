@@ -0,0 +1,59 @@
+package redact_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"testing"
+)
+
+func TestSQL(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{
+			"SELECT * FROM user WHERE (`name` = \"a\") AND (`password` = \"p@ss\")",
+			"SELECT * FROM user WHERE (`name` = \"a\") AND (`password` = \"***\")",
+		},
+		{
+			"UPDATE user SET `access_token`=\"abc\", `age`=1 WHERE id = 1",
+			"UPDATE user SET `access_token`=\"***\", `age`=1 WHERE id = 1",
+		},
+		{
+			"SELECT * FROM user WHERE email IN ('a@x.com','b@x.com')",
+			"SELECT * FROM user WHERE email IN (***)",
+		},
+		{
+			"INSERT INTO `user` (`name`,`password`,`age`) VALUES (\"a\",\"x,y\",1),(\"b\",'z',2) RETURNING `id`",
+			"INSERT INTO `user` (`name`,`password`,`age`) VALUES (\"a\",\"***\",1),(\"b\",\"***\",2) RETURNING `id`",
+		},
+		{
+			"SELECT * FROM user WHERE id = 1",
+			"SELECT * FROM user WHERE id = 1",
+		},
+	}
+
+	for _, c := range cases {
+		if got := redact.SQL(c.in); got != c.out {
+			t.Errorf("\nin:  %s\ngot: %s\nwant:%s", c.in, got, c.out)
+		}
+	}
+}
+
+func TestMapAndKey(t *testing.T) {
+	m := redact.Map(map[string]any{
+		"name":  "a",
+		"Token": "x",
+		"profile": map[string]any{
+			"user_email": "a@x.com",
+		},
+	})
+	if m["name"] != "a" || m["Token"] != redact.Mask || m["profile"].(map[string]any)["user_email"] != redact.Mask {
+		t.Errorf("unexpected result: %v", m)
+	}
+
+	if got := redact.Key("app:session:token:abc:meta"); got != "app:session:token:***:meta" {
+		t.Errorf("key:%s", got)
+	}
+	if got := redact.Key("app:user:1"); got != "app:user:1" {
+		t.Errorf("key:%s", got)
+	}
+}
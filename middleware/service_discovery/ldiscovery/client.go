@@ -4,10 +4,8 @@ import (
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/lrpc/middleware/core"
 	"github.com/lazygophers/lrpc/middleware/xerror"
-	"github.com/lazygophers/utils/app"
 	"github.com/valyala/fasthttp"
 	"math/rand"
-	"net"
 	"net/url"
 	"time"
 )
@@ -55,22 +53,10 @@ func ChooseNode(name string) (*core.ServiceDiscoveryNode, error) {
 	return nil, xerror.NewError(int32(core.ErrCode_ServerAliveNodeNotFound))
 }
 
+// DiscoveryClient 返回服务共用的连接池与请求，重试的规则见 PoolConfig.MaxIdempotentCallAttempts
 func DiscoveryClient(c *core.ServiceDiscoveryClient) (*fasthttp.HostClient, *fasthttp.Request) {
-	client := &fasthttp.HostClient{
-		Addr:                     c.ServiceName,
-		Name:                     app.Name,
-		NoDefaultUserAgentHeader: true,
-		Dial: func(addr string) (net.Conn, error) {
-			node, err := ChooseNode(addr)
-			if err != nil {
-				log.Errorf("err:%v", err)
-				return nil, err
-			}
-
-			return net.Dial("tcp", net.JoinHostPort(node.Host, node.Port))
-		},
-		Transport: nil,
-	}
+	// 同一个服务共用连接池
+	client := getHostClient(c.ServiceName)
 
 	u := &url.URL{
 		Scheme: "lrpc",
@@ -0,0 +1,166 @@
+package ldiscovery
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/app"
+	"github.com/valyala/fasthttp"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PoolConfig 每个服务共用一个 fasthttp.HostClient，建立连接时通过 ChooseNode 选择节点
+// 只支持 HTTP/1.1，不区分节点建立连接池，也不做主动的健康检查
+// 节点是否可用以服务发现中的 Alive 为准，出错的连接由 fasthttp 直接关闭，不会放回连接池
+type PoolConfig struct {
+	// 每个服务的最大连接数，默认 512
+	MaxConns int
+
+	// 空闲连接的最大保持时间，默认 10s
+	MaxIdleConnDuration time.Duration
+
+	// 连接的最大存活时间，到期后关闭重建，用于节点变化后重新均衡，为 0 时不限制
+	MaxConnDuration time.Duration
+
+	// 连接数达到上限时的最大等待时间，为 0 时直接返回 fasthttp.ErrNoFreeConns
+	MaxConnWaitTimeout time.Duration
+
+	// 建立连接的超时时间，默认 3s
+	DialTimeout time.Duration
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// 使用 LIFO 复用连接，低峰期多余的连接会因为空闲而被回收
+	LIFO bool
+
+	// 请求的最大尝试次数，默认 5
+	// GET、HEAD、PUT 在连接出错时重试，lrpc 的调用默认为 POST，只在复用的空闲连接已经被对端关闭(io.EOF)时重试，
+	// 其他错误直接返回，需要调用方自行决定是否重试
+	MaxIdempotentCallAttempts int
+}
+
+func (c *PoolConfig) apply() {
+	if c.MaxConns == 0 {
+		c.MaxConns = fasthttp.DefaultMaxConnsPerHost
+	}
+
+	if c.MaxIdleConnDuration == 0 {
+		c.MaxIdleConnDuration = fasthttp.DefaultMaxIdleConnDuration
+	}
+
+	if c.DialTimeout == 0 {
+		c.DialTimeout = time.Second * 3
+	}
+
+	if c.MaxIdempotentCallAttempts == 0 {
+		c.MaxIdempotentCallAttempts = fasthttp.DefaultMaxIdemponentCallAttempts
+	}
+}
+
+type PoolStats struct {
+	ServiceName string
+
+	// 当前的连接数，包括空闲和使用中的
+	Conns int
+
+	// 正在处理的请求数
+	Pending int
+}
+
+var (
+	poolLock   sync.RWMutex
+	poolConfig = &PoolConfig{}
+	pool       = map[string]*fasthttp.HostClient{}
+)
+
+func init() {
+	poolConfig.apply()
+}
+
+// SetPoolConfig 修改连接池配置，只对之后新建的连接池生效，已有的连接池会被关闭重建
+func SetPoolConfig(c *PoolConfig) {
+	c.apply()
+
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	poolConfig = c
+
+	for name, client := range pool {
+		client.CloseIdleConnections()
+		delete(pool, name)
+	}
+}
+
+func newHostClient(serviceName string, c *PoolConfig) *fasthttp.HostClient {
+	client := &fasthttp.HostClient{
+		Addr:                      serviceName,
+		Name:                      app.Name,
+		NoDefaultUserAgentHeader:  true,
+		MaxConns:                  c.MaxConns,
+		MaxConnDuration:           c.MaxConnDuration,
+		MaxIdleConnDuration:       c.MaxIdleConnDuration,
+		MaxIdemponentCallAttempts: c.MaxIdempotentCallAttempts,
+		ReadTimeout:               c.ReadTimeout,
+		WriteTimeout:              c.WriteTimeout,
+		MaxConnWaitTimeout:        c.MaxConnWaitTimeout,
+		Dial: func(addr string) (net.Conn, error) {
+			node, err := ChooseNode(addr)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			return net.DialTimeout("tcp", net.JoinHostPort(node.Host, node.Port), c.DialTimeout)
+		},
+	}
+
+	if c.LIFO {
+		client.ConnPoolStrategy = fasthttp.LIFO
+	}
+
+	return client
+}
+
+func getHostClient(serviceName string) *fasthttp.HostClient {
+	poolLock.RLock()
+	client, ok := pool[serviceName]
+	poolLock.RUnlock()
+	if ok {
+		return client
+	}
+
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	client, ok = pool[serviceName]
+	if !ok {
+		client = newHostClient(serviceName, poolConfig)
+		pool[serviceName] = client
+	}
+
+	return client
+}
+
+// GetPoolStats 返回各个服务连接池的状态
+func GetPoolStats() []*PoolStats {
+	poolLock.RLock()
+	defer poolLock.RUnlock()
+
+	stats := make([]*PoolStats, 0, len(pool))
+	for name, client := range pool {
+		stats = append(stats, &PoolStats{
+			ServiceName: name,
+			Conns:       client.ConnsCount(),
+			Pending:     client.PendingRequests(),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].ServiceName < stats[j].ServiceName
+	})
+
+	return stats
+}
@@ -0,0 +1,56 @@
+package ldiscovery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetHostClient(t *testing.T) {
+	t.Cleanup(func() {
+		SetPoolConfig(&PoolConfig{})
+	})
+	SetPoolConfig(&PoolConfig{})
+
+	// 同一个服务共用连接池
+	a := getHostClient("a")
+	if getHostClient("a") != a {
+		t.Error("client should be shared by the same service")
+	}
+	if getHostClient("b") == a {
+		t.Error("client should not be shared by different services")
+	}
+
+	if a.MaxConns != 512 || a.MaxIdleConnDuration != 10*time.Second || a.MaxIdemponentCallAttempts != 5 {
+		t.Errorf("max conns:%d, max idle:%v, attempts:%d", a.MaxConns, a.MaxIdleConnDuration, a.MaxIdemponentCallAttempts)
+	}
+
+	stats := GetPoolStats()
+	if len(stats) != 2 || stats[0].ServiceName != "a" || stats[1].ServiceName != "b" {
+		t.Errorf("stats:%v", stats)
+	}
+}
+
+func TestSetPoolConfig(t *testing.T) {
+	t.Cleanup(func() {
+		SetPoolConfig(&PoolConfig{})
+	})
+	SetPoolConfig(&PoolConfig{})
+
+	old := getHostClient("a")
+
+	// 修改配置后已有的连接池被移除，之后按新的配置重建
+	SetPoolConfig(&PoolConfig{
+		MaxConns: 8,
+	})
+	if stats := GetPoolStats(); len(stats) != 0 {
+		t.Errorf("stats:%v", stats)
+	}
+
+	client := getHostClient("a")
+	if client == old {
+		t.Fatal("client should be rebuilt")
+	}
+	if client.MaxConns != 8 || client.MaxIdleConnDuration != 10*time.Second {
+		t.Errorf("max conns:%d, max idle:%v", client.MaxConns, client.MaxIdleConnDuration)
+	}
+}
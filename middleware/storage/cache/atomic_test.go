@@ -0,0 +1,125 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompareAndSet(t *testing.T) {
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	for name, c := range map[string]cache.Cache{"mem": mem, "bbolt": bolt, "namespace": mem.Namespace("ns:")} {
+		ok, err := c.CompareAndSet("cas", "a", "b")
+		if err != nil || ok {
+			t.Errorf("%s: missing key, ok:%v, err:%v", name, ok, err)
+		}
+
+		_ = c.SetEx("cas", 1, time.Minute)
+
+		ok, err = c.CompareAndSet("cas", "2", "3")
+		if err != nil || ok {
+			t.Errorf("%s: mismatch, ok:%v, err:%v", name, ok, err)
+		}
+
+		ok, err = c.CompareAndSet("cas", 1, 2)
+		if err != nil || !ok {
+			t.Errorf("%s: match, ok:%v, err:%v", name, ok, err)
+		}
+
+		s, err := c.Get("cas")
+		if err != nil || s != "2" {
+			t.Errorf("%s: value:%s, err:%v", name, s, err)
+		}
+	}
+
+	// 保留原有的过期时间
+	ttl, err := mem.Ttl("cas")
+	if err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl:%v, err:%v", ttl, err)
+	}
+}
+
+func TestGetDel(t *testing.T) {
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	for name, c := range map[string]cache.Cache{"mem": mem, "bbolt": bolt, "namespace": mem.Namespace("ns:")} {
+		_, err := c.GetDel("k")
+		if !errors.Is(err, cache.NotFound) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		_ = c.Set("k", "v")
+
+		s, err := c.GetDel("k")
+		if err != nil || s != "v" {
+			t.Errorf("%s: value:%s, err:%v", name, s, err)
+		}
+
+		_, err = c.Get("k")
+		if !errors.Is(err, cache.NotFound) {
+			t.Errorf("%s: not deleted, err:%v", name, err)
+		}
+	}
+}
+
+func TestGetEx(t *testing.T) {
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	for name, c := range map[string]cache.Cache{"mem": mem, "bbolt": bolt} {
+		_, err := c.GetEx("k", time.Minute)
+		if !errors.Is(err, cache.NotFound) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		_ = c.SetEx("k", "v", time.Minute)
+
+		// 过期时间被缩短后 key 很快失效
+		s, err := c.GetEx("k", time.Millisecond*10)
+		if err != nil || s != "v" {
+			t.Errorf("%s: value:%s, err:%v", name, s, err)
+		}
+
+		time.Sleep(time.Millisecond * 20)
+
+		_, err = c.Get("k")
+		if !errors.Is(err, cache.NotFound) {
+			t.Errorf("%s: not expired, err:%v", name, err)
+		}
+	}
+
+	// timeout 为 0 时移除过期时间
+	_ = mem.SetEx("k", "v", time.Minute)
+	_, err = mem.GetEx("k", 0)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	ttl, _ := mem.Ttl("k")
+	if ttl != -time.Second {
+		t.Errorf("ttl:%v", ttl)
+	}
+}
@@ -3,11 +3,15 @@ package cache
 import (
 	"github.com/lazygophers/utils/anyx"
 	"github.com/lazygophers/utils/json"
+	"sync"
 	"time"
 )
 
 type baseCache struct {
 	BaseCache
+
+	// 正在后台刷新的 key
+	refreshing sync.Map
 }
 
 func (p *baseCache) GetBool(key string) (bool, error) {
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"github.com/beefsack/go-rate"
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/utils/app"
@@ -11,7 +12,13 @@ import (
 )
 
 var (
-	bboltBucket = []byte(app.Name)
+	// bucket 名不能为空，没有设置 app.Name 时使用默认值
+	bboltBucket = func() []byte {
+		if app.Name == "" {
+			return []byte("lrpc")
+		}
+		return []byte(app.Name)
+	}()
 )
 
 type Bbolt struct {
@@ -54,6 +61,95 @@ func (p *Bbolt) Exists(keys ...string) (bool, error) {
 	panic("implement me")
 }
 
+// 读取未过期的 item，不存在时返回 nil
+func (p *Bbolt) getItem(b *bbolt.Bucket, key string) (*Item, error) {
+	v := b.Get([]byte(key))
+	if v == nil {
+		return nil, nil
+	}
+
+	var item Item
+	err := json.Unmarshal(v, &item)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	if !item.ExpireAt.IsZero() && time.Now().After(item.ExpireAt) {
+		return nil, nil
+	}
+
+	return &item, nil
+}
+
+func (p *Bbolt) CompareAndSet(key string, old, value any) (bool, error) {
+	var ok bool
+	err := p.conn.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+
+		item, err := p.getItem(b, key)
+		if err != nil {
+			return err
+		}
+
+		if item == nil || item.Data != utils.ToString(old) {
+			return nil
+		}
+
+		ok = true
+		item.Data = utils.ToString(value)
+
+		return b.Put([]byte(key), item.Bytes())
+	})
+	return ok, err
+}
+
+func (p *Bbolt) GetDel(key string) (string, error) {
+	var value string
+	err := p.conn.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+
+		item, err := p.getItem(b, key)
+		if err != nil {
+			return err
+		}
+
+		if item == nil {
+			return NotFound
+		}
+
+		value = item.Data
+
+		return b.Delete([]byte(key))
+	})
+	return value, err
+}
+
+func (p *Bbolt) GetEx(key string, timeout time.Duration) (string, error) {
+	var value string
+	err := p.conn.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+
+		item, err := p.getItem(b, key)
+		if err != nil {
+			return err
+		}
+
+		if item == nil {
+			return NotFound
+		}
+
+		value = item.Data
+		item.ExpireAt = time.Time{}
+		if timeout > 0 {
+			item.ExpireAt = time.Now().Add(timeout)
+		}
+
+		return b.Put([]byte(key), item.Bytes())
+	})
+	return value, err
+}
+
 func (p *Bbolt) HIncr(key string, subKey string) (int64, error) {
 	//TODO implement me
 	panic("implement me")
@@ -192,7 +288,7 @@ func (p *Bbolt) Get(key string) (string, error) {
 		}
 
 		var item Item
-		err := json.Unmarshal(v, &value)
+		err := json.Unmarshal(v, &item)
 		if err != nil {
 			log.Error(err)
 			return err
@@ -276,6 +372,45 @@ func (p *Bbolt) Del(key ...string) error {
 	})
 }
 
+func (p *Bbolt) DelPrefix(prefix string) (int64, error) {
+	var count int64
+	err := p.conn.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+
+		// 遍历时删除会跳过部分 key，先收集再删除
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for _, k := range keys {
+			err := b.Delete(k)
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// OnKeyEvent bbolt 没有事件通知，不支持订阅
+func (p *Bbolt) OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error {
+	return ErrNotSupported
+}
+
+// Publish/Subscribe bbolt 只在单个进程内使用，不支持发布订阅
+func (p *Bbolt) Publish(channel string, message any) error {
+	return ErrNotSupported
+}
+
+func (p *Bbolt) Subscribe(channel string, handler func(message string)) error {
+	return ErrNotSupported
+}
+
 func (p *Bbolt) Close() error {
 	return p.conn.Close()
 }
@@ -304,6 +439,11 @@ func NewBbolt(addr string, options *bbolt.Options) (Cache, error) {
 		_, err := tx.CreateBucketIfNotExists(bboltBucket)
 		return err
 	})
+	if err != nil {
+		log.Errorf("err:%v", err)
+		_ = conn.Close()
+		return nil, err
+	}
 
 	p.conn = conn
 
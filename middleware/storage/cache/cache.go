@@ -14,6 +14,8 @@ var NotFound = errors.New("key not found")
 
 type BaseCache interface {
 	Get(key string) (string, error)
+	// MGet 批量读取，结果中只包含存在的 key
+	MGet(keys ...string) (map[string]string, error)
 
 	Set(key string, value any) error
 	SetEx(key string, value any, timeout time.Duration) error
@@ -30,6 +32,27 @@ type BaseCache interface {
 
 	Exists(keys ...string) (bool, error)
 
+	// CompareAndSet 当前值等于 old 时设置为 value 并保留过期时间，key 不存在时返回 false
+	CompareAndSet(key string, old, value any) (bool, error)
+	// GetDel 读取后删除，不存在时返回 NotFound
+	GetDel(key string) (string, error)
+	// GetEx 读取并重置过期时间，timeout 为 0 时移除过期时间，不存在时返回 NotFound
+	GetEx(key string, timeout time.Duration) (string, error)
+
+	// 字符串操作，不存在的 key 视为空字符串，修改时保留原有的过期时间
+	Append(key string, value any) (int64, error)
+	StrLen(key string) (int64, error)
+	GetRange(key string, start, end int64) (string, error)
+	SetRange(key string, offset int64, value string) (int64, error)
+
+	// 位操作，可以用于按用户 id 记录日活等位图，返回 SetBit 之前的值
+	SetBit(key string, offset int64, value bool) (bool, error)
+	GetBit(key string, offset int64) (bool, error)
+	BitCount(key string) (int64, error)
+
+	// RunScript 执行 RegisterScript 注册的脚本，一般通过 Cache.Script 调用
+	RunScript(name string, keys []string, args ...any) (any, error)
+
 	HSet(key string, field string, value interface{}) (bool, error)
 	HGet(key, field string) (string, error)
 	HDel(key string, fields ...string) (int64, error)
@@ -50,6 +73,16 @@ type BaseCache interface {
 
 	Del(key ...string) error
 
+	// DelPrefix 删除前缀匹配的全部 key，返回删除的数量
+	DelPrefix(prefix string) (int64, error)
+
+	// OnKeyEvent 订阅匹配 pattern 的 key 的变化，events 为空时订阅全部事件，bbolt 返回 ErrNotSupported
+	OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error
+
+	// Publish/Subscribe 发布订阅，不保证送达，mem 只在当前进程内有效，bbolt 返回 ErrNotSupported
+	Publish(channel string, message any) error
+	Subscribe(channel string, handler func(message string)) error
+
 	//Reset() error
 
 	Close() error
@@ -84,10 +117,23 @@ type Cache interface {
 	HGetJson(key, field string, j interface{}) error
 
 	Limit(key string, limit int64, timeout time.Duration) (bool, error)
+
+	OnExpire(pattern string, handler func(key string)) error
+
+	// Namespace 返回一个所有 key 都带有 prefix 的视图，可以嵌套
+	Namespace(prefix string) Cache
+	// InvalidateNamespace 删除当前命名空间下的全部 key，只能在 Namespace 返回的视图上调用
+	InvalidateNamespace() (int64, error)
+
+	// Script 返回注册的脚本，不存在时在执行时返回 ErrScriptNotFound
+	Script(name string) *ScriptRunner
+
+	GetOrLoad(key string, timeout time.Duration, loader func() (any, error), opts ...LoadOption) (string, error)
+	GetJsonOrLoad(key string, j interface{}, timeout time.Duration, loader func() (any, error), opts ...LoadOption) error
 }
 
 type Config struct {
-	// Cache type, support mem, redis, bbolt, default mem
+	// Cache type, support mem, redis, bbolt, shard, default mem
 	Type string `yaml:"type"`
 
 	// Cache address
@@ -101,6 +147,20 @@ type Config struct {
 	// redis: redis password
 	// bbolt: empty
 	Password string `yaml:"password"`
+
+	// In-process cache of Get results, invalidated by redis client tracking
+	// mem: ignored
+	// redis: require redis 6+, disabled when empty
+	// bbolt: ignored
+	ClientCache *ClientCacheConfig `yaml:"client_cache"`
+
+	// Random ratio applied to SetEx ttl, e.g. 0.1 for ±10%, so keys written together do not expire together
+	// disabled when 0
+	Jitter float64 `yaml:"jitter"`
+
+	// Nodes of the shard type, keys are distributed by consistent hashing
+	// required when type is shard, ignored otherwise
+	Shard *ShardConfig `yaml:"shard"`
 }
 
 func (c *Config) apply() {
@@ -124,6 +184,15 @@ func (c *Config) apply() {
 func New(c *Config) (Cache, error) {
 	c.apply()
 
+	p, err := newCache(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJitter(p, c.Jitter), nil
+}
+
+func newCache(c *Config) (Cache, error) {
 	switch c.Type {
 	case "bbolt":
 		return NewBbolt(c.Address, &bbolt.Options{
@@ -132,7 +201,7 @@ func New(c *Config) (Cache, error) {
 		})
 
 	case "redis":
-		return NewRedis(c.Address,
+		p, err := newRedis(c.Address,
 			redis.DialDatabase(0),
 			redis.DialConnectTimeout(time.Second*3),
 			redis.DialReadTimeout(time.Second*3),
@@ -140,10 +209,25 @@ func New(c *Config) (Cache, error) {
 			redis.DialKeepAlive(time.Minute),
 			redis.DialPassword(c.Password),
 		)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.ClientCache != nil {
+			p.EnableClientCache(c.ClientCache)
+		}
+
+		return newBaseCache(p), nil
 
 	case "mem":
 		return NewMem(), nil
 
+	case "shard":
+		if c.Shard == nil {
+			return nil, errors.New("shard config is required")
+		}
+		return NewSharded(c.Shard)
+
 	default:
 		return nil, errors.New("cache type not support")
 	}
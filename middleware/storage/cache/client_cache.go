@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"github.com/garyburd/redigo/redis"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/app"
+	"github.com/lazygophers/utils/routine"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ClientCacheConfig struct {
+	// Key prefixes cached in process, without the app name, empty means all keys
+	Prefixes []string `yaml:"prefixes"`
+
+	// Max keys kept in process, random keys are evicted when full, default 10000
+	MaxKeys int `yaml:"max_keys"`
+
+	// Max lifetime of a local key, guards against invalidations lost while reconnecting, default 1m
+	TTL time.Duration `yaml:"ttl"`
+}
+
+func (c *ClientCacheConfig) apply() {
+	if c.MaxKeys == 0 {
+		c.MaxKeys = 10000
+	}
+
+	if c.TTL == 0 {
+		c.TTL = time.Minute
+	}
+}
+
+type clientCacheItem struct {
+	value    string
+	expireAt time.Time
+}
+
+// 基于 redis 6 的 CLIENT TRACKING(BCAST 模式)，只缓存 Get 的结果
+type clientCache struct {
+	c        *ClientCacheConfig
+	prefixes []string
+
+	lock  sync.RWMutex
+	items map[string]*clientCacheItem
+
+	// 每次失效都会增加，用于丢弃在查询期间被修改过的结果
+	version atomic.Uint64
+
+	// tracking 未生效时不使用本地缓存
+	active atomic.Bool
+}
+
+func newClientCache(c *ClientCacheConfig) *clientCache {
+	c.apply()
+
+	p := &clientCache{
+		c:     c,
+		items: make(map[string]*clientCacheItem, c.MaxKeys),
+	}
+
+	for _, prefix := range c.Prefixes {
+		p.prefixes = append(p.prefixes, app.Name+":"+prefix)
+	}
+	if len(p.prefixes) == 0 {
+		p.prefixes = []string{app.Name + ":"}
+	}
+
+	return p
+}
+
+func (p *clientCache) match(key string) bool {
+	if p == nil || !p.active.Load() {
+		return false
+	}
+
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *clientCache) get(key string) (string, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	item, ok := p.items[key]
+	if !ok || time.Now().After(item.expireAt) {
+		return "", false
+	}
+
+	return item.value, true
+}
+
+func (p *clientCache) set(key, value string, version uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.version.Load() != version {
+		return
+	}
+
+	if _, ok := p.items[key]; !ok && len(p.items) >= p.c.MaxKeys {
+		for k := range p.items {
+			delete(p.items, k)
+			break
+		}
+	}
+
+	p.items[key] = &clientCacheItem{
+		value:    value,
+		expireAt: time.Now().Add(p.c.TTL),
+	}
+}
+
+func (p *clientCache) del(keys ...string) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.version.Add(1)
+	for _, key := range keys {
+		delete(p.items, key)
+	}
+}
+
+func (p *clientCache) delPrefix(prefix string) {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.version.Add(1)
+	for key := range p.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(p.items, key)
+		}
+	}
+}
+
+func (p *clientCache) flush() {
+	if p == nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.version.Add(1)
+	p.items = make(map[string]*clientCacheItem, p.c.MaxKeys)
+}
+
+// 失效通知的内容是 key 的数组，PubSubConn 无法解析，需要自己读取
+// 一个连接接收 __redis__:invalidate，另一个连接开启 tracking 并重定向过去
+func (p *Redis) track(local *clientCache) error {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	id, err := redis.Int64(conn.Do("CLIENT", "ID"))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	_, err = conn.Do("SUBSCRIBE", "__redis__:invalidate")
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	tracker := p.cli.GetConnection()
+	defer tracker.Close()
+
+	args := []interface{}{"TRACKING", "ON", "REDIRECT", id, "BCAST"}
+	for _, prefix := range local.prefixes {
+		args = append(args, "PREFIX", prefix)
+	}
+
+	_, err = tracker.Do("CLIENT", args...)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	// 未开启 tracking 期间的修改无法感知
+	local.flush()
+	local.active.Store(true)
+	defer local.active.Store(false)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	// tracker 断开后 tracking 失效且没有任何通知，需要定时检查
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Second * 30)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				_ = conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := tracker.Do("PING")
+				if err != nil {
+					log.Errorf("err:%v", err)
+					_ = conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		// 没有修改时可能长时间收不到消息，不能使用连接的读超时
+		reply, err := redis.Values(redis.ReceiveWithTimeout(conn, 0))
+		if err != nil {
+			return err
+		}
+
+		if len(reply) != 3 {
+			continue
+		}
+
+		if kind, _ := redis.String(reply[0], nil); kind != "message" {
+			continue
+		}
+
+		// FLUSHALL、FLUSHDB 时为 nil
+		if reply[2] == nil {
+			local.flush()
+			continue
+		}
+
+		keys, err := redis.Strings(reply[2], nil)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			local.flush()
+			continue
+		}
+
+		local.del(keys...)
+	}
+}
+
+// EnableClientCache 开启进程内缓存，需要 redis 6 及以上，只对 Get 生效
+// 跨进程的修改通过 tracking 的失效通知清理，连接断开期间的本地缓存会被清空
+// 可以在读写的同时调用，重复调用时只有第一次生效
+func (p *Redis) EnableClientCache(c *ClientCacheConfig) {
+	local := newClientCache(c)
+	if !p.local.CompareAndSwap(nil, local) {
+		return
+	}
+
+	p.wg.Add(1)
+	routine.GoWithRecover(func() error {
+		defer p.wg.Done()
+
+		for {
+			err := p.track(local)
+
+			// 连接断开后收不到通知，已缓存的内容不再可信
+			local.flush()
+
+			select {
+			case <-p.stop:
+				return nil
+			default:
+			}
+
+			log.Errorf("client cache tracking broken, err:%v", err)
+
+			select {
+			case <-p.stop:
+				return nil
+			case <-time.After(time.Second):
+			}
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"errors"
+	"github.com/garyburd/redigo/redis"
+	"github.com/shomali11/xredis"
+	"sync"
+	"testing"
+)
+
+func TestClientCacheVersion(t *testing.T) {
+	local := newClientCache(&ClientCacheConfig{})
+
+	// 查询期间 key 被修改，查询到的旧值不能写入
+	version := local.version.Load()
+	local.del("k")
+	local.set("k", "old", version)
+	if _, ok := local.get("k"); ok {
+		t.Error("stale value cached")
+	}
+
+	local.set("k", "new", local.version.Load())
+	if v, ok := local.get("k"); !ok || v != "new" {
+		t.Errorf("value:%s, ok:%v", v, ok)
+	}
+}
+
+func TestEnableClientCacheConcurrent(t *testing.T) {
+	p := &Redis{
+		cli: xredis.NewClient(&redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return nil, errors.New("unreachable")
+			},
+		}),
+		stop: make(chan struct{}),
+	}
+	// tracking 的 goroutine 需要在测试结束前退出，否则会与之后的测试产生竞争
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+
+	// 开启与读写同时进行，需要通过 -race 检查
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			p.EnableClientCache(&ClientCacheConfig{})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = p.Del("k")
+			_, _ = p.Get("k")
+		}()
+	}
+	wg.Wait()
+
+	local := p.local.Load()
+	if local == nil {
+		t.Fatal("client cache not enabled")
+	}
+
+	p.EnableClientCache(&ClientCacheConfig{})
+	if p.local.Load() != local {
+		t.Error("client cache replaced")
+	}
+}
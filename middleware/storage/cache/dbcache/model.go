@@ -0,0 +1,296 @@
+package dbcache
+
+import (
+	"context"
+	"errors"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/json"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	fillScript = "dbcache:fill"
+
+	// 加载中的占位值，读取时视为未命中
+	loadingPrefix = "\x00loading:"
+
+	// 占位的有效期，加载超过该时间时不再回写
+	loadingTimeout = time.Second * 10
+)
+
+var loadingSeq atomic.Uint64
+
+// 回写时值仍然是当前加载的占位才写入，加载期间的更新、删除会删除占位，避免把旧的记录写回缓存
+func init() {
+	cache.RegisterScript(&cache.Script{
+		Name: fillScript,
+		Lua: `
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then return 0 end
+if tonumber(ARGV[3]) > 0 then redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3]) else redis.call('SET', KEYS[1], ARGV[2]) end
+return 1`,
+		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) {
+			ms, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := tx.Get(keys[0])
+			if err != nil {
+				if errors.Is(err, cache.NotFound) {
+					return int64(0), nil
+				}
+				return nil, err
+			}
+			if value != args[0] {
+				return int64(0), nil
+			}
+
+			if ms > 0 {
+				return int64(1), tx.SetEx(keys[0], args[1], time.Duration(ms)*time.Millisecond)
+			}
+			return int64(1), tx.Set(keys[0], args[1])
+		},
+	})
+}
+
+// ModelCache 按主键缓存数据库的记录，读取时未命中从数据库加载，通过 ModelCache 修改、删除时同步删除缓存
+// 绕过 ModelCache 直接修改数据库时需要调用 Invalidate
+type ModelCache[M any] struct {
+	model *db.Model[M]
+	cache cache.Cache
+	ttl   time.Duration
+
+	prefix  string
+	primary *schema.Field
+}
+
+// CacheModel 缓存的 key 为 model:{table}:{id}，ttl 为 0 时不过期
+func CacheModel[M any](client *db.Client, c cache.Cache, ttl time.Duration) (*ModelCache[M], error) {
+	stmt := &gorm.Statement{DB: client.Database()}
+	err := stmt.Parse(new(M))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		return nil, errors.New("model has no primary key")
+	}
+
+	model := db.NewModel[M](client)
+
+	return &ModelCache[M]{
+		model:   model,
+		cache:   c,
+		ttl:     ttl,
+		prefix:  "model:" + model.TableName() + ":",
+		primary: stmt.Schema.PrioritizedPrimaryField,
+	}, nil
+}
+
+func (p *ModelCache[M]) key(id any) string {
+	return p.prefix + anyx.ToString(id)
+}
+
+// 加载前写入占位，已经有值或者其他请求正在加载时返回空，不回写
+func (p *ModelCache[M]) lock(key string) string {
+	token := loadingPrefix + strconv.FormatInt(time.Now().UnixNano(), 36) + ":" + strconv.FormatUint(loadingSeq.Add(1), 36)
+	ok, err := p.cache.SetNxWithTimeout(key, token, loadingTimeout)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+// 占位没有被删除时写入缓存
+func (p *ModelCache[M]) fill(key, token string, m *M) {
+	if token == "" {
+		return
+	}
+
+	value, err := json.MarshalString(m)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return
+	}
+
+	// 写缓存失败不影响读取的结果
+	_, err = p.cache.Script(fillScript).Run([]string{key}, token, value, p.ttl.Milliseconds())
+	if err != nil {
+		log.Errorf("err:%v", err)
+	}
+}
+
+// 未命中、占位以及无法解析的值返回 false
+func (p *ModelCache[M]) decode(value string) (*M, bool) {
+	if strings.HasPrefix(value, loadingPrefix) {
+		return nil, false
+	}
+
+	var m M
+	err := json.UnmarshalString(value, &m)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, false
+	}
+	return &m, true
+}
+
+func (p *ModelCache[M]) id(m *M) any {
+	value, _ := p.primary.ValueOf(context.Background(), reflect.ValueOf(m).Elem())
+	return value
+}
+
+// GetByID 不存在时返回 Model 的 NotFound 错误
+func (p *ModelCache[M]) GetByID(id any) (*M, error) {
+	key := p.key(id)
+	value, err := p.cache.Get(key)
+	if err == nil {
+		if m, ok := p.decode(value); ok {
+			return m, nil
+		}
+	} else if err != cache.NotFound {
+		log.Errorf("err:%v", err)
+	}
+
+	token := p.lock(key)
+
+	m, err := p.model.NewScoop().Equal(p.primary.DBName, id).First()
+	if err != nil {
+		return nil, err
+	}
+
+	p.fill(key, token, m)
+
+	return m, nil
+}
+
+// GetByIDs 缓存未命中的记录通过一次 IN 查询加载，按 ids 的顺序返回，不存在的记录会被忽略
+func (p *ModelCache[M]) GetByIDs(ids ...any) ([]*M, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = p.key(id)
+	}
+
+	values, err := p.cache.MGet(keys...)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		values = map[string]string{}
+	}
+
+	found := make(map[string]*M, len(ids))
+	var misses []any
+	for i, key := range keys {
+		if _, ok := found[key]; ok {
+			continue
+		}
+
+		if value, ok := values[key]; ok {
+			if m, ok := p.decode(value); ok {
+				found[key] = m
+				continue
+			}
+		}
+
+		found[key] = nil
+		misses = append(misses, ids[i])
+	}
+
+	tokens := make(map[string]string, len(misses))
+	for _, id := range misses {
+		key := p.key(id)
+		tokens[key] = p.lock(key)
+	}
+
+	if len(misses) > 0 {
+		list, err := p.model.NewScoop().In(p.primary.DBName, misses).Find()
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		for _, m := range list {
+			key := p.key(p.id(m))
+			found[key] = m
+			p.fill(key, tokens[key], m)
+		}
+	}
+
+	ms := make([]*M, 0, len(ids))
+	for _, key := range keys {
+		if m := found[key]; m != nil {
+			ms = append(ms, m)
+		}
+	}
+
+	return ms, nil
+}
+
+// UpdateByID 更新数据库后删除缓存，下次读取时重新加载
+func (p *ModelCache[M]) UpdateByID(id any, values map[string]any) (int64, error) {
+	res := p.model.NewScoop().Equal(p.primary.DBName, id).Updates(values)
+	if res.Error != nil {
+		log.Errorf("err:%v", res.Error)
+		return 0, res.Error
+	}
+
+	err := p.Invalidate(id)
+	if err != nil {
+		return res.RowsAffected, err
+	}
+
+	return res.RowsAffected, nil
+}
+
+// DeleteByID 删除数据库的记录后删除缓存
+func (p *ModelCache[M]) DeleteByID(id any) (int64, error) {
+	res := p.model.NewScoop().Equal(p.primary.DBName, id).Delete()
+	if res.Error != nil {
+		log.Errorf("err:%v", res.Error)
+		return 0, res.Error
+	}
+
+	err := p.Invalidate(id)
+	if err != nil {
+		return res.RowsAffected, err
+	}
+
+	return res.RowsAffected, nil
+}
+
+// Invalidate 删除缓存，用于在 ModelCache 之外修改了记录的场景
+func (p *ModelCache[M]) Invalidate(ids ...any) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = p.key(id)
+	}
+
+	err := p.cache.Del(keys...)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package dbcache_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/storage/cache/dbcache"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type cacheUser struct {
+	Id   int64 `gorm:"primaryKey"`
+	Name string
+}
+
+func (cacheUser) TableName() string {
+	return "cache_user"
+}
+
+func TestModelCache(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "dbcache",
+	}, &cacheUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		err = cli.NewScoop().Create(&cacheUser{Id: i, Name: "user"}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	c := cache.NewMem()
+	mc, err := dbcache.CacheModel[cacheUser](cli, c, 0)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	// 每次查询前执行一次 hook
+	var queries int
+	var hook func()
+	err = cli.Database().Callback().Row().Before("gorm:row").Register("test:hook", func(*gorm.DB) {
+		queries++
+		if hook != nil {
+			h := hook
+			hook = nil
+			h()
+		}
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	m, err := mc.GetByID(1)
+	if err != nil || m.Name != "user" {
+		t.Fatalf("m:%+v, err:%v", m, err)
+	}
+	m, err = mc.GetByID(1)
+	if err != nil || m.Name != "user" || queries != 1 {
+		t.Fatalf("m:%+v, queries:%d, err:%v", m, queries, err)
+	}
+
+	_, err = mc.UpdateByID(1, map[string]any{"name": "updated"})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	m, err = mc.GetByID(1)
+	if err != nil || m.Name != "updated" {
+		t.Fatalf("m:%+v, err:%v", m, err)
+	}
+
+	// 加载期间被更新时不回写旧的记录
+	hook = func() {
+		_ = mc.Invalidate(2)
+	}
+	_, err = mc.GetByID(2)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	_, err = c.Get("model:cache_user:2")
+	if err != cache.NotFound {
+		t.Errorf("stale write-back, err:%v", err)
+	}
+
+	hook = func() {
+		_ = mc.Invalidate(3)
+	}
+	list, err := mc.GetByIDs(1, 2, 3, 4)
+	if err != nil || len(list) != 3 {
+		t.Fatalf("list:%+v, err:%v", list, err)
+	}
+	_, err = c.Get("model:cache_user:2")
+	if err != nil {
+		t.Errorf("err:%v", err)
+	}
+	_, err = c.Get("model:cache_user:3")
+	if err != cache.NotFound {
+		t.Errorf("stale write-back, err:%v", err)
+	}
+
+	_, err = mc.DeleteByID(1)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	_, err = mc.GetByID(1)
+	if err == nil {
+		t.Error("expected not found")
+	}
+}
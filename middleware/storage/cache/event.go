@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type KeyEvent string
+
+const (
+	KeyEventSet     KeyEvent = "set"
+	KeyEventDel     KeyEvent = "del"
+	KeyEventExpire  KeyEvent = "expire"
+	KeyEventExpired KeyEvent = "expired"
+)
+
+type KeyEventHandler func(key string, event KeyEvent)
+
+// ErrNotSupported 当前的缓存实现不支持该操作，例如 bbolt 的 key 事件订阅
+var ErrNotSupported = errors.New("not supported")
+
+// 与 redis 的 glob 规则一致，支持 * ? 和 [...]
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	inClass := false
+	for _, r := range pattern {
+		switch {
+		case inClass:
+			b.WriteRune(r)
+			if r == ']' {
+				inClass = false
+			}
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		case r == '[':
+			inClass = true
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+type keySubscription struct {
+	pattern *regexp.Regexp
+	events  map[KeyEvent]bool
+	handler KeyEventHandler
+}
+
+func (p *keySubscription) match(key string, event KeyEvent) bool {
+	if len(p.events) > 0 && !p.events[event] {
+		return false
+	}
+	return p.pattern.MatchString(key)
+}
+
+type keySubscriptions struct {
+	lock sync.RWMutex
+	list []*keySubscription
+}
+
+func (p *keySubscriptions) add(pattern string, handler KeyEventHandler, events ...KeyEvent) {
+	sub := &keySubscription{
+		pattern: globToRegexp(pattern),
+		events:  make(map[KeyEvent]bool, len(events)),
+		handler: handler,
+	}
+	for _, e := range events {
+		sub.events[e] = true
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.list = append(p.list, sub)
+}
+
+func (p *keySubscriptions) empty() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return len(p.list) == 0
+}
+
+func (p *keySubscriptions) notify(key string, event KeyEvent) {
+	p.lock.RLock()
+	list := p.list
+	p.lock.RUnlock()
+
+	for _, sub := range list {
+		if sub.match(key, event) {
+			sub.handler(key, event)
+		}
+	}
+}
+
+func (p *baseCache) OnExpire(pattern string, handler func(key string)) error {
+	return p.OnKeyEvent(pattern, func(key string, event KeyEvent) {
+		handler(key)
+	}, KeyEventExpired)
+}
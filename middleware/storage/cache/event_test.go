@@ -0,0 +1,75 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnKeyEvent(t *testing.T) {
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	var keys []string
+	err := mem.OnKeyEvent("user:*", func(key string, event cache.KeyEvent) {
+		keys = append(keys, key+":"+string(event))
+	}, cache.KeyEventSet)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	_ = mem.Set("user:1", "a")
+	_ = mem.Set("order:1", "a")
+	_ = mem.Del("user:1")
+
+	if len(keys) != 1 || keys[0] != "user:1:set" {
+		t.Errorf("unexpected events:%v", keys)
+	}
+
+	// bbolt 没有事件通知，需要返回错误而不是 panic
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	err = bolt.OnKeyEvent("user:*", func(key string, event cache.KeyEvent) {})
+	if !errors.Is(err, cache.ErrNotSupported) {
+		t.Errorf("err:%v", err)
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	var msgs []string
+	err := mem.Subscribe("topic", func(message string) {
+		msgs = append(msgs, message)
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = mem.Publish("topic", 1)
+	if err != nil || len(msgs) != 1 || msgs[0] != "1" {
+		t.Errorf("msgs:%v, err:%v", msgs, err)
+	}
+
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	err = bolt.Subscribe("topic", func(message string) {})
+	if !errors.Is(err, cache.ErrNotSupported) {
+		t.Errorf("subscribe err:%v", err)
+	}
+
+	err = bolt.Publish("topic", 1)
+	if !errors.Is(err, cache.ErrNotSupported) {
+		t.Errorf("publish err:%v", err)
+	}
+}
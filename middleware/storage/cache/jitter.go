@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// WithJitter 在 ttl 上增加 ±ratio 的随机偏移，ratio 为 0.1 时结果在 [0.9ttl, 1.1ttl] 之间
+// 批量写入（例如预热）的 key 不会在同一时刻过期，避免同时回源
+func WithJitter(ttl time.Duration, ratio float64) time.Duration {
+	if ttl <= 0 || ratio <= 0 {
+		return ttl
+	}
+
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	delta := time.Duration(float64(ttl) * ratio * (rand.Float64()*2 - 1))
+	if ttl+delta <= 0 {
+		return ttl
+	}
+
+	return ttl + delta
+}
+
+// jitterCache 对 SetEx 的过期时间增加随机偏移，锁相关的 SetNxWithTimeout 不受影响
+type jitterCache struct {
+	BaseCache
+	ratio float64
+}
+
+func (p *jitterCache) SetEx(key string, value any, timeout time.Duration) error {
+	return p.BaseCache.SetEx(key, value, WithJitter(timeout, p.ratio))
+}
+
+// NewJitter 返回 SetEx 带有随机过期偏移的 Cache，ratio 为 0 时直接返回 c
+func NewJitter(c Cache, ratio float64) Cache {
+	if ratio <= 0 {
+		return c
+	}
+
+	if b, ok := c.(*baseCache); ok {
+		return newBaseCache(&jitterCache{
+			BaseCache: b.BaseCache,
+			ratio:     ratio,
+		})
+	}
+
+	return newBaseCache(&jitterCache{
+		BaseCache: c,
+		ratio:     ratio,
+	})
+}
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"fmt"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"testing"
+	"time"
+)
+
+func TestWithJitter(t *testing.T) {
+	ttl := time.Hour
+
+	var lo, hi time.Duration = ttl, ttl
+	for i := 0; i < 1000; i++ {
+		d := cache.WithJitter(ttl, 0.1)
+		if d < ttl*9/10 || d > ttl*11/10 {
+			t.Fatalf("out of range:%s", d)
+		}
+		if d < lo {
+			lo = d
+		}
+		if d > hi {
+			hi = d
+		}
+	}
+	if lo == ttl || hi == ttl {
+		t.Errorf("no jitter, min:%s, max:%s", lo, hi)
+	}
+
+	// ratio 大于 1 时按 1 处理，结果始终为正且不超过 2ttl
+	for i := 0; i < 1000; i++ {
+		d := cache.WithJitter(ttl, 5)
+		if d <= 0 || d > ttl*2 {
+			t.Fatalf("out of range:%s", d)
+		}
+	}
+
+	for _, tc := range []struct {
+		ttl   time.Duration
+		ratio float64
+	}{
+		{ttl, 0},
+		{ttl, -1},
+		{0, 0.1},
+		{-time.Second, 0.1},
+	} {
+		if d := cache.WithJitter(tc.ttl, tc.ratio); d != tc.ttl {
+			t.Errorf("ttl:%s, ratio:%v, got:%s", tc.ttl, tc.ratio, d)
+		}
+	}
+}
+
+func TestNewJitter(t *testing.T) {
+	mem := cache.NewMem()
+	if cache.NewJitter(mem, 0) != mem {
+		t.Error("ratio 0 should return the cache itself")
+	}
+
+	c := cache.NewJitter(mem, 0.5)
+
+	var jittered bool
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("ex%d", i)
+		if err := c.SetEx(key, "v", time.Hour); err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		ttl, err := mem.Ttl(key)
+		if err != nil || ttl < time.Minute*29 || ttl > time.Minute*90 {
+			t.Fatalf("ttl:%s, err:%v", ttl, err)
+		}
+		if ttl < time.Minute*59 {
+			jittered = true
+		}
+	}
+	if !jittered {
+		t.Error("SetEx should be jittered")
+	}
+
+	// 锁的过期时间需要精确，不增加偏移
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("nx%d", i)
+		ok, err := c.SetNxWithTimeout(key, "v", time.Hour)
+		if err != nil || !ok {
+			t.Fatalf("ok:%v, err:%v", ok, err)
+		}
+
+		ttl, err := mem.Ttl(key)
+		if err != nil || ttl < time.Minute*59 || ttl > time.Hour {
+			t.Fatalf("ttl:%s, err:%v", ttl, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/json"
+	"github.com/lazygophers/utils/routine"
+	"time"
+)
+
+type loadOption struct {
+	maxStale time.Duration
+}
+
+type LoadOption func(o *loadOption)
+
+// WithStaleWhileRevalidate 数据过期后的 maxStale 时间内直接返回旧值，同时由一个后台协程刷新
+func WithStaleWhileRevalidate(maxStale time.Duration) LoadOption {
+	return func(o *loadOption) {
+		o.maxStale = maxStale
+	}
+}
+
+func (p *baseCache) load(key string, timeout time.Duration, o *loadOption, loader func() (any, error)) (string, error) {
+	value, err := loader()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	data := anyx.ToString(value)
+
+	if o.maxStale > 0 {
+		// 数据本身的过期时间记录在 Item 中，缓存的过期时间需要加上允许的陈旧时间
+		item := &Item{
+			Data:     data,
+			ExpireAt: time.Now().Add(timeout),
+		}
+		err = p.SetEx(key, item.String(), timeout+o.maxStale)
+	} else {
+		err = p.SetEx(key, data, timeout)
+	}
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	return data, nil
+}
+
+func (p *baseCache) refresh(key string, timeout time.Duration, o *loadOption, loader func() (any, error)) {
+	// 同一个 key 同时只会有一个协程在刷新
+	if _, loaded := p.refreshing.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+
+	routine.GoWithRecover(func() error {
+		defer p.refreshing.Delete(key)
+
+		// 刷新失败时保留旧值，错误已经在 load 中输出
+		_, _ = p.load(key, timeout, o, loader)
+		return nil
+	})
+}
+
+func (p *baseCache) GetOrLoad(key string, timeout time.Duration, loader func() (any, error), opts ...LoadOption) (string, error) {
+	o := &loadOption{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	value, err := p.Get(key)
+	if err != nil {
+		if err != NotFound {
+			log.Errorf("err:%v", err)
+			return "", err
+		}
+
+		return p.load(key, timeout, o, loader)
+	}
+
+	if o.maxStale <= 0 {
+		return value, nil
+	}
+
+	var item Item
+	err = json.UnmarshalString(value, &item)
+	if err != nil {
+		log.Warnf("invalid stale item for %s, reload", redact.Key(key))
+		return p.load(key, timeout, o, loader)
+	}
+
+	if time.Now().After(item.ExpireAt) {
+		p.refresh(key, timeout, o, loader)
+	}
+
+	return item.Data, nil
+}
+
+func (p *baseCache) GetJsonOrLoad(key string, j interface{}, timeout time.Duration, loader func() (any, error), opts ...LoadOption) error {
+	value, err := p.GetOrLoad(key, timeout, loader, opts...)
+	if err != nil {
+		return err
+	}
+
+	return json.UnmarshalString(value, j)
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testLoader struct {
+	calls atomic.Int32
+
+	lock    sync.Mutex
+	err     error
+	release chan struct{}
+}
+
+func (p *testLoader) load() (any, error) {
+	n := p.calls.Add(1)
+
+	p.lock.Lock()
+	err, release := p.err, p.release
+	p.lock.Unlock()
+
+	if release != nil {
+		<-release
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strconv.Itoa(int(n)), nil
+}
+
+func (p *testLoader) set(err error, release chan struct{}) {
+	p.lock.Lock()
+	p.err, p.release = err, release
+	p.lock.Unlock()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	t.Fatal("timeout")
+}
+
+func TestGetOrLoad(t *testing.T) {
+	c := NewMem()
+	l := &testLoader{}
+
+	for i := 0; i < 2; i++ {
+		value, err := c.GetOrLoad("k", time.Minute, l.load)
+		if err != nil || value != "1" {
+			t.Fatalf("value:%s, err:%v", value, err)
+		}
+	}
+	if l.calls.Load() != 1 {
+		t.Errorf("calls:%d", l.calls.Load())
+	}
+
+	l.set(errors.New("unavailable"), nil)
+	_, err := c.GetOrLoad("missing", time.Minute, l.load)
+	if err == nil {
+		t.Error("loader error should be returned")
+	}
+}
+
+func TestGetOrLoadStale(t *testing.T) {
+	const timeout, maxStale = time.Millisecond * 50, time.Millisecond * 200
+
+	c := NewMem()
+	l := &testLoader{}
+	get := func() (string, error) {
+		return c.GetOrLoad("k", timeout, l.load, WithStaleWhileRevalidate(maxStale))
+	}
+	refreshed := func() bool {
+		_, ok := c.(*baseCache).refreshing.Load("k")
+		return !ok
+	}
+
+	value, err := get()
+	if err != nil || value != "1" {
+		t.Fatalf("value:%s, err:%v", value, err)
+	}
+
+	// 过期后返回旧值，只有一个协程在刷新
+	time.Sleep(timeout + time.Millisecond*20)
+	release := make(chan struct{})
+	l.set(nil, release)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := get()
+			if err != nil || value != "1" {
+				t.Errorf("value:%s, err:%v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+	waitFor(t, func() bool {
+		return l.calls.Load() == 2
+	})
+
+	close(release)
+	l.set(nil, nil)
+	waitFor(t, refreshed)
+	if l.calls.Load() != 2 {
+		t.Errorf("calls:%d", l.calls.Load())
+	}
+	value, err = get()
+	if err != nil || value != "2" {
+		t.Fatalf("value:%s, err:%v", value, err)
+	}
+
+	// 刷新失败时保留旧值
+	time.Sleep(timeout + time.Millisecond*20)
+	l.set(errors.New("unavailable"), nil)
+	value, err = get()
+	if err != nil || value != "2" {
+		t.Fatalf("value:%s, err:%v", value, err)
+	}
+	waitFor(t, func() bool {
+		return l.calls.Load() == 3 && refreshed()
+	})
+	value, err = get()
+	if err != nil || value != "2" {
+		t.Fatalf("value:%s, err:%v", value, err)
+	}
+	waitFor(t, refreshed)
+
+	// 超过 maxStale 后不再返回旧值
+	time.Sleep(maxStale)
+	value, err = get()
+	if err == nil {
+		t.Errorf("value:%s", value)
+	}
+
+	l.set(nil, nil)
+	value, err = get()
+	if err != nil || value == "2" {
+		t.Errorf("value:%s, err:%v", value, err)
+	}
+}
+
+func TestGetJsonOrLoad(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	c := NewMem()
+	var calls int
+	loader := func() (any, error) {
+		calls++
+		return &user{Name: "alice"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		var u user
+		err := c.GetJsonOrLoad("user", &u, time.Minute, loader, WithStaleWhileRevalidate(time.Minute))
+		if err != nil || u.Name != "alice" {
+			t.Fatalf("user:%+v, err:%v", u, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("calls:%d", calls)
+	}
+}
@@ -2,7 +2,11 @@ package cache
 
 import (
 	"github.com/beefsack/go-rate"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/routine"
 	"gorm.io/gorm/utils"
+	"strconv"
+	"strings"
 
 	"sync"
 	"time"
@@ -13,41 +17,179 @@ type Mem struct {
 
 	data map[string]*Item
 	rt   *rate.RateLimiter
+
+	subs      keySubscriptions
+	watchOnce sync.Once
+	stop      chan struct{}
+
+	channelLock sync.RWMutex
+	channels    map[string][]func(message string)
 }
 
+// 读取未过期的 item，调用方需要持有锁
+func (p *Mem) getItem(key string) (*Item, bool) {
+	val, ok := p.data[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !val.ExpireAt.IsZero() && time.Now().After(val.ExpireAt) {
+		return nil, false
+	}
+
+	return val, true
+}
+
+// 与 redis 一致，不存在时从 0 开始，保留原有的过期时间
 func (p *Mem) IncrBy(key string, value int64) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if !ok {
+		item = &Item{}
+		p.data[key] = item
+	}
+
+	var cnt int64
+	if item.Data != "" {
+		var err error
+		cnt, err = strconv.ParseInt(item.Data, 10, 64)
+		if err != nil {
+			p.Unlock()
+			return 0, err
+		}
+	}
+
+	cnt += value
+	item.Data = strconv.FormatInt(cnt, 10)
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
+
+	return cnt, nil
 }
 
 func (p *Mem) DecrBy(key string, value int64) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return p.IncrBy(key, -value)
 }
 
 func (p *Mem) Expire(key string, timeout time.Duration) (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if ok {
+		item.ExpireAt = time.Now().Add(timeout)
+	}
+	p.Unlock()
+
+	if ok {
+		p.subs.notify(key, KeyEventExpire)
+	}
+
+	return ok, nil
 }
 
+// Ttl 与 redis 一致，不存在时返回 -2s，没有过期时间时返回 -1s
 func (p *Mem) Ttl(key string) (time.Duration, error) {
-	//TODO implement me
-	panic("implement me")
+	p.RLock()
+	defer p.RUnlock()
+
+	item, ok := p.getItem(key)
+	if !ok {
+		return -2 * time.Second, nil
+	}
+
+	if item.ExpireAt.IsZero() {
+		return -time.Second, nil
+	}
+
+	return time.Until(item.ExpireAt), nil
 }
 
 func (p *Mem) Incr(key string) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return p.IncrBy(key, 1)
 }
 
 func (p *Mem) Decr(key string) (int64, error) {
-	//TODO implement me
-	panic("implement me")
+	return p.IncrBy(key, -1)
 }
 
 func (p *Mem) Exists(keys ...string) (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	p.RLock()
+	defer p.RUnlock()
+
+	for _, key := range keys {
+		if _, ok := p.getItem(key); ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p *Mem) CompareAndSet(key string, old, value any) (bool, error) {
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if !ok || item.Data != utils.ToString(old) {
+		p.Unlock()
+		return false, nil
+	}
+
+	p.data[key] = &Item{
+		Data:     utils.ToString(value),
+		ExpireAt: item.ExpireAt,
+	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
+
+	return true, nil
+}
+
+func (p *Mem) GetDel(key string) (string, error) {
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if !ok {
+		p.Unlock()
+		return "", NotFound
+	}
+	delete(p.data, key)
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventDel)
+
+	return item.Data, nil
+}
+
+func (p *Mem) GetEx(key string, timeout time.Duration) (string, error) {
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if !ok {
+		p.Unlock()
+		return "", NotFound
+	}
+
+	var expireAt time.Time
+	if timeout > 0 {
+		expireAt = time.Now().Add(timeout)
+	}
+	p.data[key] = &Item{
+		Data:     item.Data,
+		ExpireAt: expireAt,
+	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventExpire)
+
+	return item.Data, nil
 }
 
 func (p *Mem) HIncr(key string, subKey string) (int64, error) {
@@ -134,12 +276,14 @@ func (p *Mem) SetEx(key string, value any, timeout time.Duration) error {
 	p.autoClear()
 
 	p.Lock()
-	defer p.Unlock()
-
 	p.data[key] = &Item{
 		Data:     utils.ToString(value),
 		ExpireAt: time.Now().Add(timeout),
 	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
+	p.subs.notify(key, KeyEventExpire)
 
 	return nil
 }
@@ -155,38 +299,43 @@ func (p *Mem) autoClear() {
 
 func (p *Mem) clear() {
 	p.Lock()
-	defer p.Unlock()
 
 	data := make(map[string]*Item)
 
+	var expired []string
 	for k, v := range p.data {
-		if v.ExpireAt.IsZero() {
-			data[k] = v
+		if !v.ExpireAt.IsZero() && time.Now().After(v.ExpireAt) {
+			expired = append(expired, k)
 			continue
 		}
 
-		if time.Now().After(v.ExpireAt) {
-			continue
-		}
+		data[k] = v
 	}
 
 	p.data = data
+	p.Unlock()
+
+	for _, k := range expired {
+		p.subs.notify(k, KeyEventExpired)
+	}
 }
 
 func (p *Mem) SetNx(key string, value interface{}) (bool, error) {
 	p.autoClear()
 
 	p.Lock()
-	defer p.Unlock()
-
 	_, ok := p.data[key]
 	if ok {
+		p.Unlock()
 		return false, nil
 	}
 
 	p.data[key] = &Item{
 		Data: utils.ToString(value),
 	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
 
 	return true, nil
 }
@@ -195,10 +344,9 @@ func (p *Mem) SetNxWithTimeout(key string, value interface{}, timeout time.Durat
 	p.autoClear()
 
 	p.Lock()
-	defer p.Unlock()
-
 	_, ok := p.data[key]
 	if ok {
+		p.Unlock()
 		return false, nil
 	}
 
@@ -206,6 +354,10 @@ func (p *Mem) SetNxWithTimeout(key string, value interface{}, timeout time.Durat
 		Data:     utils.ToString(value),
 		ExpireAt: time.Now().Add(timeout),
 	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
+	p.subs.notify(key, KeyEventExpire)
 
 	return true, nil
 }
@@ -232,11 +384,12 @@ func (p *Mem) Set(key string, val any) error {
 	p.autoClear()
 
 	p.Lock()
-	defer p.Unlock()
-
 	p.data[key] = &Item{
 		Data: utils.ToString(val),
 	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
 
 	return nil
 }
@@ -245,11 +398,82 @@ func (p *Mem) Del(key ...string) error {
 	p.autoClear()
 
 	p.Lock()
-	defer p.Unlock()
-
+	var deleted []string
 	for _, k := range key {
+		if _, ok := p.data[k]; ok {
+			deleted = append(deleted, k)
+		}
 		delete(p.data, k)
 	}
+	p.Unlock()
+
+	for _, k := range deleted {
+		p.subs.notify(k, KeyEventDel)
+	}
+
+	return nil
+}
+
+func (p *Mem) DelPrefix(prefix string) (int64, error) {
+	p.Lock()
+	var deleted []string
+	for k := range p.data {
+		if strings.HasPrefix(k, prefix) {
+			deleted = append(deleted, k)
+			delete(p.data, k)
+		}
+	}
+	p.Unlock()
+
+	for _, k := range deleted {
+		p.subs.notify(k, KeyEventDel)
+	}
+
+	return int64(len(deleted)), nil
+}
+
+// OnKeyEvent 内存版本通过定时扫描模拟过期事件，精度为 1 秒
+func (p *Mem) OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error {
+	p.subs.add(pattern, handler, events...)
+
+	p.watchOnce.Do(func() {
+		routine.GoWithRecover(func() error {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					p.clear()
+				case <-p.stop:
+					return nil
+				}
+			}
+		})
+	})
+
+	return nil
+}
+
+// Publish 同步调用当前进程内的订阅者
+func (p *Mem) Publish(channel string, message any) error {
+	p.channelLock.RLock()
+	handlers := p.channels[channel]
+	p.channelLock.RUnlock()
+
+	msg := anyx.ToString(message)
+	for _, handler := range handlers {
+		handler(msg)
+	}
+
+	return nil
+}
+
+func (p *Mem) Subscribe(channel string, handler func(message string)) error {
+	p.channelLock.Lock()
+	defer p.channelLock.Unlock()
+
+	p.channels[channel] = append(p.channels[channel], handler)
 
 	return nil
 }
@@ -260,6 +484,12 @@ func (p *Mem) Close() error {
 
 	p.data = make(map[string]*Item)
 
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+
 	return nil
 }
 
@@ -276,6 +506,9 @@ func NewMem() Cache {
 	p := &Mem{
 		data: make(map[string]*Item),
 		rt:   rate.New(2, time.Minute),
+		stop: make(chan struct{}),
+
+		channels: make(map[string][]func(message string)),
 	}
 
 	return newBaseCache(p)
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"github.com/garyburd/redigo/redis"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/app"
+	"go.etcd.io/bbolt"
+	"strings"
+)
+
+func (p *Mem) MGet(keys ...string) (map[string]string, error) {
+	p.autoClear()
+
+	p.RLock()
+	defer p.RUnlock()
+
+	m := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if item, ok := p.getItem(key); ok {
+			m[key] = item.Data
+		}
+	}
+
+	return m, nil
+}
+
+func (p *Bbolt) MGet(keys ...string) (map[string]string, error) {
+	m := make(map[string]string, len(keys))
+	err := p.conn.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+		for _, key := range keys {
+			item, err := p.getItem(b, key)
+			if err != nil {
+				return err
+			}
+			if item != nil {
+				m[key] = item.Data
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		xerror.LogError(err)
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// MGet 一次请求读取全部的 key，不经过本地缓存
+func (p *Redis) MGet(keys ...string) (map[string]string, error) {
+	if len(keys) == 0 {
+		return map[string]string{}, nil
+	}
+
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		args[i] = app.Name + ":" + key
+	}
+
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("MGET", args...))
+	if err != nil {
+		xerror.LogError(err)
+		return nil, err
+	}
+
+	m := make(map[string]string, len(keys))
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+
+		s, err := redis.String(v, nil)
+		if err != nil {
+			xerror.LogError(err)
+			return nil, err
+		}
+		m[keys[i]] = s
+	}
+
+	return m, nil
+}
+
+func (p *namespaceCache) MGet(keys ...string) (map[string]string, error) {
+	values, err := p.base.MGet(p.keys(keys)...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(values))
+	for key, value := range values {
+		m[strings.TrimPrefix(key, p.prefix)] = value
+	}
+
+	return m, nil
+}
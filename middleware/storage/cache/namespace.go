@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrNoNamespace = errors.New("not a namespace")
+
+// namespaceCache 给所有的 key、channel 加上前缀
+type namespaceCache struct {
+	base   BaseCache
+	prefix string
+}
+
+func (p *namespaceCache) key(key string) string {
+	return p.prefix + key
+}
+
+func (p *namespaceCache) keys(keys []string) []string {
+	list := make([]string, len(keys))
+	for i, key := range keys {
+		list[i] = p.prefix + key
+	}
+	return list
+}
+
+func (p *namespaceCache) Get(key string) (string, error) {
+	return p.base.Get(p.key(key))
+}
+
+func (p *namespaceCache) Set(key string, value any) error {
+	return p.base.Set(p.key(key), value)
+}
+
+func (p *namespaceCache) SetEx(key string, value any, timeout time.Duration) error {
+	return p.base.SetEx(p.key(key), value, timeout)
+}
+
+func (p *namespaceCache) SetNx(key string, value interface{}) (bool, error) {
+	return p.base.SetNx(p.key(key), value)
+}
+
+func (p *namespaceCache) SetNxWithTimeout(key string, value interface{}, timeout time.Duration) (bool, error) {
+	return p.base.SetNxWithTimeout(p.key(key), value, timeout)
+}
+
+func (p *namespaceCache) Ttl(key string) (time.Duration, error) {
+	return p.base.Ttl(p.key(key))
+}
+
+func (p *namespaceCache) Expire(key string, timeout time.Duration) (bool, error) {
+	return p.base.Expire(p.key(key), timeout)
+}
+
+func (p *namespaceCache) Incr(key string) (int64, error) {
+	return p.base.Incr(p.key(key))
+}
+
+func (p *namespaceCache) Decr(key string) (int64, error) {
+	return p.base.Decr(p.key(key))
+}
+
+func (p *namespaceCache) IncrBy(key string, value int64) (int64, error) {
+	return p.base.IncrBy(p.key(key), value)
+}
+
+func (p *namespaceCache) DecrBy(key string, value int64) (int64, error) {
+	return p.base.DecrBy(p.key(key), value)
+}
+
+func (p *namespaceCache) Exists(keys ...string) (bool, error) {
+	return p.base.Exists(p.keys(keys)...)
+}
+
+func (p *namespaceCache) CompareAndSet(key string, old, value any) (bool, error) {
+	return p.base.CompareAndSet(p.key(key), old, value)
+}
+
+func (p *namespaceCache) GetDel(key string) (string, error) {
+	return p.base.GetDel(p.key(key))
+}
+
+func (p *namespaceCache) GetEx(key string, timeout time.Duration) (string, error) {
+	return p.base.GetEx(p.key(key), timeout)
+}
+
+func (p *namespaceCache) HSet(key string, field string, value interface{}) (bool, error) {
+	return p.base.HSet(p.key(key), field, value)
+}
+
+func (p *namespaceCache) HGet(key, field string) (string, error) {
+	return p.base.HGet(p.key(key), field)
+}
+
+func (p *namespaceCache) HDel(key string, fields ...string) (int64, error) {
+	return p.base.HDel(p.key(key), fields...)
+}
+
+func (p *namespaceCache) HKeys(key string) ([]string, error) {
+	return p.base.HKeys(p.key(key))
+}
+
+func (p *namespaceCache) HGetAll(key string) (map[string]string, error) {
+	return p.base.HGetAll(p.key(key))
+}
+
+func (p *namespaceCache) HExists(key string, field string) (bool, error) {
+	return p.base.HExists(p.key(key), field)
+}
+
+func (p *namespaceCache) HIncr(key string, subKey string) (int64, error) {
+	return p.base.HIncr(p.key(key), subKey)
+}
+
+func (p *namespaceCache) HIncrBy(key string, field string, increment int64) (int64, error) {
+	return p.base.HIncrBy(p.key(key), field, increment)
+}
+
+func (p *namespaceCache) HDecr(key string, field string) (int64, error) {
+	return p.base.HDecr(p.key(key), field)
+}
+
+func (p *namespaceCache) HDecrBy(key string, field string, increment int64) (int64, error) {
+	return p.base.HDecrBy(p.key(key), field, increment)
+}
+
+func (p *namespaceCache) SAdd(key string, members ...string) (int64, error) {
+	return p.base.SAdd(p.key(key), members...)
+}
+
+func (p *namespaceCache) SMembers(key string) ([]string, error) {
+	return p.base.SMembers(p.key(key))
+}
+
+func (p *namespaceCache) SRem(key string, members ...string) (int64, error) {
+	return p.base.SRem(p.key(key), members...)
+}
+
+func (p *namespaceCache) SRandMember(key string, count ...int64) ([]string, error) {
+	return p.base.SRandMember(p.key(key), count...)
+}
+
+func (p *namespaceCache) SPop(key string) (string, error) {
+	return p.base.SPop(p.key(key))
+}
+
+func (p *namespaceCache) SisMember(key, field string) (bool, error) {
+	return p.base.SisMember(p.key(key), field)
+}
+
+func (p *namespaceCache) Del(key ...string) error {
+	return p.base.Del(p.keys(key)...)
+}
+
+func (p *namespaceCache) DelPrefix(prefix string) (int64, error) {
+	return p.base.DelPrefix(p.key(prefix))
+}
+
+func (p *namespaceCache) OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error {
+	return p.base.OnKeyEvent(p.key(pattern), func(key string, event KeyEvent) {
+		handler(strings.TrimPrefix(key, p.prefix), event)
+	}, events...)
+}
+
+func (p *namespaceCache) Publish(channel string, message any) error {
+	return p.base.Publish(p.key(channel), message)
+}
+
+func (p *namespaceCache) Subscribe(channel string, handler func(message string)) error {
+	return p.base.Subscribe(p.key(channel), handler)
+}
+
+// Close 命名空间只是视图，不关闭底层的连接
+func (p *namespaceCache) Close() error {
+	return nil
+}
+
+func (p *baseCache) Namespace(prefix string) Cache {
+	return newBaseCache(&namespaceCache{
+		base:   p.BaseCache,
+		prefix: prefix,
+	})
+}
+
+func (p *baseCache) InvalidateNamespace() (int64, error) {
+	ns, ok := p.BaseCache.(*namespaceCache)
+	if !ok || ns.prefix == "" {
+		return 0, ErrNoNamespace
+	}
+
+	return ns.DelPrefix("")
+}
@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	mem := cache.NewMem()
+	users := mem.Namespace("user:")
+	orders := mem.Namespace("order:")
+	admins := users.Namespace("admin:")
+
+	_ = mem.Set("1", "root")
+	_ = users.Set("1", "alice")
+	_ = orders.Set("1", "book")
+	_ = admins.Set("1", "bob")
+
+	// 底层的 key 带有前缀，嵌套时前缀叠加
+	for key, want := range map[string]string{
+		"1":            "root",
+		"user:1":       "alice",
+		"order:1":      "book",
+		"user:admin:1": "bob",
+	} {
+		value, err := mem.Get(key)
+		if err != nil || value != want {
+			t.Errorf("key:%s, value:%s, err:%v", key, value, err)
+		}
+	}
+
+	if value, err := users.Get("1"); err != nil || value != "alice" {
+		t.Errorf("value:%s, err:%v", value, err)
+	}
+
+	// 只删除当前命名空间(包括嵌套的)下的 key
+	n, err := users.InvalidateNamespace()
+	if err != nil || n != 2 {
+		t.Errorf("n:%d, err:%v", n, err)
+	}
+	if _, err = users.Get("1"); err != cache.NotFound {
+		t.Errorf("err:%v", err)
+	}
+	if _, err = admins.Get("1"); err != cache.NotFound {
+		t.Errorf("err:%v", err)
+	}
+	if value, err := orders.Get("1"); err != nil || value != "book" {
+		t.Errorf("value:%s, err:%v", value, err)
+	}
+	if value, err := mem.Get("1"); err != nil || value != "root" {
+		t.Errorf("value:%s, err:%v", value, err)
+	}
+
+	_, err = mem.InvalidateNamespace()
+	if !errors.Is(err, cache.ErrNoNamespace) {
+		t.Errorf("err:%v", err)
+	}
+	_, err = mem.Namespace("").InvalidateNamespace()
+	if !errors.Is(err, cache.ErrNoNamespace) {
+		t.Errorf("err:%v", err)
+	}
+}
@@ -2,11 +2,18 @@ package cache
 
 import (
 	"errors"
+	"fmt"
 	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"github.com/lazygophers/lrpc/middleware/xerror"
 	"github.com/lazygophers/utils/anyx"
 	"github.com/lazygophers/utils/app"
 	"github.com/lazygophers/utils/atexit"
 	"github.com/lazygophers/utils/candy"
+	"github.com/lazygophers/utils/routine"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/garyburd/redigo/redis"
@@ -15,9 +22,29 @@ import (
 
 type Redis struct {
 	cli *xredis.Client
+
+	subs      keySubscriptions
+	watchOnce sync.Once
+	stop      chan struct{}
+
+	// 后台的订阅、tracking，Close 时等待退出
+	wg sync.WaitGroup
+
+	// 为空时不开启进程内缓存，可能在使用过程中开启
+	local atomic.Pointer[clientCache]
 }
 
 func NewRedis(address string, opts ...redis.DialOption) (Cache, error) {
+	p, err := newRedis(address, opts...)
+	if err != nil {
+		xerror.LogError(err)
+		return nil, err
+	}
+
+	return newBaseCache(p), nil
+}
+
+func newRedis(address string, opts ...redis.DialOption) (*Redis, error) {
 	p := &Redis{
 		cli: xredis.NewClient(&redis.Pool{
 			Dial: func() (redis.Conn, error) {
@@ -28,51 +55,70 @@ func NewRedis(address string, opts ...redis.DialOption) (Cache, error) {
 			IdleTimeout: time.Second * 5,
 			Wait:        true,
 		}),
+		stop: make(chan struct{}),
 	}
 
 	atexit.Register(func() {
 		err := p.Close()
 		if err != nil {
-			log.Errorf("err:%v", err)
+			xerror.LogError(err)
 			return
 		}
 	})
 
 	pong, err := p.cli.Ping()
 	if err != nil {
-		log.Errorf("err:%v", err)
+		xerror.LogError(err)
 		return nil, err
 	}
 
 	log.Infof("ping:%v", pong)
 
-	return newBaseCache(p), nil
+	return p, nil
 }
 
 func (p *Redis) Incr(key string) (int64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
 	return p.cli.Incr(app.Name + ":" + key)
 }
 
 func (p *Redis) Decr(key string) (int64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
 	return p.cli.Decr(app.Name + ":" + key)
 }
 
 func (p *Redis) IncrBy(key string, value int64) (int64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
 	return p.cli.IncrBy(app.Name+":"+key, value)
 }
 
 func (p *Redis) IncrByFloat(key string, increment float64) (float64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
 	return p.cli.IncrByFloat(app.Name+":"+key, increment)
 }
 
 func (p *Redis) DecrBy(key string, value int64) (int64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
 	return p.cli.DecrBy(app.Name+":"+key, value)
 }
 
 func (p *Redis) Get(key string) (string, error) {
-	log.Debugf("get %s", key)
+	log.Debugf("get %s", redact.Key(key))
+
+	key = app.Name + ":" + key
+
+	var version uint64
+	local := p.local.Load()
+	cached := local.match(key)
+	if cached {
+		if val, ok := local.get(key); ok {
+			return val, nil
+		}
+
+		version = local.version.Load()
+	}
 
-	val, ok, err := p.cli.Get(app.Name + ":" + key)
+	val, ok, err := p.cli.Get(key)
 	if err != nil {
 		return "", err
 	}
@@ -80,6 +126,10 @@ func (p *Redis) Get(key string) (string, error) {
 		return "", NotFound
 	}
 
+	if cached {
+		local.set(key, val, version)
+	}
+
 	return val, nil
 }
 
@@ -99,7 +149,9 @@ func (p *Redis) Exists(keys ...string) (bool, error) {
 }
 
 func (p *Redis) SetNx(key string, value interface{}) (bool, error) {
-	log.Debugf("set nx %s", key)
+	defer p.local.Load().del(app.Name + ":" + key)
+
+	log.Debugf("set nx %s", redact.Key(key))
 
 	ok, err := p.cli.SetNx(app.Name+":"+key, anyx.ToString(value))
 	if err != nil {
@@ -131,7 +183,7 @@ func (p *Redis) Ttl(key string) (time.Duration, error) {
 
 	ttl, err := redis.Int64(connection.Do("TTL", app.Name+":"+key))
 	if err != nil {
-		log.Errorf("err:%v", err)
+		xerror.LogError(err)
 		return 0, err
 	}
 
@@ -139,32 +191,36 @@ func (p *Redis) Ttl(key string) (time.Duration, error) {
 }
 
 func (p *Redis) Set(key string, value interface{}) (err error) {
-	log.Debugf("set %s", app.Name+":"+key)
+	defer p.local.Load().del(app.Name + ":" + key)
+
+	log.Debugf("set %s", redact.Key(app.Name+":"+key))
 
 	_, err = p.cli.Set(app.Name+":"+key, anyx.ToString(value))
 	return err
 }
 
 func (p *Redis) SetEx(key string, value interface{}, timeout time.Duration) error {
-	log.Debugf("set ex %s", app.Name+":"+key)
+	defer p.local.Load().del(app.Name + ":" + key)
+
+	log.Debugf("set ex %s", redact.Key(app.Name+":"+key))
 
 	_, err := p.cli.SetEx(app.Name+":"+key, anyx.ToString(value), int64(timeout.Seconds()))
 	return err
 }
 
 func (p *Redis) SetNxWithTimeout(key string, value interface{}, timeout time.Duration) (bool, error) {
-	log.Debugf("set nx ex %s", app.Name+":"+key)
+	log.Debugf("set nx ex %s", redact.Key(app.Name+":"+key))
 
 	ok, err := p.SetNx(key, value)
 	if err != nil {
-		log.Errorf("err:%v", err)
+		xerror.LogError(err)
 		return false, err
 	}
 
 	if ok {
 		_, err = p.Expire(key, timeout)
 		if err != nil {
-			log.Errorf("err:%v", err)
+			xerror.LogError(err)
 		}
 	}
 
@@ -172,9 +228,12 @@ func (p *Redis) SetNxWithTimeout(key string, value interface{}, timeout time.Dur
 }
 
 func (p *Redis) Del(keys ...string) (err error) {
-	_, err = p.cli.Del(candy.Map(keys, func(key string) string {
+	keys = candy.Map(keys, func(key string) string {
 		return app.Name + ":" + key
-	})...)
+	})
+	defer p.local.Load().del(keys...)
+
+	_, err = p.cli.Del(keys...)
 	return
 }
 
@@ -302,6 +361,235 @@ func (p *Redis) SisMember(key, field string) (bool, error) {
 	return redis.Bool(conn.Do("SISMEMBER", args...))
 }
 
+// 在原有配置的基础上打开 keyspace 通知，没有权限(例如云厂商禁用了 CONFIG)时需要手动配置
+func (p *Redis) enableKeyspaceEvents() {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	values, err := redis.Strings(conn.Do("CONFIG", "GET", "notify-keyspace-events"))
+	if err != nil {
+		log.Warnf("get notify-keyspace-events failed, err:%v", err)
+		return
+	}
+
+	var flags string
+	if len(values) == 2 {
+		flags = values[1]
+	}
+
+	changed := false
+	for _, flag := range []string{"K", "g", "$", "x"} {
+		if !strings.Contains(flags, flag) && !(flag != "K" && strings.Contains(flags, "A")) {
+			flags += flag
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	_, err = conn.Do("CONFIG", "SET", "notify-keyspace-events", flags)
+	if err != nil {
+		log.Warnf("set notify-keyspace-events failed, err:%v", err)
+	}
+}
+
+var (
+	// ErrDelPrefixIncomplete DelPrefix 超过单次的预算后停止，已经删除的数量依旧返回，再次调用可以继续删除
+	ErrDelPrefixIncomplete = errors.New("del prefix stopped by budget, call again to continue")
+
+	// DelPrefix 单次调用最多删除的 key 数量以及耗时，避免一次同步扫描过长时间占用 redis 与调用方
+	DelPrefixMaxKeys int64 = 100000
+	DelPrefixTimeout       = time.Second * 10
+)
+
+// MATCH 使用 glob 语法，前缀中的通配符需要转义，否则例如 "*" 会匹配全部的 key
+func escapeGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// DelPrefix 通过 SCAN + UNLINK 分批删除，不会像 KEYS 一样阻塞 redis
+// 超过 DelPrefixMaxKeys 或者 DelPrefixTimeout 时返回 ErrDelPrefixIncomplete
+func (p *Redis) DelPrefix(prefix string) (int64, error) {
+	defer p.local.Load().delPrefix(app.Name + ":" + prefix)
+
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	pattern := escapeGlob(app.Name+":"+prefix) + "*"
+	deadline := time.Now().Add(DelPrefixTimeout)
+
+	var count int64
+	cursor := "0"
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 500))
+		if err != nil {
+			xerror.LogError(err)
+			return count, err
+		}
+
+		cursor, err = redis.String(values[0], nil)
+		if err != nil {
+			xerror.LogError(err)
+			return count, err
+		}
+
+		keys, err := redis.Strings(values[1], nil)
+		if err != nil {
+			xerror.LogError(err)
+			return count, err
+		}
+
+		if len(keys) > 0 {
+			n, err := redis.Int64(conn.Do("UNLINK", redis.Args{}.AddFlat(keys)...))
+			if err != nil {
+				xerror.LogError(err)
+				return count, err
+			}
+			count += n
+		}
+
+		if cursor == "0" {
+			return count, nil
+		}
+
+		if count >= DelPrefixMaxKeys || time.Now().After(deadline) {
+			log.Warnf("del prefix %s stopped by budget, deleted:%d", prefix, count)
+			return count, ErrDelPrefixIncomplete
+		}
+	}
+}
+
+// 在独立的连接上订阅，直到连接断开或者关闭
+func (p *Redis) listen(subscribe func(psc *redis.PubSubConn) error, handle func(msg redis.Message)) error {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	psc := &redis.PubSubConn{Conn: conn}
+
+	err := subscribe(psc)
+	if err != nil {
+		xerror.LogError(err)
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-p.stop:
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		// 连接池配置了读超时，订阅时没有消息是常态，不能使用
+		switch v := psc.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			handle(v)
+
+		case error:
+			return v
+		}
+	}
+}
+
+// 断开后自动重连，期间的消息会丢失
+func (p *Redis) listenForever(name string, subscribe func(psc *redis.PubSubConn) error, handle func(msg redis.Message)) {
+	p.wg.Add(1)
+	routine.GoWithRecover(func() error {
+		defer p.wg.Done()
+
+		for {
+			err := p.listen(subscribe, handle)
+
+			select {
+			case <-p.stop:
+				return nil
+			default:
+			}
+
+			xerror.LogError(fmt.Errorf("%s subscribe broken: %w", name, err))
+
+			select {
+			case <-p.stop:
+				return nil
+			case <-time.After(time.Second):
+			}
+		}
+	})
+}
+
+// OnKeyEvent 基于 redis 的 keyspace 通知，连接断开后会自动重连，期间的事件会丢失
+func (p *Redis) OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error {
+	p.subs.add(pattern, handler, events...)
+
+	p.watchOnce.Do(func() {
+		p.enableKeyspaceEvents()
+
+		p.listenForever("keyspace", func(psc *redis.PubSubConn) error {
+			return psc.PSubscribe("__keyspace@*__:" + app.Name + ":*")
+		}, func(msg redis.Message) {
+			// __keyspace@0__:app:key
+			_, key, ok := strings.Cut(msg.Channel, "__:")
+			if !ok {
+				return
+			}
+
+			p.subs.notify(strings.TrimPrefix(key, app.Name+":"), KeyEvent(msg.Data))
+		})
+	})
+
+	return nil
+}
+
+func (p *Redis) Publish(channel string, message any) error {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	_, err := conn.Do("PUBLISH", app.Name+":"+channel, anyx.ToString(message))
+	if err != nil {
+		xerror.LogError(err)
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe 每次订阅使用一个独立的连接
+func (p *Redis) Subscribe(channel string, handler func(message string)) error {
+	p.listenForever(channel, func(psc *redis.PubSubConn) error {
+		return psc.Subscribe(app.Name + ":" + channel)
+	}, func(msg redis.Message) {
+		handler(string(msg.Data))
+	})
+
+	return nil
+}
+
 func (p *Redis) Close() error {
-	return p.cli.Close()
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+
+	err := p.cli.Close()
+	p.wg.Wait()
+	return err
 }
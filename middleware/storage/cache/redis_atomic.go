@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"github.com/garyburd/redigo/redis"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/app"
+	"time"
+)
+
+var (
+	// KEEPTTL 需要 redis 6.0 以上
+	compareAndSetScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[2], 'KEEPTTL')
+	return 1
+end
+return 0
+`)
+
+	// GETDEL/GETEX 需要 redis 6.2，通过脚本兼容低版本
+	getDelScript = redis.NewScript(1, `
+local v = redis.call('GET', KEYS[1])
+if v then
+	redis.call('DEL', KEYS[1])
+end
+return v
+`)
+
+	getExScript = redis.NewScript(1, `
+local v = redis.call('GET', KEYS[1])
+if v then
+	if tonumber(ARGV[1]) > 0 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	else
+		redis.call('PERSIST', KEYS[1])
+	end
+end
+return v
+`)
+)
+
+func (p *Redis) CompareAndSet(key string, old, value any) (bool, error) {
+	key = app.Name + ":" + key
+	defer p.local.Load().del(key)
+
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	ok, err := redis.Bool(compareAndSetScript.Do(conn, key, anyx.ToString(old), anyx.ToString(value)))
+	if err != nil {
+		xerror.LogError(err)
+		return false, err
+	}
+
+	return ok, nil
+}
+
+func (p *Redis) GetDel(key string) (string, error) {
+	key = app.Name + ":" + key
+	defer p.local.Load().del(key)
+
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	val, err := redis.String(getDelScript.Do(conn, key))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", NotFound
+		}
+
+		xerror.LogError(err)
+		return "", err
+	}
+
+	return val, nil
+}
+
+func (p *Redis) GetEx(key string, timeout time.Duration) (string, error) {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	val, err := redis.String(getExScript.Do(conn, app.Name+":"+key, timeout.Milliseconds()))
+	if err != nil {
+		if err == redis.ErrNil {
+			return "", NotFound
+		}
+
+		xerror.LogError(err)
+		return "", err
+	}
+
+	return val, nil
+}
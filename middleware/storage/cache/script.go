@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/garyburd/redigo/redis"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/app"
+	"go.etcd.io/bbolt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrScriptNotFound = errors.New("script not found")
+	// ErrScriptNotSupport 脚本没有提供 Local 实现，无法在 mem、bbolt 中执行
+	ErrScriptNotSupport = errors.New("script not support")
+)
+
+// ScriptTx 本地脚本可以使用的操作，mem 在锁内执行，bbolt 在同一个事务中执行
+// 返回错误时 bbolt 会回滚，mem 与 redis 一致，已经执行的写入不会回滚
+type ScriptTx interface {
+	// Get 不存在时返回 NotFound
+	Get(key string) (string, error)
+	Set(key string, value any) error
+	SetEx(key string, value any, timeout time.Duration) error
+	Del(keys ...string) error
+	IncrBy(key string, value int64) (int64, error)
+}
+
+// Script 命名的多 key 原子操作，redis 中以 lua 执行，其他实现中执行 Local
+type Script struct {
+	Name string
+
+	// 通过 KEYS[i]、ARGV[i] 访问 key 与参数
+	Lua string
+
+	// 与 Lua 等价的实现，args 已经转换为字符串
+	Local func(tx ScriptTx, keys []string, args []string) (any, error)
+
+	// 缓存了 sha1，通过 EVALSHA 执行，服务端没有时自动 EVAL
+	redis *redis.Script
+}
+
+var scripts sync.Map
+
+// RegisterScript 在启动时注册脚本，名字重复时覆盖
+//
+//	cache.RegisterScript(&cache.Script{
+//		Name: "reserve_stock",
+//		Lua: `
+//	local stock = tonumber(redis.call('GET', KEYS[1]) or '0')
+//	if stock < tonumber(ARGV[1]) then return 0 end
+//	redis.call('DECRBY', KEYS[1], ARGV[1])
+//	redis.call('SET', KEYS[2], ARGV[1])
+//	return 1`,
+//		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) { ... },
+//	})
+//
+//	res, err := c.Script("reserve_stock").Run([]string{"stock:1", "order:1"}, 2)
+func RegisterScript(s *Script) {
+	if s.Name == "" {
+		panic("script name is empty")
+	}
+
+	if s.Lua != "" {
+		s.redis = redis.NewScript(-1, s.Lua)
+	}
+
+	scripts.Store(s.Name, s)
+}
+
+func getScript(name string) (*Script, error) {
+	v, ok := scripts.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrScriptNotFound, name)
+	}
+	return v.(*Script), nil
+}
+
+// 本地执行时参数统一为字符串，与 redis 的 ARGV 一致
+func scriptArgs(args []any) []string {
+	list := make([]string, len(args))
+	for i, arg := range args {
+		list[i] = anyx.ToString(arg)
+	}
+	return list
+}
+
+// redis 返回的 bulk string 转换为 string，保持与本地实现的返回一致
+func scriptReply(reply any) any {
+	switch x := reply.(type) {
+	case []byte:
+		return string(x)
+	case []any:
+		for i, v := range x {
+			x[i] = scriptReply(v)
+		}
+		return x
+	default:
+		return reply
+	}
+}
+
+type ScriptRunner struct {
+	cache BaseCache
+	name  string
+}
+
+// Run 执行脚本，redis 中 nil 返回 nil，整数返回 int64，字符串返回 string，数组返回 []any
+func (p *ScriptRunner) Run(keys []string, args ...any) (any, error) {
+	return p.cache.RunScript(p.name, keys, args...)
+}
+
+func (p *ScriptRunner) Int64(keys []string, args ...any) (int64, error) {
+	v, err := p.Run(keys, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return 0, NotFound
+	case int64:
+		return x, nil
+	case string:
+		return strconv.ParseInt(x, 10, 64)
+	default:
+		return anyx.ToInt64(x), nil
+	}
+}
+
+func (p *baseCache) Script(name string) *ScriptRunner {
+	return &ScriptRunner{
+		cache: p.BaseCache,
+		name:  name,
+	}
+}
+
+// memScriptTx 调用方持有 Mem 的写锁，变化的 key 在解锁后统一通知
+type memScriptTx struct {
+	mem    *Mem
+	events []func()
+}
+
+func (p *memScriptTx) Get(key string) (string, error) {
+	item, ok := p.mem.getItem(key)
+	if !ok {
+		return "", NotFound
+	}
+	return item.Data, nil
+}
+
+func (p *memScriptTx) Set(key string, value any) error {
+	p.mem.data[key] = &Item{
+		Data: anyx.ToString(value),
+	}
+	p.events = append(p.events, func() {
+		p.mem.subs.notify(key, KeyEventSet)
+	})
+	return nil
+}
+
+func (p *memScriptTx) SetEx(key string, value any, timeout time.Duration) error {
+	p.mem.data[key] = &Item{
+		Data:     anyx.ToString(value),
+		ExpireAt: time.Now().Add(timeout),
+	}
+	p.events = append(p.events, func() {
+		p.mem.subs.notify(key, KeyEventSet)
+		p.mem.subs.notify(key, KeyEventExpire)
+	})
+	return nil
+}
+
+func (p *memScriptTx) Del(keys ...string) error {
+	for _, key := range keys {
+		if _, ok := p.mem.data[key]; !ok {
+			continue
+		}
+		delete(p.mem.data, key)
+
+		key := key
+		p.events = append(p.events, func() {
+			p.mem.subs.notify(key, KeyEventDel)
+		})
+	}
+	return nil
+}
+
+func (p *memScriptTx) IncrBy(key string, value int64) (int64, error) {
+	item, ok := p.mem.getItem(key)
+	if !ok {
+		item = &Item{}
+		p.mem.data[key] = item
+	}
+
+	var cnt int64
+	if item.Data != "" {
+		var err error
+		cnt, err = strconv.ParseInt(item.Data, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	cnt += value
+	item.Data = strconv.FormatInt(cnt, 10)
+	p.events = append(p.events, func() {
+		p.mem.subs.notify(key, KeyEventSet)
+	})
+	return cnt, nil
+}
+
+// RunScript 在写锁内执行 Local，期间其他操作会被阻塞，脚本中不能调用 Mem 本身的方法
+func (p *Mem) RunScript(name string, keys []string, args ...any) (any, error) {
+	s, err := getScript(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.Local == nil {
+		return nil, fmt.Errorf("%w: %s", ErrScriptNotSupport, name)
+	}
+
+	p.autoClear()
+
+	tx := &memScriptTx{mem: p}
+	p.Lock()
+	res, err := s.Local(tx, keys, scriptArgs(args))
+	p.Unlock()
+
+	for _, event := range tx.events {
+		event()
+	}
+
+	return res, err
+}
+
+type bboltScriptTx struct {
+	cache  *Bbolt
+	bucket *bbolt.Bucket
+}
+
+func (p *bboltScriptTx) Get(key string) (string, error) {
+	item, err := p.cache.getItem(p.bucket, key)
+	if err != nil {
+		return "", err
+	}
+	if item == nil {
+		return "", NotFound
+	}
+	return item.Data, nil
+}
+
+func (p *bboltScriptTx) Set(key string, value any) error {
+	item := &Item{
+		Data: anyx.ToString(value),
+	}
+	return p.bucket.Put([]byte(key), item.Bytes())
+}
+
+func (p *bboltScriptTx) SetEx(key string, value any, timeout time.Duration) error {
+	item := &Item{
+		Data:     anyx.ToString(value),
+		ExpireAt: time.Now().Add(timeout),
+	}
+	return p.bucket.Put([]byte(key), item.Bytes())
+}
+
+func (p *bboltScriptTx) Del(keys ...string) error {
+	for _, key := range keys {
+		err := p.bucket.Delete([]byte(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *bboltScriptTx) IncrBy(key string, value int64) (int64, error) {
+	item, err := p.cache.getItem(p.bucket, key)
+	if err != nil {
+		return 0, err
+	}
+	if item == nil {
+		item = &Item{}
+	}
+
+	var cnt int64
+	if item.Data != "" {
+		cnt, err = strconv.ParseInt(item.Data, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	cnt += value
+	item.Data = strconv.FormatInt(cnt, 10)
+
+	return cnt, p.bucket.Put([]byte(key), item.Bytes())
+}
+
+// RunScript 在同一个写事务中执行 Local，返回错误时全部回滚
+func (p *Bbolt) RunScript(name string, keys []string, args ...any) (any, error) {
+	s, err := getScript(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.Local == nil {
+		return nil, fmt.Errorf("%w: %s", ErrScriptNotSupport, name)
+	}
+
+	var res any
+	err = p.conn.Update(func(tx *bbolt.Tx) error {
+		var err error
+		res, err = s.Local(&bboltScriptTx{
+			cache:  p,
+			bucket: tx.Bucket(bboltBucket),
+		}, keys, scriptArgs(args))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (p *Redis) RunScript(name string, keys []string, args ...any) (any, error) {
+	s, err := getScript(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.redis == nil {
+		return nil, fmt.Errorf("%w: %s", ErrScriptNotSupport, name)
+	}
+
+	params := make([]any, 0, len(keys)+len(args)+1)
+	params = append(params, len(keys))
+	for _, key := range keys {
+		key = app.Name + ":" + key
+		defer p.local.Load().del(key)
+		params = append(params, key)
+	}
+	params = append(params, args...)
+
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	reply, err := s.redis.Do(conn, params...)
+	if err != nil {
+		xerror.LogError(err)
+		return nil, err
+	}
+
+	return scriptReply(reply), nil
+}
+
+func (p *namespaceCache) RunScript(name string, keys []string, args ...any) (any, error) {
+	return p.base.RunScript(name, p.keys(keys), args...)
+}
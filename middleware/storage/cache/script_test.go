@@ -0,0 +1,134 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+var errOutOfStock = errors.New("out of stock")
+
+func init() {
+	cache.RegisterScript(&cache.Script{
+		Name: "test:reserve",
+		Lua: `
+local stock = tonumber(redis.call('GET', KEYS[1]) or '0')
+if stock < tonumber(ARGV[1]) then return 0 end
+redis.call('DECRBY', KEYS[1], ARGV[1])
+redis.call('SET', KEYS[2], ARGV[1])
+return 1`,
+		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) {
+			s, err := tx.Get(keys[0])
+			if err != nil && !errors.Is(err, cache.NotFound) {
+				return nil, err
+			}
+			stock, _ := strconv.ParseInt(s, 10, 64)
+
+			n, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if stock < n {
+				return int64(0), nil
+			}
+
+			_, err = tx.IncrBy(keys[0], -n)
+			if err != nil {
+				return nil, err
+			}
+
+			return int64(1), tx.Set(keys[1], n)
+		},
+	})
+
+	// 写入后返回错误，用于检查回滚
+	cache.RegisterScript(&cache.Script{
+		Name: "test:fail",
+		Local: func(tx cache.ScriptTx, keys []string, args []string) (any, error) {
+			err := tx.Set(keys[0], "dirty")
+			if err != nil {
+				return nil, err
+			}
+			return nil, errOutOfStock
+		},
+	})
+
+	cache.RegisterScript(&cache.Script{
+		Name: "test:lua_only",
+		Lua:  "return 1",
+	})
+}
+
+func TestScript(t *testing.T) {
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	for name, c := range map[string]cache.Cache{"mem": mem, "bbolt": bolt, "namespace": mem.Namespace("ns:")} {
+		_ = c.Set("stock", 3)
+
+		ok, err := c.Script("test:reserve").Int64([]string{"stock", "order"}, 5)
+		if err != nil || ok != 0 {
+			t.Errorf("%s: ok:%d, err:%v", name, ok, err)
+		}
+
+		ok, err = c.Script("test:reserve").Int64([]string{"stock", "order"}, 2)
+		if err != nil || ok != 1 {
+			t.Errorf("%s: ok:%d, err:%v", name, ok, err)
+		}
+
+		stock, _ := c.Get("stock")
+		order, _ := c.Get("order")
+		if stock != "1" || order != "2" {
+			t.Errorf("%s: stock:%s, order:%s", name, stock, order)
+		}
+
+		_, err = c.Script("test:missing").Run(nil)
+		if !errors.Is(err, cache.ErrScriptNotFound) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		_, err = c.Script("test:lua_only").Run(nil)
+		if !errors.Is(err, cache.ErrScriptNotSupport) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+	}
+
+	// bbolt 在同一个事务中执行，出错时回滚
+	_, err = bolt.Script("test:fail").Run([]string{"rollback"})
+	if !errors.Is(err, errOutOfStock) {
+		t.Errorf("err:%v", err)
+	}
+	_, err = bolt.Get("rollback")
+	if !errors.Is(err, cache.NotFound) {
+		t.Errorf("not rolled back, err:%v", err)
+	}
+}
+
+func TestScriptEvent(t *testing.T) {
+	mem := cache.NewMem()
+	defer mem.Close()
+
+	_ = mem.Set("stock", 1)
+
+	// 事件在解锁后触发，handler 中可以继续访问缓存
+	var order string
+	err := mem.OnKeyEvent("order", func(key string, event cache.KeyEvent) {
+		order, _ = mem.Get(key)
+	}, cache.KeyEventSet)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	_, err = mem.Script("test:reserve").Run([]string{"stock", "order"}, 1)
+	if err != nil || order != "1" {
+		t.Errorf("order:%s, err:%v", order, err)
+	}
+}
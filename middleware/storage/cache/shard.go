@@ -0,0 +1,564 @@
+package cache
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCrossShard 脚本的 key 分布在不同的节点上，可以通过 {tag} 让相关的 key 落在同一个节点
+var ErrCrossShard = errors.New("keys belong to different shards")
+
+type ShardConfig struct {
+	// 各个节点的配置，节点之间相互独立，不需要 redis cluster
+	Nodes []*Config `yaml:"nodes"`
+
+	// 每个节点在哈希环上的虚拟节点数，默认 160
+	VirtualNodes int `yaml:"virtual_nodes"`
+
+	// 连续出现网络错误的次数达到后摘除节点，默认 3
+	FailureThreshold int `yaml:"failure_threshold"`
+
+	// 摘除的时长，之后重新加入并按请求结果判断，默认 30s
+	EjectDuration time.Duration `yaml:"eject_duration"`
+}
+
+func (c *ShardConfig) apply() {
+	if c.VirtualNodes <= 0 {
+		c.VirtualNodes = 160
+	}
+
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = 3
+	}
+
+	if c.EjectDuration <= 0 {
+		c.EjectDuration = time.Second * 30
+	}
+}
+
+type shardNode struct {
+	name  string
+	cache BaseCache
+
+	threshold     int32
+	ejectDuration time.Duration
+
+	failures   atomic.Int32
+	ejectUntil atomic.Int64
+}
+
+func (p *shardNode) available(now int64) bool {
+	return p.ejectUntil.Load() <= now
+}
+
+// 只有网络错误计入失败次数，NotFound 等业务错误不影响节点的状态
+func (p *shardNode) report(err error) {
+	if !isNodeFailure(err) {
+		if p.failures.Swap(0) >= p.threshold {
+			log.Infof("cache shard %s recovered", p.name)
+		}
+		return
+	}
+
+	// 重新加入后再次失败时立即摘除
+	if p.failures.Add(1) >= p.threshold {
+		p.ejectUntil.Store(time.Now().Add(p.ejectDuration).UnixNano())
+		log.Warnf("cache shard %s ejected for %s, err:%v", p.name, p.ejectDuration, err)
+	}
+}
+
+func isNodeFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Sharded 通过一致性哈希将 key 分布到多个独立的节点，节点摘除时只有该节点上的 key 会迁移到相邻节点
+// 与 redis cluster 一致，key 中包含 {tag} 时只对 tag 计算哈希
+type Sharded struct {
+	nodes []*shardNode
+
+	// 按哈希值排序的虚拟节点
+	ring  []uint32
+	owner []int
+}
+
+func NewSharded(c *ShardConfig) (Cache, error) {
+	p, err := newSharded(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBaseCache(p), nil
+}
+
+func newSharded(c *ShardConfig) (*Sharded, error) {
+	if len(c.Nodes) == 0 {
+		return nil, errors.New("shard nodes is empty")
+	}
+
+	c.apply()
+
+	p := &Sharded{}
+	for i, nc := range c.Nodes {
+		if nc.Type == "shard" {
+			return nil, errors.New("shard node can not be shard")
+		}
+
+		nc.apply()
+		node, err := newCache(nc)
+		if err != nil {
+			xerror.LogError(err)
+			_ = p.Close()
+			return nil, err
+		}
+
+		name := nc.Address
+		if name == "" {
+			name = nc.Type + "#" + strconv.Itoa(i)
+		}
+
+		p.nodes = append(p.nodes, &shardNode{
+			name:          name,
+			cache:         node,
+			threshold:     int32(c.FailureThreshold),
+			ejectDuration: c.EjectDuration,
+		})
+	}
+
+	type point struct {
+		hash  uint32
+		owner int
+	}
+
+	points := make([]point, 0, len(p.nodes)*c.VirtualNodes)
+	for i, node := range p.nodes {
+		for v := 0; v < c.VirtualNodes; v++ {
+			points = append(points, point{
+				hash:  shardHash(node.name + "#" + strconv.Itoa(v)),
+				owner: i,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	p.ring = make([]uint32, len(points))
+	p.owner = make([]int, len(points))
+	for i, x := range points {
+		p.ring[i] = x.hash
+		p.owner[i] = x.owner
+	}
+
+	return p, nil
+}
+
+// 与 ketama 一致使用 md5，虚拟节点名相近时 fnv 等哈希的分布不均匀
+func shardHash(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+func shardKey(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// 顺时针找到第一个可用的节点，全部被摘除时使用原本的节点
+func (p *Sharded) pick(key string) *shardNode {
+	hash := shardHash(shardKey(key))
+	start := sort.Search(len(p.ring), func(i int) bool {
+		return p.ring[i] >= hash
+	})
+
+	now := time.Now().UnixNano()
+	for i := 0; i < len(p.ring); i++ {
+		node := p.nodes[p.owner[(start+i)%len(p.ring)]]
+		if node.available(now) {
+			return node
+		}
+	}
+
+	return p.nodes[p.owner[start%len(p.ring)]]
+}
+
+// 按节点分组，保持每个节点内 key 的顺序
+func (p *Sharded) group(keys []string) map[*shardNode][]string {
+	m := make(map[*shardNode][]string)
+	for _, key := range keys {
+		node := p.pick(key)
+		m[node] = append(m[node], key)
+	}
+	return m
+}
+
+func shardCall[T any](p *Sharded, key string, logic func(c BaseCache) (T, error)) (T, error) {
+	node := p.pick(key)
+	v, err := logic(node.cache)
+	node.report(err)
+	return v, err
+}
+
+func shardExec(p *Sharded, key string, logic func(c BaseCache) error) error {
+	node := p.pick(key)
+	err := logic(node.cache)
+	node.report(err)
+	return err
+}
+
+func (p *Sharded) Get(key string) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.Get(key)
+	})
+}
+
+func (p *Sharded) MGet(keys ...string) (map[string]string, error) {
+	m := make(map[string]string, len(keys))
+	for node, list := range p.group(keys) {
+		values, err := node.cache.MGet(list...)
+		node.report(err)
+		if err != nil {
+			xerror.LogError(err)
+			return nil, err
+		}
+
+		for k, v := range values {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+func (p *Sharded) Set(key string, value any) error {
+	return shardExec(p, key, func(c BaseCache) error {
+		return c.Set(key, value)
+	})
+}
+
+func (p *Sharded) SetEx(key string, value any, timeout time.Duration) error {
+	return shardExec(p, key, func(c BaseCache) error {
+		return c.SetEx(key, value, timeout)
+	})
+}
+
+func (p *Sharded) SetNx(key string, value interface{}) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.SetNx(key, value)
+	})
+}
+
+func (p *Sharded) SetNxWithTimeout(key string, value interface{}, timeout time.Duration) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.SetNxWithTimeout(key, value, timeout)
+	})
+}
+
+func (p *Sharded) Ttl(key string) (time.Duration, error) {
+	return shardCall(p, key, func(c BaseCache) (time.Duration, error) {
+		return c.Ttl(key)
+	})
+}
+
+func (p *Sharded) Expire(key string, timeout time.Duration) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.Expire(key, timeout)
+	})
+}
+
+func (p *Sharded) Incr(key string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.Incr(key)
+	})
+}
+
+func (p *Sharded) Decr(key string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.Decr(key)
+	})
+}
+
+func (p *Sharded) IncrBy(key string, value int64) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.IncrBy(key, value)
+	})
+}
+
+func (p *Sharded) DecrBy(key string, value int64) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.DecrBy(key, value)
+	})
+}
+
+func (p *Sharded) Exists(keys ...string) (bool, error) {
+	for node, list := range p.group(keys) {
+		ok, err := node.cache.Exists(list...)
+		node.report(err)
+		if err != nil {
+			xerror.LogError(err)
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *Sharded) CompareAndSet(key string, old, value any) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.CompareAndSet(key, old, value)
+	})
+}
+
+func (p *Sharded) GetDel(key string) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.GetDel(key)
+	})
+}
+
+func (p *Sharded) GetEx(key string, timeout time.Duration) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.GetEx(key, timeout)
+	})
+}
+
+func (p *Sharded) Append(key string, value any) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.Append(key, value)
+	})
+}
+
+func (p *Sharded) StrLen(key string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.StrLen(key)
+	})
+}
+
+func (p *Sharded) GetRange(key string, start, end int64) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.GetRange(key, start, end)
+	})
+}
+
+func (p *Sharded) SetRange(key string, offset int64, value string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.SetRange(key, offset, value)
+	})
+}
+
+func (p *Sharded) SetBit(key string, offset int64, value bool) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.SetBit(key, offset, value)
+	})
+}
+
+func (p *Sharded) GetBit(key string, offset int64) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.GetBit(key, offset)
+	})
+}
+
+func (p *Sharded) BitCount(key string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.BitCount(key)
+	})
+}
+
+// RunScript 全部的 key 需要在同一个节点上，没有 key 的脚本按脚本名选择节点
+func (p *Sharded) RunScript(name string, keys []string, args ...any) (any, error) {
+	if len(keys) == 0 {
+		return shardCall(p, name, func(c BaseCache) (any, error) {
+			return c.RunScript(name, keys, args...)
+		})
+	}
+
+	node := p.pick(keys[0])
+	for _, key := range keys[1:] {
+		if p.pick(key) != node {
+			return nil, fmt.Errorf("%w: script %s", ErrCrossShard, name)
+		}
+	}
+
+	v, err := node.cache.RunScript(name, keys, args...)
+	node.report(err)
+	return v, err
+}
+
+func (p *Sharded) HSet(key string, field string, value interface{}) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.HSet(key, field, value)
+	})
+}
+
+func (p *Sharded) HGet(key, field string) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.HGet(key, field)
+	})
+}
+
+func (p *Sharded) HDel(key string, fields ...string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.HDel(key, fields...)
+	})
+}
+
+func (p *Sharded) HKeys(key string) ([]string, error) {
+	return shardCall(p, key, func(c BaseCache) ([]string, error) {
+		return c.HKeys(key)
+	})
+}
+
+func (p *Sharded) HGetAll(key string) (map[string]string, error) {
+	return shardCall(p, key, func(c BaseCache) (map[string]string, error) {
+		return c.HGetAll(key)
+	})
+}
+
+func (p *Sharded) HExists(key string, field string) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.HExists(key, field)
+	})
+}
+
+func (p *Sharded) HIncr(key string, subKey string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.HIncr(key, subKey)
+	})
+}
+
+func (p *Sharded) HIncrBy(key string, field string, increment int64) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.HIncrBy(key, field, increment)
+	})
+}
+
+func (p *Sharded) HDecr(key string, field string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.HDecr(key, field)
+	})
+}
+
+func (p *Sharded) HDecrBy(key string, field string, increment int64) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.HDecrBy(key, field, increment)
+	})
+}
+
+func (p *Sharded) SAdd(key string, members ...string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.SAdd(key, members...)
+	})
+}
+
+func (p *Sharded) SMembers(key string) ([]string, error) {
+	return shardCall(p, key, func(c BaseCache) ([]string, error) {
+		return c.SMembers(key)
+	})
+}
+
+func (p *Sharded) SRem(key string, members ...string) (int64, error) {
+	return shardCall(p, key, func(c BaseCache) (int64, error) {
+		return c.SRem(key, members...)
+	})
+}
+
+func (p *Sharded) SRandMember(key string, count ...int64) ([]string, error) {
+	return shardCall(p, key, func(c BaseCache) ([]string, error) {
+		return c.SRandMember(key, count...)
+	})
+}
+
+func (p *Sharded) SPop(key string) (string, error) {
+	return shardCall(p, key, func(c BaseCache) (string, error) {
+		return c.SPop(key)
+	})
+}
+
+func (p *Sharded) SisMember(key, field string) (bool, error) {
+	return shardCall(p, key, func(c BaseCache) (bool, error) {
+		return c.SisMember(key, field)
+	})
+}
+
+func (p *Sharded) Del(keys ...string) error {
+	for node, list := range p.group(keys) {
+		err := node.cache.Del(list...)
+		node.report(err)
+		if err != nil {
+			xerror.LogError(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// DelPrefix 在全部节点上执行，包括被摘除的节点
+func (p *Sharded) DelPrefix(prefix string) (int64, error) {
+	var total int64
+	for _, node := range p.nodes {
+		cnt, err := node.cache.DelPrefix(prefix)
+		node.report(err)
+		if err != nil {
+			xerror.LogError(err)
+			return total, err
+		}
+		total += cnt
+	}
+	return total, nil
+}
+
+// OnKeyEvent 在全部节点上订阅
+func (p *Sharded) OnKeyEvent(pattern string, handler KeyEventHandler, events ...KeyEvent) error {
+	for _, node := range p.nodes {
+		err := node.cache.OnKeyEvent(pattern, handler, events...)
+		if err != nil {
+			xerror.LogError(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish/Subscribe 按 channel 选择节点，两端需要使用相同的节点配置
+func (p *Sharded) Publish(channel string, message any) error {
+	return shardExec(p, channel, func(c BaseCache) error {
+		return c.Publish(channel, message)
+	})
+}
+
+func (p *Sharded) Subscribe(channel string, handler func(message string)) error {
+	return shardExec(p, channel, func(c BaseCache) error {
+		return c.Subscribe(channel, handler)
+	})
+}
+
+func (p *Sharded) Close() error {
+	var errs []error
+	for _, node := range p.nodes {
+		err := node.cache.Close()
+		if err != nil {
+			xerror.LogError(err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func newMemShard(t *testing.T, n int) *Sharded {
+	c := &ShardConfig{}
+	for i := 0; i < n; i++ {
+		c.Nodes = append(c.Nodes, &Config{Type: "mem"})
+	}
+
+	p, err := newSharded(c)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+
+	return p
+}
+
+func TestShardRouting(t *testing.T) {
+	p := newMemShard(t, 3)
+
+	count := make(map[*shardNode]int)
+	for i := 0; i < 3000; i++ {
+		key := "k" + strconv.Itoa(i)
+		err := p.Set(key, i)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		// 只写入到选中的节点
+		owner := p.pick(key)
+		for _, node := range p.nodes {
+			_, err = node.cache.Get(key)
+			if (node == owner) != (err == nil) {
+				t.Fatalf("key %s on %s, owner:%s, err:%v", key, node.name, owner.name, err)
+			}
+		}
+		count[owner]++
+	}
+
+	// 虚拟节点保证分布大致均匀
+	for _, node := range p.nodes {
+		if count[node] < 600 {
+			t.Errorf("node %s has %d keys", node.name, count[node])
+		}
+	}
+
+	// 相同 tag 的 key 落在同一个节点
+	if p.pick("user:{1}:name") != p.pick("user:{1}:age") || p.pick("user:{1}:name") != p.pick("1") {
+		t.Error("keys with the same tag on different nodes")
+	}
+
+	_, err := p.RunScript("script", []string{"{a}1", "{b}1"})
+	if p.pick("{a}1") != p.pick("{b}1") && !errors.Is(err, ErrCrossShard) {
+		t.Errorf("err:%v", err)
+	}
+}
+
+func TestShardRebalance(t *testing.T) {
+	before := newMemShard(t, 3)
+	after := newMemShard(t, 4)
+
+	// 增加节点时只有迁移到新节点的 key 会改变位置
+	var moved int
+	for i := 0; i < 3000; i++ {
+		key := "k" + strconv.Itoa(i)
+		x, y := before.pick(key), after.pick(key)
+		if x.name == y.name {
+			continue
+		}
+
+		moved++
+		if y.name != after.nodes[3].name {
+			t.Fatalf("key %s moved from %s to %s", key, x.name, y.name)
+		}
+	}
+	if moved == 0 || moved > 1200 {
+		t.Errorf("moved %d keys", moved)
+	}
+}
+
+func TestShardEject(t *testing.T) {
+	p := newMemShard(t, 3)
+
+	owners := make(map[string]*shardNode)
+	for i := 0; i < 1000; i++ {
+		key := "k" + strconv.Itoa(i)
+		owners[key] = p.pick(key)
+	}
+
+	ejected := p.nodes[0]
+
+	// 业务错误不计入失败次数
+	for i := 0; i < 5; i++ {
+		ejected.report(NotFound)
+	}
+	if ejected.failures.Load() != 0 {
+		t.Fatalf("failures:%d", ejected.failures.Load())
+	}
+
+	for i := 0; i < 3; i++ {
+		ejected.report(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	}
+
+	// 摘除后只有该节点上的 key 迁移到其他节点
+	for key, owner := range owners {
+		node := p.pick(key)
+		if owner == ejected {
+			if node == ejected {
+				t.Fatalf("key %s still on ejected node", key)
+			}
+			continue
+		}
+
+		if node != owner {
+			t.Fatalf("key %s moved from %s to %s", key, owner.name, node.name)
+		}
+	}
+
+	// 摘除时间结束后恢复原本的分布
+	ejected.ejectUntil.Store(0)
+	for key, owner := range owners {
+		if p.pick(key) != owner {
+			t.Fatalf("key %s not restored", key)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package cache
+
+import (
+	"errors"
+	"github.com/garyburd/redigo/redis"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/app"
+	"go.etcd.io/bbolt"
+	"math/bits"
+)
+
+// ErrInvalidOffset SetRange、SetBit 等的 offset 为负数或者超过 redis 的限制
+var ErrInvalidOffset = errors.New("offset is out of range")
+
+const (
+	// 与 redis 一致，字符串最大 512M，bit offset 小于 2^32
+	maxStringSize = 512 * 1024 * 1024
+	maxBitOffset  = 1<<32 - 1
+)
+
+func checkRangeOffset(offset int64, value string) error {
+	if offset < 0 || offset+int64(len(value)) > maxStringSize {
+		return ErrInvalidOffset
+	}
+	return nil
+}
+
+func checkBitOffset(offset int64) error {
+	if offset < 0 || offset > maxBitOffset {
+		return ErrInvalidOffset
+	}
+	return nil
+}
+
+// 以下与 redis 的语义保持一致，字符串按字节处理，bit 0 为第一个字节的最高位
+
+// 负数表示从末尾开始，超出范围时截断
+func strRange(s string, start, end int64) string {
+	n := int64(len(s))
+	if start < 0 {
+		start += n
+	}
+	if end < 0 {
+		end += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end >= n {
+		end = n - 1
+	}
+	if start > end || n == 0 {
+		return ""
+	}
+	return s[start : end+1]
+}
+
+// 长度不够时以 \x00 填充
+func strSetRange(s string, offset int64, value string) string {
+	if len(value) == 0 {
+		return s
+	}
+
+	b := []byte(s)
+	if end := int(offset) + len(value); end > len(b) {
+		b = append(b, make([]byte, end-len(b))...)
+	}
+	copy(b[offset:], value)
+	return string(b)
+}
+
+func strGetBit(s string, offset int64) bool {
+	i := offset / 8
+	if i >= int64(len(s)) {
+		return false
+	}
+	return s[i]&(0x80>>(offset%8)) != 0
+}
+
+func strSetBit(s string, offset int64, value bool) string {
+	b := []byte(s)
+	i := int(offset / 8)
+	if i >= len(b) {
+		b = append(b, make([]byte, i+1-len(b))...)
+	}
+
+	mask := byte(0x80 >> (offset % 8))
+	if value {
+		b[i] |= mask
+	} else {
+		b[i] &^= mask
+	}
+	return string(b)
+}
+
+func strBitCount(s string) int64 {
+	var cnt int
+	for i := 0; i < len(s); i++ {
+		cnt += bits.OnesCount8(s[i])
+	}
+	return int64(cnt)
+}
+
+// 在锁内修改 key 的值，不存在时从空字符串开始，保留原有的过期时间
+func (p *Mem) updateString(key string, logic func(s string) (string, error)) error {
+	p.autoClear()
+
+	p.Lock()
+	item, ok := p.getItem(key)
+	if !ok {
+		item = &Item{}
+	}
+
+	data, err := logic(item.Data)
+	if err != nil {
+		p.Unlock()
+		return err
+	}
+
+	p.data[key] = &Item{
+		Data:     data,
+		ExpireAt: item.ExpireAt,
+	}
+	p.Unlock()
+
+	p.subs.notify(key, KeyEventSet)
+
+	return nil
+}
+
+// 不存在时视为空字符串
+func (p *Mem) getString(key string) string {
+	p.autoClear()
+
+	p.RLock()
+	defer p.RUnlock()
+
+	item, ok := p.getItem(key)
+	if !ok {
+		return ""
+	}
+	return item.Data
+}
+
+func (p *Mem) Append(key string, value any) (int64, error) {
+	var n int64
+	err := p.updateString(key, func(s string) (string, error) {
+		s += anyx.ToString(value)
+		n = int64(len(s))
+		return s, nil
+	})
+	return n, err
+}
+
+func (p *Mem) StrLen(key string) (int64, error) {
+	return int64(len(p.getString(key))), nil
+}
+
+func (p *Mem) GetRange(key string, start, end int64) (string, error) {
+	return strRange(p.getString(key), start, end), nil
+}
+
+func (p *Mem) SetRange(key string, offset int64, value string) (int64, error) {
+	if err := checkRangeOffset(offset, value); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := p.updateString(key, func(s string) (string, error) {
+		s = strSetRange(s, offset, value)
+		n = int64(len(s))
+		return s, nil
+	})
+	return n, err
+}
+
+func (p *Mem) SetBit(key string, offset int64, value bool) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+
+	var old bool
+	err := p.updateString(key, func(s string) (string, error) {
+		old = strGetBit(s, offset)
+		return strSetBit(s, offset, value), nil
+	})
+	return old, err
+}
+
+func (p *Mem) GetBit(key string, offset int64) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+	return strGetBit(p.getString(key), offset), nil
+}
+
+func (p *Mem) BitCount(key string) (int64, error) {
+	return strBitCount(p.getString(key)), nil
+}
+
+func (p *Bbolt) updateString(key string, logic func(s string) (string, error)) error {
+	return p.conn.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bboltBucket)
+
+		item, err := p.getItem(b, key)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			item = &Item{}
+		}
+
+		item.Data, err = logic(item.Data)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(key), item.Bytes())
+	})
+}
+
+func (p *Bbolt) getString(key string) (string, error) {
+	var s string
+	err := p.conn.View(func(tx *bbolt.Tx) error {
+		item, err := p.getItem(tx.Bucket(bboltBucket), key)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			s = item.Data
+		}
+		return nil
+	})
+	return s, err
+}
+
+func (p *Bbolt) Append(key string, value any) (int64, error) {
+	var n int64
+	err := p.updateString(key, func(s string) (string, error) {
+		s += anyx.ToString(value)
+		n = int64(len(s))
+		return s, nil
+	})
+	return n, err
+}
+
+func (p *Bbolt) StrLen(key string) (int64, error) {
+	s, err := p.getString(key)
+	return int64(len(s)), err
+}
+
+func (p *Bbolt) GetRange(key string, start, end int64) (string, error) {
+	s, err := p.getString(key)
+	return strRange(s, start, end), err
+}
+
+func (p *Bbolt) SetRange(key string, offset int64, value string) (int64, error) {
+	if err := checkRangeOffset(offset, value); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err := p.updateString(key, func(s string) (string, error) {
+		s = strSetRange(s, offset, value)
+		n = int64(len(s))
+		return s, nil
+	})
+	return n, err
+}
+
+func (p *Bbolt) SetBit(key string, offset int64, value bool) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+
+	var old bool
+	err := p.updateString(key, func(s string) (string, error) {
+		old = strGetBit(s, offset)
+		return strSetBit(s, offset, value), nil
+	})
+	return old, err
+}
+
+func (p *Bbolt) GetBit(key string, offset int64) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+
+	s, err := p.getString(key)
+	return strGetBit(s, offset), err
+}
+
+func (p *Bbolt) BitCount(key string) (int64, error) {
+	s, err := p.getString(key)
+	return strBitCount(s), err
+}
+
+func (p *Redis) do(cmd string, key string, args ...any) (any, error) {
+	conn := p.cli.GetConnection()
+	defer conn.Close()
+
+	reply, err := conn.Do(cmd, append([]any{app.Name + ":" + key}, args...)...)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+func (p *Redis) Append(key string, value any) (int64, error) {
+	defer p.local.Load().del(app.Name + ":" + key)
+	return redis.Int64(p.do("APPEND", key, anyx.ToString(value)))
+}
+
+func (p *Redis) StrLen(key string) (int64, error) {
+	return redis.Int64(p.do("STRLEN", key))
+}
+
+func (p *Redis) GetRange(key string, start, end int64) (string, error) {
+	return redis.String(p.do("GETRANGE", key, start, end))
+}
+
+func (p *Redis) SetRange(key string, offset int64, value string) (int64, error) {
+	if err := checkRangeOffset(offset, value); err != nil {
+		return 0, err
+	}
+
+	defer p.local.Load().del(app.Name + ":" + key)
+	return redis.Int64(p.do("SETRANGE", key, offset, value))
+}
+
+func (p *Redis) SetBit(key string, offset int64, value bool) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+
+	var bit int
+	if value {
+		bit = 1
+	}
+
+	defer p.local.Load().del(app.Name + ":" + key)
+	return redis.Bool(p.do("SETBIT", key, offset, bit))
+}
+
+func (p *Redis) GetBit(key string, offset int64) (bool, error) {
+	if err := checkBitOffset(offset); err != nil {
+		return false, err
+	}
+	return redis.Bool(p.do("GETBIT", key, offset))
+}
+
+func (p *Redis) BitCount(key string) (int64, error) {
+	return redis.Int64(p.do("BITCOUNT", key))
+}
+
+func (p *namespaceCache) Append(key string, value any) (int64, error) {
+	return p.base.Append(p.key(key), value)
+}
+
+func (p *namespaceCache) StrLen(key string) (int64, error) {
+	return p.base.StrLen(p.key(key))
+}
+
+func (p *namespaceCache) GetRange(key string, start, end int64) (string, error) {
+	return p.base.GetRange(p.key(key), start, end)
+}
+
+func (p *namespaceCache) SetRange(key string, offset int64, value string) (int64, error) {
+	return p.base.SetRange(p.key(key), offset, value)
+}
+
+func (p *namespaceCache) SetBit(key string, offset int64, value bool) (bool, error) {
+	return p.base.SetBit(p.key(key), offset, value)
+}
+
+func (p *namespaceCache) GetBit(key string, offset int64) (bool, error) {
+	return p.base.GetBit(p.key(key), offset)
+}
+
+func (p *namespaceCache) BitCount(key string) (int64, error) {
+	return p.base.BitCount(p.key(key))
+}
@@ -0,0 +1,49 @@
+package cache_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"path/filepath"
+	"testing"
+)
+
+func TestStringOffset(t *testing.T) {
+	bolt, err := cache.NewBbolt(filepath.Join(t.TempDir(), "cache.db"), nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	defer bolt.Close()
+
+	for name, c := range map[string]cache.Cache{"mem": cache.NewMem(), "bbolt": bolt} {
+		n, err := c.SetRange("k", 2, "ab")
+		if err != nil || n != 4 {
+			t.Errorf("%s: n:%d, err:%v", name, n, err)
+		}
+
+		_, err = c.SetBit("k", 7, true)
+		if err != nil {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		// 与 redis 一致的上限，避免分配超大的内存
+		_, err = c.SetBit("k", 1<<40, true)
+		if !errors.Is(err, cache.ErrInvalidOffset) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		_, err = c.SetRange("k", 512*1024*1024, "a")
+		if !errors.Is(err, cache.ErrInvalidOffset) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		_, err = c.SetBit("k", -1, true)
+		if !errors.Is(err, cache.ErrInvalidOffset) {
+			t.Errorf("%s: err:%v", name, err)
+		}
+
+		s, err := c.Get("k")
+		if err != nil || s != "\x01\x00ab" {
+			t.Errorf("%s: value:%q, err:%v", name, s, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/routine"
+	"golang.org/x/sync/errgroup"
+	"sync"
+	"time"
+)
+
+// WarmLoader 写入 pattern 对应的数据，返回写入的 key 数量
+type WarmLoader func(ctx context.Context, c Cache) (int, error)
+
+type WarmProgress struct {
+	Pattern string
+
+	// 写入的 key 数量
+	Keys     int
+	Duration time.Duration
+	Err      error
+
+	// 本轮已完成与总的 loader 数量
+	Done, Total int
+}
+
+type WarmConfig struct {
+	// 同时执行的 loader 数量，默认 4
+	Concurrency int
+
+	// 定时重新预热的间隔，为 0 时只在 Start 时执行一次
+	Interval time.Duration
+
+	// 单个 loader 的超时时间，默认 1 分钟
+	Timeout time.Duration
+
+	// 每个 loader 完成后回调，串行调用
+	OnProgress func(p *WarmProgress)
+}
+
+func (c *WarmConfig) apply() {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = time.Minute
+	}
+}
+
+type warmEntry struct {
+	pattern string
+	loader  WarmLoader
+}
+
+// Warmer 在启动时以及定时执行注册的 loader，避免发布后缓存全部失效导致的延迟毛刺
+type Warmer struct {
+	cache Cache
+	c     *WarmConfig
+
+	lock    sync.Mutex
+	entries []*warmEntry
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewWarmer(cache Cache, configs ...*WarmConfig) *Warmer {
+	c := &WarmConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	return &Warmer{
+		cache: cache,
+		c:     c,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Register 注册一个 loader，pattern 用于进度上报与日志，例如 config:*、product:top100
+func (p *Warmer) Register(pattern string, loader WarmLoader) *Warmer {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.entries = append(p.entries, &warmEntry{
+		pattern: pattern,
+		loader:  loader,
+	})
+
+	return p
+}
+
+// Run 执行一轮预热，单个 loader 失败不影响其他 loader，返回所有失败的进度
+func (p *Warmer) Run(ctx context.Context) []*WarmProgress {
+	p.lock.Lock()
+	entries := append([]*warmEntry(nil), p.entries...)
+	p.lock.Unlock()
+
+	var (
+		lock   sync.Mutex
+		done   int
+		failed []*WarmProgress
+	)
+
+	g := &errgroup.Group{}
+	g.SetLimit(p.c.Concurrency)
+	for _, entry := range entries {
+		entry := entry
+		g.Go(func() error {
+			start := time.Now()
+
+			lctx, cancel := context.WithTimeout(ctx, p.c.Timeout)
+			defer cancel()
+
+			progress := &WarmProgress{
+				Pattern: entry.pattern,
+				Total:   len(entries),
+			}
+
+			func() {
+				// loader panic 时只影响自己
+				defer func() {
+					if r := recover(); r != nil {
+						progress.Err = fmt.Errorf("panic: %v", r)
+					}
+				}()
+
+				progress.Keys, progress.Err = entry.loader(lctx, p.cache)
+			}()
+			progress.Duration = time.Since(start)
+
+			lock.Lock()
+			done++
+			progress.Done = done
+			if progress.Err != nil {
+				failed = append(failed, progress)
+			}
+
+			// 持锁回调，保证 OnProgress 串行且按 Done 顺序
+			if progress.Err != nil {
+				log.Errorf("warm %s failed, err:%v", entry.pattern, progress.Err)
+			} else {
+				log.Infof("warm %s, keys:%d, cost:%s", entry.pattern, progress.Keys, progress.Duration)
+			}
+
+			if p.c.OnProgress != nil {
+				p.c.OnProgress(progress)
+			}
+			lock.Unlock()
+
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return failed
+}
+
+// Start 同步执行一轮预热，配置了 Interval 时在后台定时执行，返回第一轮失败的进度
+func (p *Warmer) Start() []*WarmProgress {
+	failed := p.Run(context.Background())
+
+	if p.c.Interval > 0 {
+		p.wg.Add(1)
+		routine.GoWithRecover(func() error {
+			defer p.wg.Done()
+
+			ticker := time.NewTicker(p.c.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-p.stop:
+					return nil
+				case <-ticker.C:
+					p.Run(context.Background())
+				}
+			}
+		})
+	}
+
+	return failed
+}
+
+// Stop 停止定时预热，等待正在执行的一轮完成后返回
+func (p *Warmer) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
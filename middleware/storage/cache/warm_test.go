@@ -0,0 +1,119 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmer(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	loader := func(ctx context.Context, c cache.Cache) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * 20)
+		return 1, c.Set("k", "v")
+	}
+
+	// OnProgress 串行调用，无需加锁
+	var progress []*cache.WarmProgress
+
+	mem := cache.NewMem()
+	w := cache.NewWarmer(mem, &cache.WarmConfig{
+		Concurrency: 2,
+		Timeout:     time.Millisecond * 50,
+		OnProgress: func(p *cache.WarmProgress) {
+			progress = append(progress, p)
+		},
+	})
+	for i := 0; i < 5; i++ {
+		w.Register("ok", loader)
+	}
+
+	// 超时后 loader 通过 ctx 感知并返回
+	w.Register("slow", func(ctx context.Context, c cache.Cache) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	w.Register("panic", func(ctx context.Context, c cache.Cache) (int, error) {
+		panic("boom")
+	})
+
+	failed := w.Run(context.Background())
+
+	if maxRunning.Load() != 2 {
+		t.Errorf("max running:%d", maxRunning.Load())
+	}
+
+	if len(failed) != 2 {
+		t.Fatalf("failed:%d", len(failed))
+	}
+	for _, p := range failed {
+		switch p.Pattern {
+		case "slow":
+			if !errors.Is(p.Err, context.DeadlineExceeded) {
+				t.Errorf("err:%v", p.Err)
+			}
+		case "panic":
+			if p.Err == nil {
+				t.Error("panic should be reported")
+			}
+		default:
+			t.Errorf("pattern:%s", p.Pattern)
+		}
+	}
+
+	// 每个 loader 回调一次，Done 依次递增
+	if len(progress) != 7 {
+		t.Fatalf("progress:%d", len(progress))
+	}
+	for i, p := range progress {
+		if p.Done != i+1 || p.Total != 7 {
+			t.Errorf("done:%d, total:%d", p.Done, p.Total)
+		}
+		if p.Pattern == "ok" && (p.Keys != 1 || p.Err != nil) {
+			t.Errorf("keys:%d, err:%v", p.Keys, p.Err)
+		}
+	}
+}
+
+func TestWarmerInterval(t *testing.T) {
+	var runs atomic.Int32
+	w := cache.NewWarmer(cache.NewMem(), &cache.WarmConfig{
+		Interval: time.Millisecond * 10,
+	})
+	w.Register("count", func(ctx context.Context, c cache.Cache) (int, error) {
+		runs.Add(1)
+		return 0, nil
+	})
+
+	if failed := w.Start(); len(failed) != 0 {
+		t.Fatalf("failed:%d", len(failed))
+	}
+
+	for i := 0; i < 100 && runs.Load() < 3; i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if runs.Load() < 3 {
+		t.Fatalf("runs:%d", runs.Load())
+	}
+
+	// Stop 返回后不再执行
+	w.Stop()
+	stopped := runs.Load()
+	time.Sleep(time.Millisecond * 50)
+	if runs.Load() != stopped {
+		t.Errorf("runs after stop:%d", runs.Load()-stopped)
+	}
+	w.Stop()
+}
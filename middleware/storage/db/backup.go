@@ -0,0 +1,221 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/routine"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrBackupNotSupport = errors.New("backup only support sqlite")
+
+const backupPrefix = "backup-"
+
+// 绕过 gorm 的预编译缓存，VACUUM 不能在有未结束的语句时执行
+func (p *Client) exec(query string, args ...any) error {
+	conn, err := p.db.DB()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	_, err = conn.Exec(query, args...)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Backup 在线备份 sqlite 到 destPath，基于 VACUUM INTO，备份期间不阻塞读
+// 先写入临时文件再重命名，destPath 已存在时会被覆盖
+func (p *Client) Backup(destPath string) error {
+	if p.clientType != "sqlite" {
+		return ErrBackupNotSupport
+	}
+
+	err := os.MkdirAll(filepath.Dir(destPath), 0o755)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	tmp := destPath + ".tmp"
+	_ = os.Remove(tmp)
+
+	err = p.exec("VACUUM INTO ?", tmp)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, destPath)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// Vacuum 重建数据库文件，回收空间
+func (p *Client) Vacuum() error {
+	if p.clientType != "sqlite" {
+		return ErrBackupNotSupport
+	}
+
+	err := p.exec("VACUUM")
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+// Checkpoint 将 WAL 中的内容写回数据库文件并截断 WAL，非 WAL 模式下无副作用
+func (p *Client) Checkpoint() error {
+	if p.clientType != "sqlite" {
+		return ErrBackupNotSupport
+	}
+
+	err := p.exec("PRAGMA wal_checkpoint(TRUNCATE)")
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
+
+type BackupConfig struct {
+	// 备份文件存放的目录，必填
+	Dir string
+
+	// 备份间隔，默认 1h
+	Interval time.Duration
+
+	// 保留的备份数量，默认 7，小于 0 时不清理
+	Keep int
+
+	// 备份失败时回调
+	OnError func(err error)
+}
+
+func (c *BackupConfig) apply() {
+	if c.Interval == 0 {
+		c.Interval = time.Hour
+	}
+
+	if c.Keep == 0 {
+		c.Keep = 7
+	}
+}
+
+type BackupScheduler struct {
+	cli *Client
+	c   *BackupConfig
+
+	stop chan struct{}
+	once sync.Once
+}
+
+func (p *BackupScheduler) backup() error {
+	path := filepath.Join(p.c.Dir, fmt.Sprintf("%s%s.db", backupPrefix, time.Now().Format("20060102150405")))
+
+	err := p.cli.Backup(path)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	log.Infof("sqlite backup to %s", path)
+
+	return p.prune()
+}
+
+func (p *BackupScheduler) prune() error {
+	if p.c.Keep < 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(p.c.Dir, backupPrefix+"*.db"))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	if len(files) <= p.c.Keep {
+		return nil
+	}
+
+	// 文件名中的时间可以直接按字典序排序
+	sort.Strings(files)
+	for _, file := range files[:len(files)-p.c.Keep] {
+		if !strings.HasPrefix(filepath.Base(file), backupPrefix) {
+			continue
+		}
+
+		err = os.Remove(file)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop 停止定时备份，不会中断正在进行的备份
+func (p *BackupScheduler) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+}
+
+// StartBackup 定时备份 sqlite 到 BackupConfig.Dir，并清理旧的备份
+func (p *Client) StartBackup(c *BackupConfig) (*BackupScheduler, error) {
+	if p.clientType != "sqlite" {
+		return nil, ErrBackupNotSupport
+	}
+
+	if c.Dir == "" {
+		return nil, errors.New("backup dir required")
+	}
+
+	c.apply()
+
+	s := &BackupScheduler{
+		cli:  p,
+		c:    c,
+		stop: make(chan struct{}),
+	}
+
+	routine.GoWithRecover(func() error {
+		ticker := time.NewTicker(c.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return nil
+			case <-ticker.C:
+				err := s.backup()
+				if err != nil && c.OnError != nil {
+					c.OnError(err)
+				}
+			}
+		}
+	})
+
+	return s, nil
+}
@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	cli, err := db.New(&db.Config{
+		Address: dir,
+		Name:    "src",
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.NewScoop().Create(&scanUser{Name: "a"}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.Backup(filepath.Join(dir, "backup", "dst.db"))
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.Checkpoint()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.Vacuum()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	backup, err := db.New(&db.Config{
+		Address: filepath.Join(dir, "backup"),
+		Name:    "dst",
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var users []*scanUser
+	err = backup.NewScoop().Model(&scanUser{}).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(users) != 1 || users[0].Name != "a" {
+		t.Fatalf("unexpected result: %+v", users)
+	}
+}
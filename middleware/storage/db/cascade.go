@@ -0,0 +1,222 @@
+package db
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cascade 描述父表软删除时需要一起软删除的子表，子表需要有 deleted_at
+type Cascade struct {
+	// 子表的模型，子表自身的级联也会生效
+	Model any
+
+	// 子表中引用父表的列，例如 post_id
+	ForeignKey string
+
+	// 父表中被引用的列，默认 id
+	References string
+}
+
+// Cascader 模型实现后，通过 Scoop.Cascade 删除时会级联软删除子表
+type Cascader interface {
+	Cascades() []*Cascade
+}
+
+var cascades sync.Map
+
+// RegisterCascade 用于无法修改的模型，优先于 Cascader
+func RegisterCascade(model any, list ...*Cascade) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	cascades.Store(rt, list)
+}
+
+func getCascades(rt reflect.Type) []*Cascade {
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice {
+		rt = rt.Elem()
+	}
+
+	if v, ok := cascades.Load(rt); ok {
+		return v.([]*Cascade)
+	}
+
+	if x, ok := reflect.New(rt).Interface().(Cascader); ok {
+		return x.Cascades()
+	}
+
+	return nil
+}
+
+// Cascade 软删除时按模型声明的关系一起软删除子表，不在事务中时会自动开启事务
+// 只对通过 Model 指定了模型的软删除生效，硬删除请使用数据库的外键
+func (p *Scoop) Cascade(b ...bool) *Scoop {
+	if len(b) == 0 {
+		p.cascade = true
+		return p
+	}
+	p.cascade = b[0]
+	return p
+}
+
+type cascadeStep struct {
+	table string
+	conds []string
+}
+
+// 从深到浅生成子表的条件，子表的条件以子查询引用父表的条件，所以需要在父表之前执行
+func (p *Scoop) cascadeSteps(rt reflect.Type, table string, conds []string, visited map[string]bool) ([]*cascadeStep, error) {
+	var steps []*cascadeStep
+	for _, c := range getCascades(rt) {
+		childType := reflect.TypeOf(c.Model)
+		for childType.Kind() == reflect.Ptr {
+			childType = childType.Elem()
+		}
+
+		child := getTableName(childType)
+		if visited[child] {
+			continue
+		}
+
+		if !hasDeleted(childType) {
+			return nil, fmt.Errorf("cascade table %s has no deleted_at", child)
+		}
+
+		fk, err := quoteIdentifier(c.ForeignKey, p.quote(), false)
+		if err != nil {
+			return nil, err
+		}
+
+		references := c.References
+		if references == "" {
+			references = "id"
+		}
+		ref, err := quoteIdentifier(references, p.quote(), false)
+		if err != nil {
+			return nil, err
+		}
+
+		sub := "SELECT " + ref + " FROM " + table
+		if len(conds) > 0 {
+			sub += " WHERE " + conds[0]
+			for _, cond := range conds[1:] {
+				sub += " AND " + cond
+			}
+		}
+
+		childConds := []string{"deleted_at = 0", fk + " IN (" + sub + ")"}
+
+		visited[child] = true
+		children, err := p.cascadeSteps(childType, child, childConds, visited)
+		if err != nil {
+			return nil, err
+		}
+		delete(visited, child)
+
+		steps = append(steps, children...)
+		steps = append(steps, &cascadeStep{
+			table: child,
+			conds: childConds,
+		})
+	}
+
+	return steps, nil
+}
+
+func (p *Scoop) cascadeDelete(now int64) *DeleteResult {
+	rt := p.model
+	if rt == nil {
+		return p.execDelete(now)
+	}
+
+	steps, err := p.cascadeSteps(rt, p.table, p.cond.conds, map[string]bool{p.table: true})
+	if err != nil {
+		return &DeleteResult{
+			Error: err,
+		}
+	}
+	if len(steps) == 0 {
+		return p.execDelete(now)
+	}
+
+	// 已经在事务中时直接执行，否则开启一个新的事务
+	db := p._db
+	_, inTx := db.Statement.ConnPool.(gorm.TxCommitter)
+	if !inTx {
+		p._db = db.Begin()
+		if p._db.Error != nil {
+			err = p._db.Error
+			p._db = db
+			return &DeleteResult{
+				Error: wrapTransient(err),
+			}
+		}
+		defer func() {
+			p._db = db
+		}()
+	}
+
+	rollback := func(err error) *DeleteResult {
+		if !inTx {
+			p._db.Rollback()
+		}
+		return &DeleteResult{
+			Error: wrapTransient(err),
+		}
+	}
+
+	cascaded := make(map[string]int64, len(steps))
+	for _, step := range steps {
+		b := log.GetBuffer()
+		b.WriteString("UPDATE ")
+		b.WriteString(step.table)
+		b.WriteString(" SET deleted_at = ")
+		b.WriteString(strconv.FormatInt(now, 10))
+		b.WriteString(" WHERE ")
+		b.WriteString(step.conds[0])
+		for _, c := range step.conds[1:] {
+			b.WriteString(" AND ")
+			b.WriteString(c)
+		}
+		p.writeComment(b)
+		sqlRaw := b.String()
+		log.PutBuffer(b)
+
+		start := time.Now()
+		res := p._db.Exec(sqlRaw)
+		p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+			return sqlRaw, res.RowsAffected
+		}, res.Error)
+		if res.Error != nil {
+			return rollback(res.Error)
+		}
+
+		cascaded[step.table] += res.RowsAffected
+	}
+
+	p.inc()
+	res := p.execDelete(now)
+	p.dec()
+	if res.Error != nil {
+		return rollback(res.Error)
+	}
+
+	if !inTx {
+		err = p._db.Commit().Error
+		if err != nil {
+			return &DeleteResult{
+				Error: wrapTransient(err),
+			}
+		}
+	}
+
+	res.Cascaded = cascaded
+	return res
+}
@@ -0,0 +1,87 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type cascadePost struct {
+	Id        int64 `gorm:"primaryKey"`
+	Title     string
+	DeletedAt int64
+}
+
+func (cascadePost) TableName() string {
+	return "cascade_post"
+}
+
+func (cascadePost) Cascades() []*db.Cascade {
+	return []*db.Cascade{
+		{Model: &cascadeComment{}, ForeignKey: "post_id"},
+	}
+}
+
+type cascadeComment struct {
+	Id        int64 `gorm:"primaryKey"`
+	PostId    int64
+	DeletedAt int64
+}
+
+func (cascadeComment) TableName() string {
+	return "cascade_comment"
+}
+
+func (cascadeComment) Cascades() []*db.Cascade {
+	return []*db.Cascade{
+		{Model: &cascadeLike{}, ForeignKey: "comment_id"},
+	}
+}
+
+type cascadeLike struct {
+	Id        int64 `gorm:"primaryKey"`
+	CommentId int64
+	DeletedAt int64
+}
+
+func (cascadeLike) TableName() string {
+	return "cascade_like"
+}
+
+func TestCascadeDelete(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "cascade",
+	}, &cascadePost{}, &cascadeComment{}, &cascadeLike{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for _, v := range []any{
+		&cascadePost{Id: 1}, &cascadePost{Id: 2},
+		&cascadeComment{Id: 1, PostId: 1}, &cascadeComment{Id: 2, PostId: 1}, &cascadeComment{Id: 3, PostId: 2},
+		&cascadeLike{Id: 1, CommentId: 1}, &cascadeLike{Id: 2, CommentId: 3},
+	} {
+		err = cli.NewScoop().Create(v).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	res := db.NewModelScoop[cascadePost](cli.Database()).Equal("id", 1).Cascade().Delete()
+	if res.Error != nil {
+		t.Fatalf("err:%v", res.Error)
+	}
+	if res.RowsAffected != 1 || res.Cascaded["cascade_comment"] != 2 || res.Cascaded["cascade_like"] != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	comments, err := db.NewModelScoop[cascadeComment](cli.Database()).Count()
+	if err != nil || comments != 1 {
+		t.Errorf("comments:%d, err:%v", comments, err)
+	}
+
+	likes, err := db.NewModelScoop[cascadeLike](cli.Database()).Count()
+	if err != nil || likes != 1 {
+		t.Errorf("likes:%d, err:%v", likes, err)
+	}
+}
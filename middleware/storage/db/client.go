@@ -1,10 +1,12 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 	"github.com/lazygophers/log"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
@@ -13,11 +15,45 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
 type Client struct {
 	db *gorm.DB
+
+	clientType string
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	slowTxThreshold time.Duration
+	onSlowTx        func(stats *TxStats)
+
+	maxUnboundedRows int64
+	rejectUnbounded  bool
+	maxRows          uint64
+	tableStatsTTL    time.Duration
+
+	lockDiagnosticsInterval time.Duration
+	// 上一次采集锁信息的时间
+	lockDiagnosedAt atomic.Int64
+	// 表名 -> *tableRows
+	tableRows sync.Map
+
+	// 配置了 Credentials 时不为空
+	credentials *credentialConnector
+	stop        chan struct{}
+}
+
+// 通过 gorm.Dialector 找到对应的 Client，Session 会复制 Config，但所有 session 共用一个 Dialector
+var clientByDialector sync.Map
+
+func getClientByDB(db *gorm.DB) *Client {
+	if v, ok := clientByDialector.Load(db.Dialector); ok {
+		return v.(*Client)
+	}
+	return nil
 }
 
 func New(c *Config, tables ...interface{}) (*Client, error) {
@@ -25,8 +61,36 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 
 	c.apply()
 
+	p.clientType = c.Type
+	p.maxRetries = c.MaxRetries
+	p.retryBackoff = c.RetryBackoff
+	p.slowTxThreshold = c.SlowTxThreshold
+	p.onSlowTx = c.OnSlowTx
+	p.maxUnboundedRows = c.MaxUnboundedRows
+	p.rejectUnbounded = c.RejectUnbounded
+	p.maxRows = c.MaxRows
+	p.tableStatsTTL = c.TableStatsTTL
+
+	// 每个 Client 使用单独的日志，SetLogLevel 只修改级别，不替换日志
 	if c.Logger == nil {
-		c.Logger = getDefaultLogger()
+		if c.LogLevel != "" {
+			c.Logger = NewLogger().LogMode(parseLogLevel(c.LogLevel))
+		} else {
+			c.Logger = NewLogger()
+		}
+	}
+
+	// 通过 connector 建立连接，账号密码轮换时不需要重新创建 Client，新建的连接会设置会话变量
+	var pool gorm.ConnPool
+	if c.Credentials != nil || len(c.Session) > 0 {
+		var err error
+		p.credentials, err = newCredentialConnector(c)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+		c.Username = p.credentials.credentials.Username
+		pool = sql.OpenDB(p.credentials)
 	}
 
 	var d gorm.Dialector
@@ -39,7 +103,7 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 		d = mysql.New(mysql.Config{
 			DriverName:    "",
 			ServerVersion: "",
-			DSN:           fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", c.Username, c.Password, c.Address, c.Port, c.Name),
+			DSN:           c.dsn(c.Username, c.Password),
 			DSNConfig: &mysqlC.Config{
 				Timeout:                 time.Second * 5,
 				ReadTimeout:             time.Second * 30,
@@ -55,11 +119,11 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 				MultiStatements:         true,
 				ParseTime:               true,
 			},
-			Conn:                          nil,
+			Conn:                          pool,
 			SkipInitializeWithVersion:     true,
 			DefaultStringSize:             500,
 			DefaultDatetimePrecision:      nil,
-			DisableWithReturning:          false,
+			DisableWithReturning:          !GetDialect(c.Type).SupportReturning,
 			DisableDatetimePrecision:      false,
 			DontSupportRenameIndex:        false,
 			DontSupportRenameColumn:       false,
@@ -70,18 +134,21 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 
 		_ = mysqlC.SetLogger(&mysqlLogger{})
 
-	case "postgres":
-		log.Infof("postgres://%s:******@%s:%d/%s", c.Username, c.Address, c.Port, c.Name)
+	case "postgres", "gaussdb":
+		log.Infof("%s://%s:******@%s:%d/%s", c.Type, c.Username, c.Address, c.Port, c.Name)
 		d = postgres.New(postgres.Config{
-			DSN:                  fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", c.Address, c.Port, c.Username, c.Password, c.Name),
+			DSN:                  c.dsn(c.Username, c.Password),
 			PreferSimpleProtocol: true,
-			WithoutReturning:     false,
-			Conn:                 nil,
+			WithoutReturning:     !GetDialect(c.Type).SupportReturning,
+			Conn:                 pool,
 		})
 
 	case "sqlserver":
 		log.Infof("sqlserver://%s:******@%s:%d/%s", c.Username, c.Address, c.Port, c.Name)
-		d = sqlserver.Open(fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s", c.Username, c.Password, c.Address, c.Port, c.Name))
+		d = sqlserver.New(sqlserver.Config{
+			DSN:  c.dsn(c.Username, c.Password),
+			Conn: pool,
+		})
 
 	default:
 		return nil, errors.New("unknown database")
@@ -120,6 +187,52 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 		return nil, err
 	}
 
+	clientByDialector.Store(p.db.Dialector, p)
+
+	if c.IdGenerator != nil {
+		err = p.db.Callback().Create().Before("gorm:create").Register("lrpc:fill_id", fillId(c.IdGenerator))
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+	}
+
+	if c.NPlusOneThreshold > 0 {
+		detect := detectNPlusOne(c.NPlusOneThreshold, c.OnNPlusOne)
+
+		err = p.db.Callback().Query().After("gorm:query").Register("lrpc:n_plus_one", detect)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		err = p.db.Callback().Row().After("gorm:row").Register("lrpc:n_plus_one", detect)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+	}
+
+	if c.LockDiagnostics {
+		p.lockDiagnosticsInterval = c.LockDiagnosticsInterval
+
+		cb := p.db.Callback()
+		for _, register := range []func(name string, fn func(*gorm.DB)) error{
+			cb.Query().After("gorm:query").Register,
+			cb.Row().After("gorm:row").Register,
+			cb.Raw().After("gorm:raw").Register,
+			cb.Create().After("gorm:create").Register,
+			cb.Update().After("gorm:update").Register,
+			cb.Delete().After("gorm:delete").Register,
+		} {
+			err = register("lrpc:lock_diagnostics", captureLockError)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+		}
+	}
+
 	if c.Debug {
 		p.db = p.db.Debug()
 	}
@@ -154,9 +267,32 @@ func New(c *Config, tables ...interface{}) (*Client, error) {
 		return nil, err
 	}
 
+	if c.Credentials != nil {
+		p.stop = make(chan struct{})
+		go p.refreshCredentials(c.CredentialRefresh)
+	}
+
 	return p, nil
 }
 
+// Close 停止账号密码的轮换并关闭连接池
+func (p *Client) Close() error {
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+
+	clientByDialector.Delete(p.db.Dialector)
+
+	conn, err := p.db.DB()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return conn.Close()
+}
+
 func (p *Client) AutoMigrate(dst ...interface{}) error {
 	for _, table := range dst {
 		if x, ok := table.(Tabler); ok {
@@ -179,6 +315,12 @@ func (p *Client) AutoMigrate(dst ...interface{}) error {
 			}
 			return err
 		}
+
+		err = p.syncTableComment(table)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
 	}
 
 	return nil
@@ -191,6 +333,22 @@ func (p *Client) Database() *gorm.DB {
 	})
 }
 
+// SetLogLevel 修改当前 Client 的日志级别，不影响其他 Client，可以在运行时并发调用
+// 只支持 Logger，通过 Config.Logger 设置的其他日志需要自行修改
+func (p *Client) SetLogLevel(level logger.LogLevel) {
+	l, ok := p.db.Logger.(*Logger)
+	if !ok {
+		log.Warnf("set log level not support logger %T", p.db.Logger)
+		return
+	}
+
+	l.LogMode(level)
+}
+
+func (p *Client) ClientType() string {
+	return p.clientType
+}
+
 func (p *Client) NewScoop() *Scoop {
 	return NewScoop(p.db)
 }
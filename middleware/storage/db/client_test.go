@@ -0,0 +1,28 @@
+package db
+
+import "testing"
+
+func TestClientClose(t *testing.T) {
+	cli, err := New(&Config{
+		Address: t.TempDir(),
+		Name:    "close",
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	gdb := cli.Database()
+	if getClientByDB(gdb) != cli {
+		t.Fatal("client not registered")
+	}
+
+	err = cli.Close()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	// 关闭后不再被全局的映射引用
+	if getClientByDB(gdb) != nil {
+		t.Error("client not removed on close")
+	}
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+)
+
+// TableCommenter 由需要表注释的 model 实现，列注释使用 gorm 的 comment 标签
+//
+//	type User struct {
+//		Id   int64  `gorm:"primaryKey"`
+//		Name string `gorm:"comment:用户名"`
+//	}
+//
+//	func (User) TableComment() string {
+//		return "用户"
+//	}
+type TableCommenter interface {
+	TableComment() string
+}
+
+func getTableComment(model any) (string, bool) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	x, ok := reflect.New(rt).Interface().(TableCommenter)
+	if !ok {
+		return "", false
+	}
+
+	return x.TableComment(), true
+}
+
+// 生成修改表注释的语句，注释直接写入语句中，不支持注释时返回空
+func (p *Client) tableCommentSQL(table, comment string) string {
+	d := p.Dialect()
+	if d.TableComment == "" {
+		return ""
+	}
+
+	return p.explain(fmt.Sprintf(d.TableComment, d.QuoteName(table)), comment)
+}
+
+// 将参数写入语句中，? 会按数据库转换为对应的占位符后再替换
+func (p *Client) explain(sql string, vars ...any) string {
+	return p.db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Exec(sql, vars...)
+	})
+}
+
+func (p *Client) inspectTableComment(table string) (string, error) {
+	var query string
+	switch p.clientType {
+	case "mysql":
+		query = "SELECT TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+	case "postgres", "gaussdb":
+		query = "SELECT COALESCE(obj_description(to_regclass(?), 'pg_class'), '')"
+	default:
+		return "", nil
+	}
+
+	var comments []string
+	err := p.noPrepare().Raw(query, table).Scan(&comments).Error
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	if len(comments) == 0 {
+		return "", nil
+	}
+
+	return comments[0], nil
+}
+
+// AutoMigrate 之后同步表注释，列注释由 gorm 处理
+func (p *Client) syncTableComment(model any) error {
+	comment, ok := getTableComment(model)
+	if !ok {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: p.db}
+	err := stmt.Parse(model)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+	table := stmt.Schema.Table
+
+	sql := p.tableCommentSQL(table, comment)
+	if sql == "" {
+		return nil
+	}
+
+	current, err := p.inspectTableComment(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	if current == comment {
+		return nil
+	}
+
+	err = p.db.Exec(sql).Error
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"database/sql/driver"
 	"fmt"
 	"github.com/lazygophers/log"
 	"reflect"
@@ -18,13 +19,32 @@ type Cond struct {
 
 	// 标记跳过请求，用于一些逻辑上就不需要进行请求的场景
 	skip bool
+
+	// 非法的列名，Scoop 执行时返回
+	err error
+}
+
+// Err 构造条件时的错误，例如外部传入了非法的列名
+func (p *Cond) Err() error {
+	return p.err
 }
 
-func quoteFieldName(name string) string {
-	if !strings.HasPrefix(name, "`") {
-		name = fmt.Sprintf("`%s`", name)
+// 列名不合法时记录错误，并替换为恒不成立的条件，避免外部传入的列名拼接到语句中，也避免忽略错误时扩大匹配范围
+func (p *Cond) quoteField(name string) (string, bool) {
+	if p.tablePrefix != "" {
+		name = p.tablePrefix + "." + name
+	}
+
+	quoted, err := quoteIdentifier(name, '`', false)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		if p.err == nil {
+			p.err = err
+		}
+		p.conds = append(p.conds, "(1 = 0)")
+		return "", false
 	}
-	return name
+	return quoted, true
 }
 
 func quoteStr(s string) string {
@@ -32,10 +52,50 @@ func quoteStr(s string) string {
 	//return fmt.Sprintf("'%s'", s)
 }
 
-func simpleTypeToStr(value interface{}, quoteSlice bool) string {
+// 是否需要按 NULL 处理，nil、空指针以及 Valid 为 false 的 sql.Null*
+func isNullValue(value interface{}) bool {
 	if value == nil {
-		panic("value nil")
+		return true
+	}
+
+	if x, ok := value.(driver.Valuer); ok {
+		vo := reflect.ValueOf(x)
+		if vo.Kind() == reflect.Ptr && vo.IsNil() {
+			return true
+		}
+
+		v, err := x.Value()
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return false
+		}
+		return v == nil
+	}
+
+	vo := reflect.ValueOf(value)
+	for vo.Kind() == reflect.Ptr || vo.Kind() == reflect.Interface {
+		if vo.IsNil() {
+			return true
+		}
+		vo = vo.Elem()
+	}
+
+	return false
+}
+
+func simpleTypeToStr(value interface{}, quoteSlice bool) string {
+	if isNullValue(value) {
+		return "NULL"
 	}
+
+	if x, ok := value.(driver.Valuer); ok {
+		v, err := x.Value()
+		if err != nil {
+			panic(fmt.Sprintf("invalid value %v, err:%v", value, err))
+		}
+		return simpleTypeToStr(v, quoteSlice)
+	}
+
 	vo := reflect.ValueOf(value)
 	for vo.Kind() == reflect.Ptr || vo.Kind() == reflect.Interface {
 		vo = vo.Elem()
@@ -111,12 +171,11 @@ func (p *Cond) addCond(fieldName, op string, val interface{}) {
 	if op == "" {
 		panic(fmt.Sprintf("empty op for field %s", fieldName))
 	}
-	if p.tablePrefix == "" {
-		p.conds = append(p.conds,
-			fmt.Sprintf("(%s %s %s)", quoteFieldName(fieldName), op, simpleTypeToStr(val, true)))
-	} else {
-		p.conds = append(p.conds, fmt.Sprintf("(%s.%s %s %s)", p.tablePrefix, fieldName, op, simpleTypeToStr(val, true)))
+	quoted, ok := p.quoteField(fieldName)
+	if !ok {
+		return
 	}
+	p.conds = append(p.conds, fmt.Sprintf("(%s %s %s)", quoted, op, simpleTypeToStr(val, true)))
 }
 
 func getFirstInvalidFieldNameCharIndex(s string) int {
@@ -138,6 +197,9 @@ func (p *Cond) addSubWhere(isOr bool, args ...interface{}) {
 		tablePrefix: p.tablePrefix,
 	}
 	subCond.where(args...)
+	if subCond.err != nil && p.err == nil {
+		p.err = subCond.err
+	}
 	c := subCond.ToString()
 	if c == "" {
 		return
@@ -409,5 +471,6 @@ func (p *Cond) Clean() *Cond {
 	p.appId = 0
 	p.tablePrefix = ""
 	p.skip = false
+	p.err = nil
 	return p
 }
@@ -1,16 +1,20 @@
 package db
 
 import (
+	"fmt"
 	"github.com/lazygophers/utils/app"
 	"gorm.io/gorm/logger"
 	"os"
+	"sort"
+	"time"
 )
 
 type Config struct {
-	// Database type, support sqlite, mysql, postgres, sqlserver, default sqlite
+	// Database type, support sqlite, mysql, postgres, gaussdb, sqlserver, default sqlite
 	// sqlite: sqlite|sqlite3
 	// mysql: mysql
 	// postgres: postgres|pg|postgresql|pgsql
+	// gaussdb: gaussdb|opengauss
 	// sqlserver: sqlserver|mssql
 	Type string `yaml:"type"`
 
@@ -21,6 +25,7 @@ type Config struct {
 	// sqlite: full filepath, default exec path
 	// mysql: database address, default 127.0.0.1
 	// postgres: database address, default 127.0.0.1
+	// gaussdb: database address, default 127.0.0.1
 	// sqlserver: database address, default 127.0.0.1
 	Address string `yaml:"address"`
 
@@ -28,6 +33,7 @@ type Config struct {
 	// sqlite: empty
 	// mysql: database port, default 3306
 	// postgres: database port, default 5432
+	// gaussdb: database port, default 8000
 	// sqlserver: database port, default 1433
 	Port int `yaml:"port"`
 
@@ -35,6 +41,7 @@ type Config struct {
 	// sqlite: database file name, default ice.db
 	// mysql: database name, default ice
 	// postgres: database name, default ice
+	// gaussdb: database name, default ice
 	// sqlserver: database name, default ice
 	Name string `yaml:"name"`
 
@@ -42,6 +49,7 @@ type Config struct {
 	// sqlite: empty
 	// mysql: database username
 	// postgres: database username
+	// gaussdb: database username
 	// sqlserver: database username
 	Username string `yaml:"username"`
 
@@ -49,10 +57,75 @@ type Config struct {
 	// sqlite: empty
 	// mysql: database password
 	// postgres: database password
+	// gaussdb: database password
 	// sqlserver: database password
 	Password string `yaml:"password"`
 
+	// Max retry times for transient errors (bad connection, mysql 2006/2013, connection reset) on read, default 0
+	// Write operations are never retried, the error is wrapped as ErrTransient instead
+	MaxRetries int `yaml:"max_retries"`
+
+	// Backoff before the first retry, doubled after each retry, default 100ms
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+
+	// Log level of this client, support silent, error, warn, info, default info
+	// Ignored when Logger is set
+	LogLevel string `yaml:"log_level"`
+
 	Logger logger.Interface `json:"-" yaml:"-"`
+
+	// Warn when a transaction started by Scoop.Begin lasts longer than this, default 0 (disabled)
+	SlowTxThreshold time.Duration `yaml:"slow_tx_threshold"`
+
+	// Called after a slow transaction is committed or rolled back, e.g. report metrics
+	OnSlowTx func(stats *TxStats) `json:"-" yaml:"-"`
+
+	// Fill the int64/uint64 primary key on Create when it is zero, e.g. idgen.NextIdE
+	// An error aborts the Create instead of panicking inside the callback
+	IdGenerator func() (int64, error) `json:"-" yaml:"-"`
+
+	// Guard Find without Limit on tables with more rows than this, default 0 (disabled)
+	// Row counts are estimated from database statistics, use Scoop.Unbounded for intended full reads
+	MaxUnboundedRows int64 `yaml:"max_unbounded_rows"`
+
+	// Return ErrUnboundedQuery instead of logging a warning with the call site, default false
+	RejectUnbounded bool `yaml:"reject_unbounded"`
+
+	// Default cap on rows returned by Find, the first MaxRows rows are returned with ErrTruncated when exceeded
+	// Use Scoop.MaxRows to override for a single query, default 0 (disabled)
+	MaxRows uint64 `yaml:"max_rows"`
+
+	// Warn when the same statement is executed with different arguments more than this many times
+	// within a context created by TrackQueries and passed by Scoop.WithContext, e.g. one query per row of a list
+	// Meant for development, default 0 (disabled)
+	NPlusOneThreshold int `yaml:"n_plus_one_threshold"`
+
+	// Called once per statement when NPlusOneThreshold is exceeded, e.g. fail the test
+	OnNPlusOne func(stats *NPlusOneStats) `json:"-" yaml:"-"`
+
+	// Capture lock diagnostics (SHOW ENGINE INNODB STATUS, pg_locks, sys.dm_tran_locks) when a statement fails
+	// with a deadlock or lock wait timeout, the error is returned as *LockError, default false
+	LockDiagnostics bool `yaml:"lock_diagnostics"`
+
+	// Capture at most once per interval, default 1m
+	LockDiagnosticsInterval time.Duration `yaml:"lock_diagnostics_interval"`
+
+	// How long the table row counts are cached, default 10m
+	TableStatsTTL time.Duration `yaml:"table_stats_ttl"`
+
+	// Provide the username and password instead of Username/Password, e.g. EnvCredentials, FileCredentials, VaultCredentials
+	// Not supported by sqlite
+	Credentials CredentialProvider `json:"-" yaml:"-"`
+
+	// How often Credentials is checked for rotation, new connections use the new credentials
+	// while old connections are closed once they are idle, default 1m
+	CredentialRefresh time.Duration `yaml:"credential_refresh"`
+
+	// Session variables set on every new connection, so behavior does not depend on server defaults
+	// e.g. mysql: time_zone, sql_mode; postgres: statement_timeout, search_path; sqlserver: LOCK_TIMEOUT
+	// Values are written as is, quote strings yourself, e.g. "'+00:00'"
+	// Not supported by sqlite
+	Session map[string]string `yaml:"session"`
 }
 
 func (c *Config) apply() {
@@ -60,6 +133,22 @@ func (c *Config) apply() {
 		c.Type = "sqlite"
 	}
 
+	if c.RetryBackoff == 0 {
+		c.RetryBackoff = time.Millisecond * 100
+	}
+
+	if c.TableStatsTTL == 0 {
+		c.TableStatsTTL = time.Minute * 10
+	}
+
+	if c.LockDiagnosticsInterval == 0 {
+		c.LockDiagnosticsInterval = time.Minute
+	}
+
+	if c.CredentialRefresh == 0 {
+		c.CredentialRefresh = time.Minute
+	}
+
 	switch c.Type {
 	case "sqlite", "sqlite3":
 		c.Type = "sqlite"
@@ -100,6 +189,21 @@ func (c *Config) apply() {
 			c.Name = app.Name
 		}
 
+	case "gaussdb", "opengauss":
+		c.Type = "gaussdb"
+
+		if c.Address == "" {
+			c.Address = "127.0.0.1"
+		}
+
+		if c.Port == 0 {
+			c.Port = 8000
+		}
+
+		if c.Name == "" {
+			c.Name = app.Name
+		}
+
 	case "sqlserver", "mssql":
 		c.Type = "sqlserver"
 
@@ -116,3 +220,36 @@ func (c *Config) apply() {
 		}
 	}
 }
+
+func (c *Config) dsn(username, password string) string {
+	switch c.Type {
+	case "mysql":
+		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", username, password, c.Address, c.Port, c.Name)
+	case "postgres", "gaussdb":
+		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", c.Address, c.Port, username, password, c.Name)
+	case "sqlserver":
+		return fmt.Sprintf("sqlserver://%s:%s@%s:%d?database=%s", username, password, c.Address, c.Port, c.Name)
+	default:
+		return ""
+	}
+}
+
+// 按变量名排序，保证每个连接执行的顺序一致
+func (c *Config) sessionStatements() []string {
+	if len(c.Session) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.Session))
+	for name := range c.Session {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	format := GetDialect(c.Type).SetSession
+	stmts := make([]string, 0, len(names))
+	for _, name := range names {
+		stmts = append(stmts, fmt.Sprintf(format, name, c.Session[name]))
+	}
+	return stmts
+}
@@ -0,0 +1,394 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/anyx"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Credentials struct {
+	// 为空时使用 Config.Username
+	Username string
+	Password string
+}
+
+// CredentialProvider 提供数据库的账号密码，定期调用，返回的值变化时新的连接使用新的账号密码
+// 加密的密码可以在实现中解密后返回
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (*Credentials, error)
+}
+
+type CredentialProviderFunc func(ctx context.Context) (*Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (*Credentials, error) {
+	return f(ctx)
+}
+
+// EnvCredentials 从环境变量读取，usernameKey 为空时只读取密码
+func EnvCredentials(usernameKey, passwordKey string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (*Credentials, error) {
+		password, ok := os.LookupEnv(passwordKey)
+		if !ok {
+			return nil, fmt.Errorf("env %s not found", passwordKey)
+		}
+
+		c := &Credentials{
+			Password: password,
+		}
+		if usernameKey != "" {
+			c.Username = os.Getenv(usernameKey)
+		}
+		return c, nil
+	})
+}
+
+// FileCredentials 从文件读取，例如 k8s secret 或者 vault agent 挂载的文件，usernamePath 为空时只读取密码
+func FileCredentials(usernamePath, passwordPath string) CredentialProvider {
+	read := func(path string) (string, error) {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return "", err
+		}
+		return strings.TrimSpace(string(buf)), nil
+	}
+
+	return CredentialProviderFunc(func(ctx context.Context) (*Credentials, error) {
+		password, err := read(passwordPath)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &Credentials{
+			Password: password,
+		}
+		if usernamePath != "" {
+			c.Username, err = read(usernamePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+	})
+}
+
+// SecretReader 读取密钥，例如 vault 的 KV 客户端，避免直接依赖 vault 的 sdk
+type SecretReader interface {
+	ReadSecret(ctx context.Context, path string) (map[string]any, error)
+}
+
+// VaultCredentials 读取 path 中的 username 与 password 字段
+func VaultCredentials(reader SecretReader, path string) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (*Credentials, error) {
+		data, err := reader.ReadSecret(ctx, path)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		password, ok := data["password"]
+		if !ok {
+			return nil, fmt.Errorf("secret %s has no password", path)
+		}
+
+		return &Credentials{
+			Username: anyx.ToString(data["username"]),
+			Password: anyx.ToString(password),
+		}, nil
+	})
+}
+
+// credentialConnector 每次建立连接时使用最新的账号密码并设置会话变量，轮换之后旧的连接在归还连接池时关闭
+type credentialConnector struct {
+	c        *Config
+	provider CredentialProvider
+
+	// 新建连接后执行
+	session []string
+
+	driver driver.Driver
+
+	lock        sync.Mutex
+	credentials Credentials
+	connector   driver.Connector
+
+	// 每次轮换加 1，连接记录创建时的值
+	generation atomic.Int64
+}
+
+func newCredentialConnector(c *Config) (*credentialConnector, error) {
+	var driverName string
+	switch c.Type {
+	case "mysql":
+		driverName = "mysql"
+	case "postgres", "gaussdb":
+		driverName = "pgx"
+	case "sqlserver":
+		driverName = "sqlserver"
+	default:
+		return nil, fmt.Errorf("credential provider and session not support %s", c.Type)
+	}
+
+	// 只用于获取注册的驱动，不会建立连接
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	defer db.Close()
+
+	p := &credentialConnector{
+		c:        c,
+		provider: c.Credentials,
+		session:  c.sessionStatements(),
+		driver:   db.Driver(),
+	}
+
+	// 只设置了会话变量时使用固定的账号密码
+	if p.provider == nil {
+		p.provider = CredentialProviderFunc(func(ctx context.Context) (*Credentials, error) {
+			return &Credentials{
+				Username: c.Username,
+				Password: c.Password,
+			}, nil
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	_, err = p.refresh(ctx)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// refresh 读取最新的账号密码，变化时返回 true
+func (p *credentialConnector) refresh(ctx context.Context) (bool, error) {
+	credentials, err := p.provider.Credentials(ctx)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return false, err
+	}
+
+	username := credentials.Username
+	if username == "" {
+		username = p.c.Username
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.connector != nil && p.credentials.Username == username && p.credentials.Password == credentials.Password {
+		return false, nil
+	}
+
+	dsn := p.c.dsn(username, credentials.Password)
+	var connector driver.Connector
+	if dc, ok := p.driver.(driver.DriverContext); ok {
+		connector, err = dc.OpenConnector(dsn)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return false, err
+		}
+	} else {
+		connector = &dsnConnector{dsn: dsn, driver: p.driver}
+	}
+
+	p.credentials = Credentials{
+		Username: username,
+		Password: credentials.Password,
+	}
+	p.connector = connector
+	if p.generation.Add(1) > 1 {
+		log.Warnf("database credentials rotated, username:%s", username)
+	}
+
+	return true, nil
+}
+
+func (p *credentialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	p.lock.Lock()
+	connector := p.connector
+	generation := p.generation.Load()
+	p.lock.Unlock()
+
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := &credentialConn{
+		Conn:       conn,
+		owner:      p,
+		generation: generation,
+	}
+
+	for _, stmt := range p.session {
+		err = cc.exec(ctx, stmt)
+		if err != nil {
+			log.Errorf("set session failed, stmt:%s, err:%v", stmt, err)
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	return cc, nil
+}
+
+func (p *credentialConnector) Driver() driver.Driver {
+	return p.driver
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (p *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return p.driver.Open(p.dsn)
+}
+
+func (p *dsnConnector) Driver() driver.Driver {
+	return p.driver
+}
+
+// credentialConn 转发驱动连接的可选接口，账号密码轮换后不再放回连接池
+type credentialConn struct {
+	driver.Conn
+
+	owner      *credentialConnector
+	generation int64
+}
+
+func (p *credentialConn) exec(ctx context.Context, query string) error {
+	_, err := p.ExecContext(ctx, query, nil)
+	if err != driver.ErrSkip {
+		return err
+	}
+
+	stmt, err := p.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+func (p *credentialConn) expired() bool {
+	return p.generation != p.owner.generation.Load()
+}
+
+func (p *credentialConn) IsValid() bool {
+	if p.expired() {
+		return false
+	}
+	if v, ok := p.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (p *credentialConn) ResetSession(ctx context.Context) error {
+	if p.expired() {
+		return driver.ErrBadConn
+	}
+	if r, ok := p.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (p *credentialConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if c, ok := p.Conn.(driver.ConnPrepareContext); ok {
+		return c.PrepareContext(ctx, query)
+	}
+	return p.Conn.Prepare(query)
+}
+
+func (p *credentialConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c, ok := p.Conn.(driver.ConnBeginTx); ok {
+		return c.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != 0 || opts.ReadOnly {
+		return nil, errors.New("driver does not support non-default transaction options")
+	}
+	return p.Conn.Begin()
+}
+
+func (p *credentialConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c, ok := p.Conn.(driver.ExecerContext); ok {
+		return c.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (p *credentialConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c, ok := p.Conn.(driver.QueryerContext); ok {
+		return c.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (p *credentialConn) Ping(ctx context.Context) error {
+	if c, ok := p.Conn.(driver.Pinger); ok {
+		return c.Ping(ctx)
+	}
+	return nil
+}
+
+func (p *credentialConn) CheckNamedValue(v *driver.NamedValue) error {
+	if c, ok := p.Conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// RotateCredentials 立即读取最新的账号密码，配置了 CredentialRefresh 时会定期调用
+// 变化时返回 true，之后新建的连接使用新的账号密码，旧的连接在当前语句执行完成后关闭
+func (p *Client) RotateCredentials(ctx context.Context) (bool, error) {
+	if p.credentials == nil {
+		return false, nil
+	}
+
+	rotated, err := p.credentials.refresh(ctx)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return false, err
+	}
+
+	return rotated, nil
+}
+
+func (p *Client) refreshCredentials(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			_, err := p.RotateCredentials(ctx)
+			cancel()
+			if err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package db_test
+
+import (
+	"context"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "username"), []byte("app\n"), 0600)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	c, err := db.FileCredentials(filepath.Join(dir, "username"), filepath.Join(dir, "password")).Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if c.Username != "app" || c.Password != "secret" {
+		t.Errorf("unexpected credentials: %+v", c)
+	}
+
+	t.Setenv("DB_PASSWORD", "env-secret")
+	c, err = db.EnvCredentials("", "DB_PASSWORD").Credentials(context.Background())
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if c.Username != "" || c.Password != "env-secret" {
+		t.Errorf("unexpected credentials: %+v", c)
+	}
+
+	_, err = db.EnvCredentials("", "DB_PASSWORD_MISSING").Credentials(context.Background())
+	if err == nil {
+		t.Errorf("expected error for missing env")
+	}
+
+	// sqlite 没有账号密码
+	_, err = db.New(&db.Config{
+		Address:     dir,
+		Name:        "credential",
+		Credentials: db.EnvCredentials("", "DB_PASSWORD"),
+	})
+	if err == nil {
+		t.Errorf("expected error for sqlite")
+	}
+}
+
+func TestSessionNotSupported(t *testing.T) {
+	// sqlite 使用 PRAGMA，不通过 Session 设置
+	_, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "session",
+		Session: map[string]string{
+			"time_zone": "'+00:00'",
+		},
+	})
+	if err == nil {
+		t.Errorf("expected error for sqlite")
+	}
+}
@@ -0,0 +1,109 @@
+// Package dbtest 用于记录 Scoop 生成的 sql 并与 golden 文件对比，防止重构时无意中改变了查询
+package dbtest
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// EnvUpdateGolden 设置为 1 时重新生成 golden 文件
+const EnvUpdateGolden = "LRPC_UPDATE_GOLDEN"
+
+type Recorder struct {
+	lock sync.Mutex
+	sqls []string
+}
+
+func (p *Recorder) record(tx *gorm.DB) {
+	if tx.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	sql := tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.sqls = append(p.sqls, strings.ReplaceAll(sql, "\n", " "))
+}
+
+// SQLs 返回记录的 sql，参数已经填充
+func (p *Recorder) SQLs() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return append([]string(nil), p.sqls...)
+}
+
+func (p *Recorder) Reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.sqls = nil
+}
+
+// Record 在 Client 上注册回调，记录之后执行的全部 sql
+func Record(cli *db.Client) *Recorder {
+	p := &Recorder{}
+
+	callback := cli.Database().Callback()
+	_ = callback.Query().After("gorm:query").Register("dbtest:query", p.record)
+	_ = callback.Row().After("gorm:row").Register("dbtest:row", p.record)
+	_ = callback.Raw().After("gorm:raw").Register("dbtest:raw", p.record)
+	_ = callback.Create().After("gorm:create").Register("dbtest:create", p.record)
+	_ = callback.Update().After("gorm:update").Register("dbtest:update", p.record)
+	_ = callback.Delete().After("gorm:delete").Register("dbtest:delete", p.record)
+
+	return p
+}
+
+// NewClient 在临时目录中创建 sqlite 数据库并开始记录
+func NewClient(t testing.TB, tables ...interface{}) (*db.Client, *Recorder) {
+	t.Helper()
+
+	cli, err := db.New(&db.Config{
+		Type:    "sqlite",
+		Address: t.TempDir(),
+		Name:    "dbtest",
+	}, tables...)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	return cli, Record(cli)
+}
+
+// AssertGolden 与 testdata/<name>.golden 对比，每行一条 sql
+func (p *Recorder) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+
+	got := strings.Join(p.SQLs(), "\n") + "\n"
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(EnvUpdateGolden) == "1" {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		err = os.WriteFile(path, []byte(got), 0644)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden %s failed, run with %s=1 to create it, err:%v", path, EnvUpdateGolden, err)
+	}
+
+	if string(want) != got {
+		t.Errorf("sql changed, run with %s=1 to update %s\nwant:\n%s\ngot:\n%s", EnvUpdateGolden, path, want, got)
+	}
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+	"sync"
+)
+
+// Dialect 描述不同数据库之间的差异，生成 sql 时按能力判断而不是按类型
+type Dialect struct {
+	Name string
+
+	// 标识符的引号
+	Quote byte
+
+	// 是否支持 INSERT ... RETURNING，不支持时自增 id 通过 last insert id 获取
+	SupportReturning bool
+
+	// 忽略唯一索引冲突的写法，为空时表示不支持
+	InsertIgnore func() []clause.Expression
+
+	// 唯一索引冲突时错误信息中的特征
+	DuplicateKeyErrors []string
+
+	// 修改表注释的语句，%s 为表名，? 为注释，为空时表示不支持注释
+	TableComment string
+
+	// 修改列注释的语句，%s 为表名与列名，为空时列注释只能随列定义一起修改
+	ColumnComment string
+
+	// 设置会话变量的语句，两个 %s 依次为变量名与值，为空时表示不支持
+	SetSession string
+
+	// UPDATE/DELETE 返回受影响行的写法，RETURNING 在语句末尾，OUTPUT 在 WHERE 之前
+	// 为空时 UpdatesReturning/DeleteReturning 在事务中先锁定行再修改
+	ReturningClause string
+
+	// 死锁或者等待锁超时后采集锁信息的语句，为空时表示不支持
+	LockDiagnostics string
+
+	// 创建会话级临时表的语句，两个 %s 依次为表名与列类型，为空时 InTemp 退化为 In
+	CreateTempTable string
+	// 删除临时表的语句，%s 为表名，为空时表示事务结束时自动删除
+	DropTempTable string
+}
+
+// QuoteName 名字中的引号加倍转义，已经完整引用的名字保持不变
+func (d *Dialect) QuoteName(name string) string {
+	q := string(d.Quote)
+	if len(name) >= 2 && strings.HasPrefix(name, q) && strings.HasSuffix(name, q) &&
+		!strings.Contains(strings.ReplaceAll(name[1:len(name)-1], q+q, ""), q) {
+		return name
+	}
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// onDuplicateNothing openGauss/GaussDB 的 ON DUPLICATE KEY UPDATE NOTHING
+type onDuplicateNothing struct{}
+
+func (onDuplicateNothing) Name() string {
+	return "ON CONFLICT"
+}
+
+func (p onDuplicateNothing) Build(builder clause.Builder) {
+	builder.WriteString("ON DUPLICATE KEY UPDATE NOTHING")
+}
+
+func (p onDuplicateNothing) MergeClause(c *clause.Clause) {
+	c.Name = ""
+	c.Expression = p
+}
+
+var (
+	dialectLock sync.RWMutex
+	dialects    = map[string]*Dialect{
+		"mysql": {
+			Name:             "mysql",
+			Quote:            '`',
+			SupportReturning: false,
+			InsertIgnore: func() []clause.Expression {
+				return []clause.Expression{clause.Insert{Modifier: "IGNORE"}}
+			},
+			DuplicateKeyErrors: []string{"Error 1062", "Duplicate entry"},
+			TableComment:       "ALTER TABLE %s COMMENT = ?",
+			SetSession:         "SET SESSION %s = %s",
+			LockDiagnostics:    "SHOW ENGINE INNODB STATUS",
+			CreateTempTable:    "CREATE TEMPORARY TABLE %s (v %s PRIMARY KEY)",
+			DropTempTable:      "DROP TEMPORARY TABLE IF EXISTS %s",
+		},
+		"postgres": {
+			Name:             "postgres",
+			Quote:            '"',
+			SupportReturning: true,
+			InsertIgnore: func() []clause.Expression {
+				return []clause.Expression{clause.OnConflict{DoNothing: true}}
+			},
+			DuplicateKeyErrors: []string{"SQLSTATE 23505", "duplicate key value violates unique constraint"},
+			TableComment:       "COMMENT ON TABLE %s IS ?",
+			ColumnComment:      "COMMENT ON COLUMN %s.%s IS ?",
+			SetSession:         "SET %s = %s",
+			ReturningClause:    "RETURNING",
+			// 等待中的锁以及阻塞它们的会话持有的锁
+			LockDiagnostics: "SELECT l.pid, l.locktype, l.mode, l.granted, l.relation::regclass AS relation, " +
+				"pg_blocking_pids(l.pid) AS blocked_by, now() - a.xact_start AS xact_age, left(a.query, 200) AS query " +
+				"FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid " +
+				"WHERE NOT l.granted OR l.pid IN (SELECT unnest(pg_blocking_pids(pid)) FROM pg_locks WHERE NOT granted) " +
+				"ORDER BY l.granted, l.pid LIMIT 50",
+			CreateTempTable: "CREATE TEMP TABLE %s (v %s PRIMARY KEY) ON COMMIT DROP",
+		},
+		"gaussdb": {
+			Name:  "gaussdb",
+			Quote: '"',
+			// 分布式部署下不支持 RETURNING
+			SupportReturning: false,
+			InsertIgnore: func() []clause.Expression {
+				return []clause.Expression{onDuplicateNothing{}}
+			},
+			DuplicateKeyErrors: []string{"SQLSTATE 23505", "duplicate key value violates unique constraint"},
+			TableComment:       "COMMENT ON TABLE %s IS ?",
+			ColumnComment:      "COMMENT ON COLUMN %s.%s IS ?",
+			SetSession:         "SET %s = %s",
+			// 不支持 pg_blocking_pids
+			LockDiagnostics: "SELECT l.pid, l.locktype, l.mode, l.relation::regclass AS relation, left(a.query, 200) AS query " +
+				"FROM pg_locks l JOIN pg_stat_activity a ON a.pid = l.pid WHERE NOT l.granted LIMIT 50",
+			CreateTempTable: "CREATE TEMP TABLE %s (v %s PRIMARY KEY) ON COMMIT DROP",
+		},
+		"sqlite": {
+			Name:             "sqlite",
+			Quote:            '`',
+			SupportReturning: true,
+			InsertIgnore: func() []clause.Expression {
+				return []clause.Expression{clause.Insert{Modifier: "OR IGNORE"}}
+			},
+			DuplicateKeyErrors: []string{"UNIQUE constraint failed"},
+			ReturningClause:    "RETURNING",
+			CreateTempTable:    "CREATE TEMP TABLE %s (v %s PRIMARY KEY)",
+			DropTempTable:      "DROP TABLE IF EXISTS %s",
+		},
+		"sqlserver": {
+			Name:               "sqlserver",
+			Quote:              '"',
+			SupportReturning:   false,
+			DuplicateKeyErrors: []string{"Cannot insert duplicate key"},
+			SetSession:         "SET %s %s",
+			ReturningClause:    "OUTPUT",
+			LockDiagnostics: "SELECT TOP 50 request_session_id, resource_type, resource_description, request_mode, request_status " +
+				"FROM sys.dm_tran_locks WHERE request_status = 'WAIT'",
+		},
+	}
+)
+
+// RegisterDialect 注册或覆盖数据库的差异描述
+func RegisterDialect(d *Dialect) {
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+
+	dialects[d.Name] = d
+}
+
+// GetDialect 不存在时返回 mysql 的
+func GetDialect(name string) *Dialect {
+	dialectLock.RLock()
+	defer dialectLock.RUnlock()
+
+	if d, ok := dialects[name]; ok {
+		return d
+	}
+
+	return dialects["mysql"]
+}
+
+func getDialectByDB(db *gorm.DB) *Dialect {
+	if c := getClientByDB(db); c != nil {
+		return c.Dialect()
+	}
+
+	return GetDialect(db.Dialector.Name())
+}
+
+var ErrInsertIgnoreNotSupport = errors.New("insert ignore not support")
+
+func (p *Client) Dialect() *Dialect {
+	return GetDialect(p.clientType)
+}
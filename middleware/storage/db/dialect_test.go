@@ -0,0 +1,78 @@
+package db_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type dialectUser struct {
+	Id   int64
+	Name string
+}
+
+func TestDialectInsertIgnore(t *testing.T) {
+	// 与 Client 一致，不支持 RETURNING 的方言关闭 RETURNING
+	for name, want := range map[string]string{
+		"postgres": `INSERT INTO "dialect_users" ("name") VALUES ($1) ON CONFLICT DO NOTHING RETURNING "id"`,
+		"gaussdb":  `INSERT INTO "dialect_users" ("name") VALUES ($1) ON DUPLICATE KEY UPDATE NOTHING`,
+	} {
+		gdb, err := gorm.Open(postgres.New(postgres.Config{
+			DSN:              "host=127.0.0.1 port=8000 user=u password=p dbname=d sslmode=disable",
+			WithoutReturning: !db.GetDialect(name).SupportReturning,
+		}), &gorm.Config{
+			DryRun:                 true,
+			DisableAutomaticPing:   true,
+			SkipDefaultTransaction: true,
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		stmt := gdb.Clauses(db.GetDialect(name).InsertIgnore()...).Create(&dialectUser{Name: "a"}).Statement
+		if stmt.SQL.String() != want {
+			t.Errorf("%s: %s", name, stmt.SQL.String())
+		}
+	}
+
+	if db.GetDialect("sqlserver").InsertIgnore != nil {
+		t.Error("sqlserver should not support insert ignore")
+	}
+}
+
+func TestDialectQuoteName(t *testing.T) {
+	d := db.GetDialect("postgres")
+	for name, want := range map[string]string{
+		"user":          `"user"`,
+		`"user"`:        `"user"`,
+		`a"b`:           `"a""b"`,
+		`"a"; DROP "b"`: `"""a""; DROP ""b"""`,
+	} {
+		if got := d.QuoteName(name); got != want {
+			t.Errorf("%s: %s", name, got)
+		}
+	}
+
+	if got := db.GetDialect("mysql").QuoteName("a`b"); got != "`a``b`" {
+		t.Errorf("mysql: %s", got)
+	}
+}
+
+func TestIsUniqueIndexConflictErr(t *testing.T) {
+	for _, msg := range []string{
+		"Error 1062: Duplicate entry 'a' for key 'name'",
+		`ERROR: duplicate key value violates unique constraint "idx_name" (SQLSTATE 23505)`,
+		"UNIQUE constraint failed: user.name",
+		"Cannot insert duplicate key row in object 'dbo.user'",
+	} {
+		if !db.IsUniqueIndexConflictErr(errors.New(msg)) {
+			t.Errorf("not conflict: %s", msg)
+		}
+	}
+
+	if db.IsUniqueIndexConflictErr(gorm.ErrRecordNotFound) {
+		t.Error("record not found should not be conflict")
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+)
+
+// As 设置表的别名，用于自关联的子查询中区分内外层的同名表
+func (p *Scoop) As(alias string) *Scoop {
+	if !isIdentifierPart(alias) {
+		p.setErr(fmt.Errorf("%w: %s", ErrInvalidIdentifier, alias))
+		return p
+	}
+
+	p.alias = alias
+	return p
+}
+
+func (p *Scoop) writeTable(b *bytes.Buffer) {
+	b.WriteString(p.table)
+	if p.alias != "" {
+		b.WriteString(" AS ")
+		b.WriteString(p.alias)
+	}
+}
+
+// EqualColumn 两列相等，用于 Exists 中关联外层的表，例如 EqualColumn("orders.user_id", "users.id")
+func (p *Scoop) EqualColumn(column, other string) *Scoop {
+	left, err := quoteIdentifier(column, '`', false)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.setErr(err)
+		return p
+	}
+
+	right, err := quoteIdentifier(other, '`', false)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.setErr(err)
+		return p
+	}
+
+	p.cond.conds = append(p.cond.conds, "("+left+" = "+right+")")
+	return p
+}
+
+// 子查询只关心是否有记录，排序、分页都会被忽略，软删除和默认条件与直接查询时保持一致
+func (p *Scoop) existsSql() (string, error) {
+	if err := p.buildErr(); err != nil {
+		return "", err
+	}
+
+	if p.table == "" {
+		return "", errors.New("table name is empty")
+	}
+
+	if !p.unscoped && p.hasDeletedAt {
+		p.cond.whereRaw("deleted_at = 0")
+	}
+	p.applyDefaultScope(p.model)
+
+	b := log.GetBuffer()
+	defer log.PutBuffer(b)
+
+	b.WriteString("SELECT 1 FROM ")
+	p.writeTable(b)
+
+	if len(p.cond.conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(p.cond.conds[0])
+		for _, c := range p.cond.conds[1:] {
+			b.WriteString(" AND ")
+			b.WriteString(c)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func (p *Scoop) exists(op string, sub *Scoop) *Scoop {
+	if sub.cond.skip {
+		// 子查询必定为空
+		if op == "EXISTS" {
+			p.cond.where(false)
+		}
+		return p
+	}
+
+	sqlRaw, err := sub.existsSql()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.setErr(err)
+		return p
+	}
+
+	p.cond.conds = append(p.cond.conds, "("+op+" ("+sqlRaw+"))")
+	return p
+}
+
+// Exists 子查询有记录时满足条件，子查询中通过 EqualColumn 关联外层的表
+func (p *Scoop) Exists(sub *Scoop) *Scoop {
+	return p.exists("EXISTS", sub)
+}
+
+// NotExists 子查询没有记录时满足条件，用于 anti join
+func (p *Scoop) NotExists(sub *Scoop) *Scoop {
+	return p.exists("NOT EXISTS", sub)
+}
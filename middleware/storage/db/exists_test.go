@@ -0,0 +1,87 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type existsUser struct {
+	Id        int64 `gorm:"primaryKey"`
+	Name      string
+	DeletedAt int64
+}
+
+func (existsUser) TableName() string {
+	return "exists_user"
+}
+
+type existsOrder struct {
+	Id        int64 `gorm:"primaryKey"`
+	UserId    int64
+	DeletedAt int64
+}
+
+func (existsOrder) TableName() string {
+	return "exists_order"
+}
+
+func TestExists(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "exists",
+	}, &existsUser{}, &existsOrder{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for _, u := range []*existsUser{{Id: 1, Name: "a"}, {Id: 2, Name: "b"}, {Id: 3, Name: "c"}} {
+		err = cli.NewScoop().Create(u).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+	for _, o := range []*existsOrder{{Id: 1, UserId: 1}, {Id: 2, UserId: 2, DeletedAt: 1}} {
+		err = cli.NewScoop().Create(o).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	orders := func() *db.Scoop {
+		return cli.NewScoop().Model(&existsOrder{}).EqualColumn("exists_order.user_id", "exists_user.id")
+	}
+
+	var users []*existsUser
+	err = cli.NewScoop().Exists(orders()).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(users) != 1 || users[0].Name != "a" {
+		t.Fatalf("unexpected result: %+v", users)
+	}
+
+	cnt, err := db.NewModelScoop[existsUser](cli.Database()).NotExists(orders()).Count()
+	if err != nil || cnt != 2 {
+		t.Fatalf("count:%d, err:%v", cnt, err)
+	}
+
+	// 自关联时通过别名区分
+	var others []*existsUser
+	err = cli.NewScoop().Exists(
+		cli.NewScoop().Model(&existsUser{}).As("u2").
+			EqualColumn("u2.name", "exists_user.name").
+			Where("u2.id !=", 0).
+			Where("u2.id <", 2),
+	).Find(&others).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(others) != 1 || others[0].Id != 1 {
+		t.Fatalf("unexpected result: %+v", others)
+	}
+
+	err = cli.NewScoop().Exists(cli.NewScoop().Model(&existsOrder{}).EqualColumn("user_id", "a b")).Find(&users).Error
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,267 @@
+package db
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/anyx"
+	"github.com/lazygophers/utils/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrMaskedColumn SelectRaw 等无法改写的表达式中引用了有脱敏规则的列，导出时无法脱敏
+var ErrMaskedColumn = errors.New("masked column in expression")
+
+type ExportFormat string
+
+const (
+	ExportCSV   ExportFormat = "csv"
+	ExportJSONL ExportFormat = "jsonl"
+)
+
+type ExportConfig struct {
+	// 默认 csv，第一行为列名
+	Format ExportFormat
+
+	// 额外的脱敏规则，key 为列名，与 RegisterMaskRules 注册的规则合并，不能覆盖注册的规则
+	Masks map[string]MaskRule
+}
+
+func (c *ExportConfig) apply() {
+	if c.Format == "" {
+		c.Format = ExportCSV
+	}
+}
+
+// Export 按查询条件逐行导出到 w，返回导出的行数，需要通过 Model 或 NewModelScoop 指定表
+// 注册的脱敏规则总是生效，可以用于导出生产数据到测试环境
+func (p *Scoop) Export(w io.Writer, c *ExportConfig) (int64, error) {
+	if c == nil {
+		c = &ExportConfig{}
+	}
+	c.apply()
+
+	if err := p.buildErr(); err != nil {
+		return 0, err
+	}
+
+	if p.table == "" {
+		return 0, errors.New("export requires a model")
+	}
+
+	if p.cond.skip {
+		return 0, nil
+	}
+
+	if !p.unscoped && p.hasDeletedAt {
+		p.cond.whereRaw("deleted_at = 0")
+	}
+	p.applyDefaultScope(nil)
+
+	// 在按列策略改写之前解析，别名按原始的列脱敏
+	masks := getMaskRules(p.table, c.Masks)
+	sources, err := exportSources(p.selects, masks)
+	if err != nil {
+		return 0, err
+	}
+
+	p.applyColumnPolicy()
+	if err := p.buildErr(); err != nil {
+		return 0, err
+	}
+
+	p.inc()
+	defer p.dec()
+
+	sqlRaw := p.findSql()
+	start := time.Now()
+
+	var cnt int64
+	err = p.export(sqlRaw, w, c, masks, sources, &cnt)
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+		return sqlRaw, cnt
+	}, err)
+	if err != nil {
+		return cnt, err
+	}
+
+	return cnt, nil
+}
+
+// 结果中的列名 -> 原始的列名，只记录使用了别名的列，表达式中引用了脱敏的列时返回错误
+func exportSources(selects []string, masks map[string]MaskRule) (map[string]string, error) {
+	sources := map[string]string{}
+	for _, s := range selects {
+		words := strings.Fields(s)
+
+		var parts, aliasParts []string
+		var err error
+		switch {
+		case len(words) == 1:
+			_, err = parseIdentifier(words[0], true)
+		case len(words) == 3 && strings.EqualFold(words[1], "AS"):
+			parts, err = parseIdentifier(words[0], false)
+			if err == nil {
+				aliasParts, err = parseIdentifier(words[2], false)
+			}
+		default:
+			err = ErrInvalidIdentifier
+		}
+
+		if err != nil {
+			for column := range masks {
+				if regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(column) + `\b`).MatchString(s) {
+					return nil, fmt.Errorf("%w: %s", ErrMaskedColumn, column)
+				}
+			}
+			continue
+		}
+
+		if aliasParts != nil {
+			sources[strings.ToLower(aliasParts[0])] = strings.ToLower(parts[len(parts)-1])
+		}
+	}
+
+	return sources, nil
+}
+
+func (p *Scoop) export(sqlRaw string, w io.Writer, c *ExportConfig, masks map[string]MaskRule, sources map[string]string, cnt *int64) error {
+	var rows *sql.Rows
+	err := p.retryRead(func() (err error) {
+		rows, err = p._db.Raw(sqlRaw).Rows()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	rules := make([]MaskRule, len(cols))
+	for i, col := range cols {
+		col = strings.ToLower(col)
+		if source, ok := sources[col]; ok {
+			col = source
+		}
+		rules[i] = masks[col]
+	}
+
+	values := make([]any, len(cols))
+	scanArgs := make([]any, len(cols))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	bw := bufio.NewWriter(w)
+	var cw *csv.Writer
+	var write func(values []any) error
+	switch c.Format {
+	case ExportCSV:
+		cw = csv.NewWriter(bw)
+		err = cw.Write(cols)
+		if err != nil {
+			return err
+		}
+
+		record := make([]string, len(cols))
+		write = func(values []any) error {
+			for i, v := range values {
+				record[i] = exportString(v)
+			}
+			return cw.Write(record)
+		}
+
+	case ExportJSONL:
+		// 按列的顺序输出，便于对比
+		keys := make([][]byte, len(cols))
+		for i, col := range cols {
+			keys[i], err = json.Marshal(col)
+			if err != nil {
+				return err
+			}
+		}
+
+		write = func(values []any) error {
+			_ = bw.WriteByte('{')
+			for i, v := range values {
+				if i > 0 {
+					_ = bw.WriteByte(',')
+				}
+				_, _ = bw.Write(keys[i])
+				_ = bw.WriteByte(':')
+
+				buf, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				_, _ = bw.Write(buf)
+			}
+			_, err := bw.WriteString("}\n")
+			return err
+		}
+
+	default:
+		return errors.New("unsupported export format")
+	}
+
+	for rows.Next() {
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			return err
+		}
+
+		for i, v := range values {
+			// 部分驱动以 []byte 返回字符串
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			if rules[i] != nil {
+				v = rules[i](v)
+			}
+			values[i] = v
+		}
+
+		err = write(values)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		*cnt++
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	if cw != nil {
+		cw.Flush()
+		err = cw.Error()
+		if err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func exportString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	default:
+		return anyx.ToString(v)
+	}
+}
@@ -0,0 +1,101 @@
+package db_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"strings"
+	"testing"
+)
+
+type exportUser struct {
+	Id    int64 `gorm:"primaryKey"`
+	Name  string
+	Email string
+	Phone string
+}
+
+func (exportUser) TableName() string {
+	return "export_user"
+}
+
+func TestExport(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "export",
+	}, &exportUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.NewScoop().Create(&exportUser{Id: 1, Name: "alice", Email: "alice@example.com", Phone: "13812345678"}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	db.RegisterMaskRules(&exportUser{}, map[string]db.MaskRule{
+		"email": db.MaskEmail(),
+		"phone": db.MaskPartial(3, 4),
+	})
+
+	var b bytes.Buffer
+	cnt, err := db.NewModelScoop[exportUser](cli.Database()).Export(&b, &db.ExportConfig{
+		Masks: map[string]db.MaskRule{
+			"name": db.MaskReplace("user"),
+			// 不能覆盖注册的规则
+			"email": db.MaskNull(),
+		},
+	})
+	if err != nil || cnt != 1 {
+		t.Fatalf("cnt:%d, err:%v", cnt, err)
+	}
+	if got := b.String(); got != "id,name,email,phone\n1,user,a***@example.com,138****5678\n" {
+		t.Errorf("unexpected csv: %q", got)
+	}
+
+	b.Reset()
+	_, err = db.NewModelScoop[exportUser](cli.Database()).Export(&b, &db.ExportConfig{
+		Format: db.ExportJSONL,
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if got := strings.TrimSpace(b.String()); got != `{"id":1,"name":"alice","email":"a***@example.com","phone":"138****5678"}` {
+		t.Errorf("unexpected jsonl: %s", got)
+	}
+}
+
+func TestExportAlias(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "export",
+	}, &exportUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.NewScoop().Create(&exportUser{Id: 1, Name: "alice", Email: "alice@example.com", Phone: "13812345678"}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	db.RegisterMaskRules(&exportUser{}, map[string]db.MaskRule{
+		"email": db.MaskEmail(),
+	})
+
+	// 别名按原始的列脱敏
+	var b bytes.Buffer
+	_, err = db.NewModelScoop[exportUser](cli.Database()).Select("id", "email AS e").Export(&b, nil)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if got := b.String(); got != "id,e\n1,a***@example.com\n" {
+		t.Errorf("unexpected csv: %q", got)
+	}
+
+	// 表达式无法脱敏
+	_, err = db.NewModelScoop[exportUser](cli.Database()).SelectRaw("UPPER(email) AS e").Export(&b, nil)
+	if !errors.Is(err, db.ErrMaskedColumn) {
+		t.Errorf("expected ErrMaskedColumn, got %v", err)
+	}
+}
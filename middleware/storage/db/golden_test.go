@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"github.com/lazygophers/lrpc/middleware/storage/db/dbtest"
+	"testing"
+)
+
+type goldenOrder struct {
+	Id     int64 `gorm:"primaryKey"`
+	UserId int64
+	Status int32
+	Amount int64
+	Remark string
+}
+
+func (goldenOrder) TableName() string {
+	return "golden_order"
+}
+
+func TestScoopGolden(t *testing.T) {
+	cli, rec := dbtest.NewClient(t, &goldenOrder{})
+	rec.Reset()
+
+	newScoop := func() *db.Scoop {
+		return cli.NewScoop().Model(&goldenOrder{})
+	}
+
+	var orders []*goldenOrder
+	_ = newScoop().Equal("user_id", 1).In("status", []int32{1, 2}).Order("id desc").Limit(10).Offset(20).Find(&orders)
+	_ = newScoop().Select("id", "amount").Where("amount >", 100).Like("remark", "vip").Find(&orders)
+	_ = newScoop().IsNull("remark").NotEqual("status", 0).First(&goldenOrder{})
+	_, _ = newScoop().Between("amount", 1, 100).Count()
+	_, _ = newScoop().Equal("id", 1).Exist()
+	_ = newScoop().Equal("id", 1).Updates(map[string]interface{}{"status": 2, "amount": 10, "remark": "a"})
+	_ = newScoop().Equal("user_id", 1).Delete()
+
+	_ = newScoop().Tag("Feature = checkout", "owner=pay*/team").Equal("user_id", 1).Find(&orders)
+	_, _ = newScoop().Tag("feature=checkout").Count()
+
+	rec.AssertGolden(t, "scoop")
+}
+
+func BenchmarkCond(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		c := &db.Cond{}
+		c.Where("user_id", 1).Where("status", "IN", []int32{1, 2, 3}).OrWhere(map[string]interface{}{
+			"amount >": 100,
+			"remark":   "vip",
+		})
+		_ = c.ToString()
+	}
+}
+
+func BenchmarkScoopFind(b *testing.B) {
+	cli, _ := dbtest.NewClient(b, &goldenOrder{})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var orders []*goldenOrder
+		_ = cli.NewScoop().Model(&goldenOrder{}).Equal("user_id", 1).In("status", []int32{1, 2}).Order("id desc").Limit(10).Find(&orders)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// ErrUnboundedQuery 没有 Limit 的 Find 查询了超过 MaxUnboundedRows 的表
+var ErrUnboundedQuery = errors.New("unbounded query")
+
+// ErrTruncated Find 的结果超过了 MaxRows，只返回了前 MaxRows 行
+var ErrTruncated = errors.New("result truncated")
+
+type tableRows struct {
+	rows     int64
+	expireAt time.Time
+}
+
+// TableRows 表的估算行数，结果缓存 TableStatsTTL
+func (p *Client) TableRows(table string) (int64, error) {
+	if v, ok := p.tableRows.Load(table); ok {
+		x := v.(*tableRows)
+		if time.Now().Before(x.expireAt) {
+			return x.rows, nil
+		}
+	}
+
+	stats, err := p.TableStats(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return 0, err
+	}
+
+	var rows int64
+	if len(stats) > 0 {
+		rows = stats[0].Rows
+	}
+
+	p.tableRows.Store(table, &tableRows{
+		rows:     rows,
+		expireAt: time.Now().Add(p.tableStatsTTL),
+	})
+
+	return rows, nil
+}
+
+// Unbounded 标记有意不带 Limit 的查询，不受 MaxUnboundedRows 的限制
+func (p *Scoop) Unbounded() *Scoop {
+	p.unbounded = true
+	return p
+}
+
+// MaxRows 限制 Find 返回的行数，超过时返回前 n 行与 ErrTruncated，同时视为带有 Limit 的查询
+func (p *Scoop) MaxRows(n uint64) *Scoop {
+	p.maxRows = n
+	return p
+}
+
+func (p *Scoop) findMaxRows() uint64 {
+	if p.maxRows > 0 {
+		return p.maxRows
+	}
+
+	if c := getClientByDB(p._db); c != nil {
+		return c.maxRows
+	}
+
+	return 0
+}
+
+// 由 Find 调用，skip 指向业务代码的调用处
+func (p *Scoop) checkUnbounded(skip int) error {
+	if p.limit > 0 || p.unbounded || p.maxRows > 0 {
+		return nil
+	}
+
+	c := getClientByDB(p._db)
+	if c == nil || c.maxUnboundedRows <= 0 {
+		return nil
+	}
+
+	// 统计信息获取失败时不影响查询
+	rows, err := c.TableRows(p.table)
+	if err != nil || rows <= c.maxUnboundedRows {
+		return nil
+	}
+
+	var caller string
+	if _, file, line, ok := runtime.Caller(skip); ok {
+		caller = file + ":" + strconv.Itoa(line)
+	}
+
+	if c.rejectUnbounded {
+		log.Errorf("reject unbounded query on %s, rows:%d, caller:%s", p.table, rows, caller)
+		return fmt.Errorf("%w: table %s has about %d rows", ErrUnboundedQuery, p.table, rows)
+	}
+
+	log.Warnf("unbounded query on %s, rows:%d, caller:%s", p.table, rows, caller)
+	return nil
+}
@@ -0,0 +1,115 @@
+package db_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type guardItem struct {
+	Id int64 `gorm:"primaryKey"`
+}
+
+func (guardItem) TableName() string {
+	return "guard_item"
+}
+
+func TestUnboundedQuery(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address:          t.TempDir(),
+		Name:             "guard",
+		MaxUnboundedRows: 2,
+		RejectUnbounded:  true,
+	}, &guardItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		err = cli.NewScoop().Create(&guardItem{Id: i}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	_, err = db.NewModelScoop[guardItem](cli.Database()).Find()
+	if !errors.Is(err, db.ErrUnboundedQuery) {
+		t.Fatalf("expected ErrUnboundedQuery, got %v", err)
+	}
+
+	items, err := db.NewModelScoop[guardItem](cli.Database()).Limit(10).Find()
+	if err != nil || len(items) != 3 {
+		t.Errorf("items:%d, err:%v", len(items), err)
+	}
+
+	items, err = db.NewModelScoop[guardItem](cli.Database()).Unbounded().Find()
+	if err != nil || len(items) != 3 {
+		t.Errorf("items:%d, err:%v", len(items), err)
+	}
+}
+
+func TestTableStats(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "stats",
+	}, &guardItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := int64(1); i <= 2; i++ {
+		err = cli.NewScoop().Create(&guardItem{Id: i}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	stats, err := cli.TableStats("guard_item", "not_exists")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(stats) != 1 || stats[0].Table != "guard_item" || stats[0].Rows != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	t.Logf("%+v", stats[0])
+}
+
+func TestMaxRows(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "max_rows",
+		MaxRows: 2,
+	}, &guardItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		err = cli.NewScoop().Create(&guardItem{Id: i}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	items, err := db.NewModelScoop[guardItem](cli.Database()).Find()
+	if !errors.Is(err, db.ErrTruncated) || len(items) != 2 {
+		t.Errorf("items:%d, err:%v", len(items), err)
+	}
+
+	var list []*guardItem
+	res := cli.NewScoop().MaxRows(1).Find(&list)
+	if !res.Truncated || len(list) != 1 {
+		t.Errorf("truncated:%v, items:%d", res.Truncated, len(list))
+	}
+
+	items, err = db.NewModelScoop[guardItem](cli.Database()).MaxRows(3).Find()
+	if err != nil || len(items) != 3 {
+		t.Errorf("items:%d, err:%v", len(items), err)
+	}
+
+	// Limit 小于上限时不会截断
+	items, err = db.NewModelScoop[guardItem](cli.Database()).Limit(2).Find()
+	if err != nil || len(items) != 2 {
+		t.Errorf("items:%d, err:%v", len(items), err)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+)
+
+// 主键为 int64/uint64 且为 0 时填充，已经赋值的不会覆盖，生成失败时中止创建
+func fillId(gen func() (int64, error)) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil {
+			return
+		}
+
+		field := tx.Statement.Schema.PrioritizedPrimaryField
+		if field == nil {
+			return
+		}
+
+		switch field.FieldType.Kind() {
+		case reflect.Int64, reflect.Uint64:
+		default:
+			return
+		}
+
+		fill := func(rv reflect.Value) error {
+			if _, zero := field.ValueOf(tx.Statement.Context, rv); !zero {
+				return nil
+			}
+
+			id, err := gen()
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return err
+			}
+
+			return field.Set(tx.Statement.Context, rv, id)
+		}
+
+		rv := tx.Statement.ReflectValue
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				err := fill(reflect.Indirect(rv.Index(i)))
+				if err != nil {
+					_ = tx.AddError(err)
+					return
+				}
+			}
+		case reflect.Struct:
+			err := fill(rv)
+			if err != nil {
+				_ = tx.AddError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidIdentifier 列名、表名中包含了非法字符，需要表达式时使用 SelectRaw、OrderRaw、GroupRaw
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+func isIdentifierPart(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !((c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') ||
+			c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// 去掉已有的引号，只允许 column、table.column 以及 select 中的 * 和 table.*
+func parseIdentifier(name string, allowStar bool) ([]string, error) {
+	parts := strings.Split(strings.TrimSpace(name), ".")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIdentifier, name)
+	}
+
+	for i, part := range parts {
+		if len(part) >= 2 && (part[0] == '`' || part[0] == '"') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+
+		if part == "*" && allowStar && i == len(parts)-1 {
+			parts[i] = part
+			continue
+		}
+
+		if !isIdentifierPart(part) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidIdentifier, name)
+		}
+
+		parts[i] = part
+	}
+
+	return parts, nil
+}
+
+func quoteIdentifier(name string, quote byte, allowStar bool) (string, error) {
+	parts, err := parseIdentifier(name, allowStar)
+	if err != nil {
+		return "", err
+	}
+
+	q := string(quote)
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = q + part + q
+		}
+	}
+
+	return strings.Join(parts, "."), nil
+}
+
+// column [AS] alias
+func quoteSelect(field string, quote byte) (string, error) {
+	words := strings.Fields(field)
+	switch {
+	case len(words) == 1:
+		return quoteIdentifier(words[0], quote, true)
+
+	case len(words) == 2 || (len(words) == 3 && strings.EqualFold(words[1], "AS")):
+		column, err := quoteIdentifier(words[0], quote, false)
+		if err != nil {
+			return "", err
+		}
+
+		alias, err := quoteIdentifier(words[len(words)-1], quote, false)
+		if err != nil {
+			return "", err
+		}
+
+		return column + " AS " + alias, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrInvalidIdentifier, field)
+}
+
+// column [ASC|DESC]
+func quoteOrder(field string, quote byte) (string, error) {
+	words := strings.Fields(field)
+	if len(words) == 0 || len(words) > 2 {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIdentifier, field)
+	}
+
+	column, err := quoteIdentifier(words[0], quote, false)
+	if err != nil {
+		return "", err
+	}
+
+	if len(words) == 1 {
+		return column, nil
+	}
+
+	direction := strings.ToUpper(words[1])
+	if direction != "ASC" && direction != "DESC" {
+		return "", fmt.Errorf("%w: %s", ErrInvalidIdentifier, field)
+	}
+
+	return column + " " + direction, nil
+}
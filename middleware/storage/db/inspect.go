@@ -0,0 +1,295 @@
+package db
+
+import (
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+)
+
+type Schema struct {
+	Tables []*TableSchema
+}
+
+func (p *Schema) Table(name string) *TableSchema {
+	for _, t := range p.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+type TableSchema struct {
+	Name        string
+	Comment     string
+	Columns     []*ColumnSchema
+	Indexes     []*IndexSchema
+	ForeignKeys []*ForeignKeySchema
+}
+
+func (p *TableSchema) Column(name string) *ColumnSchema {
+	for _, c := range p.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (p *TableSchema) Index(name string) *IndexSchema {
+	for _, i := range p.Indexes {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+type ColumnSchema struct {
+	Name string
+	// 数据库中的完整类型，例如 varchar(255)、bigint unsigned
+	Type     string
+	Nullable bool
+
+	// HasDefault 为 false 时没有默认值，Default 为原始的表达式
+	HasDefault bool
+	Default    string
+
+	PrimaryKey    bool
+	AutoIncrement bool
+
+	Comment string
+}
+
+type IndexSchema struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Primary bool
+}
+
+type ForeignKeySchema struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// 绕过预编译缓存，表结构变化后缓存的 SELECT * 依旧返回旧的列
+func (p *Client) noPrepare() *gorm.DB {
+	tx := p.db.Session(&gorm.Session{})
+	if pdb, ok := tx.Statement.ConnPool.(*gorm.PreparedStmtDB); ok {
+		tx.Statement.ConnPool = pdb.ConnPool
+	}
+	return tx
+}
+
+// Inspect 读取数据库当前的表结构，tables 为空时读取全部的表，结果按表名排序
+func (p *Client) Inspect(tables ...string) (*Schema, error) {
+	if len(tables) == 0 {
+		var err error
+		tables, err = p.noPrepare().Migrator().GetTables()
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+	}
+
+	schema := &Schema{}
+	for _, table := range tables {
+		// sqlite 内部使用的表
+		if strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+
+		t, err := p.inspectTable(table)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		schema.Tables = append(schema.Tables, t)
+	}
+
+	sort.Slice(schema.Tables, func(i, j int) bool {
+		return schema.Tables[i].Name < schema.Tables[j].Name
+	})
+
+	return schema, nil
+}
+
+func (p *Client) inspectTable(table string) (*TableSchema, error) {
+	t := &TableSchema{
+		Name: table,
+	}
+
+	columnTypes, err := p.noPrepare().Migrator().ColumnTypes(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	for _, ct := range columnTypes {
+		c := &ColumnSchema{
+			Name: ct.Name(),
+			Type: ct.DatabaseTypeName(),
+		}
+
+		if typ, ok := ct.ColumnType(); ok && typ != "" {
+			c.Type = typ
+		}
+		c.Nullable, _ = ct.Nullable()
+		c.Default, c.HasDefault = ct.DefaultValue()
+		c.PrimaryKey, _ = ct.PrimaryKey()
+		c.AutoIncrement, _ = ct.AutoIncrement()
+		c.Comment, _ = ct.Comment()
+
+		t.Columns = append(t.Columns, c)
+	}
+
+	if p.clientType == "sqlite" {
+		t.Indexes, err = p.sqliteIndexes(table)
+	} else {
+		t.Indexes, err = p.migratorIndexes(table)
+	}
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	sort.Slice(t.Indexes, func(i, j int) bool {
+		return t.Indexes[i].Name < t.Indexes[j].Name
+	})
+
+	t.ForeignKeys, err = p.foreignKeys(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	t.Comment, err = p.inspectTableComment(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (p *Client) migratorIndexes(table string) ([]*IndexSchema, error) {
+	indexes, err := p.noPrepare().Migrator().GetIndexes(table)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	var list []*IndexSchema
+	for _, idx := range indexes {
+		i := &IndexSchema{
+			Name:    idx.Name(),
+			Columns: idx.Columns(),
+		}
+		i.Unique, _ = idx.Unique()
+		i.Primary, _ = idx.PrimaryKey()
+
+		list = append(list, i)
+	}
+
+	return list, nil
+}
+
+// gorm 的 sqlite 驱动会跳过 UNIQUE 约束生成的索引，这里直接读取
+func (p *Client) sqliteIndexes(table string) ([]*IndexSchema, error) {
+	var rows []struct {
+		Name   string
+		Unique bool
+		Origin string
+	}
+	err := p.noPrepare().Raw("SELECT name, `unique`, origin FROM pragma_index_list(?)", table).Scan(&rows).Error
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	var list []*IndexSchema
+	for _, row := range rows {
+		var columns []string
+		err = p.noPrepare().Raw("SELECT name FROM pragma_index_info(?) ORDER BY seqno", row.Name).Scan(&columns).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		list = append(list, &IndexSchema{
+			Name:    row.Name,
+			Columns: columns,
+			Unique:  row.Unique,
+			Primary: row.Origin == "pk",
+		})
+	}
+
+	return list, nil
+}
+
+func (p *Client) foreignKeys(table string) ([]*ForeignKeySchema, error) {
+	var query string
+	switch p.clientType {
+	case "sqlite":
+		// 没有约束名，以 id 区分
+		query = "SELECT CAST(id AS TEXT), `from`, `table`, COALESCE(`to`, '') FROM pragma_foreign_key_list(?) ORDER BY id, seq"
+	case "mysql":
+		query = "SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME " +
+			"FROM information_schema.KEY_COLUMN_USAGE " +
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL " +
+			"ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION"
+	case "postgres", "gaussdb":
+		query = "SELECT c.conname, a.attname, cf.relname, af.attname " +
+			"FROM pg_constraint c " +
+			"JOIN pg_class cl ON cl.oid = c.conrelid " +
+			"JOIN pg_class cf ON cf.oid = c.confrelid " +
+			"CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, n) " +
+			"JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum " +
+			"JOIN pg_attribute af ON af.attrelid = c.confrelid AND af.attnum = k.fattnum " +
+			"WHERE c.contype = 'f' AND cl.relname = ? AND cl.relnamespace = current_schema()::regnamespace " +
+			"ORDER BY c.conname, k.n"
+	default:
+		return nil, nil
+	}
+
+	rows, err := p.noPrepare().Raw(query, table).Rows()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*ForeignKeySchema
+	for rows.Next() {
+		var name, column, refTable, refColumn string
+		err = rows.Scan(&name, &column, &refTable, &refColumn)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		if len(list) == 0 || list[len(list)-1].Name != name {
+			list = append(list, &ForeignKeySchema{
+				Name:     name,
+				RefTable: refTable,
+			})
+		}
+
+		fk := list[len(list)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.RefColumns = append(fk.RefColumns, refColumn)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return list, nil
+}
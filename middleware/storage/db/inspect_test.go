@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type inspectUser struct {
+	Id    int64  `gorm:"primaryKey;autoIncrement"`
+	Email string `gorm:"size:128;not null;uniqueIndex:uk_email"`
+	Name  string `gorm:"default:'guest'"`
+	Age   *int   `gorm:"index:idx_age"`
+}
+
+func (inspectUser) TableName() string {
+	return "inspect_user"
+}
+
+func TestInspect(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "inspect",
+	}, &inspectUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.Database().Exec("CREATE TABLE inspect_order (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES inspect_user(id))").Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	schema, err := cli.Inspect()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	if len(schema.Tables) != 2 || schema.Tables[0].Name != "inspect_order" {
+		t.Fatalf("unexpected tables: %+v", schema.Tables)
+	}
+
+	user := schema.Table("inspect_user")
+	if c := user.Column("id"); c == nil || !c.PrimaryKey {
+		t.Errorf("unexpected id column: %+v", c)
+	}
+	if c := user.Column("email"); c == nil || c.Nullable {
+		t.Errorf("unexpected email column: %+v", c)
+	}
+	if c := user.Column("name"); c == nil || !c.HasDefault {
+		t.Errorf("unexpected name column: %+v", c)
+	}
+	if i := user.Index("uk_email"); i == nil || !i.Unique || len(i.Columns) != 1 || i.Columns[0] != "email" {
+		t.Errorf("unexpected uk_email index: %+v", i)
+	}
+	if i := user.Index("idx_age"); i == nil || i.Unique {
+		t.Errorf("unexpected idx_age index: %+v", i)
+	}
+
+	order := schema.Table("inspect_order")
+	if len(order.ForeignKeys) != 1 || order.ForeignKeys[0].RefTable != "inspect_user" || order.ForeignKeys[0].Columns[0] != "user_id" {
+		t.Errorf("unexpected foreign keys: %+v", order.ForeignKeys)
+	}
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// 少于该数量时直接使用 IN
+	inTempThreshold = 1000
+
+	// 每条 INSERT 写入的行数，避免超过占位符数量的限制
+	inTempBatchSize = 500
+)
+
+var (
+	inTempSeq atomic.Uint64
+
+	// 事务 -> 需要在事务结束前删除的临时表
+	inTempTables sync.Map
+)
+
+// InTemp 与 In 相同，值较多时在当前事务中将去重后的值写入临时表，通过 IN (SELECT ...) 与临时表关联，代替超长的 IN 列表
+// 临时表在 Commit、Rollback 时删除，不在事务中、数据库不支持临时表或者值的类型不是整数与字符串时退化为 In
+func (p *Scoop) InTemp(column string, values interface{}) *Scoop {
+	vo := EnsureIsSliceOrArray(values)
+	if vo.Len() < inTempThreshold {
+		return p.In(column, values)
+	}
+
+	d := getDialectByDB(p._db)
+	tx, ok := p._db.Statement.ConnPool.(gorm.TxCommitter)
+	if !ok || d.CreateTempTable == "" {
+		return p.In(column, values)
+	}
+
+	vo = reflect.ValueOf(UniqueSlice(vo.Interface()))
+	typ := tempColumnType(vo)
+	if typ == "" {
+		return p.In(column, values)
+	}
+
+	quoted, err := quoteIdentifier(column, p.quote(), false)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.setErr(err)
+		return p
+	}
+
+	p.inc()
+	defer p.dec()
+
+	name := "lrpc_in_" + strconv.FormatUint(inTempSeq.Add(1), 10)
+	err = p.loadTemp(d, tx, name, typ, vo)
+	if err != nil {
+		p.setErr(err)
+		return p
+	}
+
+	p.cond.whereRaw(quoted + " IN (SELECT v FROM " + name + ")")
+	return p
+}
+
+// 临时表的列类型，字符串超过 255 时不使用临时表
+func tempColumnType(vo reflect.Value) string {
+	switch vo.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "BIGINT"
+
+	case reflect.String:
+		for i := 0; i < vo.Len(); i++ {
+			if vo.Index(i).Len() > 255 {
+				return ""
+			}
+		}
+		return "VARCHAR(255)"
+
+	default:
+		return ""
+	}
+}
+
+func (p *Scoop) loadTemp(d *Dialect, tx gorm.TxCommitter, name, typ string, vo reflect.Value) error {
+	_, err := p.execReturning(p._db, fmt.Sprintf(d.CreateTempTable, name, typ), nil)
+	if err != nil {
+		return err
+	}
+
+	if d.DropTempTable != "" {
+		list, _ := inTempTables.LoadOrStore(tx, &[]string{})
+		*list.(*[]string) = append(*list.(*[]string), name)
+	}
+
+	for i := 0; i < vo.Len(); i += inTempBatchSize {
+		end := i + inTempBatchSize
+		if end > vo.Len() {
+			end = vo.Len()
+		}
+
+		sqlRaw := log.GetBuffer()
+		sqlRaw.WriteString("INSERT INTO ")
+		sqlRaw.WriteString(name)
+		sqlRaw.WriteString(" (v) VALUES (?)")
+
+		values := make([]interface{}, 0, end-i)
+		for j := i; j < end; j++ {
+			if j > i {
+				sqlRaw.WriteString(",(?)")
+			}
+			values = append(values, vo.Index(j).Interface())
+		}
+
+		_, err = p.execReturning(p._db, sqlRaw.String(), values)
+		log.PutBuffer(sqlRaw)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 事务结束前删除 InTemp 创建的临时表，同一个连接之后还会被复用
+func (p *Scoop) dropTempTables() {
+	tx, ok := p._db.Statement.ConnPool.(gorm.TxCommitter)
+	if !ok {
+		return
+	}
+
+	list, ok := inTempTables.LoadAndDelete(tx)
+	if !ok {
+		return
+	}
+
+	d := getDialectByDB(p._db)
+	for _, name := range *list.(*[]string) {
+		err := p._db.Exec(fmt.Sprintf(d.DropTempTable, name)).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+type inTempItem struct {
+	Id        int64 `gorm:"primaryKey"`
+	Name      string
+	DeletedAt int64
+}
+
+func (inTempItem) TableName() string {
+	return "in_temp_item"
+}
+
+func TestInTemp(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "in_temp",
+	}, &inTempItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		err = cli.NewScoop().Create(&inTempItem{Id: int64(i), Name: "item"}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	ids := []int64{2, 4, 4}
+	for i := 0; i < 1500; i++ {
+		ids = append(ids, int64(i+100))
+	}
+
+	model := db.NewModel[inTempItem](cli)
+
+	for i := 0; i < 2; i++ {
+		tx := cli.NewScoop().Begin()
+
+		list, err := model.NewScoop(tx).InTemp("id", ids).Find()
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		if len(list) != 2 || list[0].Id+list[1].Id != 6 {
+			t.Fatalf("unexpected list: %+v", list)
+		}
+
+		res := model.NewScoop(tx).InTemp("id", ids).Updates(map[string]any{"name": "updated"})
+		if res.Error != nil || res.RowsAffected != 2 {
+			t.Fatalf("rows:%d, err:%v", res.RowsAffected, res.Error)
+		}
+
+		tx.Commit()
+	}
+
+	// 不在事务中时退化为 In
+	cnt, err := model.NewScoop().InTemp("id", ids).Count()
+	if err != nil || cnt != 2 {
+		t.Fatalf("count:%d, err:%v", cnt, err)
+	}
+}
+
+// 记录执行的语句，查询总是返回错误
+type recordConnPool struct {
+	sqls []string
+}
+
+func (p *recordConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *recordConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.sqls = append(p.sqls, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (p *recordConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.sqls = append(p.sqls, query)
+	return nil, errors.New("not supported")
+}
+
+func (p *recordConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	p.sqls = append(p.sqls, query)
+	return nil
+}
+
+func (p *recordConnPool) Commit() error {
+	return nil
+}
+
+func (p *recordConnPool) Rollback() error {
+	return nil
+}
+
+func TestInTempPostgres(t *testing.T) {
+	pool := &recordConnPool{}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: pool,
+	}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	ids := make([]int64, 1500)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	_, _ = db.NewModelScoop[inTempItem](gdb).InTemp("id", ids).Find()
+
+	for _, s := range pool.sqls {
+		if strings.Contains(s, "`") {
+			t.Errorf("unexpected quote: %s", s)
+		}
+	}
+
+	last := pool.sqls[len(pool.sqls)-1]
+	if !strings.Contains(last, `"id" IN (SELECT v FROM lrpc_in_`) {
+		t.Errorf("unexpected sql: %s", last)
+	}
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/routine"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+var ErrLeaderNotSupport = errors.New("leader election not support")
+
+type LeaderConfig struct {
+	// 检查会话是否存活的间隔，默认 5s
+	KeepAlive time.Duration
+
+	// 连接断开导致锁丢失时回调，Release 不会触发
+	OnLost func(name string)
+}
+
+func (c *LeaderConfig) apply() {
+	if c.KeepAlive == 0 {
+		c.KeepAlive = time.Second * 5
+	}
+}
+
+// Leader 基于数据库的会话级锁(mysql GET_LOCK、postgres advisory lock)，锁与连接绑定，连接断开后自动释放
+type Leader struct {
+	name string
+	c    *LeaderConfig
+
+	dialect string
+	conn    *sql.Conn
+
+	lock   sync.Mutex
+	leader bool
+	stop   chan struct{}
+}
+
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func (p *Leader) tryLock(ctx context.Context) (bool, error) {
+	var ok bool
+	var err error
+	switch p.dialect {
+	case "mysql":
+		var res sql.NullInt64
+		err = p.conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", p.name).Scan(&res)
+		ok = res.Valid && res.Int64 == 1
+	case "postgres", "gaussdb":
+		err = p.conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", advisoryKey(p.name)).Scan(&ok)
+	default:
+		return false, ErrLeaderNotSupport
+	}
+
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return false, err
+	}
+
+	return ok, nil
+}
+
+func (p *Leader) unlock(ctx context.Context) error {
+	var err error
+	switch p.dialect {
+	case "mysql":
+		_, err = p.conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", p.name)
+	case "postgres", "gaussdb":
+		_, err = p.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", advisoryKey(p.name))
+	}
+
+	return err
+}
+
+// 关闭连接且不归还到连接池，会话结束时数据库会释放锁，避免仍然持有锁的连接被复用
+func (p *Leader) discard() {
+	_ = p.conn.Raw(func(driverConn any) error {
+		return driver.ErrBadConn
+	})
+}
+
+func (p *Leader) keepAlive() {
+	ticker := time.NewTicker(p.c.KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.c.KeepAlive)
+		err := p.conn.PingContext(ctx)
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		p.lock.Lock()
+		// 已经主动释放
+		if !p.leader {
+			p.lock.Unlock()
+			return
+		}
+		p.leader = false
+		p.lock.Unlock()
+
+		log.Errorf("leader %s lost, err:%v", p.name, err)
+
+		p.discard()
+
+		if p.c.OnLost != nil {
+			p.c.OnLost(p.name)
+		}
+		return
+	}
+}
+
+// TryAcquire 尝试成为 name 的 leader，没有抢到时返回 false，需要调用方定期重试
+func (p *Client) TryAcquire(name string, configs ...*LeaderConfig) (*Leader, bool, error) {
+	c := &LeaderConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	d := p.Dialect().Name
+	if d != "mysql" && d != "postgres" && d != "gaussdb" {
+		return nil, false, ErrLeaderNotSupport
+	}
+
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.KeepAlive)
+	defer cancel()
+
+	// 锁是会话级的，需要独占一个连接
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, false, err
+	}
+
+	l := &Leader{
+		name:    name,
+		c:       c,
+		dialect: d,
+		conn:    conn,
+		stop:    make(chan struct{}),
+	}
+
+	ok, err := l.tryLock(ctx)
+	if err != nil {
+		// 超时等情况下不确定是否已经拿到锁
+		l.discard()
+		return nil, false, err
+	}
+	if !ok {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	l.leader = true
+
+	routine.GoWithRecover(func() error {
+		l.keepAlive()
+		return nil
+	})
+
+	return l, true, nil
+}
+
+func (p *Leader) IsLeader() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.leader
+}
+
+// Release 主动释放锁并归还连接，释放失败时关闭连接
+func (p *Leader) Release() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.leader {
+		return nil
+	}
+	p.leader = false
+	close(p.stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.c.KeepAlive)
+	defer cancel()
+
+	err := p.unlock(ctx)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.discard()
+		return err
+	}
+
+	return p.conn.Close()
+}
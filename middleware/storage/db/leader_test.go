@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 只实现 Leader 用到的 Exec，unlock 的结果由 unlockErr 决定
+type leaderConnector struct {
+	unlockErr error
+	closed    int
+}
+
+func (p *leaderConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &leaderConn{connector: p}, nil
+}
+
+func (p *leaderConnector) Driver() driver.Driver {
+	return nil
+}
+
+type leaderConn struct {
+	connector *leaderConnector
+}
+
+func (p *leaderConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *leaderConn) Close() error {
+	p.connector.closed++
+	return nil
+}
+
+func (p *leaderConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *leaderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), p.connector.unlockErr
+}
+
+func TestLeaderRelease(t *testing.T) {
+	for _, unlockErr := range []error{nil, errors.New("connection reset")} {
+		connector := &leaderConnector{unlockErr: unlockErr}
+		sqlDB := sql.OpenDB(connector)
+
+		conn, err := sqlDB.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		l := &Leader{
+			name:    "job",
+			c:       &LeaderConfig{KeepAlive: time.Second},
+			dialect: "mysql",
+			conn:    conn,
+			leader:  true,
+			stop:    make(chan struct{}),
+		}
+
+		err = l.Release()
+		if (err != nil) != (unlockErr != nil) {
+			t.Errorf("unlock err:%v, err:%v", unlockErr, err)
+		}
+
+		// 释放失败时连接可能仍然持有锁，不能归还到连接池
+		want := 0
+		if unlockErr != nil {
+			want = 1
+		}
+		if connector.closed != want || sqlDB.Stats().Idle != 1-want {
+			t.Errorf("unlock err:%v, closed:%d, idle:%d", unlockErr, connector.closed, sqlDB.Stats().Idle)
+		}
+
+		_ = sqlDB.Close()
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/anyx"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+
+	mysqlC "github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrDeadlock    = errors.New("deadlock")
+	ErrLockTimeout = errors.New("lock wait timeout")
+)
+
+// LockError 死锁或者等待锁超时，配置了 LockDiagnostics 时附带数据库当时的锁信息
+type LockError struct {
+	Err error
+	// ErrDeadlock 或者 ErrLockTimeout
+	Kind error
+
+	// 采集失败、被限流或者数据库不支持时为空
+	Diagnostics string
+}
+
+func (p *LockError) Error() string {
+	return p.Kind.Error() + ": " + p.Err.Error()
+}
+
+func (p *LockError) Unwrap() error {
+	return p.Err
+}
+
+func (p *LockError) Is(target error) bool {
+	return target == p.Kind
+}
+
+var (
+	deadlockErrors = []string{
+		// postgres deadlock_detected
+		"sqlstate 40p01",
+		// sqlserver 1205
+		"was deadlocked on lock",
+		"deadlock found",
+	}
+	lockTimeoutErrors = []string{
+		// postgres lock_not_available
+		"sqlstate 55p03",
+		// sqlserver 1222
+		"lock request time out period exceeded",
+		"lock wait timeout exceeded",
+		// sqlite SQLITE_BUSY
+		"database is locked",
+	}
+)
+
+// lockErrorKind 返回 ErrDeadlock、ErrLockTimeout，不是锁相关的错误时返回 nil
+func lockErrorKind(err error) error {
+	var mysqlErr *mysqlC.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1213:
+			return ErrDeadlock
+		case 1205:
+			return ErrLockTimeout
+		default:
+			return nil
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, x := range deadlockErrors {
+		if strings.Contains(msg, x) {
+			return ErrDeadlock
+		}
+	}
+	for _, x := range lockTimeoutErrors {
+		if strings.Contains(msg, x) {
+			return ErrLockTimeout
+		}
+	}
+
+	return nil
+}
+
+// IsLockErr 判断是否是死锁或者等待锁超时，一般可以重试整个事务
+func IsLockErr(err error) bool {
+	return err != nil && lockErrorKind(err) != nil
+}
+
+// 语句执行失败后将锁相关的错误替换为 LockError
+func captureLockError(tx *gorm.DB) {
+	if tx.Error == nil {
+		return
+	}
+
+	var lockErr *LockError
+	if errors.As(tx.Error, &lockErr) {
+		return
+	}
+
+	kind := lockErrorKind(tx.Error)
+	if kind == nil {
+		return
+	}
+
+	lockErr = &LockError{
+		Err:  tx.Error,
+		Kind: kind,
+	}
+
+	if c := getClientByDB(tx); c != nil && c.claimLockDiagnostics() {
+		lockErr.Diagnostics = c.lockDiagnostics(kind)
+		if lockErr.Diagnostics != "" {
+			log.Warnf("%s, sql:%s, diagnostics:\n%s", lockErr.Error(), tx.Statement.SQL.String(), lockErr.Diagnostics)
+		}
+	}
+
+	tx.Error = lockErr
+}
+
+// 限制采集的频率，采集的语句本身也有开销
+func (p *Client) claimLockDiagnostics() bool {
+	now := time.Now().UnixNano()
+	last := p.lockDiagnosedAt.Load()
+	if last > 0 && time.Duration(now-last) < p.lockDiagnosticsInterval {
+		return false
+	}
+	return p.lockDiagnosedAt.CompareAndSwap(last, now)
+}
+
+// 最多保留的诊断信息长度
+const maxLockDiagnostics = 8 << 10
+
+// 在新的连接上执行 Dialect.LockDiagnostics，当前连接可能处于已经失败的事务中
+func (p *Client) lockDiagnostics(kind error) string {
+	query := p.Dialect().LockDiagnostics
+	if query == "" {
+		return ""
+	}
+
+	conn, err := p.db.DB()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+
+	values := make([]any, len(cols))
+	scanArgs := make([]any, len(cols))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	var b strings.Builder
+	for rows.Next() && b.Len() < maxLockDiagnostics {
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return ""
+		}
+
+		for i, col := range cols {
+			v := values[i]
+			if x, ok := v.([]byte); ok {
+				v = string(x)
+			}
+
+			// SHOW ENGINE INNODB STATUS 只保留相关的部分
+			if strings.EqualFold(col, "Status") {
+				section := "TRANSACTIONS"
+				if kind == ErrDeadlock {
+					section = "LATEST DETECTED DEADLOCK"
+				}
+				b.WriteString(innodbSection(anyx.ToString(v), section))
+				continue
+			}
+
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(col)
+			b.WriteByte('=')
+			b.WriteString(anyx.ToString(v))
+		}
+		b.WriteByte('\n')
+	}
+
+	s := b.String()
+	if len(s) > maxLockDiagnostics {
+		s = s[:maxLockDiagnostics]
+	}
+	return strings.TrimSpace(s)
+}
+
+// innodb status 的每一部分以 ---- 包围的标题开始
+func innodbSection(status, name string) string {
+	lines := strings.Split(status, "\n")
+	isRule := func(i int) bool {
+		return i < len(lines) && len(lines[i]) > 0 && strings.Trim(lines[i], "-") == ""
+	}
+
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == name && isRule(i+1) {
+			start = i + 2
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+
+	end := len(lines)
+	for i := start; i < len(lines); i++ {
+		if isRule(i) && isRule(i+2) {
+			end = i
+			break
+		}
+	}
+
+	return strings.Join(lines[start:end], "\n")
+}
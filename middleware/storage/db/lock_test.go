@@ -0,0 +1,42 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+
+	mysqlC "github.com/go-sql-driver/mysql"
+)
+
+func TestLockError(t *testing.T) {
+	cases := []struct {
+		err  error
+		lock bool
+	}{
+		{&mysqlC.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{&mysqlC.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&mysqlC.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{fmt.Errorf("ERROR: deadlock detected (SQLSTATE 40P01)"), true},
+		{fmt.Errorf("ERROR: canceling statement due to lock timeout (SQLSTATE 55P03)"), true},
+		{errors.New("record not found"), false},
+	}
+	for _, c := range cases {
+		if db.IsLockErr(c.err) != c.lock {
+			t.Errorf("IsLockErr(%v) should be %v", c.err, c.lock)
+		}
+	}
+
+	err := fmt.Errorf("update order: %w", &db.LockError{
+		Err:  cases[0].err,
+		Kind: db.ErrDeadlock,
+	})
+	if !errors.Is(err, db.ErrDeadlock) || errors.Is(err, db.ErrLockTimeout) {
+		t.Errorf("unexpected kind: %v", err)
+	}
+
+	var mysqlErr *mysqlC.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1213 {
+		t.Errorf("should unwrap to the driver error: %v", err)
+	}
+}
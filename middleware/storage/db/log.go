@@ -3,10 +3,14 @@ package db
 import (
 	"context"
 	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/redact"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"io"
 	"path"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gookit/color"
@@ -15,11 +19,17 @@ import (
 
 type Logger struct {
 	logger *log.Logger
+
+	// gorm 的日志级别，运行时通过 LogMode 修改，不修改 logger 本身的级别，避免与输出日志并发
+	level atomic.Int32
 }
 
 var (
 	syncOnce sync.Once
 	_logger  *Logger
+
+	silentOnce    sync.Once
+	_silentLogger *Logger
 )
 
 func getDefaultLogger() *Logger {
@@ -29,39 +39,83 @@ func getDefaultLogger() *Logger {
 	return _logger
 }
 
+func getSilentLogger() *Logger {
+	silentOnce.Do(func() {
+		_silentLogger = NewLogger()
+		_silentLogger.LogMode(logger.Silent)
+	})
+	return _silentLogger
+}
+
+// 支持 silent、error、warn、info，其他值视为 info
+func parseLogLevel(level string) logger.LogLevel {
+	switch strings.ToLower(level) {
+	case "silent":
+		return logger.Silent
+	case "error":
+		return logger.Error
+	case "warn", "warning":
+		return logger.Warn
+	default:
+		return logger.Info
+	}
+}
+
 func NewLogger() *Logger {
 	l := &Logger{
-		logger: log.Clone().SetCallerDepth(5),
+		logger: log.Clone().SetCallerDepth(5).SetLevel(log.TraceLevel),
 	}
 	l.LogMode(logger.Info)
 	return l
 }
 
+// 复制一份带有前缀的日志，用于输出请求的信息
+func (l *Logger) withPrefix(prefix []byte) *Logger {
+	x := l.logger.Clone()
+	x.PrefixMsg = prefix
+	p := &Logger{
+		logger: x,
+	}
+	p.level.Store(l.level.Load())
+	return p
+}
+
+func (l *Logger) SetOutput(writes ...io.Writer) *Logger {
+	l.logger.SetOutput(writes...)
+	return l
+}
+
 func (l *Logger) LogMode(logLevel logger.LogLevel) logger.Interface {
-	switch logLevel {
-	case logger.Silent:
-		l.logger.SetLevel(log.TraceLevel)
-	case logger.Error:
-		l.logger.SetLevel(log.ErrorLevel)
-	case logger.Warn:
-		l.logger.SetLevel(log.WarnLevel)
-	case logger.Info:
-		l.logger.SetLevel(log.InfoLevel)
-	default:
-		l.logger.SetLevel(log.DebugLevel)
+	// 其他值视为 info
+	if logLevel < logger.Silent || logLevel > logger.Info {
+		logLevel = logger.Info
 	}
+	l.level.Store(int32(logLevel))
 	return l
 }
 
+func (l *Logger) enabled(level logger.LogLevel) bool {
+	return logger.LogLevel(l.level.Load()) >= level
+}
+
 func (l *Logger) Info(ctx context.Context, s string, i ...interface{}) {
+	if !l.enabled(logger.Info) {
+		return
+	}
 	l.logger.Infof(s, i...)
 }
 
 func (l *Logger) Warn(ctx context.Context, s string, i ...interface{}) {
+	if !l.enabled(logger.Warn) {
+		return
+	}
 	l.logger.Warnf(s, i...)
 }
 
 func (l *Logger) Error(ctx context.Context, s string, i ...interface{}) {
+	if !l.enabled(logger.Error) {
+		return
+	}
 	l.logger.Errorf(s, i...)
 }
 
@@ -70,6 +124,15 @@ func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql stri
 }
 
 func (l *Logger) Log(skip int, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if err == nil && !l.enabled(logger.Info) || err != nil && !l.enabled(logger.Error) {
+		return
+	}
+
+	// 数据库故障时同一种错误只输出部分日志
+	if err != nil && !xerror.ShouldLog(err) {
+		return
+	}
+
 	var callerName string
 	pc, file, callerLine, ok := runtime.Caller(skip)
 	if ok {
@@ -86,7 +149,7 @@ func (l *Logger) Log(skip int, begin time.Time, fc func() (sql string, rowsAffec
 	b.WriteString(" ")
 
 	sql, rowsAffected := fc()
-	b.WriteString(strings.ReplaceAll(sql, "\n", " "))
+	b.WriteString(strings.ReplaceAll(redact.SQL(sql), "\n", " "))
 	b.WriteString(" ")
 
 	b.WriteString(color.Blue.Sprintf("[%d rows]", rowsAffected))
@@ -0,0 +1,99 @@
+package db_test
+
+import (
+	"bytes"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/gorm/logger"
+	"sync"
+	"testing"
+)
+
+func TestScoopLogger(t *testing.T) {
+	var b bytes.Buffer
+	l := db.NewLogger().SetOutput(&b)
+
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "log",
+		Logger:  l,
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	b.Reset()
+	var users []*scanUser
+	err = cli.NewScoop().Model(&scanUser{}).Silence().Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("unexpected log: %s", b.String())
+	}
+
+	err = cli.NewScoop().Model(&scanUser{}).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte("scan_user")) {
+		t.Fatalf("missing log: %s", b.String())
+	}
+
+	var other bytes.Buffer
+	b.Reset()
+	err = cli.NewScoop().Model(&scanUser{}).WithLogger(db.NewLogger().SetOutput(&other)).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if b.Len() != 0 || other.Len() == 0 {
+		t.Fatalf("log not redirected, client:%q, scoop:%q", b.String(), other.String())
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	var b bytes.Buffer
+	l := db.NewLogger().SetOutput(&b)
+
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "log",
+		Logger:  l,
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	// 与查询并发修改级别
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			cli.SetLogLevel(logger.Info)
+		}
+	}()
+	var users []*scanUser
+	for i := 0; i < 10; i++ {
+		_ = cli.NewScoop().Model(&scanUser{}).Silence().Find(&users)
+	}
+	wg.Wait()
+
+	cli.SetLogLevel(logger.Silent)
+	b.Reset()
+	err = cli.NewScoop().Model(&scanUser{}).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("unexpected log: %s", b.String())
+	}
+
+	cli.SetLogLevel(logger.Info)
+	err = cli.NewScoop().Model(&scanUser{}).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if !bytes.Contains(b.Bytes(), []byte("scan_user")) {
+		t.Fatalf("missing log: %s", b.String())
+	}
+}
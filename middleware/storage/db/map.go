@@ -0,0 +1,183 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 按列的数据库类型还原为 go 的类型，mysql 的文本协议会把所有值都返回为 []byte
+func decodeColumnValue(ct *sql.ColumnType, v any) any {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+
+	s := string(b)
+	typeName := strings.ToUpper(ct.DatabaseTypeName())
+	switch {
+	case strings.Contains(typeName, "INT"), typeName == "YEAR":
+		if strings.HasPrefix(typeName, "UNSIGNED") {
+			if x, err := strconv.ParseUint(s, 10, 64); err == nil {
+				return x
+			}
+		}
+		if x, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return x
+		}
+
+	case strings.Contains(typeName, "DECIMAL"), strings.Contains(typeName, "NUMERIC"),
+		strings.Contains(typeName, "FLOAT"), strings.Contains(typeName, "DOUBLE"), strings.Contains(typeName, "REAL"):
+		if x, err := strconv.ParseFloat(s, 64); err == nil {
+			return x
+		}
+
+	case strings.Contains(typeName, "BOOL"), typeName == "BIT":
+		if x, err := strconv.ParseBool(s); err == nil {
+			return x
+		}
+
+	case strings.Contains(typeName, "BLOB"), strings.Contains(typeName, "BINARY"), typeName == "BYTEA":
+		return append([]byte(nil), b...)
+	}
+
+	return s
+}
+
+func (p *Scoop) findMaps() ([]map[string]any, error) {
+	if p.table == "" {
+		panic("table name is empty")
+	}
+
+	if !p.unscoped && p.hasDeletedAt {
+		p.cond.whereRaw("deleted_at = 0")
+	}
+	p.applyDefaultScope(p.model)
+	p.applyColumnPolicy()
+	if err := p.buildErr(); err != nil {
+		return nil, err
+	}
+
+	sqlRaw := p.findSql()
+	start := time.Now()
+
+	var rows *sql.Rows
+	err := p.retryRead(func() (err error) {
+		rows, err = p._db.Raw(sqlRaw).Rows()
+		return err
+	})
+	if err != nil {
+		p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+			return sqlRaw, -1
+		}, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.ColumnTypes()
+	if err != nil {
+		p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+			return sqlRaw, -1
+		}, err)
+		return nil, err
+	}
+
+	values := make([]any, len(cols))
+	scanArgs := make([]any, len(cols))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	var out []map[string]any
+	for rows.Next() {
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+				return sqlRaw, int64(len(out))
+			}, err)
+			return nil, err
+		}
+
+		m := make(map[string]any, len(cols))
+		for i, col := range cols {
+			m[col.Name()] = decodeColumnValue(col, values[i])
+		}
+		out = append(out, m)
+	}
+
+	err = rows.Err()
+	p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+		return sqlRaw, int64(len(out))
+	}, err)
+
+	return out, err
+}
+
+// FindMaps 查询结果写入 map，key 为列名(或别名)，适用于聚合、关联等没有对应结构体的查询
+// 需要先通过 Model 指定表，列按数据库类型解码为 int64、uint64、float64、bool、string、[]byte、time.Time，NULL 为 nil
+func (p *Scoop) FindMaps(out *[]map[string]any) *FindResult {
+	if err := p.buildErr(); err != nil {
+		return &FindResult{
+			Error: err,
+		}
+	}
+
+	if p.cond.skip {
+		return &FindResult{}
+	}
+
+	p.inc()
+	defer p.dec()
+
+	rows, err := p.findMaps()
+	if err != nil {
+		return &FindResult{
+			Error: err,
+		}
+	}
+
+	*out = append(*out, rows...)
+
+	return &FindResult{
+		RowsAffected: int64(len(rows)),
+	}
+}
+
+// FirstMap 与 FindMaps 相同，只取第一行，没有数据时返回 NotFound
+func (p *Scoop) FirstMap(out *map[string]any) *FirstResult {
+	if err := p.buildErr(); err != nil {
+		return &FirstResult{
+			Error: err,
+		}
+	}
+
+	if p.cond.skip {
+		return &FirstResult{
+			Error: p.getNotFoundError(),
+		}
+	}
+
+	p.offset = 0
+	p.limit = 1
+
+	p.inc()
+	defer p.dec()
+
+	rows, err := p.findMaps()
+	if err != nil {
+		return &FirstResult{
+			Error: err,
+		}
+	}
+
+	if len(rows) == 0 {
+		return &FirstResult{
+			Error: p.getNotFoundError(),
+		}
+	}
+
+	*out = rows[0]
+
+	return &FirstResult{}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/lazygophers/utils/anyx"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MaskRule 导出时替换列的值，value 为数据库返回的原始值，NULL 为 nil，返回 nil 时输出 NULL
+type MaskRule func(value any) any
+
+// MaskNull 输出 NULL
+func MaskNull() MaskRule {
+	return func(value any) any {
+		return nil
+	}
+}
+
+// MaskReplace 替换为固定的值，NULL 保持不变
+func MaskReplace(v any) MaskRule {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+		return v
+	}
+}
+
+// MaskHash 替换为加盐的哈希，相同的值结果相同，导出的数据之间依旧可以关联
+func MaskHash(salt string) MaskRule {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+
+		sum := sha256.Sum256([]byte(salt + anyx.ToString(value)))
+		return hex.EncodeToString(sum[:8])
+	}
+}
+
+// MaskPartial 保留前 prefix 与后 suffix 个字符，其余替换为 *，例如手机号、证件号
+func MaskPartial(prefix, suffix int) MaskRule {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+
+		rs := []rune(anyx.ToString(value))
+		// 长度不够时全部替换
+		if len(rs) <= prefix+suffix {
+			return strings.Repeat("*", len(rs))
+		}
+
+		for i := prefix; i < len(rs)-suffix; i++ {
+			rs[i] = '*'
+		}
+		return string(rs)
+	}
+}
+
+// MaskEmail 只保留用户名的首字母与域名
+func MaskEmail() MaskRule {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+
+		s := anyx.ToString(value)
+		at := strings.LastIndexByte(s, '@')
+		if at <= 0 {
+			return strings.Repeat("*", len([]rune(s)))
+		}
+
+		return string([]rune(s)[:1]) + "***" + s[at:]
+	}
+}
+
+// MaskFake 按原值生成替换的数据，seed 由原值计算，相同的值结果相同
+func MaskFake(gen func(seed uint64) any) MaskRule {
+	return func(value any) any {
+		if value == nil {
+			return nil
+		}
+
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(anyx.ToString(value)))
+		return gen(h.Sum64())
+	}
+}
+
+var (
+	maskLock  sync.RWMutex
+	maskRules = map[string]map[string]MaskRule{}
+)
+
+// RegisterMaskRules 注册模型导出时的脱敏规则，key 为列名，Export 时总是生效，ExportConfig 只能追加规则
+//
+//	db.RegisterMaskRules(&User{}, map[string]db.MaskRule{
+//		"email": db.MaskEmail(),
+//		"phone": db.MaskPartial(3, 4),
+//		"password": db.MaskNull(),
+//	})
+func RegisterMaskRules(model any, rules map[string]MaskRule) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	m := make(map[string]MaskRule, len(rules))
+	for column, rule := range rules {
+		m[strings.ToLower(column)] = rule
+	}
+
+	maskLock.Lock()
+	defer maskLock.Unlock()
+
+	maskRules[getTableName(rt)] = m
+}
+
+// 合并注册的规则与额外的规则，注册的规则优先
+func getMaskRules(table string, extra map[string]MaskRule) map[string]MaskRule {
+	maskLock.RLock()
+	registered := maskRules[table]
+	maskLock.RUnlock()
+
+	m := make(map[string]MaskRule, len(registered)+len(extra))
+	for column, rule := range extra {
+		m[strings.ToLower(column)] = rule
+	}
+	for column, rule := range registered {
+		m[column] = rule
+	}
+	return m
+}
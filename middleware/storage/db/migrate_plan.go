@@ -0,0 +1,343 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"sort"
+	"strings"
+	"time"
+)
+
+type MigrationSafety int
+
+const (
+	// MigrationSafe 可以在线执行，不阻塞读写
+	MigrationSafe MigrationSafety = iota
+	// MigrationLocking 执行期间可能锁表、重写表，或者在已有数据时失败
+	MigrationLocking
+	// MigrationDestructive 会丢失数据
+	MigrationDestructive
+)
+
+func (s MigrationSafety) String() string {
+	switch s {
+	case MigrationSafe:
+		return "safe"
+	case MigrationLocking:
+		return "locking"
+	case MigrationDestructive:
+		return "destructive"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type MigrationStep struct {
+	Table  string
+	Safety MigrationSafety
+	// 为什么需要这一步，以及为什么是这个安全级别
+	Reason string
+	SQL    []string
+}
+
+type MigrationPlan struct {
+	Steps []*MigrationStep
+}
+
+// Safety 返回所有步骤中最高的级别
+func (p *MigrationPlan) Safety() MigrationSafety {
+	s := MigrationSafe
+	for _, step := range p.Steps {
+		if step.Safety > s {
+			s = step.Safety
+		}
+	}
+	return s
+}
+
+// String 输出可以直接 review 的 sql
+func (p *MigrationPlan) String() string {
+	var b strings.Builder
+	for _, step := range p.Steps {
+		b.WriteString(fmt.Sprintf("-- [%s] %s\n", step.Safety, step.Reason))
+		for _, s := range step.SQL {
+			b.WriteString(s)
+			b.WriteString(";\n")
+		}
+	}
+	return b.String()
+}
+
+// 只记录 DryRun 生成的语句，不输出日志
+type captureLogger struct {
+	sqls []string
+}
+
+func (l *captureLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *captureLogger) Info(context.Context, string, ...interface{}) {}
+
+func (l *captureLogger) Warn(context.Context, string, ...interface{}) {}
+
+func (l *captureLogger) Error(context.Context, string, ...interface{}) {}
+
+func (l *captureLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	sql, _ := fc()
+	l.sqls = append(l.sqls, sql)
+}
+
+// 通过 gorm 的 migrator 生成对应数据库的 DDL，但不执行
+func (p *Client) dryRun(logic func(m gorm.Migrator) error) ([]string, error) {
+	l := &captureLogger{}
+	err := logic(p.db.Session(&gorm.Session{
+		DryRun: true,
+		Logger: l,
+	}).Migrator())
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return l.sqls, nil
+}
+
+// PlanMigration 对比 models 与数据库当前的结构，按 建表、加列、改注释、加索引、删索引、删列 的顺序生成 DDL，不会执行
+// 只处理 models 对应的表，不比较已有列的类型
+func (p *Client) PlanMigration(models ...interface{}) (*MigrationPlan, error) {
+	current, err := p.Inspect()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	d := p.Dialect()
+
+	var createTables, addColumns, comments, createIndexes, dropIndexes, dropColumns []*MigrationStep
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: p.db}
+		err = stmt.Parse(model)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+		s := stmt.Schema
+
+		table := current.Table(s.Table)
+		if table == nil {
+			sqls, err := p.dryRun(func(m gorm.Migrator) error {
+				return m.CreateTable(model)
+			})
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			if comment, ok := getTableComment(model); ok && comment != "" {
+				if sql := p.tableCommentSQL(s.Table, comment); sql != "" {
+					sqls = append(sqls, sql)
+				}
+			}
+
+			createTables = append(createTables, &MigrationStep{
+				Table:  s.Table,
+				Safety: MigrationSafe,
+				Reason: "create table " + s.Table,
+				SQL:    sqls,
+			})
+			continue
+		}
+
+		if comment, ok := getTableComment(model); ok && comment != table.Comment {
+			if sql := p.tableCommentSQL(s.Table, comment); sql != "" {
+				comments = append(comments, &MigrationStep{
+					Table:  s.Table,
+					Safety: MigrationSafe,
+					Reason: "change comment of table " + s.Table,
+					SQL:    []string{sql},
+				})
+			}
+		}
+
+		columns := make(map[string]bool, len(s.DBNames))
+		for _, name := range s.DBNames {
+			columns[strings.ToLower(name)] = true
+
+			field := s.LookUpField(name)
+			if field == nil || field.IgnoreMigration {
+				continue
+			}
+
+			if c := table.columnFold(name); c != nil {
+				if field.Comment == "" || field.Comment == c.Comment || d.TableComment == "" {
+					continue
+				}
+
+				step, err := p.planColumnComment(model, s.Table, field)
+				if err != nil {
+					log.Errorf("err:%v", err)
+					return nil, err
+				}
+				comments = append(comments, step)
+				continue
+			}
+
+			sqls, err := p.dryRun(func(m gorm.Migrator) error {
+				return m.AddColumn(model, field.Name)
+			})
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			step := &MigrationStep{
+				Table:  s.Table,
+				Safety: MigrationSafe,
+				Reason: fmt.Sprintf("add column %s.%s", s.Table, name),
+				SQL:    sqls,
+			}
+			if field.NotNull && !field.HasDefaultValue {
+				step.Safety = MigrationLocking
+				step.Reason += ", NOT NULL without default fails on a non-empty table"
+			}
+			addColumns = append(addColumns, step)
+		}
+
+		indexes := s.ParseIndexes()
+		names := make([]string, 0, len(indexes))
+		for name := range indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if table.Index(name) != nil {
+				continue
+			}
+
+			sqls, err := p.dryRun(func(m gorm.Migrator) error {
+				return m.CreateIndex(model, name)
+			})
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			step := &MigrationStep{
+				Table:  s.Table,
+				Safety: MigrationLocking,
+				Reason: fmt.Sprintf("create index %s on %s, blocks writes while building", name, s.Table),
+				SQL:    sqls,
+			}
+			if indexes[name].Class == "UNIQUE" {
+				step.Reason += ", fails on duplicated rows"
+			}
+			createIndexes = append(createIndexes, step)
+		}
+
+		for _, idx := range table.Indexes {
+			if idx.Primary || strings.HasPrefix(idx.Name, "sqlite_autoindex_") {
+				continue
+			}
+			if _, ok := indexes[idx.Name]; ok {
+				continue
+			}
+
+			sqls, err := p.dryRun(func(m gorm.Migrator) error {
+				return m.DropIndex(s.Table, idx.Name)
+			})
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+
+			dropIndexes = append(dropIndexes, &MigrationStep{
+				Table:  s.Table,
+				Safety: MigrationDestructive,
+				Reason: fmt.Sprintf("drop index %s on %s, not defined in model", idx.Name, s.Table),
+				SQL:    sqls,
+			})
+		}
+
+		for _, c := range table.Columns {
+			if columns[strings.ToLower(c.Name)] {
+				continue
+			}
+
+			// sqlite 的 DropColumn 需要读取建表语句重建表，无法 DryRun，直接生成
+			dropColumns = append(dropColumns, &MigrationStep{
+				Table:  s.Table,
+				Safety: MigrationDestructive,
+				Reason: fmt.Sprintf("drop column %s.%s, not defined in model", s.Table, c.Name),
+				SQL:    []string{"ALTER TABLE " + d.QuoteName(s.Table) + " DROP COLUMN " + d.QuoteName(c.Name)},
+			})
+		}
+	}
+
+	plan := &MigrationPlan{}
+	for _, steps := range [][]*MigrationStep{createTables, addColumns, comments, createIndexes, dropIndexes, dropColumns} {
+		plan.Steps = append(plan.Steps, steps...)
+	}
+
+	return plan, nil
+}
+
+// mysql 没有单独修改列注释的语句，需要连同类型一起 MODIFY COLUMN
+func (p *Client) planColumnComment(model any, table string, field *schema.Field) (*MigrationStep, error) {
+	step := &MigrationStep{
+		Table:  table,
+		Safety: MigrationSafe,
+		Reason: fmt.Sprintf("change comment of column %s.%s", table, field.DBName),
+	}
+
+	d := p.Dialect()
+	if d.ColumnComment != "" {
+		step.SQL = []string{p.explain(fmt.Sprintf(d.ColumnComment, d.QuoteName(table), d.QuoteName(field.DBName)), field.Comment)}
+		return step, nil
+	}
+
+	sqls, err := p.dryRun(func(m gorm.Migrator) error {
+		return m.AlterColumn(model, field.Name)
+	})
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	step.SQL = sqls
+	step.Reason += ", redefines the column"
+
+	return step, nil
+}
+
+// ApplyMigration 按顺序执行 plan，存在高于 maxSafety 的步骤时一步都不执行
+func (p *Client) ApplyMigration(plan *MigrationPlan, maxSafety MigrationSafety) error {
+	if s := plan.Safety(); s > maxSafety {
+		return fmt.Errorf("migration plan is %s, exceeds %s", s, maxSafety)
+	}
+
+	for _, step := range plan.Steps {
+		for _, s := range step.SQL {
+			err := p.db.Exec(s).Error
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *TableSchema) columnFold(name string) *ColumnSchema {
+	for _, c := range p.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"strings"
+	"testing"
+)
+
+type planUserV1 struct {
+	Id     int64 `gorm:"primaryKey"`
+	Name   string
+	Legacy string `gorm:"index:idx_legacy"`
+}
+
+func (planUserV1) TableName() string {
+	return "plan_user"
+}
+
+type planUserV2 struct {
+	Id    int64  `gorm:"primaryKey"`
+	Name  string `gorm:"index:idx_name"`
+	Email string `gorm:"not null;default:''"`
+	Age   int    `gorm:"not null"`
+}
+
+func (planUserV2) TableName() string {
+	return "plan_user"
+}
+
+type planOrder struct {
+	Id     int64 `gorm:"primaryKey"`
+	UserId int64 `gorm:"index;comment:buyer"`
+}
+
+func (planOrder) TableName() string {
+	return "plan_order"
+}
+
+// sqlite 不支持注释，不会生成修改注释的步骤
+func (planOrder) TableComment() string {
+	return "orders"
+}
+
+func TestPlanMigration(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "plan",
+	}, &planUserV1{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	plan, err := cli.PlanMigration(&planUserV2{}, &planOrder{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	t.Log("\n" + plan.String())
+
+	var reasons []string
+	for _, step := range plan.Steps {
+		reasons = append(reasons, step.Safety.String()+" "+strings.SplitN(step.Reason, ",", 2)[0])
+	}
+
+	expected := []string{
+		"safe create table plan_order",
+		"safe add column plan_user.email",
+		"locking add column plan_user.age",
+		"locking create index idx_name on plan_user",
+		"destructive drop index idx_legacy on plan_user",
+		"destructive drop column plan_user.legacy",
+	}
+	if strings.Join(reasons, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected plan:\n%s", strings.Join(reasons, "\n"))
+	}
+
+	if plan.Safety() != db.MigrationDestructive {
+		t.Errorf("safety:%s", plan.Safety())
+	}
+
+	err = cli.ApplyMigration(plan, db.MigrationLocking)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	err = cli.ApplyMigration(plan, db.MigrationDestructive)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	plan, err = cli.PlanMigration(&planUserV2{}, &planOrder{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(plan.Steps) != 0 {
+		t.Errorf("unexpected plan:\n%s", plan)
+	}
+}
@@ -1,7 +1,7 @@
 package db
 
 import (
-	"fmt"
+	"context"
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/lrpc/middleware/core"
 	"github.com/lazygophers/utils/anyx"
@@ -21,15 +21,66 @@ func NewModelScoop[M any](db *gorm.DB) *ModelScoop[M] {
 		},
 	}
 
+	scoop.Model(new(M))
 	scoop.inc()
 
 	return scoop
 }
 
+func (p *ModelScoop[M]) WithLogger(l *Logger) *ModelScoop[M] {
+	p.Scoop.WithLogger(l)
+	return p
+}
+
+func (p *ModelScoop[M]) Silence() *ModelScoop[M] {
+	p.Scoop.Silence()
+	return p
+}
+
+func (p *ModelScoop[M]) WithoutDefaultScope() *ModelScoop[M] {
+	p.Scoop.WithoutDefaultScope()
+	return p
+}
+
+func (p *ModelScoop[M]) Tag(tags ...string) *ModelScoop[M] {
+	p.Scoop.Tag(tags...)
+	return p
+}
+
+func (p *ModelScoop[M]) WithContext(ctx context.Context) *ModelScoop[M] {
+	p.Scoop.WithContext(ctx)
+	return p
+}
+
+func (p *ModelScoop[M]) WithRoles(roles ...string) *ModelScoop[M] {
+	p.Scoop.WithRoles(roles...)
+	return p
+}
+
+func (p *ModelScoop[M]) Cascade(b ...bool) *ModelScoop[M] {
+	p.Scoop.Cascade(b...)
+	return p
+}
+
+func (p *ModelScoop[M]) Unbounded() *ModelScoop[M] {
+	p.Scoop.Unbounded()
+	return p
+}
+
+func (p *ModelScoop[M]) MaxRows(n uint64) *ModelScoop[M] {
+	p.Scoop.MaxRows(n)
+	return p
+}
+
 // ——————————条件——————————
 
 func (p *ModelScoop[M]) Select(fields ...string) *ModelScoop[M] {
-	p.selects = append(p.selects, fields...)
+	p.Scoop.Select(fields...)
+	return p
+}
+
+func (p *ModelScoop[M]) SelectRaw(fields ...string) *ModelScoop[M] {
+	p.Scoop.SelectRaw(fields...)
 	return p
 }
 
@@ -58,6 +109,11 @@ func (p *ModelScoop[M]) In(column string, values interface{}) *ModelScoop[M] {
 	return p
 }
 
+func (p *ModelScoop[M]) InTemp(column string, values interface{}) *ModelScoop[M] {
+	p.Scoop.InTemp(column, values)
+	return p
+}
+
 func (p *ModelScoop[M]) NotIn(column string, values interface{}) *ModelScoop[M] {
 	vo := EnsureIsSliceOrArray(values)
 	if vo.Len() == 0 {
@@ -67,6 +123,41 @@ func (p *ModelScoop[M]) NotIn(column string, values interface{}) *ModelScoop[M]
 	return p
 }
 
+func (p *ModelScoop[M]) As(alias string) *ModelScoop[M] {
+	p.Scoop.As(alias)
+	return p
+}
+
+func (p *ModelScoop[M]) EqualColumn(column, other string) *ModelScoop[M] {
+	p.Scoop.EqualColumn(column, other)
+	return p
+}
+
+func (p *ModelScoop[M]) Exists(sub *Scoop) *ModelScoop[M] {
+	p.Scoop.Exists(sub)
+	return p
+}
+
+func (p *ModelScoop[M]) NotExists(sub *Scoop) *ModelScoop[M] {
+	p.Scoop.NotExists(sub)
+	return p
+}
+
+func (p *ModelScoop[M]) IsNull(column string) *ModelScoop[M] {
+	p.Scoop.IsNull(column)
+	return p
+}
+
+func (p *ModelScoop[M]) IsNotNull(column string) *ModelScoop[M] {
+	p.Scoop.IsNotNull(column)
+	return p
+}
+
+func (p *ModelScoop[M]) NullEq(column string, value interface{}) *ModelScoop[M] {
+	p.Scoop.NullEq(column, value)
+	return p
+}
+
 func (p *ModelScoop[M]) Like(column string, value string) *ModelScoop[M] {
 	p.cond.where(column, "LIKE", "%"+value+"%")
 	return p
@@ -98,12 +189,16 @@ func (p *ModelScoop[M]) NotRightLike(column string, value string) *ModelScoop[M]
 }
 
 func (p *ModelScoop[M]) Between(column string, min, max interface{}) *ModelScoop[M] {
-	p.cond.whereRaw(fmt.Sprintf(quoteFieldName(column))+" BETWEEN ? AND ?", min, max)
+	if quoted, ok := p.cond.quoteField(column); ok {
+		p.cond.whereRaw(quoted+" BETWEEN ? AND ?", min, max)
+	}
 	return p
 }
 
 func (p *ModelScoop[M]) NotBetween(column string, min, max interface{}) *ModelScoop[M] {
-	p.cond.whereRaw(fmt.Sprintf(quoteFieldName(column))+" NOT BETWEEN ? AND ?", min, max)
+	if quoted, ok := p.cond.quoteField(column); ok {
+		p.cond.whereRaw(quoted+" NOT BETWEEN ? AND ?", min, max)
+	}
 	return p
 }
 
@@ -127,12 +222,22 @@ func (p *ModelScoop[M]) Offset(offset uint64) *ModelScoop[M] {
 }
 
 func (p *ModelScoop[M]) Group(fields ...string) *ModelScoop[M] {
-	p.groups = append(p.groups, fields...)
+	p.Scoop.Group(fields...)
+	return p
+}
+
+func (p *ModelScoop[M]) GroupRaw(fields ...string) *ModelScoop[M] {
+	p.Scoop.GroupRaw(fields...)
 	return p
 }
 
 func (p *ModelScoop[M]) Order(fields ...string) *ModelScoop[M] {
-	p.orders = append(p.orders, fields...)
+	p.Scoop.Order(fields...)
+	return p
+}
+
+func (p *ModelScoop[M]) OrderRaw(fields ...string) *ModelScoop[M] {
+	p.Scoop.OrderRaw(fields...)
 	return p
 }
 
@@ -147,6 +252,11 @@ func (p *ModelScoop[M]) Ignore(b ...bool) *ModelScoop[M] {
 	return p
 }
 
+func (p *ModelScoop[M]) StrictScan(b ...bool) *ModelScoop[M] {
+	p.Scoop.StrictScan(b...)
+	return p
+}
+
 // ——————————操作——————————
 
 func (p *ModelScoop[M]) First() (*M, error) {
@@ -169,12 +279,42 @@ func (p *ModelScoop[M]) Find() ([]*M, error) {
 	var ms []*M
 	err := p.Scoop.Find(&ms).Error
 	if err != nil {
+		// 被截断时同样返回已经读取的数据
+		if err == ErrTruncated {
+			return ms, err
+		}
 		return nil, err
 	}
 
 	return ms, nil
 }
 
+func (p *ModelScoop[M]) FindMaps() ([]map[string]any, error) {
+	p.inc()
+	defer p.dec()
+
+	var ms []map[string]any
+	err := p.Scoop.FindMaps(&ms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ms, nil
+}
+
+func (p *ModelScoop[M]) FirstMap() (map[string]any, error) {
+	p.inc()
+	defer p.dec()
+
+	var m map[string]any
+	err := p.Scoop.FirstMap(&m).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
 func (p *ModelScoop[M]) Create(m *M) error {
 	p.inc()
 	defer p.dec()
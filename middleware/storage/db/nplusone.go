@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// NPlusOneStats 同一个请求中，同一条语句以不同的参数执行了多次
+type NPlusOneStats struct {
+	// 参数替换为 ? 之后的语句
+	Statement string
+	Count     int
+
+	// 执行语句的业务代码位置，去重
+	Callers []string
+}
+
+type trackerKey struct{}
+
+type queryTracker struct {
+	lock  sync.Mutex
+	stmts map[string]*trackedStmt
+}
+
+type trackedStmt struct {
+	queries  map[string]bool
+	callers  []string
+	reported bool
+}
+
+// TrackQueries 返回的 ctx 通过 Scoop.WithContext 传入后，配置了 NPlusOneThreshold 时记录执行的语句，通常每个请求调用一次
+func TrackQueries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trackerKey{}, &queryTracker{
+		stmts: map[string]*trackedStmt{},
+	})
+}
+
+var (
+	// Cond 通过 strconv.Quote 拼接字符串
+	sqlStringRe = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"\\]|\\.)*"`)
+	sqlNumberRe = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlListRe   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+)
+
+// 将字面量替换为 ?，IN 的列表合并为一个，Scoop 生成的语句参数是直接拼接的
+func normalizeSQL(sql string) string {
+	sql = sqlStringRe.ReplaceAllString(sql, "?")
+	sql = sqlNumberRe.ReplaceAllString(sql, "?")
+	sql = sqlListRe.ReplaceAllString(sql, "(?)")
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// 第一个不在 gorm 以及当前包中的调用位置
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") &&
+			!strings.HasPrefix(frame.Function, "github.com/lazygophers/lrpc/middleware/storage/db.") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
+
+func detectNPlusOne(threshold int, onNPlusOne func(stats *NPlusOneStats)) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if tx.Statement.Context == nil {
+			return
+		}
+
+		tracker, ok := tx.Statement.Context.Value(trackerKey{}).(*queryTracker)
+		if !ok {
+			return
+		}
+
+		sql := tx.Statement.SQL.String()
+		if sql == "" {
+			return
+		}
+
+		query := sql
+		if len(tx.Statement.Vars) > 0 {
+			query += fmt.Sprint(tx.Statement.Vars...)
+		}
+		caller := queryCaller()
+
+		stmt := normalizeSQL(sql)
+
+		tracker.lock.Lock()
+		s, ok := tracker.stmts[stmt]
+		if !ok {
+			s = &trackedStmt{
+				queries: map[string]bool{},
+			}
+			tracker.stmts[stmt] = s
+		}
+
+		s.queries[query] = true
+		if caller != "" && !containsString(s.callers, caller) {
+			s.callers = append(s.callers, caller)
+		}
+
+		if s.reported || len(s.queries) <= threshold {
+			tracker.lock.Unlock()
+			return
+		}
+
+		s.reported = true
+		stats := &NPlusOneStats{
+			Statement: stmt,
+			Count:     len(s.queries),
+			Callers:   append([]string(nil), s.callers...),
+		}
+		tracker.lock.Unlock()
+
+		log.Warnf("possible N+1 query, executed %d times with different arguments, sql:%s, callers:%v", stats.Count, stats.Statement, stats.Callers)
+
+		if onNPlusOne != nil {
+			onNPlusOne(stats)
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package db_test
+
+import (
+	"context"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"strings"
+	"testing"
+)
+
+type nPlusOneItem struct {
+	Id   int64 `gorm:"primaryKey"`
+	Name string
+}
+
+func (nPlusOneItem) TableName() string {
+	return "n_plus_one_item"
+}
+
+func TestNPlusOne(t *testing.T) {
+	var reports []*db.NPlusOneStats
+	cli, err := db.New(&db.Config{
+		Address:           t.TempDir(),
+		Name:              "n_plus_one",
+		NPlusOneThreshold: 3,
+		OnNPlusOne: func(stats *db.NPlusOneStats) {
+			reports = append(reports, stats)
+		},
+	}, &nPlusOneItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i := int64(1); i <= 5; i++ {
+		err = cli.NewScoop().Create(&nPlusOneItem{Id: i, Name: "item"}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	// 没有 TrackQueries 时不记录
+	for i := int64(1); i <= 5; i++ {
+		_, _ = db.NewModelScoop[nPlusOneItem](cli.Database()).Equal("id", i).First()
+	}
+	if len(reports) != 0 {
+		t.Fatalf("unexpected reports: %d", len(reports))
+	}
+
+	ctx := db.TrackQueries(context.Background())
+
+	// 相同的参数不计数
+	for i := 0; i < 5; i++ {
+		_, _ = db.NewModelScoop[nPlusOneItem](cli.Database()).WithContext(ctx).Equal("id", 1).Equal("name", "item").First()
+	}
+	if len(reports) != 0 {
+		t.Fatalf("unexpected reports: %d", len(reports))
+	}
+
+	for i := int64(2); i <= 5; i++ {
+		_, _ = db.NewModelScoop[nPlusOneItem](cli.Database()).WithContext(ctx).Equal("id", i).Equal("name", "item").First()
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+
+	stats := reports[0]
+	if stats.Count != 4 {
+		t.Errorf("expected count 4, got %d", stats.Count)
+	}
+	if !strings.Contains(stats.Statement, "n_plus_one_item") || strings.Contains(stats.Statement, "item\"") {
+		t.Errorf("unexpected statement: %s", stats.Statement)
+	}
+	if len(stats.Callers) != 2 || !strings.Contains(stats.Callers[0], "nplusone_test.go") {
+		t.Errorf("unexpected callers: %v", stats.Callers)
+	}
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PartitionInterval int
+
+const (
+	PartitionDaily PartitionInterval = iota + 1
+	PartitionMonthly
+)
+
+// Partitioner 由需要按时间分区的 model 实现，分区字段为 unix 时间戳（秒）
+//
+// 分区表的读写依旧使用父表，由数据库负责路由，Scoop 不需要做额外处理
+type Partitioner interface {
+	PartitionKey() string
+	PartitionInterval() PartitionInterval
+}
+
+var ErrPartitionNotSupport = errors.New("partition not support")
+
+type Partition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+func (i PartitionInterval) layout() string {
+	switch i {
+	case PartitionDaily:
+		return "20060102"
+	case PartitionMonthly:
+		return "200601"
+	default:
+		panic(fmt.Sprintf("invalid partition interval %d", i))
+	}
+}
+
+func (i PartitionInterval) truncate(t time.Time) time.Time {
+	switch i {
+	case PartitionDaily:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case PartitionMonthly:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		panic(fmt.Sprintf("invalid partition interval %d", i))
+	}
+}
+
+func (i PartitionInterval) next(t time.Time) time.Time {
+	switch i {
+	case PartitionDaily:
+		return t.AddDate(0, 0, 1)
+	case PartitionMonthly:
+		return t.AddDate(0, 1, 0)
+	default:
+		panic(fmt.Sprintf("invalid partition interval %d", i))
+	}
+}
+
+func (i PartitionInterval) partition(table string, t time.Time) *Partition {
+	start := i.truncate(t)
+	return &Partition{
+		Name:  table + "_p" + start.Format(i.layout()),
+		Start: start,
+		End:   i.next(start),
+	}
+}
+
+// 从分区名中解析分区的时间范围
+func (i PartitionInterval) parse(table, name string) (*Partition, bool) {
+	if !strings.HasPrefix(name, table+"_p") {
+		return nil, false
+	}
+
+	start, err := time.ParseInLocation(i.layout(), strings.TrimPrefix(name, table+"_p"), time.Local)
+	if err != nil {
+		return nil, false
+	}
+
+	return &Partition{
+		Name:  name,
+		Start: start,
+		End:   i.next(start),
+	}, true
+}
+
+// 计算从当前时间所在分区开始，到 now+horizon 所在分区为止的全部分区
+func (i PartitionInterval) plan(table string, now time.Time, horizon time.Duration) []*Partition {
+	var list []*Partition
+	end := now.Add(horizon)
+	for t := i.truncate(now); !t.After(end); t = i.next(t) {
+		list = append(list, i.partition(table, t))
+	}
+	return list
+}
+
+func getPartitioner(model any) (Partitioner, string) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	x, ok := reflect.New(rt).Interface().(Partitioner)
+	if !ok {
+		panic(fmt.Sprintf("%s is not implement db.Partitioner", rt.String()))
+	}
+
+	return x, getTableName(rt)
+}
+
+func (p *Client) ListPartitions(model any) ([]*Partition, error) {
+	pt, table := getPartitioner(model)
+
+	var names []string
+	switch p.clientType {
+	case "mysql":
+		err := p.db.Raw("SELECT PARTITION_NAME FROM information_schema.PARTITIONS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL", table).Scan(&names).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+	case "postgres":
+		err := p.db.Raw("SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_class pc ON pc.oid = i.inhparent WHERE pc.relname = ?", table).Scan(&names).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+	default:
+		return nil, ErrPartitionNotSupport
+	}
+
+	var list []*Partition
+	for _, name := range names {
+		if x, ok := pt.PartitionInterval().parse(table, name); ok {
+			list = append(list, x)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+
+	return list, nil
+}
+
+// EnsurePartitions 确保当前时间到 now+horizon 的分区都已存在
+//
+// mysql 的表没有分区时通过 ALTER TABLE ... PARTITION BY RANGE 转换为分区表，会重建整张表，
+// 主键与唯一索引需要包含分区键；已经分区的表需要是 RANGE 分区，且不能存在 MAXVALUE 分区
+// postgres 的父表需要以 PARTITION BY RANGE 的方式创建
+func (p *Client) EnsurePartitions(model any, horizon time.Duration) error {
+	pt, table := getPartitioner(model)
+
+	exists, err := p.ListPartitions(model)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	existMap := make(map[string]bool, len(exists))
+	for _, x := range exists {
+		existMap[x.Name] = true
+	}
+
+	for _, x := range pt.PartitionInterval().plan(table, time.Now(), horizon) {
+		if existMap[x.Name] {
+			continue
+		}
+
+		// mysql 的分区只能追加在最后，postgres 可以补齐中间缺失的分区
+		if p.clientType == "mysql" && len(exists) > 0 && !x.Start.After(exists[len(exists)-1].Start) {
+			continue
+		}
+
+		var sqlRaw string
+		switch p.clientType {
+		case "mysql":
+			names, err := quoteNames(table, x.Name, pt.PartitionKey())
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return err
+			}
+
+			sqlRaw = fmt.Sprintf("ALTER TABLE %s ADD PARTITION (PARTITION %s VALUES LESS THAN (%d))",
+				names[0], names[1], x.End.Unix())
+			if len(exists) == 0 {
+				sqlRaw = fmt.Sprintf("ALTER TABLE %s PARTITION BY RANGE (%s) (PARTITION %s VALUES LESS THAN (%d))",
+					names[0], names[2], names[1], x.End.Unix())
+			}
+
+		case "postgres":
+			sqlRaw = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" PARTITION OF "%s" FOR VALUES FROM (%d) TO (%d)`,
+				x.Name, table, x.Start.Unix(), x.End.Unix())
+
+		default:
+			return ErrPartitionNotSupport
+		}
+
+		log.Infof("create partition %s for %s", x.Name, table)
+
+		err = p.db.Exec(sqlRaw).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+
+		exists = append(exists, x)
+	}
+
+	return nil
+}
+
+// DropPartitionsOlderThan 删除所有结束时间早于 now-d 的分区，返回被删除的分区
+func (p *Client) DropPartitionsOlderThan(model any, d time.Duration) ([]*Partition, error) {
+	_, table := getPartitioner(model)
+
+	exists, err := p.ListPartitions(model)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	deadline := time.Now().Add(-d)
+
+	var drops []*Partition
+	for _, x := range exists {
+		if x.End.After(deadline) {
+			continue
+		}
+
+		drops = append(drops, x)
+	}
+
+	if len(drops) == 0 {
+		return nil, nil
+	}
+
+	switch p.clientType {
+	case "mysql":
+		names := []string{table}
+		for _, x := range drops {
+			names = append(names, x.Name)
+		}
+
+		names, err = quoteNames(names...)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		err = p.db.Exec(fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s", names[0], strings.Join(names[1:], ", "))).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+	case "postgres":
+		for _, x := range drops {
+			err = p.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, x.Name)).Error
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, ErrPartitionNotSupport
+	}
+
+	log.Infof("drop %d partitions for %s", len(drops), table)
+
+	return drops, nil
+}
+
+// 表名、分区名不合法时返回错误
+func quoteNames(names ...string) ([]string, error) {
+	list := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted, err := quoteIdentifier(name, '`', false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, quoted)
+	}
+	return list, nil
+}
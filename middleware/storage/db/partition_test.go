@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type partitionItem struct {
+	Id        int64
+	CreatedAt int64
+}
+
+func (partitionItem) TableName() string {
+	return "partition_item"
+}
+
+func (partitionItem) PartitionKey() string {
+	return "created_at"
+}
+
+func (partitionItem) PartitionInterval() PartitionInterval {
+	return PartitionMonthly
+}
+
+// 查询时返回 names 作为已存在的分区，记录所有执行的 sql
+type partitionConnector struct {
+	names []string
+	execs []string
+}
+
+func (p *partitionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &partitionConn{connector: p}, nil
+}
+
+func (p *partitionConnector) Driver() driver.Driver {
+	return nil
+}
+
+type partitionConn struct {
+	connector *partitionConnector
+}
+
+func (p *partitionConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *partitionConn) Close() error {
+	return nil
+}
+
+func (p *partitionConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p *partitionConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	p.connector.execs = append(p.connector.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (p *partitionConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &partitionRows{names: p.connector.names}, nil
+}
+
+type partitionRows struct {
+	names []string
+}
+
+func (p *partitionRows) Columns() []string {
+	return []string{"name"}
+}
+
+func (p *partitionRows) Close() error {
+	return nil
+}
+
+func (p *partitionRows) Next(dest []driver.Value) error {
+	if len(p.names) == 0 {
+		return io.EOF
+	}
+	dest[0] = p.names[0]
+	p.names = p.names[1:]
+	return nil
+}
+
+func TestEnsurePartitions(t *testing.T) {
+	now := time.Now()
+	_, table := getPartitioner(partitionItem{})
+	current := PartitionMonthly.partition(table, now)
+	next := PartitionMonthly.partition(table, PartitionMonthly.next(current.Start))
+	last := PartitionMonthly.partition(table, PartitionMonthly.next(next.Start))
+
+	dialectors := map[string]func(conn gorm.ConnPool) gorm.Dialector{
+		"mysql": func(conn gorm.ConnPool) gorm.Dialector {
+			return mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true})
+		},
+		"postgres": func(conn gorm.ConnPool) gorm.Dialector {
+			return postgres.New(postgres.Config{Conn: conn})
+		},
+	}
+
+	// 只有最后一个分区存在，mysql 无法在其之前插入分区，postgres 需要补齐
+	wants := map[string][]string{
+		"mysql":    nil,
+		"postgres": {current.Name, next.Name},
+	}
+
+	for clientType, dialector := range dialectors {
+		connector := &partitionConnector{names: []string{last.Name}}
+		sqlDB := sql.OpenDB(connector)
+
+		gdb, err := gorm.Open(dialector(sqlDB), &gorm.Config{
+			DisableAutomaticPing:   true,
+			SkipDefaultTransaction: true,
+		})
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+
+		cli := &Client{db: gdb, clientType: clientType}
+		err = cli.EnsurePartitions(partitionItem{}, last.Start.Sub(now))
+		if err != nil {
+			t.Fatalf("%s err:%v", clientType, err)
+		}
+
+		if len(connector.execs) != len(wants[clientType]) {
+			t.Fatalf("%s unexpected sqls:%v", clientType, connector.execs)
+		}
+		for i, name := range wants[clientType] {
+			if !strings.Contains(connector.execs[i], `"`+name+`" PARTITION OF`) {
+				t.Errorf("%s unexpected sql:%s", clientType, connector.execs[i])
+			}
+		}
+
+		_ = sqlDB.Close()
+	}
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/core"
+	"gorm.io/gorm"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrRestrictedColumn SelectRaw 等无法改写的表达式中引用了当前角色无权访问的列
+var ErrRestrictedColumn = errors.New("restricted column")
+
+// ColumnPolicy 限制列的访问，Roles 之外的角色查询时该列会被排除或者替换为 Mask
+type ColumnPolicy struct {
+	Column string
+
+	// 有权访问的角色，为空时所有角色都无权访问
+	Roles []string
+
+	// 为空时从结果中排除该列，字段保持零值；不为空时以该值代替，只适用于字符串类型的列
+	Mask string
+}
+
+type columnPolicies struct {
+	model    reflect.Type
+	policies map[string]*ColumnPolicy
+}
+
+// 表名 -> *columnPolicies，按表名保存，DTO 以及 FindMaps 也会生效
+var columnPolicyMap sync.Map
+
+// RegisterColumnPolicy 注册模型的列访问策略，在 Find、First、FindMaps 生成语句时统一处理
+//
+//	db.RegisterColumnPolicy(&User{},
+//		&db.ColumnPolicy{Column: "salary", Roles: []string{"hr"}},
+//		&db.ColumnPolicy{Column: "ssn", Roles: []string{"hr"}, Mask: "***"},
+//	)
+func RegisterColumnPolicy(model any, policies ...*ColumnPolicy) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	m := make(map[string]*ColumnPolicy, len(policies))
+	for _, policy := range policies {
+		m[strings.ToLower(policy.Column)] = policy
+	}
+
+	columnPolicyMap.Store(getTableName(rt), &columnPolicies{
+		model:    rt,
+		policies: m,
+	})
+}
+
+func getColumnPolicies(table string) *columnPolicies {
+	if v, ok := columnPolicyMap.Load(table); ok {
+		return v.(*columnPolicies)
+	}
+	return nil
+}
+
+type rolesKey struct{}
+
+// ContextWithRoles 将当前请求的角色放入 context，Scoop.WithContext 时读取
+func ContextWithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+func RolesFromContext(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+	return roles
+}
+
+// WithContext 设置语句的 context，并读取其中的角色以及请求的日志前缀
+func (p *Scoop) WithContext(ctx context.Context) *Scoop {
+	p._db = p._db.WithContext(ctx)
+	p.roles = append(p.roles, RolesFromContext(ctx)...)
+
+	if l := core.LoggerFromContext(ctx); l != nil && len(l.PrefixMsg) > 0 {
+		p.WithLogger(p.getLogger().withPrefix(l.PrefixMsg))
+	}
+	return p
+}
+
+// WithRoles 设置当前链路的角色，用于列访问策略
+func (p *Scoop) WithRoles(roles ...string) *Scoop {
+	p.roles = append(p.roles, roles...)
+	return p
+}
+
+func (p *Scoop) allowed(policy *ColumnPolicy) bool {
+	for _, role := range p.roles {
+		for _, r := range policy.Roles {
+			if role == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 模型在数据库中的全部列，用于展开 *
+func (p *Scoop) modelColumns(rt reflect.Type) ([]string, error) {
+	stmt := &gorm.Statement{DB: p._db}
+	err := stmt.Parse(reflect.New(rt).Interface())
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	return stmt.Schema.DBNames, nil
+}
+
+// 按当前的角色改写 selects，没有 Select 时展开为模型的全部列，无法改写的表达式引用了受限列时返回错误
+func (p *Scoop) applyColumnPolicy() {
+	if p.policyApplied {
+		return
+	}
+	p.policyApplied = true
+
+	cp := getColumnPolicies(p.table)
+	if cp == nil {
+		return
+	}
+
+	restricted := make(map[string]*ColumnPolicy, len(cp.policies))
+	for column, policy := range cp.policies {
+		if !p.allowed(policy) {
+			restricted[column] = policy
+		}
+	}
+	if len(restricted) == 0 {
+		return
+	}
+
+	selects := p.selects
+	if len(selects) == 0 {
+		selects = []string{"*"}
+	}
+
+	q := string(p.quote())
+	mask := func(prefix, column, alias string) string {
+		policy, ok := restricted[strings.ToLower(column)]
+		if !ok {
+			if alias != "" {
+				return prefix + q + column + q + " AS " + q + alias + q
+			}
+			return prefix + q + column + q
+		}
+
+		if policy.Mask == "" {
+			return ""
+		}
+		if alias == "" {
+			alias = column
+		}
+		return "'" + strings.ReplaceAll(policy.Mask, "'", "''") + "' AS " + q + alias + q
+	}
+
+	var list []string
+	for _, s := range selects {
+		words := strings.Fields(s)
+
+		var parts []string
+		var alias string
+		var err error
+		switch {
+		case len(words) == 1:
+			parts, err = parseIdentifier(words[0], true)
+		case len(words) == 3 && strings.EqualFold(words[1], "AS"):
+			parts, err = parseIdentifier(words[0], false)
+			if err == nil {
+				var aliasParts []string
+				aliasParts, err = parseIdentifier(words[2], false)
+				if err == nil {
+					alias = aliasParts[0]
+				}
+			}
+		default:
+			err = ErrInvalidIdentifier
+		}
+
+		// SelectRaw 的表达式，只检查是否引用了受限列
+		if err != nil {
+			for column := range restricted {
+				if regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(column) + `\b`).MatchString(s) {
+					p.setErr(fmt.Errorf("%w: %s", ErrRestrictedColumn, column))
+					return
+				}
+			}
+			list = append(list, s)
+			continue
+		}
+
+		var prefix string
+		if len(parts) == 2 {
+			prefix = q + parts[0] + q + "."
+		}
+
+		column := parts[len(parts)-1]
+		if column != "*" {
+			if v := mask(prefix, column, alias); v != "" {
+				list = append(list, v)
+			}
+			continue
+		}
+
+		columns, err := p.modelColumns(cp.model)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			p.setErr(err)
+			return
+		}
+		for _, c := range columns {
+			if v := mask(prefix, c, ""); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+
+	if len(list) == 0 {
+		p.setErr(fmt.Errorf("%w: no column left to select", ErrRestrictedColumn))
+		return
+	}
+
+	p.selects = list
+}
@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type policyEmployee struct {
+	Id     int64 `gorm:"primaryKey"`
+	Name   string
+	Salary int64
+	Ssn    string
+}
+
+func (policyEmployee) TableName() string {
+	return "policy_employee"
+}
+
+func TestColumnPolicy(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "policy",
+	}, &policyEmployee{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	db.RegisterColumnPolicy(&policyEmployee{},
+		&db.ColumnPolicy{Column: "salary", Roles: []string{"hr"}},
+		&db.ColumnPolicy{Column: "ssn", Roles: []string{"hr"}, Mask: "***"},
+	)
+
+	err = cli.NewScoop().Create(&policyEmployee{Name: "a", Salary: 100, Ssn: "123-45"}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var e policyEmployee
+	err = cli.NewScoop().First(&e).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if e.Name != "a" || e.Salary != 0 || e.Ssn != "***" {
+		t.Errorf("unexpected result: %+v", e)
+	}
+
+	var list []*policyEmployee
+	err = cli.NewScoop().Select("name", "salary").Find(&list).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(list) != 1 || list[0].Salary != 0 {
+		t.Errorf("unexpected result: %+v", list[0])
+	}
+
+	err = cli.NewScoop().Model(&policyEmployee{}).SelectRaw("SUM(salary) AS total").Find(&list).Error
+	if !errors.Is(err, db.ErrRestrictedColumn) {
+		t.Errorf("err:%v", err)
+	}
+
+	ctx := db.ContextWithRoles(context.Background(), "hr")
+	e = policyEmployee{}
+	err = cli.NewScoop().WithContext(ctx).First(&e).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if e.Salary != 100 || e.Ssn != "123-45" {
+		t.Errorf("unexpected result: %+v", e)
+	}
+	// RETURNING 返回的列同样受限
+	var updated []*policyEmployee
+	res := db.NewModelScoop[policyEmployee](cli.Database()).Equal("name", "a").
+		UpdatesReturning(map[string]any{"salary": 200}, &updated)
+	if res.Error != nil {
+		t.Fatalf("err:%v", res.Error)
+	}
+	if len(updated) != 1 || updated[0].Salary != 0 || updated[0].Ssn != "***" || updated[0].Name != "a" {
+		t.Errorf("unexpected result: %+v", updated)
+	}
+
+	var deleted []*policyEmployee
+	del := db.NewModelScoop[policyEmployee](cli.Database()).Equal("name", "a").DeleteReturning(&deleted)
+	if del.Error != nil {
+		t.Fatalf("err:%v", del.Error)
+	}
+	if len(deleted) != 1 || deleted[0].Salary != 0 || deleted[0].Ssn != "***" {
+		t.Errorf("unexpected result: %+v", deleted)
+	}
+}
@@ -0,0 +1,297 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrMissingParam RawQuery 中引用的参数没有传入
+var ErrMissingParam = errors.New("missing param")
+
+// RawQuery 原生查询，通过 Client.RawQuery 或 Scoop.RawQuery 创建
+type RawQuery struct {
+	scoop *Scoop
+
+	sql  string
+	args []any
+	err  error
+}
+
+func isParamStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isParamPart(c byte) bool {
+	return isParamStart(c) || (c >= '0' && c <= '9')
+}
+
+// 将 :name 替换为 ?，切片展开为 ?, ?, ?，空切片替换为 NULL
+// 引号内的内容以及 postgres 的 :: 类型转换保持不变
+func bindNamed(query string, params map[string]any) (string, []any, error) {
+	var b strings.Builder
+	var args []any
+
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(query) {
+				i++
+				b.WriteByte(query[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteByte(c)
+
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+
+		case c == ':' && i+1 < len(query) && isParamStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isParamPart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			i = j - 1
+
+			value, ok := params[name]
+			if !ok {
+				return "", nil, fmt.Errorf("%w: %s", ErrMissingParam, name)
+			}
+
+			rv := reflect.ValueOf(value)
+			if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+				if rv.Len() == 0 {
+					b.WriteString("NULL")
+					continue
+				}
+
+				for k := 0; k < rv.Len(); k++ {
+					if k > 0 {
+						b.WriteString(", ")
+					}
+					b.WriteByte('?')
+					args = append(args, rv.Index(k).Interface())
+				}
+				continue
+			}
+
+			b.WriteByte('?')
+			args = append(args, value)
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), args, nil
+}
+
+// RawQuery 在当前链路（包括事务）中执行原生查询，参数以 :name 引用，切片参数会展开，用于 IN
+//
+//	var users []*User
+//	err := tx.RawQuery("SELECT * FROM user WHERE id IN (:ids) AND status = :status", map[string]any{
+//		"ids":    []int64{1, 2},
+//		"status": 1,
+//	}).Find(&users).Error
+func (p *Scoop) RawQuery(query string, params map[string]any) *RawQuery {
+	q := &RawQuery{
+		scoop: p,
+	}
+	q.sql, q.args, q.err = bindNamed(query, params)
+	return q
+}
+
+// RawQuery 与 Scoop.RawQuery 相同，不在事务中执行
+func (p *Client) RawQuery(query string, params map[string]any) *RawQuery {
+	return p.NewScoop().RawQuery(query, params)
+}
+
+// Find 结果写入 *[]T、*[]*T、*[]map[string]any，或者只有一列时写入 *[]int64 等基础类型的切片
+func (p *RawQuery) Find(out any) *FindResult {
+	vv := reflect.ValueOf(out)
+	if vv.Kind() != reflect.Ptr || vv.Elem().Kind() != reflect.Slice {
+		panic("invalid out type, not ptr of slice")
+	}
+
+	rowsAffected, err := p.query(vv.Elem(), false)
+	return &FindResult{
+		RowsAffected: rowsAffected,
+		Error:        err,
+	}
+}
+
+// First 结果写入 *T、*map[string]any，或者只有一列时写入 *int64 等基础类型，没有数据时返回 NotFound
+func (p *RawQuery) First(out any) *FirstResult {
+	vv := reflect.ValueOf(out)
+	if vv.Kind() != reflect.Ptr {
+		panic("invalid out type, not ptr")
+	}
+
+	rowsAffected, err := p.query(vv.Elem(), true)
+	if err == nil && rowsAffected == 0 {
+		err = p.scoop.getNotFoundError()
+	}
+
+	return &FirstResult{
+		Error: err,
+	}
+}
+
+func (p *RawQuery) query(out reflect.Value, first bool) (int64, error) {
+	if p.err != nil {
+		return 0, p.err
+	}
+
+	s := p.scoop
+	start := time.Now()
+
+	sqlRaw := p.sql
+	if len(s.tags) > 0 {
+		b := log.GetBuffer()
+		b.WriteString(sqlRaw)
+		s.writeComment(b)
+		sqlRaw = b.String()
+		log.PutBuffer(b)
+	}
+
+	var rows *sql.Rows
+	err := s.retryRead(func() (err error) {
+		rows, err = s._db.Raw(sqlRaw, p.args...).Rows()
+		return err
+	})
+	if err != nil {
+		p.log(start, -1, err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	elem := out.Type()
+	if !first {
+		elem = elem.Elem()
+	}
+	structType := elem
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	var rowsAffected int64
+	var scan func() (reflect.Value, error)
+
+	switch {
+	case structType.Kind() == reflect.Map:
+		cols, err := rows.ColumnTypes()
+		if err != nil {
+			p.log(start, -1, err)
+			return 0, err
+		}
+
+		values := make([]any, len(cols))
+		scanArgs := make([]any, len(cols))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		scan = func() (reflect.Value, error) {
+			err := rows.Scan(scanArgs...)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			m := make(map[string]any, len(cols))
+			for i, col := range cols {
+				m[col.Name()] = decodeColumnValue(col, values[i])
+			}
+			return reflect.ValueOf(m), nil
+		}
+
+	default:
+		cols, err := rows.Columns()
+		if err != nil {
+			p.log(start, -1, err)
+			return 0, err
+		}
+
+		values := make([]sql.RawBytes, len(cols))
+		scanArgs := make([]any, len(cols))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		isStruct := structType.Kind() == reflect.Struct
+		var fields *scanFields
+		if isStruct {
+			fields = getScanFields(structType)
+		} else if len(cols) != 1 {
+			err = fmt.Errorf("scan %d columns into %s", len(cols), structType)
+			p.log(start, -1, err)
+			return 0, err
+		}
+
+		scan = func() (reflect.Value, error) {
+			err := rows.Scan(scanArgs...)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			v := reflect.New(structType)
+			if isStruct {
+				err = s.scanRow(v.Elem(), fields, cols, values)
+			} else if values[0] != nil {
+				err = decode(v.Elem(), values[0])
+			}
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			if elem.Kind() == reflect.Ptr {
+				return v, nil
+			}
+			return v.Elem(), nil
+		}
+	}
+
+	for rows.Next() {
+		v, err := scan()
+		if err != nil {
+			p.log(start, rowsAffected, err)
+			return rowsAffected, err
+		}
+		rowsAffected++
+
+		if first {
+			out.Set(v)
+			break
+		}
+		out.Set(reflect.Append(out, v))
+	}
+
+	err = rows.Err()
+	p.log(start, rowsAffected, err)
+
+	return rowsAffected, err
+}
+
+func (p *RawQuery) log(start time.Time, rowsAffected int64, err error) {
+	// Find/First -> query -> log
+	p.scoop.getLogger().Log(p.scoop.depth+3, start, func() (sql string, n int64) {
+		return p.scoop._db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Raw(p.sql, p.args...)
+		}), rowsAffected
+	}, err)
+}
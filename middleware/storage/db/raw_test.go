@@ -0,0 +1,73 @@
+package db_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type rawUser struct {
+	Id     int64 `gorm:"primaryKey"`
+	Name   string
+	Status int
+}
+
+func (rawUser) TableName() string {
+	return "raw_user"
+}
+
+func TestRawQuery(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "raw",
+	}, &rawUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for _, u := range []*rawUser{{Name: "a", Status: 1}, {Name: "b:c", Status: 1}, {Name: "d", Status: 2}} {
+		err = cli.NewScoop().Create(u).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	var users []*rawUser
+	err = cli.RawQuery("SELECT * FROM raw_user WHERE id IN (:ids) AND status = :status AND name != ':ids' ORDER BY id", map[string]any{
+		"ids":    []int64{1, 2, 3},
+		"status": 1,
+	}).Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(users) != 2 || users[1].Name != "b:c" {
+		t.Fatalf("unexpected result: %+v", users)
+	}
+
+	var ids []int64
+	err = cli.RawQuery("SELECT id FROM raw_user WHERE id IN (:ids)", map[string]any{
+		"ids": []int64{},
+	}).Find(&ids).Error
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("ids:%v, err:%v", ids, err)
+	}
+
+	var m map[string]any
+	err = cli.RawQuery("SELECT status, COUNT(*) AS cnt FROM raw_user WHERE status = :status GROUP BY status", map[string]any{
+		"status": 1,
+	}).First(&m).Error
+	if err != nil || m["cnt"] != int64(2) {
+		t.Fatalf("m:%v, err:%v", m, err)
+	}
+
+	var cnt int64
+	err = cli.RawQuery("SELECT COUNT(*) FROM raw_user", nil).First(&cnt).Error
+	if err != nil || cnt != 3 {
+		t.Fatalf("cnt:%d, err:%v", cnt, err)
+	}
+
+	err = cli.RawQuery("SELECT * FROM raw_user WHERE id = :id", nil).First(&rawUser{}).Error
+	if !errors.Is(err, db.ErrMissingParam) {
+		t.Errorf("err:%v", err)
+	}
+}
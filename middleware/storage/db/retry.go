@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"time"
+
+	mysqlC "github.com/go-sql-driver/mysql"
+)
+
+// ErrTransient 连接类的临时错误，写操作遇到时不会自动重试，由调用方决定是否重试
+var ErrTransient = errors.New("transient error")
+
+type TransientError struct {
+	Err error
+}
+
+func (p *TransientError) Error() string {
+	return "transient error: " + p.Err.Error()
+}
+
+func (p *TransientError) Unwrap() error {
+	return p.Err
+}
+
+func (p *TransientError) Is(target error) bool {
+	return target == ErrTransient
+}
+
+var transientErrors = []string{
+	// mysql 2006/2013
+	"server has gone away",
+	"lost connection to mysql server",
+	"invalid connection",
+	"bad connection",
+	"connection reset by peer",
+	"broken pipe",
+	"conn closed",
+	"unexpected eof",
+}
+
+// IsTransientErr 判断是否是可以重试的连接类错误
+func IsTransientErr(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrTransient) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysqlC.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var mysqlErr *mysqlC.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 2006 || mysqlErr.Number == 2013
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, x := range transientErrors {
+		if strings.Contains(msg, x) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Scoop) retryPolicy() (int, time.Duration) {
+	c := getClientByDB(p._db)
+	if c == nil {
+		return 0, 0
+	}
+
+	// 事务中的连接是固定的，重试没有意义
+	if _, ok := p._db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return 0, 0
+	}
+
+	return c.maxRetries, c.retryBackoff
+}
+
+// 只用于读操作
+func (p *Scoop) retryRead(logic func() error) error {
+	maxRetries, backoff := p.retryPolicy()
+
+	var err error
+	for i := 0; ; i++ {
+		err = logic()
+		if err == nil || i >= maxRetries || !IsTransientErr(err) {
+			return err
+		}
+
+		log.Warnf("transient error, retry:%d, err:%v", i+1, err)
+		time.Sleep(backoff << i)
+	}
+}
+
+func wrapTransient(err error) error {
+	if err == nil || errors.Is(err, ErrTransient) || !IsTransientErr(err) {
+		return err
+	}
+	return &TransientError{Err: err}
+}
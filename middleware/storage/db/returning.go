@@ -0,0 +1,391 @@
+package db
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"github.com/lazygophers/log"
+	"gorm.io/gorm"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrReturningNotSupport 数据库不支持 RETURNING 时需要通过 id 回退为先锁定再修改
+var ErrReturningNotSupport = errors.New("returning requires an id column on this database")
+
+// 返回的列与 Find 一致按列访问策略改写，table 为 OUTPUT 的 inserted、deleted，改写失败时通过 buildErr 返回
+func (p *Scoop) returningColumns(table string) string {
+	all := "*"
+	if table != "" {
+		all = table + ".*"
+	}
+
+	selects, applied := p.selects, p.policyApplied
+	defer func() {
+		p.selects, p.policyApplied = selects, applied
+	}()
+
+	p.selects = []string{all}
+	p.policyApplied = false
+	p.applyColumnPolicy()
+
+	return strings.Join(p.selects, ", ")
+}
+
+func (p *Scoop) writeOutput(sqlRaw *bytes.Buffer, table string) {
+	if getDialectByDB(p._db).ReturningClause != "OUTPUT" {
+		return
+	}
+
+	sqlRaw.WriteString(" OUTPUT ")
+	sqlRaw.WriteString(p.returningColumns(table))
+}
+
+func (p *Scoop) writeReturning(sqlRaw *bytes.Buffer) {
+	if getDialectByDB(p._db).ReturningClause != "RETURNING" {
+		return
+	}
+
+	sqlRaw.WriteString(" RETURNING ")
+	sqlRaw.WriteString(p.returningColumns(""))
+}
+
+// UpdatesReturning 与 Updates 相同，同时将修改后的行写入 out，out 为切片的指针
+// postgres、sqlite 使用 RETURNING，sqlserver 使用 OUTPUT
+// 其他数据库在事务中通过 SELECT ... FOR UPDATE 锁定匹配的 id，按 id 修改后再查询，要求表有 id 列
+func (p *Scoop) UpdatesReturning(m interface{}, out interface{}) *UpdateResult {
+	if err := p.buildErr(); err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
+	if p.cond.skip {
+		return &UpdateResult{}
+	}
+
+	updateMap, err := toUpdateMap(m)
+	if err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
+	if len(updateMap) == 0 {
+		return &UpdateResult{
+			Error: errors.New("updateMap is empty"),
+		}
+	}
+
+	if p.table == "" {
+		panic("table name is empty")
+	}
+
+	if !p.unscoped && p.hasDeletedAt {
+		p.cond.whereRaw("deleted_at = 0")
+	}
+	p.applyDefaultScope(p.model)
+
+	p.inc()
+	defer p.dec()
+
+	if getDialectByDB(p._db).ReturningClause == "" {
+		return p.updatesLocked(updateMap, out)
+	}
+
+	sqlRaw := log.GetBuffer()
+	defer log.PutBuffer(sqlRaw)
+
+	values := p.writeUpdate(sqlRaw, updateMap, true)
+	p.writeComment(sqlRaw)
+	if err := p.buildErr(); err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
+	rowsAffected, err := p.queryReturning(p._db, sqlRaw.String(), values, out)
+	return &UpdateResult{
+		RowsAffected: rowsAffected,
+		Error:        wrapTransient(err),
+	}
+}
+
+// DeleteReturning 与 Delete 相同，同时将删除的行写入 out，out 为切片的指针，软删除时返回修改后的行
+// 不支持 RETURNING 的数据库在事务中先通过 SELECT ... FOR UPDATE 查询再删除，不支持级联删除
+func (p *Scoop) DeleteReturning(out interface{}) *DeleteResult {
+	if err := p.buildErr(); err != nil {
+		return &DeleteResult{
+			Error: err,
+		}
+	}
+
+	if p.cond.skip {
+		return &DeleteResult{}
+	}
+
+	if p.cascade {
+		return &DeleteResult{
+			Error: errors.New("cascade delete does not support returning"),
+		}
+	}
+
+	if p.table == "" {
+		panic("table name is empty")
+	}
+
+	if !p.unscoped && p.hasDeletedAt {
+		p.cond.whereRaw("deleted_at = 0")
+	}
+	p.applyDefaultScope(p.model)
+
+	p.inc()
+	defer p.dec()
+
+	now := time.Now().Unix()
+
+	sqlRaw := log.GetBuffer()
+	defer log.PutBuffer(sqlRaw)
+
+	if getDialectByDB(p._db).ReturningClause == "" {
+		columns := p.returningColumns("")
+		if err := p.buildErr(); err != nil {
+			return &DeleteResult{
+				Error: err,
+			}
+		}
+
+		var rowsAffected int64
+		err := p._db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			_, err = p.queryReturning(tx, p.lockSql(columns), nil, out)
+			if err != nil {
+				return err
+			}
+
+			// 匹配的行已经被锁定，按相同的条件删除
+			p.writeDelete(sqlRaw, now, false)
+			p.writeComment(sqlRaw)
+
+			rowsAffected, err = p.execReturning(tx, sqlRaw.String(), nil)
+			return err
+		})
+		return &DeleteResult{
+			RowsAffected: rowsAffected,
+			Error:        wrapTransient(err),
+		}
+	}
+
+	p.writeDelete(sqlRaw, now, true)
+	p.writeComment(sqlRaw)
+	if err := p.buildErr(); err != nil {
+		return &DeleteResult{
+			Error: err,
+		}
+	}
+
+	rowsAffected, err := p.queryReturning(p._db, sqlRaw.String(), nil, out)
+	return &DeleteResult{
+		RowsAffected: rowsAffected,
+		Error:        wrapTransient(err),
+	}
+}
+
+func (p *Scoop) updatesLocked(updateMap map[string]interface{}, out interface{}) *UpdateResult {
+	if !p.hasId {
+		return &UpdateResult{
+			Error: ErrReturningNotSupport,
+		}
+	}
+
+	columns := p.returningColumns("")
+	if err := p.buildErr(); err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
+	var rowsAffected int64
+	err := p._db.Transaction(func(tx *gorm.DB) error {
+		var ids []interface{}
+		err := p.scanIds(tx, &ids)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// 只修改锁定的行，修改后的值可能不再满足原来的条件
+		cond := p.cond
+		p.cond = Cond{}
+		p.cond.where("id", "IN", ids)
+		defer func() {
+			p.cond = cond
+		}()
+
+		sqlRaw := log.GetBuffer()
+		defer log.PutBuffer(sqlRaw)
+
+		values := p.writeUpdate(sqlRaw, updateMap, false)
+		p.writeComment(sqlRaw)
+
+		rowsAffected, err = p.execReturning(tx, sqlRaw.String(), values)
+		if err != nil {
+			return err
+		}
+
+		_, err = p.queryReturning(tx, "SELECT "+columns+" FROM "+p.table+" WHERE "+p.cond.conds[0], nil, out)
+		return err
+	})
+
+	return &UpdateResult{
+		RowsAffected: rowsAffected,
+		Error:        wrapTransient(err),
+	}
+}
+
+func (p *Scoop) lockSql(fields string) string {
+	b := log.GetBuffer()
+	defer log.PutBuffer(b)
+
+	b.WriteString("SELECT ")
+	b.WriteString(fields)
+	b.WriteString(" FROM ")
+	b.WriteString(p.table)
+
+	if len(p.cond.conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(p.cond.conds[0])
+		for _, c := range p.cond.conds[1:] {
+			b.WriteString(" AND ")
+			b.WriteString(c)
+		}
+	}
+
+	b.WriteString(" FOR UPDATE")
+
+	return b.String()
+}
+
+func (p *Scoop) scanIds(tx *gorm.DB, ids *[]interface{}) error {
+	sqlRaw := p.lockSql("id")
+
+	start := time.Now()
+	rows, err := tx.Raw(sqlRaw).Rows()
+	if err != nil {
+		p.getLogger().Log(p.depth+2, start, func() (sql string, rowsAffected int64) {
+			return sqlRaw, -1
+		}, err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id interface{}
+		err = rows.Scan(&id)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+
+		// 部分驱动以 []byte 返回
+		if b, ok := id.([]byte); ok {
+			id = string(b)
+		}
+		*ids = append(*ids, id)
+	}
+	err = rows.Err()
+
+	p.getLogger().Log(p.depth+2, start, func() (sql string, rowsAffected int64) {
+		return sqlRaw, int64(len(*ids))
+	}, err)
+
+	return err
+}
+
+func (p *Scoop) execReturning(tx *gorm.DB, sqlRaw string, values []interface{}) (int64, error) {
+	start := time.Now()
+	res := tx.Exec(sqlRaw, values...)
+	p.getLogger().Log(p.depth+2, start, func() (sql string, rowsAffected int64) {
+		return FormatSql(sqlRaw, values...), res.RowsAffected
+	}, res.Error)
+	return res.RowsAffected, res.Error
+}
+
+// 执行语句并按 Find 的规则将返回的行追加到 out
+func (p *Scoop) queryReturning(tx *gorm.DB, sqlRaw string, values []interface{}, out interface{}) (int64, error) {
+	vv := reflect.ValueOf(out)
+	if vv.Type().Kind() != reflect.Ptr {
+		panic("invalid out type, not ptr")
+	}
+	vv = vv.Elem()
+	if vv.Type().Kind() != reflect.Slice {
+		panic("invalid out type, not slice")
+	}
+
+	elem := vv.Type().Elem()
+	fields := getScanFields(elem)
+
+	start := time.Now()
+	var rowsAffected int64
+	err := func() error {
+		rows, err := tx.Raw(sqlRaw, values...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		cols, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+
+		// sqlserver 的 OUTPUT 列名大小写与定义一致，统一按小写匹配
+		for i, col := range cols {
+			cols[i] = strings.ToLower(col)
+		}
+
+		raw := make([]sql.RawBytes, len(cols))
+		scanArgs := make([]interface{}, len(raw))
+		for i := range raw {
+			scanArgs[i] = &raw[i]
+		}
+
+		for rows.Next() {
+			err = rows.Scan(scanArgs...)
+			if err != nil {
+				return err
+			}
+
+			var v reflect.Value
+			if elem.Kind() == reflect.Ptr {
+				v = reflect.New(elem.Elem())
+			} else {
+				v = reflect.New(elem)
+			}
+
+			err = p.scanRow(v.Elem(), fields, cols, raw)
+			if err != nil {
+				return err
+			}
+
+			if elem.Kind() == reflect.Ptr {
+				vv.Set(reflect.Append(vv, v))
+			} else {
+				vv.Set(reflect.Append(vv, v.Elem()))
+			}
+			rowsAffected++
+		}
+
+		return rows.Err()
+	}()
+
+	p.getLogger().Log(p.depth+2, start, func() (sql string, n int64) {
+		return FormatSql(sqlRaw, values...), rowsAffected
+	}, err)
+
+	return rowsAffected, err
+}
@@ -0,0 +1,58 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type returningItem struct {
+	Id        int64 `gorm:"primaryKey"`
+	Name      string
+	Status    int
+	DeletedAt int64
+}
+
+func (returningItem) TableName() string {
+	return "returning_item"
+}
+
+func TestReturning(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "returning",
+	}, &returningItem{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for i, name := range []string{"a", "b", "c"} {
+		err = cli.NewScoop().Create(&returningItem{Id: int64(i + 1), Name: name, Status: 1}).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	var updated []*returningItem
+	res := db.NewModelScoop[returningItem](cli.Database()).In("id", []int64{1, 2}).
+		UpdatesReturning(map[string]any{"status": 2}, &updated)
+	if res.Error != nil {
+		t.Fatalf("err:%v", res.Error)
+	}
+	if res.RowsAffected != 2 || len(updated) != 2 {
+		t.Fatalf("rows:%d, updated:%d", res.RowsAffected, len(updated))
+	}
+	for _, item := range updated {
+		if item.Status != 2 {
+			t.Errorf("unexpected status: %+v", item)
+		}
+	}
+
+	var deleted []*returningItem
+	del := db.NewModelScoop[returningItem](cli.Database()).Equal("name", "c").DeleteReturning(&deleted)
+	if del.Error != nil {
+		t.Fatalf("err:%v", del.Error)
+	}
+	if del.RowsAffected != 1 || len(deleted) != 1 || deleted[0].Id != 3 || deleted[0].DeletedAt == 0 {
+		t.Fatalf("rows:%d, deleted:%+v", del.RowsAffected, deleted)
+	}
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/utils/stringx"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownColumn StrictScan 模式下，查询返回的列在结构体中没有对应的字段
+var ErrUnknownColumn = errors.New("unknown column")
+
+type scanFields struct {
+	// 列名 -> 字段下标
+	columns map[string][]int
+
+	// 是否有字段显式声明了 db tag，有时视为 DTO，未 Select 时只查询声明的列
+	tagged bool
+	order  []string
+}
+
+var scanFieldsCache sync.Map
+
+// 列名的优先级：db tag > gorm 的 column > 字段名的蛇形
+func getScanFields(rt reflect.Type) *scanFields {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if v, ok := scanFieldsCache.Load(rt); ok {
+		return v.(*scanFields)
+	}
+
+	fields := &scanFields{
+		columns: map[string][]int{},
+	}
+	fields.walk(rt, nil)
+
+	v, _ := scanFieldsCache.LoadOrStore(rt, fields)
+	return v.(*scanFields)
+}
+
+func (p *scanFields) walk(rt reflect.Type, index []int) {
+	if rt.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		idx := append(append([]int{}, index...), i)
+
+		tag, tagged := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && !tagged && field.Type.Kind() == reflect.Struct {
+			p.walk(field.Type, idx)
+			continue
+		}
+
+		column := tag
+		if column == "" {
+			for _, s := range strings.Split(field.Tag.Get("gorm"), ";") {
+				if strings.HasPrefix(s, "column:") {
+					column = strings.TrimPrefix(s, "column:")
+					break
+				}
+			}
+		}
+		if column == "" {
+			column = stringx.Camel2Snake(field.Name)
+		}
+
+		if _, ok := p.columns[column]; ok {
+			continue
+		}
+
+		if tagged {
+			p.tagged = true
+		}
+
+		p.columns[column] = idx
+		p.order = append(p.order, column)
+	}
+}
+
+func (p *scanFields) field(v reflect.Value, column string) reflect.Value {
+	if idx, ok := p.columns[column]; ok {
+		return v.FieldByIndex(idx)
+	}
+
+	return v.FieldByName(stringx.Snake2Camel(column))
+}
+
+// StrictScan 查询返回的列没有对应的字段时报错，而不是忽略
+func (p *Scoop) StrictScan(b ...bool) *Scoop {
+	if len(b) == 0 {
+		p.strict = true
+		return p
+	}
+	p.strict = b[0]
+	return p
+}
+
+// 没有 Select 时，DTO 只查询声明了的列
+func (p *Scoop) selectFor(rt reflect.Type) {
+	if len(p.selects) > 0 {
+		return
+	}
+
+	fields := getScanFields(rt)
+	if !fields.tagged {
+		return
+	}
+
+	p.Select(fields.order...)
+}
+
+func (p *Scoop) scanRow(v reflect.Value, fields *scanFields, cols []string, values []sql.RawBytes) error {
+	for i, col := range values {
+		field := fields.field(v, cols[i])
+		if !field.IsValid() {
+			if p.strict {
+				return fmt.Errorf("%w: %s", ErrUnknownColumn, cols[i])
+			}
+			log.Warnf("invalid field: %s", stringx.Snake2Camel(cols[i]))
+			continue
+		}
+
+		if col == nil {
+			continue
+		}
+
+		err := decode(field, col)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type scanUser struct {
+	Id    int64 `gorm:"primaryKey"`
+	Name  string
+	Email string
+	Age   int64
+}
+
+func (scanUser) TableName() string {
+	return "scan_user"
+}
+
+type scanUserBrief struct {
+	UserId int64  `db:"id"`
+	Name   string `db:"name"`
+}
+
+func TestScanPartial(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "scan.db",
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.NewScoop().Create(&scanUser{Name: "a", Email: "a@b.c", Age: 18}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var briefs []*scanUserBrief
+	err = cli.NewScoop().Model(&scanUser{}).Find(&briefs).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(briefs) != 1 || briefs[0].UserId == 0 || briefs[0].Name != "a" {
+		t.Fatalf("unexpected result: %+v", briefs)
+	}
+
+	var brief scanUserBrief
+	err = cli.NewScoop().Model(&scanUser{}).Select("id", "name", "age").StrictScan().First(&brief).Error
+	if !errors.Is(err, db.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn, got %v", err)
+	}
+}
+
+type nullUser struct {
+	Id    int64 `gorm:"primaryKey"`
+	Nick  sql.NullString
+	Score *int64
+}
+
+func (nullUser) TableName() string {
+	return "null_user"
+}
+
+func TestScanNull(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "null.db",
+	}, &nullUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	score := int64(10)
+	err = cli.NewScoop().CreateInBatches([]*nullUser{
+		{Nick: sql.NullString{String: "a", Valid: true}, Score: &score},
+		{},
+	}, 10).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var u nullUser
+	err = cli.NewScoop().Model(&nullUser{}).NullEq("nick", sql.NullString{String: "a", Valid: true}).First(&u).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if !u.Nick.Valid || u.Nick.String != "a" || u.Score == nil || *u.Score != 10 {
+		t.Fatalf("unexpected result: %+v", u)
+	}
+
+	u = nullUser{}
+	err = cli.NewScoop().Model(&nullUser{}).NullEq("nick", sql.NullString{}).IsNull("score").First(&u).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if u.Nick.Valid || u.Score != nil {
+		t.Fatalf("unexpected result: %+v", u)
+	}
+
+	count, err := cli.NewScoop().Model(&nullUser{}).IsNotNull("nick").Count()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1, got %d", count)
+	}
+}
+
+func TestSelectIdentifier(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "ident.db",
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	var users []*scanUser
+	err = cli.NewScoop().Model(&scanUser{}).Select("id", "name AS name").Order("id desc").Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	err = cli.NewScoop().Model(&scanUser{}).Order("id; DROP TABLE scan_user").Find(&users).Error
+	if !errors.Is(err, db.ErrInvalidIdentifier) {
+		t.Fatalf("expected ErrInvalidIdentifier, got %v", err)
+	}
+
+	err = cli.NewScoop().Model(&scanUser{}).SelectRaw("COUNT(*) AS id").Find(&users).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	// 条件中的列名不合法时返回错误，不能 panic，也不能忽略条件
+	err = cli.NewScoop().Model(&scanUser{}).Between("id` OR 1=1 --", 1, 2).Find(&users).Error
+	if !errors.Is(err, db.ErrInvalidIdentifier) {
+		t.Fatalf("expected ErrInvalidIdentifier, got %v", err)
+	}
+
+	err = cli.NewScoop().Model(&scanUser{}).Where(map[string]any{"`name": "a"}).Delete().Error
+	if !errors.Is(err, db.ErrInvalidIdentifier) {
+		t.Fatalf("expected ErrInvalidIdentifier, got %v", err)
+	}
+}
+
+func TestFindMaps(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "map.db",
+	}, &scanUser{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for _, u := range []*scanUser{{Name: "a", Age: 18}, {Name: "b", Age: 20}, {Name: "c", Age: 20}} {
+		err = cli.NewScoop().Create(u).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	var rows []map[string]any
+	err = cli.NewScoop().Model(&scanUser{}).SelectRaw("age", "COUNT(*) AS cnt").Group("age").Order("age").FindMaps(&rows).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(rows) != 2 || rows[1]["age"] != int64(20) || rows[1]["cnt"] != int64(2) {
+		t.Fatalf("unexpected result: %+v", rows)
+	}
+
+	m, err := db.NewModelScoop[scanUser](cli.Database()).Equal("name", "b").FirstMap()
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if m["name"] != "b" || m["email"] != "" {
+		t.Fatalf("unexpected result: %+v", m)
+	}
+
+	_, err = db.NewModelScoop[scanUser](cli.Database()).Equal("name", "x").FirstMap()
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("err:%v", err)
+	}
+}
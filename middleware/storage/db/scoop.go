@@ -1,15 +1,15 @@
 package db
 
 import (
+	"bytes"
 	"database/sql"
 	"errors"
-	"fmt"
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/lrpc/middleware/core"
-	"github.com/lazygophers/utils/stringx"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -23,6 +23,11 @@ type Scoop struct {
 	hasDeletedAt bool
 	hasId        bool
 	table        string
+	alias        string
+	model        reflect.Type
+
+	noDefaultScope bool
+	defaultScoped  bool
 
 	cond          Cond
 	limit, offset uint64
@@ -32,8 +37,32 @@ type Scoop struct {
 	unscoped      bool
 
 	ignore bool
+	strict bool
+
+	// 以注释的形式附加在语句后，用于按业务统计
+	tags []string
+
+	// 构造语句时的错误，例如非法的列名，在执行时返回
+	err error
+
+	// Delete 时级联软删除子表
+	cascade bool
+
+	// 列访问策略使用的角色，同一个 Scoop 多次执行时只改写一次
+	roles         []string
+	policyApplied bool
+
+	// 有意不带 Limit 的查询
+	unbounded bool
+
+	// Find 最多返回的行数，为 0 时使用 Config.MaxRows
+	maxRows uint64
 
 	depth int
+
+	// Begin 时记录，用于慢事务检测
+	txStart  time.Time
+	txCaller string
 }
 
 func NewScoop(db *gorm.DB) *Scoop {
@@ -62,6 +91,27 @@ func (p *Scoop) AutoMigrate(dst ...interface{}) error {
 	return p._db.AutoMigrate(dst...)
 }
 
+func (p *Scoop) getLogger() *Logger {
+	if l, ok := p._db.Logger.(*Logger); ok {
+		return l
+	}
+
+	return getDefaultLogger()
+}
+
+// WithLogger 只替换当前链路的日志，不影响 Client 以及其他 Scoop
+func (p *Scoop) WithLogger(l *Logger) *Scoop {
+	p._db = p._db.Session(&gorm.Session{
+		Logger: l,
+	})
+	return p
+}
+
+// Silence 当前链路不输出日志，用于高频的内部查询
+func (p *Scoop) Silence() *Scoop {
+	return p.WithLogger(getSilentLogger())
+}
+
 func (p *Scoop) inc() {
 	p.depth++
 }
@@ -81,6 +131,7 @@ func (p *Scoop) dec() {
 
 func (p *Scoop) Model(m any) *Scoop {
 	rt := reflect.ValueOf(m).Type()
+	p.model = rt
 	p.table = getTableName(rt)
 	p.hasDeletedAt = hasDeleted(rt)
 	p.hasId = hasId(rt)
@@ -90,7 +141,39 @@ func (p *Scoop) Model(m any) *Scoop {
 
 // ——————————条件——————————
 
+func (p *Scoop) quote() byte {
+	return getDialectByDB(p._db).Quote
+}
+
+// 构造语句时的错误，包括条件中非法的列名
+func (p *Scoop) buildErr() error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.cond.Err()
+}
+
+func (p *Scoop) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Select 只支持列名，会校验并加上引号，表达式需要使用 SelectRaw
 func (p *Scoop) Select(fields ...string) *Scoop {
+	for _, field := range fields {
+		s, err := quoteSelect(field, p.quote())
+		if err != nil {
+			p.setErr(err)
+			continue
+		}
+		p.selects = append(p.selects, s)
+	}
+	return p
+}
+
+// SelectRaw 原样拼接，不能包含外部传入的内容
+func (p *Scoop) SelectRaw(fields ...string) *Scoop {
 	p.selects = append(p.selects, fields...)
 	return p
 }
@@ -129,6 +212,24 @@ func (p *Scoop) NotIn(column string, values interface{}) *Scoop {
 	return p
 }
 
+func (p *Scoop) IsNull(column string) *Scoop {
+	p.cond.where(column, "IS", nil)
+	return p
+}
+
+func (p *Scoop) IsNotNull(column string) *Scoop {
+	p.cond.where(column, "IS NOT", nil)
+	return p
+}
+
+// NullEq 值为 nil、空指针或者无效的 sql.Null* 时使用 IS NULL，否则等同于 Equal
+func (p *Scoop) NullEq(column string, value interface{}) *Scoop {
+	if isNullValue(value) {
+		return p.IsNull(column)
+	}
+	return p.Equal(column, value)
+}
+
 func (p *Scoop) Like(column string, value string) *Scoop {
 	p.cond.where(column, "LIKE", "%"+value+"%")
 	return p
@@ -160,12 +261,16 @@ func (p *Scoop) NotRightLike(column string, value string) *Scoop {
 }
 
 func (p *Scoop) Between(column string, min, max interface{}) *Scoop {
-	p.cond.whereRaw(fmt.Sprintf(quoteFieldName(column))+" BETWEEN ? AND ?", min, max)
+	if quoted, ok := p.cond.quoteField(column); ok {
+		p.cond.whereRaw(quoted+" BETWEEN ? AND ?", min, max)
+	}
 	return p
 }
 
 func (p *Scoop) NotBetween(column string, min, max interface{}) *Scoop {
-	p.cond.whereRaw(fmt.Sprintf(quoteFieldName(column))+" NOT BETWEEN ? AND ?", min, max)
+	if quoted, ok := p.cond.quoteField(column); ok {
+		p.cond.whereRaw(quoted+" NOT BETWEEN ? AND ?", min, max)
+	}
 	return p
 }
 
@@ -189,11 +294,36 @@ func (p *Scoop) Offset(offset uint64) *Scoop {
 }
 
 func (p *Scoop) Group(fields ...string) *Scoop {
+	for _, field := range fields {
+		s, err := quoteIdentifier(field, p.quote(), false)
+		if err != nil {
+			p.setErr(err)
+			continue
+		}
+		p.groups = append(p.groups, s)
+	}
+	return p
+}
+
+func (p *Scoop) GroupRaw(fields ...string) *Scoop {
 	p.groups = append(p.groups, fields...)
 	return p
 }
 
+// Order 支持 column、column ASC、column DESC，表达式需要使用 OrderRaw
 func (p *Scoop) Order(fields ...string) *Scoop {
+	for _, field := range fields {
+		s, err := quoteOrder(field, p.quote())
+		if err != nil {
+			p.setErr(err)
+			continue
+		}
+		p.orders = append(p.orders, s)
+	}
+	return p
+}
+
+func (p *Scoop) OrderRaw(fields ...string) *Scoop {
 	p.orders = append(p.orders, fields...)
 	return p
 }
@@ -225,7 +355,7 @@ func (p *Scoop) findSql() string {
 	}
 
 	b.WriteString(" FROM ")
-	b.WriteString(p.table)
+	p.writeTable(b)
 
 	if len(p.cond.conds) > 0 {
 		b.WriteString(" WHERE ")
@@ -264,15 +394,26 @@ func (p *Scoop) findSql() string {
 		b.WriteString(strconv.FormatUint(p.offset, 10))
 	}
 
+	p.writeComment(b)
+
 	return b.String()
 }
 
 type FindResult struct {
 	RowsAffected int64
 	Error        error
+
+	// 超过 MaxRows 被截断，此时 Error 为 ErrTruncated，out 中依旧有 MaxRows 行数据
+	Truncated bool
 }
 
 func (p *Scoop) Find(out interface{}) *FindResult {
+	if err := p.buildErr(); err != nil {
+		return &FindResult{
+			Error: err,
+		}
+	}
+
 	if p.cond.skip {
 		return &FindResult{}
 	}
@@ -295,6 +436,7 @@ func (p *Scoop) Find(out interface{}) *FindResult {
 	if !p.unscoped && (p.hasDeletedAt || hasDeleted(elem)) {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(elem)
 
 	p.inc()
 	defer p.dec()
@@ -302,11 +444,37 @@ func (p *Scoop) Find(out interface{}) *FindResult {
 	logBuf := log.GetBuffer()
 	defer log.PutBuffer(logBuf)
 
+	fields := getScanFields(elem)
+	p.selectFor(elem)
+	p.applyColumnPolicy()
+	if err := p.buildErr(); err != nil {
+		return &FindResult{
+			Error: err,
+		}
+	}
+
+	err := p.checkUnbounded(p.depth)
+	if err != nil {
+		return &FindResult{
+			Error: err,
+		}
+	}
+
+	// 多查询一行用于判断是否被截断
+	maxRows := p.findMaxRows()
+	limit := p.limit
+	if maxRows > 0 && (limit == 0 || limit > maxRows) {
+		p.limit = maxRows + 1
+	}
 	sqlRaw := p.findSql()
+	p.limit = limit
 	start := time.Now()
 
-	scope := p._db.Raw(sqlRaw)
-	rows, err := scope.Rows()
+	var rows *sql.Rows
+	err = p.retryRead(func() (err error) {
+		rows, err = p._db.Raw(sqlRaw).Rows()
+		return err
+	})
 	if err != nil {
 		return &FindResult{
 			Error: err,
@@ -316,7 +484,7 @@ func (p *Scoop) Find(out interface{}) *FindResult {
 
 	cols, err := rows.Columns()
 	if err != nil {
-		getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+		p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 			return sqlRaw, -1
 		}, err)
 		return &FindResult{
@@ -331,13 +499,19 @@ func (p *Scoop) Find(out interface{}) *FindResult {
 	}
 
 	var rawsAffected int64
+	var truncated bool
 	// 把数据写回到out
 	for rows.Next() {
+		if maxRows > 0 && uint64(rawsAffected) >= maxRows {
+			truncated = true
+			break
+		}
+
 		rawsAffected++
 
 		err = rows.Scan(scanArgs...)
 		if err != nil {
-			getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+			p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 				return sqlRaw, rawsAffected
 			}, err)
 			return &FindResult{
@@ -351,33 +525,32 @@ func (p *Scoop) Find(out interface{}) *FindResult {
 			v = reflect.New(elem.Elem())
 		}
 
-		for i, col := range values {
-			if col == nil {
-				continue
-			}
-			field := v.Elem().FieldByName(stringx.Snake2Camel(cols[i]))
-			if !field.IsValid() {
-				log.Warnf("invalid field: %s", stringx.Snake2Camel(cols[i]))
-				continue
-			}
-
-			err = decode(field, col)
-			if err != nil {
-				getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
-					return sqlRaw, rawsAffected
-				}, err)
-				return &FindResult{
-					Error: err,
-				}
+		err = p.scanRow(v.Elem(), fields, cols, values)
+		if err != nil {
+			p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+				return sqlRaw, rawsAffected
+			}, err)
+			return &FindResult{
+				Error: err,
 			}
 		}
 
 		vv.Set(reflect.Append(vv, v))
 	}
 
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 		return sqlRaw, rawsAffected
 	}, nil)
+
+	if truncated {
+		log.Warnf("find on %s truncated at %d rows", p.table, maxRows)
+		return &FindResult{
+			RowsAffected: rawsAffected,
+			Error:        ErrTruncated,
+			Truncated:    true,
+		}
+	}
+
 	return &FindResult{
 		RowsAffected: rawsAffected,
 	}
@@ -445,6 +618,12 @@ type FirstResult struct {
 }
 
 func (p *Scoop) First(out interface{}) *FirstResult {
+	if err := p.buildErr(); err != nil {
+		return &FirstResult{
+			Error: err,
+		}
+	}
+
 	if p.cond.skip {
 		return &FirstResult{
 			Error: p.getNotFoundError(),
@@ -463,6 +642,7 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 	if !p.unscoped && (p.hasDeletedAt || hasDeleted(vv.Type())) {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(vv.Type())
 
 	p.offset = 0
 	p.limit = 1
@@ -470,13 +650,25 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 	p.inc()
 	defer p.dec()
 
+	fields := getScanFields(vv.Type())
+	p.selectFor(vv.Type())
+	p.applyColumnPolicy()
+	if err := p.buildErr(); err != nil {
+		return &FirstResult{
+			Error: err,
+		}
+	}
+
 	sqlRaw := p.findSql()
 	start := time.Now()
 
-	scope := p._db.Raw(sqlRaw)
-	rows, err := scope.Rows()
+	var rows *sql.Rows
+	err := p.retryRead(func() (err error) {
+		rows, err = p._db.Raw(sqlRaw).Rows()
+		return err
+	})
 	if err != nil {
-		getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+		p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 			return sqlRaw, -1
 		}, err)
 		return &FirstResult{
@@ -487,7 +679,7 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 
 	cols, err := rows.Columns()
 	if err != nil {
-		getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+		p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 			return sqlRaw, -1
 		}, err)
 		return &FirstResult{
@@ -507,7 +699,7 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 		rowAffected++
 		err = rows.Scan(scanArgs...)
 		if err != nil {
-			getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+			p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 				return sqlRaw, 1
 			}, err)
 			return &FirstResult{
@@ -519,29 +711,19 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 			continue
 		}
 
-		for i, col := range values {
-			if col == nil {
-				continue
-			}
-			field := vv.Elem().FieldByName(stringx.Snake2Camel(cols[i]))
-			if !field.IsValid() {
-				log.Warnf("invalid field: %s", stringx.Snake2Camel(cols[i]))
-				continue
-			}
-			err = decode(field, col)
-			if err != nil {
-				getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
-					return sqlRaw, 1
-				}, err)
-				return &FirstResult{
-					Error: err,
-				}
+		err = p.scanRow(vv.Elem(), fields, cols, values)
+		if err != nil {
+			p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+				return sqlRaw, 1
+			}, err)
+			return &FirstResult{
+				Error: err,
 			}
 		}
 	}
 
 	if rowAffected == 0 {
-		getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+		p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 			return sqlRaw, 0
 		}, p.getNotFoundError())
 		return &FirstResult{
@@ -549,7 +731,7 @@ func (p *Scoop) First(out interface{}) *FirstResult {
 		}
 	}
 
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 		return sqlRaw, rowAffected
 	}, nil)
 	return &FirstResult{}
@@ -567,7 +749,7 @@ func (p *Scoop) Create(value interface{}) *CreateResult {
 	res := p._db.Create(value)
 	return &CreateResult{
 		RowsAffected: res.RowsAffected,
-		Error:        res.Error,
+		Error:        wrapTransient(res.Error),
 	}
 }
 
@@ -580,13 +762,21 @@ func (p *Scoop) CreateInBatches(value interface{}, batchSize int) *CreateInBatch
 	p.inc()
 	defer p.dec()
 
+	db := p._db
 	if p.ignore {
-		p._db.Clauses(clause.Insert{Modifier: "IGNORE"})
+		d := getDialectByDB(db)
+		if d.InsertIgnore == nil {
+			return &CreateInBatchesResult{
+				Error: ErrInsertIgnoreNotSupport,
+			}
+		}
+
+		db = db.Clauses(d.InsertIgnore()...)
 	}
 
-	res := p._db.CreateInBatches(value, batchSize)
+	res := db.CreateInBatches(value, batchSize)
 	return &CreateInBatchesResult{
-		Error:        res.Error,
+		Error:        wrapTransient(res.Error),
 		RowsAffected: res.RowsAffected,
 	}
 }
@@ -594,9 +784,18 @@ func (p *Scoop) CreateInBatches(value interface{}, batchSize int) *CreateInBatch
 type DeleteResult struct {
 	RowsAffected int64
 	Error        error
+
+	// Cascade 时被级联软删除的子表以及行数
+	Cascaded map[string]int64
 }
 
 func (p *Scoop) Delete() *DeleteResult {
+	if err := p.buildErr(); err != nil {
+		return &DeleteResult{
+			Error: err,
+		}
+	}
+
 	if p.cond.skip {
 		return &DeleteResult{}
 	}
@@ -608,26 +807,62 @@ func (p *Scoop) Delete() *DeleteResult {
 	if !p.unscoped && p.hasDeletedAt {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(p.model)
 
 	p.inc()
 	defer p.dec()
 
+	now := time.Now().Unix()
+	if p.cascade && !p.unscoped && p.hasDeletedAt {
+		return p.cascadeDelete(now)
+	}
+
+	return p.execDelete(now)
+}
+
+func (p *Scoop) execDelete(now int64) *DeleteResult {
 	sqlRaw := log.GetBuffer()
 	defer log.PutBuffer(sqlRaw)
 
+	p.writeDelete(sqlRaw, now, false)
+
+	p.writeComment(sqlRaw)
+
+	start := time.Now()
+	res := p._db.Exec(sqlRaw.String())
+	p.getLogger().Log(p.depth+1, start, func() (sql string, rowsAffected int64) {
+		return sqlRaw.String(), res.RowsAffected
+	}, res.Error)
+	return &DeleteResult{
+		RowsAffected: res.RowsAffected,
+		Error:        wrapTransient(res.Error),
+	}
+}
+
+// 有 deleted_at 时为软删除，returning 见 writeUpdate，软删除返回修改后的行
+func (p *Scoop) writeDelete(sqlRaw *bytes.Buffer, now int64, returning bool) {
 	// 软删除
-	if !p.unscoped && p.hasDeletedAt {
+	soft := !p.unscoped && p.hasDeletedAt
+	if soft {
 		sqlRaw.WriteString("UPDATE")
 		sqlRaw.WriteString(" ")
 		sqlRaw.WriteString(p.table)
 		sqlRaw.WriteString(" SET deleted_at = ")
-		sqlRaw.WriteString(strconv.FormatInt(time.Now().Unix(), 10))
+		sqlRaw.WriteString(strconv.FormatInt(now, 10))
 	} else {
 		sqlRaw.WriteString("DELETE FROM")
 		sqlRaw.WriteString(" ")
 		sqlRaw.WriteString(p.table)
 	}
 
+	if returning {
+		if soft {
+			p.writeOutput(sqlRaw, "inserted")
+		} else {
+			p.writeOutput(sqlRaw, "deleted")
+		}
+	}
+
 	if len(p.cond.conds) > 0 {
 		sqlRaw.WriteString(" WHERE ")
 		sqlRaw.WriteString(p.cond.conds[0])
@@ -637,14 +872,8 @@ func (p *Scoop) Delete() *DeleteResult {
 		}
 	}
 
-	start := time.Now()
-	res := p._db.Exec(sqlRaw.String())
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
-		return sqlRaw.String(), res.RowsAffected
-	}, res.Error)
-	return &DeleteResult{
-		RowsAffected: res.RowsAffected,
-		Error:        res.Error,
+	if returning {
+		p.writeReturning(sqlRaw)
 	}
 }
 
@@ -653,7 +882,58 @@ type UpdateResult struct {
 	Error        error
 }
 
+// returning 时返回修改后的行，需要 Dialect.Returning 支持
+func (p *Scoop) writeUpdate(sqlRaw *bytes.Buffer, updateMap map[string]interface{}, returning bool) []interface{} {
+	sqlRaw.WriteString("UPDATE ")
+	sqlRaw.WriteString(p.table)
+
+	sqlRaw.WriteString(" SET ")
+	d := getDialectByDB(p._db)
+	// 按列名排序，保证生成的语句稳定
+	columns := make([]string, 0, len(updateMap))
+	for k := range updateMap {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	var values []interface{}
+	for i, k := range columns {
+		if i > 0 {
+			sqlRaw.WriteString(", ")
+		}
+		sqlRaw.WriteString(d.QuoteName(k))
+		sqlRaw.WriteString("=")
+		sqlRaw.WriteString("?")
+		values = append(values, updateMap[k])
+	}
+
+	if returning {
+		p.writeOutput(sqlRaw, "inserted")
+	}
+
+	if len(p.cond.conds) > 0 {
+		sqlRaw.WriteString(" WHERE ")
+		sqlRaw.WriteString(p.cond.conds[0])
+		for _, c := range p.cond.conds[1:] {
+			sqlRaw.WriteString(" AND ")
+			sqlRaw.WriteString(c)
+		}
+	}
+
+	if returning {
+		p.writeReturning(sqlRaw)
+	}
+
+	return values
+}
+
 func (p *Scoop) update(updateMap map[string]interface{}) *UpdateResult {
+	if err := p.buildErr(); err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
 	if p.cond.skip {
 		return &UpdateResult{}
 	}
@@ -670,6 +950,7 @@ func (p *Scoop) update(updateMap map[string]interface{}) *UpdateResult {
 	if !p.unscoped && p.hasDeletedAt {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(p.model)
 
 	p.inc()
 	defer p.dec()
@@ -677,44 +958,28 @@ func (p *Scoop) update(updateMap map[string]interface{}) *UpdateResult {
 	sqlRaw := log.GetBuffer()
 	defer log.PutBuffer(sqlRaw)
 
-	sqlRaw.WriteString("UPDATE ")
-	sqlRaw.WriteString(p.table)
+	values := p.writeUpdate(sqlRaw, updateMap, false)
 
-	sqlRaw.WriteString(" SET ")
-	var i int
-	var values []interface{}
-	for k, v := range updateMap {
-		if i > 0 {
-			sqlRaw.WriteString(", ")
-		}
-		sqlRaw.WriteString(quoteFieldName(k))
-		sqlRaw.WriteString("=")
-		sqlRaw.WriteString("?")
-		values = append(values, v)
-		i++
-	}
-
-	if len(p.cond.conds) > 0 {
-		sqlRaw.WriteString(" WHERE ")
-		sqlRaw.WriteString(p.cond.conds[0])
-		for _, c := range p.cond.conds[1:] {
-			sqlRaw.WriteString(" AND ")
-			sqlRaw.WriteString(c)
-		}
-	}
+	p.writeComment(sqlRaw)
 
 	start := time.Now()
 	res := p._db.Exec(sqlRaw.String(), values...)
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 		return FormatSql(sqlRaw.String(), values...), res.RowsAffected
 	}, res.Error)
 	return &UpdateResult{
 		RowsAffected: res.RowsAffected,
-		Error:        res.Error,
+		Error:        wrapTransient(res.Error),
 	}
 }
 
 func (p *Scoop) Updates(m interface{}) *UpdateResult {
+	if err := p.buildErr(); err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+
 	if p.cond.skip {
 		return &UpdateResult{}
 	}
@@ -722,8 +987,19 @@ func (p *Scoop) Updates(m interface{}) *UpdateResult {
 	p.inc()
 	defer p.dec()
 
+	updateMap, err := toUpdateMap(m)
+	if err != nil {
+		return &UpdateResult{
+			Error: err,
+		}
+	}
+	return p.update(updateMap)
+}
+
+// 结构体忽略零值、主键以及自动维护的时间字段
+func toUpdateMap(m interface{}) (map[string]interface{}, error) {
 	if v, ok := m.(map[string]interface{}); ok {
-		return p.update(v)
+		return v, nil
 	}
 	mVal := reflect.ValueOf(m)
 	if mVal.Type().Kind() == reflect.Ptr {
@@ -731,9 +1007,7 @@ func (p *Scoop) Updates(m interface{}) *UpdateResult {
 	}
 	mType := mVal.Type()
 	if mType.Kind() != reflect.Struct {
-		return &UpdateResult{
-			Error: errors.New("m must be map or struct"),
-		}
+		return nil, errors.New("m must be map or struct")
 	}
 	fieldNum := mType.NumField()
 	valMap := make(map[string]interface{})
@@ -817,14 +1091,16 @@ func (p *Scoop) Updates(m interface{}) *UpdateResult {
 		valMap[fieldName] = fieldVal.Interface()
 	}
 	if len(valMap) == 0 {
-		return &UpdateResult{
-			Error: errors.New("no field need to update"),
-		}
+		return nil, errors.New("no field need to update")
 	}
-	return p.update(valMap)
+	return valMap, nil
 }
 
 func (p *Scoop) Count() (uint64, error) {
+	if err := p.buildErr(); err != nil {
+		return 0, err
+	}
+
 	if p.cond.skip {
 		return 0, nil
 	}
@@ -836,6 +1112,7 @@ func (p *Scoop) Count() (uint64, error) {
 	if !p.unscoped && p.hasDeletedAt {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(p.model)
 
 	p.inc()
 	defer p.dec()
@@ -844,7 +1121,7 @@ func (p *Scoop) Count() (uint64, error) {
 	defer log.PutBuffer(sqlRaw)
 
 	sqlRaw.WriteString("SELECT COUNT(*) FROM ")
-	sqlRaw.WriteString(p.table)
+	p.writeTable(sqlRaw)
 
 	if len(p.cond.conds) > 0 {
 		sqlRaw.WriteString(" WHERE ")
@@ -855,10 +1132,14 @@ func (p *Scoop) Count() (uint64, error) {
 		}
 	}
 
+	p.writeComment(sqlRaw)
+
 	start := time.Now()
 	var count uint64
-	err := p._db.Raw(sqlRaw.String()).Scan(&count).Error
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+	err := p.retryRead(func() error {
+		return p._db.Raw(sqlRaw.String()).Scan(&count).Error
+	})
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 		return sqlRaw.String(), int64(count)
 	}, err)
 
@@ -866,6 +1147,10 @@ func (p *Scoop) Count() (uint64, error) {
 }
 
 func (p *Scoop) Exist() (bool, error) {
+	if err := p.buildErr(); err != nil {
+		return false, err
+	}
+
 	if p.cond.skip {
 		return false, nil
 	}
@@ -877,6 +1162,7 @@ func (p *Scoop) Exist() (bool, error) {
 	if !p.unscoped && p.hasDeletedAt {
 		p.cond.whereRaw("deleted_at = 0")
 	}
+	p.applyDefaultScope(p.model)
 
 	p.limit = 1
 	p.offset = 0
@@ -895,7 +1181,7 @@ func (p *Scoop) Exist() (bool, error) {
 	}
 
 	sqlRaw.WriteString(" FROM ")
-	sqlRaw.WriteString(p.table)
+	p.writeTable(sqlRaw)
 
 	if len(p.cond.conds) > 0 {
 		sqlRaw.WriteString(" WHERE ")
@@ -908,10 +1194,14 @@ func (p *Scoop) Exist() (bool, error) {
 
 	sqlRaw.WriteString(" LIMIT 1 OFFSET 0")
 
+	p.writeComment(sqlRaw)
+
 	start := time.Now()
 	var count uint64
-	err := p._db.Raw(sqlRaw.String()).Scan(&count).Error
-	getDefaultLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
+	err := p.retryRead(func() error {
+		return p._db.Raw(sqlRaw.String()).Scan(&count).Error
+	})
+	p.getLogger().Log(p.depth, start, func() (sql string, rowsAffected int64) {
 		return sqlRaw.String(), 0
 	}, err)
 
@@ -949,16 +1239,26 @@ func (p *Scoop) FindByPage(opt *core.ListOption, values any) (*core.Paginate, er
 // ——————————事务——————————
 
 func (p *Scoop) Begin() *Scoop {
-	return NewScoop(p._db.Begin())
+	tx := NewScoop(p._db.Begin())
+	tx.tags = p.tags
+	tx.txStart = time.Now()
+	if _, file, line, ok := runtime.Caller(1); ok {
+		tx.txCaller = file + ":" + strconv.Itoa(line)
+	}
+	return tx
 }
 
 func (p *Scoop) Rollback() *Scoop {
+	p.dropTempTables()
 	p._db.Rollback()
+	p.finishTx(false)
 	return p
 }
 
 func (p *Scoop) Commit() *Scoop {
+	p.dropTempTables()
 	p._db.Commit()
+	p.finishTx(true)
 	return p
 }
 
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"reflect"
+	"sync"
+)
+
+// DefaultScoper 模型实现后，该模型的查询、更新、删除都会附加 DefaultScope 中的条件
+// 例如排除已归档的数据，可以通过 Unscoped 或 WithoutDefaultScope 跳过
+type DefaultScoper interface {
+	DefaultScope(cond *Cond)
+}
+
+var defaultScopes sync.Map
+
+// RegisterDefaultScope 用于无法修改的模型，优先于 DefaultScoper
+func RegisterDefaultScope(model any, scope func(cond *Cond)) {
+	rt := reflect.TypeOf(model)
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	defaultScopes.Store(rt, scope)
+}
+
+func getDefaultScope(rt reflect.Type) func(cond *Cond) {
+	for rt.Kind() == reflect.Ptr || rt.Kind() == reflect.Slice {
+		rt = rt.Elem()
+	}
+
+	if v, ok := defaultScopes.Load(rt); ok {
+		return v.(func(cond *Cond))
+	}
+
+	if x, ok := reflect.New(rt).Interface().(DefaultScoper); ok {
+		return x.DefaultScope
+	}
+
+	return nil
+}
+
+// WithoutDefaultScope 只跳过默认条件，软删除依旧生效
+func (p *Scoop) WithoutDefaultScope() *Scoop {
+	p.noDefaultScope = true
+	return p
+}
+
+// 通过 Model 指定了模型时以模型为准，而不是接收结果的结构体；同一个 Scoop 多次执行时只附加一次
+func (p *Scoop) applyDefaultScope(rt reflect.Type) {
+	if p.model != nil {
+		rt = p.model
+	}
+
+	if rt == nil || p.unscoped || p.noDefaultScope || p.defaultScoped {
+		return
+	}
+	p.defaultScoped = true
+
+	scope := getDefaultScope(rt)
+	if scope == nil {
+		return
+	}
+
+	cond := &Cond{}
+	scope(cond)
+
+	if s := cond.ToString(); s != "" {
+		p.cond.conds = append(p.cond.conds, s)
+	}
+}
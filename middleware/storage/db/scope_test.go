@@ -0,0 +1,66 @@
+package db_test
+
+import (
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+)
+
+type scopeArticle struct {
+	Id     int64 `gorm:"primaryKey"`
+	Title  string
+	Status string
+}
+
+func (scopeArticle) TableName() string {
+	return "scope_article"
+}
+
+func (scopeArticle) DefaultScope(cond *db.Cond) {
+	cond.Where("status !=", "archived")
+}
+
+func TestDefaultScope(t *testing.T) {
+	cli, err := db.New(&db.Config{
+		Address: t.TempDir(),
+		Name:    "scope",
+	}, &scopeArticle{})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	for _, a := range []*scopeArticle{{Title: "a", Status: "published"}, {Title: "b", Status: "archived"}} {
+		err = cli.NewScoop().Create(a).Error
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+	}
+
+	var articles []*scopeArticle
+	err = cli.NewScoop().Find(&articles).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(articles) != 1 || articles[0].Title != "a" {
+		t.Fatalf("unexpected result: %+v", articles)
+	}
+
+	cnt, err := db.NewModelScoop[scopeArticle](cli.Database()).Count()
+	if err != nil || cnt != 1 {
+		t.Fatalf("count:%d, err:%v", cnt, err)
+	}
+
+	cnt, err = db.NewModelScoop[scopeArticle](cli.Database()).WithoutDefaultScope().Count()
+	if err != nil || cnt != 2 {
+		t.Fatalf("count:%d, err:%v", cnt, err)
+	}
+
+	err = cli.NewScoop().Model(&scopeArticle{}).Updates(map[string]any{"title": "x"}).Error
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	archived, err := db.NewModelScoop[scopeArticle](cli.Database()).Unscoped().Equal("status", "archived").First()
+	if err != nil || archived.Title != "b" {
+		t.Fatalf("unexpected result: %+v, err:%v", archived, err)
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"github.com/lazygophers/log"
+	"sort"
+	"strings"
+)
+
+// TableStat 表的统计信息，来自数据库的统计信息，只是估算值
+type TableStat struct {
+	Table string `json:"table"`
+
+	Rows int64 `json:"rows"`
+
+	// 数据与索引占用的磁盘空间，单位字节，sqlite 需要开启 dbstat，否则为 0
+	DataSize  int64 `json:"data_size"`
+	IndexSize int64 `json:"index_size"`
+}
+
+// TableStats 读取表的行数与占用的空间，tables 为空时读取全部的表，结果按表名排序，不存在的表会被忽略
+// 行数来自统计信息，只是估算值，sqlite 没有统计信息，通过 COUNT 计算
+func (p *Client) TableStats(tables ...string) ([]*TableStat, error) {
+	var query string
+	switch p.clientType {
+	case "mysql":
+		query = "SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'"
+		if len(tables) > 0 {
+			query += " AND TABLE_NAME IN ?"
+		}
+	case "postgres", "gaussdb":
+		// 从未 ANALYZE 的表 reltuples 为 -1
+		query = "SELECT c.relname, GREATEST(c.reltuples, 0)::bigint, pg_table_size(c.oid), pg_indexes_size(c.oid) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.relkind IN ('r', 'p') AND n.nspname = current_schema()"
+		if len(tables) > 0 {
+			query += " AND c.relname IN ?"
+		}
+	case "sqlserver":
+		query = "SELECT t.name, SUM(CASE WHEN s.index_id < 2 THEN s.row_count ELSE 0 END), SUM(CASE WHEN s.index_id < 2 THEN s.used_page_count ELSE 0 END) * 8192, SUM(CASE WHEN s.index_id >= 2 THEN s.used_page_count ELSE 0 END) * 8192 FROM sys.dm_db_partition_stats s JOIN sys.tables t ON t.object_id = s.object_id"
+		if len(tables) > 0 {
+			query += " WHERE t.name IN ?"
+		}
+		query += " GROUP BY t.name"
+	case "sqlite":
+		return p.sqliteTableStats(tables)
+	default:
+		return nil, nil
+	}
+
+	var args []any
+	if len(tables) > 0 {
+		args = append(args, tables)
+	}
+
+	rows, err := p.noPrepare().Raw(query, args...).Rows()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*TableStat
+	for rows.Next() {
+		var stat TableStat
+		err = rows.Scan(&stat.Table, &stat.Rows, &stat.DataSize, &stat.IndexSize)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+		stats = append(stats, &stat)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Table < stats[j].Table
+	})
+
+	return stats, nil
+}
+
+func (p *Client) sqliteTableStats(tables []string) ([]*TableStat, error) {
+	all, err := p.noPrepare().Migrator().GetTables()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(all))
+	for _, table := range all {
+		exists[table] = true
+	}
+
+	if len(tables) == 0 {
+		tables = all
+	}
+
+	var stats []*TableStat
+	for _, table := range tables {
+		// sqlite 内部使用的表
+		if !exists[table] || strings.HasPrefix(table, "sqlite_") {
+			continue
+		}
+
+		stat := &TableStat{
+			Table: table,
+		}
+
+		err = p.noPrepare().Raw("SELECT COUNT(*) FROM " + GetDialect(p.clientType).QuoteName(table)).Scan(&stat.Rows).Error
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		// 没有编译 dbstat 时忽略占用的空间
+		err = p.noPrepare().Raw("SELECT COALESCE(SUM(CASE WHEN name = ? THEN pgsize ELSE 0 END), 0), COALESCE(SUM(CASE WHEN name <> ? THEN pgsize ELSE 0 END), 0) FROM dbstat WHERE name = ? OR name IN (SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?)", table, table, table, table).Row().Scan(&stat.DataSize, &stat.IndexSize)
+		if err != nil {
+			log.Debugf("dbstat not available, err:%v", err)
+		}
+
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Table < stats[j].Table
+	})
+
+	return stats, nil
+}
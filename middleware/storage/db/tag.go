@@ -0,0 +1,93 @@
+package db
+
+import (
+	"bytes"
+	"gorm.io/gorm"
+	"strings"
+)
+
+const tagsSettingKey = "lrpc:tags"
+
+// 只保留字母、数字以及 _-.:= ，避免注释被提前闭合，key 转为小写
+func normalizeTag(tag string) string {
+	key, value, ok := strings.Cut(tag, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+				return r
+			case r == '_', r == '-', r == '.', r == ':':
+				return r
+			case r == ' ':
+				return '_'
+			default:
+				return -1
+			}
+		}, s)
+	}
+
+	key = clean(key)
+	if !ok {
+		return key
+	}
+
+	value = clean(value)
+	if key == "" || value == "" {
+		return ""
+	}
+
+	return key + "=" + value
+}
+
+// Tag 给当前链路的语句打上标签，例如 Tag("feature=checkout")
+// 标签以注释的形式附加在语句末尾，会出现在慢日志中，gorm 的 callback 中可以通过 GetTags 获取
+// Create 由 gorm 生成语句，不会附加注释
+func (p *Scoop) Tag(tags ...string) *Scoop {
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+
+		exist := false
+		for _, t := range p.tags {
+			if t == tag {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			p.tags = append(p.tags, tag)
+		}
+	}
+
+	if len(p.tags) > 0 {
+		p._db = p._db.Set(tagsSettingKey, p.tags)
+	}
+
+	return p
+}
+
+func (p *Scoop) writeComment(b *bytes.Buffer) {
+	if len(p.tags) == 0 {
+		return
+	}
+
+	b.WriteString(" /* ")
+	b.WriteString(strings.Join(p.tags, ","))
+	b.WriteString(" */")
+}
+
+// GetTags 返回通过 Scoop.Tag 设置的标签，用于在 gorm 的 callback 中按业务统计
+func GetTags(tx *gorm.DB) []string {
+	v, ok := tx.Get(tagsSettingKey)
+	if !ok {
+		return nil
+	}
+
+	tags, _ := v.([]string)
+	return tags
+}
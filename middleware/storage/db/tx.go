@@ -0,0 +1,105 @@
+package db
+
+import (
+	"errors"
+	"github.com/lazygophers/log"
+	"time"
+)
+
+var ErrTxStatsNotSupport = errors.New("transaction stats only support mysql, postgres and gaussdb")
+
+type TxStats struct {
+	Duration  time.Duration
+	Committed bool
+
+	// 调用 Begin 的位置
+	Caller string
+
+	Tags []string
+}
+
+func (p *Scoop) finishTx(committed bool) {
+	if p.txStart.IsZero() {
+		return
+	}
+
+	duration := time.Since(p.txStart)
+	p.txStart = time.Time{}
+
+	c := getClientByDB(p._db)
+	if c == nil || c.slowTxThreshold <= 0 || duration < c.slowTxThreshold {
+		return
+	}
+
+	log.Warnf("slow transaction, duration:%s, committed:%v, caller:%s, tags:%v", duration, committed, p.txCaller, p.tags)
+
+	if c.onSlowTx != nil {
+		c.onSlowTx(&TxStats{
+			Duration:  duration,
+			Committed: committed,
+			Caller:    p.txCaller,
+			Tags:      p.tags,
+		})
+	}
+}
+
+// LongTx 数据库中正在执行的事务
+type LongTx struct {
+	// mysql 为线程 id，postgres 为 pid
+	Id       int64
+	Duration time.Duration
+
+	// mysql 为锁住的行数，postgres 为持有的锁数量
+	Locks int64
+
+	// 当前执行的语句，空闲时可能为空
+	Query string
+}
+
+// LongTransactions 通过数据库的系统表查询执行时间不少于 minDuration 的事务，用于排查锁等待
+func (p *Client) LongTransactions(minDuration time.Duration) ([]*LongTx, error) {
+	var query string
+	switch p.clientType {
+	case "mysql":
+		query = "SELECT trx_mysql_thread_id, TIMESTAMPDIFF(MICROSECOND, trx_started, NOW()), trx_rows_locked, COALESCE(trx_query, '') FROM information_schema.innodb_trx"
+	case "postgres", "gaussdb":
+		query = "SELECT a.pid, CAST(EXTRACT(EPOCH FROM now() - a.xact_start) * 1000000 AS BIGINT), " +
+			"(SELECT count(*) FROM pg_locks l WHERE l.pid = a.pid AND l.granted), COALESCE(a.query, '') " +
+			"FROM pg_stat_activity a WHERE a.xact_start IS NOT NULL AND a.pid <> pg_backend_pid()"
+	default:
+		return nil, ErrTxStatsNotSupport
+	}
+
+	rows, err := p.db.Raw(query).Rows()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*LongTx
+	for rows.Next() {
+		var tx LongTx
+		var micros int64
+		err = rows.Scan(&tx.Id, &micros, &tx.Locks, &tx.Query)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, err
+		}
+
+		tx.Duration = time.Duration(micros) * time.Microsecond
+		if tx.Duration < minDuration {
+			continue
+		}
+
+		list = append(list, &tx)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	return list, nil
+}
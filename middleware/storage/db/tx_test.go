@@ -0,0 +1,44 @@
+package db_test
+
+import (
+	"errors"
+	"github.com/lazygophers/lrpc/middleware/storage/db"
+	"testing"
+	"time"
+)
+
+func TestSlowTx(t *testing.T) {
+	var stats []*db.TxStats
+	cli, err := db.New(&db.Config{
+		Address:         t.TempDir(),
+		Name:            "tx",
+		SlowTxThreshold: time.Millisecond * 10,
+		OnSlowTx: func(s *db.TxStats) {
+			stats = append(stats, s)
+		},
+	})
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	cli.NewScoop().Begin().Commit()
+	if len(stats) != 0 {
+		t.Fatalf("unexpected slow tx: %+v", stats[0])
+	}
+
+	tx := cli.NewScoop().Tag("slow").Begin()
+	time.Sleep(time.Millisecond * 20)
+	tx.Rollback()
+
+	if len(stats) != 1 {
+		t.Fatalf("slow tx not reported")
+	}
+	if stats[0].Committed || stats[0].Duration < time.Millisecond*10 || stats[0].Caller == "" || len(stats[0].Tags) != 1 {
+		t.Errorf("unexpected stats: %+v", stats[0])
+	}
+
+	_, err = cli.LongTransactions(0)
+	if !errors.Is(err, db.ErrTxStatsNotSupport) {
+		t.Errorf("err:%v", err)
+	}
+}
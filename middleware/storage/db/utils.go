@@ -1,12 +1,15 @@
 package db
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"github.com/lazygophers/log"
 	"github.com/lazygophers/utils"
 	"github.com/lazygophers/utils/anyx"
 	"github.com/lazygophers/utils/stringx"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"reflect"
 	"strconv"
@@ -84,6 +87,13 @@ func UniqueSlice(s interface{}) interface{} {
 }
 
 func decode(field reflect.Value, col []byte) error {
+	// sql.NullString 等实现了 sql.Scanner 的类型交给自己处理
+	if field.CanAddr() {
+		if x, ok := field.Addr().Interface().(sql.Scanner); ok {
+			return x.Scan(append([]byte(nil), col...))
+		}
+	}
+
 	switch field.Kind() {
 	case reflect.Int,
 		reflect.Int8,
@@ -149,7 +159,7 @@ func decode(field reflect.Value, col []byte) error {
 		field.Set(val.Elem())
 	case reflect.Ptr:
 		val := reflect.New(field.Type().Elem())
-		err := utils.Scan(col, val.Interface())
+		err := decode(val.Elem(), col)
 		if err != nil {
 			log.Errorf("err:%v", err)
 			return err
@@ -262,8 +272,18 @@ func FormatSql(sql string, values ...interface{}) string {
 				out.WriteString(anyx.ToString(v))
 			}
 		default:
-			out.WriteString(anyx.ToString(values[i]))
+			if isNullValue(x) {
+				out.WriteString("NULL")
+				break
+			}
+
+			if v, ok := x.(driver.Valuer); ok {
+				x, _ = v.Value()
+			}
+
+			out.WriteString(anyx.ToString(reflect.Indirect(reflect.ValueOf(x)).Interface()))
 		}
+		i++
 
 	}
 
@@ -272,7 +292,26 @@ func FormatSql(sql string, values ...interface{}) string {
 }
 
 func IsUniqueIndexConflictErr(err error) bool {
-	return strings.Contains(err.Error(), "Error 1062: Duplicate entry") || strings.Contains(err.Error(), "Duplicate entry")
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+
+	dialectLock.RLock()
+	defer dialectLock.RUnlock()
+
+	for _, d := range dialects {
+		for _, x := range d.DuplicateKeyErrors {
+			if strings.Contains(err.Error(), x) {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 var ErrBatchesStop = errors.New("batches stop")
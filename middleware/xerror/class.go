@@ -0,0 +1,61 @@
+package xerror
+
+import "sync"
+
+var (
+	retryableLock  sync.RWMutex
+	retryableCodes = map[int32]bool{
+		ErrTimeout:     true,
+		ErrTooManyReq:  true,
+		ErrUnavailable: true,
+	}
+)
+
+// RegisterRetryable 将业务错误码标记为可以重试
+func RegisterRetryable(codes ...int32) {
+	retryableLock.Lock()
+	defer retryableLock.Unlock()
+
+	for _, code := range codes {
+		retryableCodes[code] = true
+	}
+}
+
+func IsNotFound(err error) bool {
+	return CheckCode(err, ErrNoData)
+}
+
+func IsConflict(err error) bool {
+	return CheckCode(err, ErrConflict)
+}
+
+func IsInvalidParam(err error) bool {
+	return CheckCode(err, ErrInvalidParam)
+}
+
+func IsNoAuth(err error) bool {
+	return CheckCode(err, ErrNoAuth)
+}
+
+func IsTimeout(err error) bool {
+	return CheckCode(err, ErrTimeout)
+}
+
+// IsSystemError 小于 0 的错误码均为系统类错误
+func IsSystemError(err error) bool {
+	code, ok := GetCode(err)
+	return ok && code < 0
+}
+
+// IsRetryable 只判断错误码，非 Error 类型的错误返回 false
+func IsRetryable(err error) bool {
+	code, ok := GetCode(err)
+	if !ok {
+		return false
+	}
+
+	retryableLock.RLock()
+	defer retryableLock.RUnlock()
+
+	return retryableCodes[code]
+}
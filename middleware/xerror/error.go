@@ -3,6 +3,8 @@ package xerror
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"time"
 )
 
 var _ error = (*Error)(nil)
@@ -17,6 +19,10 @@ const (
 	ErrInvalidParam = 1001
 	ErrNoAuth       = 1002
 	ErrNoData       = 1003
+	ErrTimeout      = 1004
+	ErrConflict     = 1005
+	ErrTooManyReq   = 1006
+	ErrUnavailable  = 1007
 )
 
 var errMap = map[int32]*Error{
@@ -36,6 +42,22 @@ var errMap = map[int32]*Error{
 		Code: ErrNoData,
 		Msg:  "No data",
 	},
+	ErrTimeout: {
+		Code: ErrTimeout,
+		Msg:  "Timeout",
+	},
+	ErrConflict: {
+		Code: ErrConflict,
+		Msg:  "Conflict",
+	},
+	ErrTooManyReq: {
+		Code: ErrTooManyReq,
+		Msg:  "Too many requests",
+	},
+	ErrUnavailable: {
+		Code: ErrUnavailable,
+		Msg:  "Service unavailable",
+	},
 }
 
 type I18n interface {
@@ -58,25 +80,48 @@ func Register(errs ...*Error) {
 type Error struct {
 	Code int32  `json:"code,omitempty" yaml:"code,omitempty"`
 	Msg  string `json:"msg,omitempty" yaml:"msg,omitempty"`
+
+	cause error
+
+	fields map[string]any
+
+	// 建议客户端重试前等待的时间
+	retryAfter time.Duration
+
+	origin uintptr
+	stack  []uintptr
 }
 
+// 包含原始错误，只用于日志，返回给客户端的是 Msg
 func (p *Error) Error() string {
-	return fmt.Sprintf("code:%d,msg:%s", p.Code, p.Msg)
+	s := fmt.Sprintf("code:%d,msg:%s", p.Code, p.Msg)
+	if p.cause != nil {
+		s += ",cause:" + p.cause.Error()
+	}
+	return s + p.fieldsString()
 }
 
 func (p *Error) Clone() *Error {
 	return &Error{
-		Code: p.Code,
-		Msg:  p.Msg,
+		Code:       p.Code,
+		Msg:        p.Msg,
+		cause:      p.cause,
+		fields:     maps.Clone(p.fields),
+		retryAfter: p.retryAfter,
 	}
 }
 
+func (p *Error) Unwrap() error {
+	return p.cause
+}
+
 func (p *Error) Is(err error) bool {
-	if x, ok := err.(*Error); ok {
+	if x, ok := err.(*Error); ok && x != nil {
 		return x.Code == p.Code
 	}
 
-	return errors.Is(p, err)
+	// 其他类型的错误交由 errors.Is 通过 Unwrap 继续匹配
+	return false
 }
 
 func (p *Error) CheckCode(code int32) bool {
@@ -84,72 +129,96 @@ func (p *Error) CheckCode(code int32) bool {
 }
 
 func Is(err1, err2 error) bool {
-	if x, ok := err1.(*Error); ok {
-		return x.Is(err2)
-	}
-
 	return errors.Is(err1, err2)
 }
 
+// CheckCode 会通过 errors.As 查找包装链中的第一个 Error
 func CheckCode(err1 error, code int32) bool {
-	if x, ok := err1.(*Error); ok {
-		return x.Code == code
+	c, ok := GetCode(err1)
+	return ok && c == code
+}
+
+func GetCode(err error) (int32, bool) {
+	var x *Error
+	if errors.As(err, &x) && x != nil {
+		return x.Code, true
 	}
 
-	return false
+	return 0, false
 }
 
 func New(code int32) *Error {
 	if err, ok := errMap[code]; ok {
-		return err.Clone()
+		return err.Clone().withStack()
 	}
 
-	return &Error{
+	return (&Error{
 		Code: code,
-	}
+	}).withStack()
 }
 
 func NewError(code int32, lang ...string) *Error {
 	if err, ok := errMap[code]; ok {
-		return err.Clone()
+		return err.Clone().withStack()
 	}
 
 	if i18n != nil {
 		msg, ok := i18n.Localize(code, lang...)
 		if ok {
-			return NewErrorWithMsg(code, msg)
+			return (&Error{
+				Code: code,
+				Msg:  msg,
+			}).withStack()
 		}
 	}
 
-	return &Error{
+	return (&Error{
 		Code: code,
-	}
+	}).withStack()
 }
 
 func NewErrorWithMsg(code int32, msg string) *Error {
-	return &Error{
+	return (&Error{
 		Code: code,
 		Msg:  msg,
-	}
+	}).withStack()
 }
 
 func NewSystemError(msg string) *Error {
-	return &Error{
+	return (&Error{
 		Code: ErrSystemError,
 		Msg:  msg,
-	}
+	}).withStack()
 }
 
 func NewInvalidParam(a ...any) *Error {
-	return &Error{
+	return (&Error{
 		Code: ErrInvalidParam,
 		Msg:  fmt.Sprint(a...),
-	}
+	}).withStack()
 }
 
 func NewNoData(a ...any) *Error {
-	return &Error{
+	return (&Error{
 		Code: ErrNoData,
 		Msg:  fmt.Sprint(a...),
+	}).withStack()
+}
+
+// Wrap 将任意错误包装为指定错误码的 Error，可以通过 errors.Unwrap 取回原始错误
+// Msg 使用错误码注册的信息，原始错误只出现在日志中，不会返回给客户端
+func Wrap(err error, code int32) *Error {
+	if err == nil {
+		return nil
 	}
+
+	x := &Error{
+		Code:  code,
+		cause: err,
+	}
+	if e, ok := errMap[code]; ok {
+		x.Msg = e.Msg
+	}
+
+	return x.withStack()
 }
@@ -0,0 +1,189 @@
+package xerror_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newNoAuth() *xerror.Error {
+	return xerror.New(xerror.ErrNoAuth)
+}
+
+func TestFingerprint(t *testing.T) {
+	if newNoAuth().Fingerprint() != newNoAuth().Fingerprint() {
+		t.Error("fingerprint should be same with same origin")
+	}
+
+	if newNoAuth().Fingerprint() == xerror.New(xerror.ErrNoAuth).Fingerprint() {
+		t.Error("fingerprint should be different with different origin")
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	err := newNoAuth()
+	if len(err.StackTrace()) == 0 {
+		t.Fatal("stack trace is empty")
+	}
+
+	t.Logf("%+v", err)
+
+	xerror.SetStackDepth(0)
+	defer xerror.SetStackDepth(32)
+
+	if len(newNoAuth().StackTrace()) != 0 {
+		t.Error("stack trace should be empty")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("cause")
+
+	err := fmt.Errorf("wrap:%w", xerror.Wrap(cause, xerror.ErrSystemError))
+	if !errors.Is(err, cause) {
+		t.Error("wrapped error should match cause")
+	}
+
+	if xerror.Wrap(nil, xerror.ErrSystemError) != nil {
+		t.Error("wrap nil should be nil")
+	}
+
+	// 原始错误只用于日志，不会作为返回给客户端的信息
+	x := xerror.Wrap(errors.New("dial tcp 10.0.0.1:3306: connection refused"), xerror.ErrSystemError)
+	if x.Msg != "System error" {
+		t.Errorf("msg:%s", x.Msg)
+	}
+	if !strings.Contains(x.Error(), "connection refused") {
+		t.Errorf("error:%s", x.Error())
+	}
+}
+
+func TestCodeClass(t *testing.T) {
+	err := fmt.Errorf("find user:%w", xerror.New(xerror.ErrNoData))
+
+	if !xerror.IsNotFound(err) {
+		t.Error("wrapped no data should be not found")
+	}
+
+	if !errors.Is(err, xerror.New(xerror.ErrNoData)) {
+		t.Error("errors.Is should match by code")
+	}
+
+	var x *xerror.Error
+	if !errors.As(err, &x) || x.Code != xerror.ErrNoData {
+		t.Error("errors.As should find the Error")
+	}
+
+	if xerror.IsConflict(err) || xerror.IsRetryable(err) {
+		t.Error("no data should not be conflict or retryable")
+	}
+
+	if !xerror.IsRetryable(xerror.New(xerror.ErrTimeout)) {
+		t.Error("timeout should be retryable")
+	}
+
+	if xerror.IsRetryable(errors.New("plain")) {
+		t.Error("plain error should not be retryable")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := fmt.Errorf("call:%w", xerror.NewRateLimited(time.Second*3))
+
+	retryAfter, ok := xerror.GetRetryAfter(err)
+	if !ok || retryAfter != time.Second*3 {
+		t.Errorf("retry after:%v, ok:%v", retryAfter, ok)
+	}
+
+	if !xerror.CheckCode(err, xerror.ErrTooManyReq) || !xerror.IsRetryable(err) {
+		t.Error("rate limited should be retryable too many requests")
+	}
+
+	if _, ok = xerror.GetRetryAfter(xerror.New(xerror.ErrUnavailable)); ok {
+		t.Error("plain unavailable should have no retry after")
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var got *xerror.Error
+	unregister := xerror.OnPanic(func(err *xerror.Error) {
+		got = err
+	})
+	defer unregister()
+
+	err := func() (err error) {
+		defer xerror.Recover(&err, "user_id", 1)
+		panic("boom")
+	}()
+
+	if !xerror.IsSystemError(err) {
+		t.Fatalf("expected system error, got %v", err)
+	}
+
+	if got == nil || got.Fields()["user_id"] != 1 {
+		t.Errorf("panic handler not called with fields: %v", got)
+	}
+
+	// 取消注册后不再调用
+	unregister()
+	got = nil
+	func() {
+		defer xerror.Recover(nil)
+		panic("boom")
+	}()
+	if got != nil {
+		t.Errorf("unregistered handler called: %v", got)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	xerror.SetThrottle(&xerror.ThrottleConfig{
+		Burst:  2,
+		Sample: 5,
+	})
+	defer xerror.SetThrottle(&xerror.ThrottleConfig{})
+	xerror.ResetOccurrences()
+
+	var logged int
+	for i := 0; i < 12; i++ {
+		// 只有数字不同的错误视为同一种
+		if xerror.ShouldLog(fmt.Errorf("dial tcp 10.0.0.%d:6379: connection refused", i)) {
+			logged++
+		}
+	}
+
+	// 前 2 次，之后第 5、10 次
+	if logged != 4 {
+		t.Errorf("expected 4 logs, got %d", logged)
+	}
+
+	occurrences := xerror.Occurrences()
+	if len(occurrences) != 1 || occurrences[0].Count != 12 || occurrences[0].Suppressed != 8 {
+		t.Errorf("unexpected occurrences: %+v", occurrences)
+	}
+}
+
+func TestThrottleEvict(t *testing.T) {
+	xerror.SetThrottle(&xerror.ThrottleConfig{
+		Window:          time.Millisecond * 50,
+		MaxFingerprints: 1,
+	})
+	defer xerror.SetThrottle(&xerror.ThrottleConfig{})
+	xerror.ResetOccurrences()
+
+	xerror.ShouldLog(errors.New("first"))
+	xerror.ShouldLog(errors.New("second"))
+	if occurrences := xerror.Occurrences(); len(occurrences) != 2 || occurrences[1].Fingerprint != "other" {
+		t.Fatalf("unexpected occurrences: %+v", occurrences)
+	}
+
+	// 超过一个周期没有出现的错误被清理，新的错误可以单独计数
+	time.Sleep(time.Millisecond * 60)
+	xerror.ShouldLog(errors.New("second"))
+	if occurrences := xerror.Occurrences(); len(occurrences) != 1 || occurrences[0].Msg != "second" {
+		t.Errorf("unexpected occurrences: %+v", occurrences)
+	}
+}
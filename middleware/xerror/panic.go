@@ -0,0 +1,159 @@
+package xerror
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type panicHandler struct {
+	fn func(err *Error)
+}
+
+var (
+	panicLock     sync.RWMutex
+	panicHandlers []*panicHandler
+)
+
+// OnPanic 注册 Recover、Go 捕获到 panic 后的处理，例如上报到 sentry，返回的函数用于取消注册
+func OnPanic(handler func(err *Error)) func() {
+	h := &panicHandler{fn: handler}
+
+	panicLock.Lock()
+	defer panicLock.Unlock()
+
+	panicHandlers = append(panicHandlers, h)
+
+	return func() {
+		panicLock.Lock()
+		defer panicLock.Unlock()
+
+		// 复制后再删除，handlePanic 可能正在遍历旧的列表
+		list := make([]*panicHandler, 0, len(panicHandlers))
+		for _, x := range panicHandlers {
+			if x != h {
+				list = append(list, x)
+			}
+		}
+		panicHandlers = list
+	}
+}
+
+// FromPanic 将 recover() 的结果转换为 ErrSystemError，fields 为 key、value 交替的上下文信息
+func FromPanic(r any, fields ...any) *Error {
+	err := &Error{
+		Code: ErrSystemError,
+		Msg:  fmt.Sprintf("panic: %v", r),
+	}
+
+	if x, ok := r.(error); ok {
+		err.cause = x
+	}
+
+	for i := 0; i+1 < len(fields); i += 2 {
+		err.WithField(fmt.Sprint(fields[i]), fields[i+1])
+	}
+
+	err.withStack()
+	err.withPanicOrigin()
+
+	return err
+}
+
+// 以触发 panic 的位置作为错误的创建位置，用于聚合
+func (p *Error) withPanicOrigin() {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	for i := 0; i+1 < n; i++ {
+		fn := runtime.FuncForPC(pcs[i] - 1)
+		if fn != nil && fn.Name() == "runtime.gopanic" {
+			p.origin = pcs[i+1]
+			return
+		}
+	}
+}
+
+func handlePanic(err *Error) {
+	log.Errorf("%v", err)
+	for _, line := range err.StackTrace() {
+		log.Error("  ", line)
+	}
+
+	panicLock.RLock()
+	handlers := panicHandlers
+	panicLock.RUnlock()
+
+	for _, handler := range handlers {
+		handler.fn(err)
+	}
+}
+
+// Recover 需要直接 defer 调用，将 panic 转换为错误写入 err
+//
+//	defer xerror.Recover(&err, "user_id", uid)
+func Recover(err *error, fields ...any) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	x := FromPanic(r, fields...)
+	handlePanic(x)
+
+	if err != nil {
+		*err = x
+	}
+}
+
+// Go 在新的 goroutine 中执行 fn，panic 和返回的错误都会记录日志，不会导致进程退出
+func Go(fn func() error, fields ...any) {
+	go func() {
+		var err error
+		defer func() {
+			if err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}()
+		defer Recover(&err, fields...)
+
+		err = fn()
+	}()
+}
+
+func (p *Error) WithField(key string, value any) *Error {
+	if p.fields == nil {
+		p.fields = make(map[string]any)
+	}
+	p.fields[key] = value
+	return p
+}
+
+// Fields 返回创建错误时附带的上下文信息
+func (p *Error) Fields() map[string]any {
+	return p.fields
+}
+
+func (p *Error) fieldsString() string {
+	if len(p.fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(p.fields))
+	for k := range p.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(",")
+		b.WriteString(k)
+		b.WriteString(":")
+		b.WriteString(fmt.Sprint(p.fields[k]))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,36 @@
+package xerror
+
+import (
+	"errors"
+	"time"
+)
+
+// NewRateLimited 请求过多，服务端返回 429 与 Retry-After，客户端至少等待 retryAfter 后重试
+func NewRateLimited(retryAfter time.Duration) *Error {
+	return New(ErrTooManyReq).WithRetryAfter(retryAfter)
+}
+
+// NewUnavailable 服务暂时不可用，服务端返回 503 与 Retry-After，客户端至少等待 retryAfter 后重试
+func NewUnavailable(retryAfter time.Duration) *Error {
+	return New(ErrUnavailable).WithRetryAfter(retryAfter)
+}
+
+// WithRetryAfter 建议客户端重试前等待的时间，小于等于 0 时表示不指定
+func (p *Error) WithRetryAfter(retryAfter time.Duration) *Error {
+	p.retryAfter = retryAfter
+	return p
+}
+
+func (p *Error) RetryAfter() time.Duration {
+	return p.retryAfter
+}
+
+// GetRetryAfter 查找包装链中的第一个 Error，没有指定等待时间时返回 false
+func GetRetryAfter(err error) (time.Duration, bool) {
+	var x *Error
+	if errors.As(err, &x) && x != nil && x.retryAfter > 0 {
+		return x.retryAfter, true
+	}
+
+	return 0, false
+}
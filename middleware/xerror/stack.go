@@ -0,0 +1,100 @@
+package xerror
+
+import (
+	"fmt"
+	"github.com/lazygophers/utils/app"
+	"hash/fnv"
+	"io"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+)
+
+// 堆栈采集的深度，为 0 时不采集，release 包默认关闭
+var stackDepth = func() *atomic.Int32 {
+	var depth atomic.Int32
+	if app.PackageType != app.Release {
+		depth.Store(32)
+	}
+	return &depth
+}()
+
+// SetStackDepth 可以在运行时调整，与创建错误并发安全
+func SetStackDepth(depth int) {
+	stackDepth.Store(int32(depth))
+}
+
+func (p *Error) withStack() *Error {
+	// 0: runtime.Callers, 1: withStack, 2: 构造函数, 3: 构造函数的调用方
+	const skip = 3
+
+	var origin [1]uintptr
+	if runtime.Callers(skip, origin[:]) > 0 {
+		p.origin = origin[0]
+	}
+
+	if depth := stackDepth.Load(); depth > 0 {
+		pcs := make([]uintptr, depth)
+		p.stack = pcs[:runtime.Callers(skip, pcs)]
+	}
+
+	return p
+}
+
+func (p *Error) originFunc() string {
+	if p.origin == 0 {
+		return ""
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{p.origin}).Next()
+	return frame.Function
+}
+
+// StackTrace 返回错误创建时的堆栈，未开启采集时为空
+func (p *Error) StackTrace() []string {
+	if len(p.stack) == 0 {
+		return nil
+	}
+
+	list := make([]string, 0, len(p.stack))
+	frames := runtime.CallersFrames(p.stack)
+	for {
+		frame, more := frames.Next()
+		list = append(list, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		if !more {
+			break
+		}
+	}
+
+	return list
+}
+
+// Fingerprint 由错误码和错误创建的位置计算，用于聚合同一类错误
+func (p *Error) Fingerprint() string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strconv.FormatInt(int64(p.Code), 10)))
+	_, _ = h.Write([]byte("|"))
+	_, _ = h.Write([]byte(p.originFunc()))
+
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Format 支持 %+v 输出带堆栈的错误信息
+func (p *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, p.Error())
+			for _, line := range p.StackTrace() {
+				_, _ = io.WriteString(s, "\n\t")
+				_, _ = io.WriteString(s, line)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, p.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", p.Error())
+	}
+}
@@ -0,0 +1,218 @@
+package xerror
+
+import (
+	"errors"
+	"github.com/lazygophers/log"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type ThrottleConfig struct {
+	// 每个周期内同一种错误完整输出的次数，默认 10
+	Burst int
+
+	// 超过 Burst 后每 Sample 次输出一次，默认 100，为负数时不再输出
+	Sample int
+
+	// 默认 1 分钟
+	Window time.Duration
+
+	// 记录的错误种类上限，超过后新的错误合并计数，默认 1000，超过一个周期没有出现的错误会被清理
+	MaxFingerprints int
+}
+
+func (p *ThrottleConfig) apply() {
+	if p.Burst == 0 {
+		p.Burst = 10
+	}
+
+	if p.Sample == 0 {
+		p.Sample = 100
+	}
+
+	if p.Window == 0 {
+		p.Window = time.Minute
+	}
+
+	if p.MaxFingerprints == 0 {
+		p.MaxFingerprints = 1000
+	}
+}
+
+// Occurrence 同一种错误出现的次数，用于监控
+type Occurrence struct {
+	Fingerprint string
+	Code        int32
+	// 第一次出现时的错误信息
+	Msg string
+
+	Count      uint64
+	Suppressed uint64
+}
+
+type occurrence struct {
+	Occurrence
+
+	windowStart time.Time
+	windowCount int
+
+	lastSeen time.Time
+}
+
+// 错误种类超过上限后合并计数的指纹
+const otherFingerprint = "other"
+
+var (
+	throttleLock   sync.Mutex
+	throttleConfig = func() *ThrottleConfig {
+		c := &ThrottleConfig{}
+		c.apply()
+		return c
+	}()
+	occurrences = map[string]*occurrence{}
+	lastSweep   time.Time
+
+	// 输出调用 LogError 的位置
+	throttleLogger = log.Clone().SetCallerDepth(4)
+)
+
+func SetThrottle(c *ThrottleConfig) {
+	c.apply()
+
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+
+	throttleConfig = c
+}
+
+// Error 使用创建时的位置，其他错误使用去掉数字后的错误信息
+func fingerprint(err error) (string, int32) {
+	var x *Error
+	if errors.As(err, &x) {
+		return x.Fingerprint(), x.Code
+	}
+
+	msg := err.Error()
+	b := make([]byte, 0, len(msg))
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= '0' && c <= '9' {
+			if len(b) == 0 || b[len(b)-1] != '#' {
+				b = append(b, '#')
+			}
+			continue
+		}
+		b = append(b, c)
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(b)
+
+	return strconv.FormatUint(h.Sum64(), 16), ErrSystemError
+}
+
+// ShouldLog 记录错误出现的次数，返回是否需要输出日志，同一种错误在每个周期内先输出 Burst 次，之后按 Sample 采样
+func ShouldLog(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	fp, code := fingerprint(err)
+	now := time.Now()
+
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+
+	c := throttleConfig
+
+	// 每个周期清理一次超过一个周期没有出现的错误，避免种类只增不减，达到上限后新的错误都合并到 other
+	if now.Sub(lastSweep) >= c.Window {
+		lastSweep = now
+		for k, v := range occurrences {
+			if now.Sub(v.lastSeen) >= c.Window {
+				delete(occurrences, k)
+			}
+		}
+	}
+
+	o, ok := occurrences[fp]
+	if !ok {
+		if len(occurrences) >= c.MaxFingerprints {
+			fp = otherFingerprint
+			o = occurrences[fp]
+		}
+
+		if o == nil {
+			o = &occurrence{
+				Occurrence: Occurrence{
+					Fingerprint: fp,
+					Code:        code,
+					Msg:         err.Error(),
+				},
+				windowStart: now,
+			}
+			occurrences[fp] = o
+		}
+	}
+
+	o.Count++
+	o.lastSeen = now
+
+	if now.Sub(o.windowStart) >= c.Window {
+		o.windowStart = now
+		o.windowCount = 0
+	}
+	o.windowCount++
+
+	if o.windowCount <= c.Burst {
+		return true
+	}
+
+	if c.Sample > 0 && (o.windowCount-c.Burst)%c.Sample == 0 {
+		return true
+	}
+
+	o.Suppressed++
+	return false
+}
+
+// LogError 按错误的种类限制输出频率，避免依赖故障时刷屏
+func LogError(err error) {
+	if !ShouldLog(err) {
+		return
+	}
+
+	throttleLogger.Errorf("err:%v", err)
+}
+
+// Occurrences 各种错误出现的次数，按次数从多到少排列，不包含已经被清理的错误
+func Occurrences() []*Occurrence {
+	throttleLock.Lock()
+	list := make([]*Occurrence, 0, len(occurrences))
+	for _, o := range occurrences {
+		x := o.Occurrence
+		list = append(list, &x)
+	}
+	throttleLock.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Fingerprint < list[j].Fingerprint
+	})
+
+	return list
+}
+
+// ResetOccurrences 清空错误的计数，一般用于测试
+func ResetOccurrences() {
+	throttleLock.Lock()
+	defer throttleLock.Unlock()
+
+	occurrences = map[string]*occurrence{}
+	lastSweep = time.Time{}
+}
@@ -0,0 +1,180 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderRetryAfter         = "Retry-After"
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+)
+
+type RateLimitConfig struct {
+	// 每个窗口内允许的请求数，为 0 时不限制
+	Limit int64
+
+	// 固定窗口的长度，默认 1 分钟
+	Window time.Duration
+
+	// 限流的维度，默认按 IP，可以使用 RateLimitByHeader、RateLimitByUser 或自定义，返回空时不限制
+	Key func(ctx *Ctx) string
+
+	// 计数的存储，为空或者出错时使用进程内的计数
+	Cache cache.Cache
+
+	// 用于单个路由关闭全局配置
+	Disable bool
+}
+
+func (c *RateLimitConfig) apply() {
+	if c.Window == 0 {
+		c.Window = time.Minute
+	}
+
+	if c.Key == nil {
+		c.Key = RateLimitByIP
+	}
+}
+
+func RateLimitByIP(ctx *Ctx) string {
+	return ctx.Context().RemoteIP().String()
+}
+
+// RateLimitByHeader 按请求头限流，例如 API Key
+func RateLimitByHeader(header string) func(ctx *Ctx) string {
+	return func(ctx *Ctx) string {
+		return ctx.Header(header)
+	}
+}
+
+// RateLimitByUser 按 Ctx.UserId 限流，需要在鉴权之后执行，未登录或者无法获取用户 ID 时按 IP
+func RateLimitByUser(ctx *Ctx) string {
+	id := ctx.UserId()
+	if id == "" {
+		return RateLimitByIP(ctx)
+	}
+
+	return "user:" + id
+}
+
+var (
+	rateLimitFallbackOnce sync.Once
+	rateLimitFallback     cache.Cache
+)
+
+// redis 不可用时退化为单机计数，限流的效果会按实例数放大
+func getRateLimitFallback() cache.Cache {
+	rateLimitFallbackOnce.Do(func() {
+		rateLimitFallback = cache.NewMem()
+	})
+	return rateLimitFallback
+}
+
+// 返回窗口内的计数
+func rateLimitIncr(c cache.Cache, key string, window time.Duration) (int64, error) {
+	cnt, err := c.Incr(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if cnt == 1 {
+		_, err = c.Expire(key, window)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return cnt, nil
+}
+
+// 固定窗口计数，每个路由单独计数
+func (p *App) rateLimitHandler(handler HandlerFunc, r *Route, c *RateLimitConfig) HandlerFunc {
+	c.apply()
+
+	prefix := "ratelimit:" + r.Method + ":" + r.Path + ":"
+
+	return func(ctx *Ctx) error {
+		id := c.Key(ctx)
+		if id == "" {
+			return handler(ctx)
+		}
+
+		now := time.Now()
+		window := now.Truncate(c.Window)
+		key := prefix + id + ":" + strconv.FormatInt(window.Unix(), 10)
+
+		var cnt int64
+		var err error
+		if c.Cache != nil {
+			cnt, err = rateLimitIncr(c.Cache, key, c.Window)
+			if err != nil {
+				log.Warnf("rate limit cache unavailable, fallback to local, err:%v", err)
+			}
+		}
+		if c.Cache == nil || err != nil {
+			cnt, err = rateLimitIncr(getRateLimitFallback(), key, c.Window)
+			if err != nil {
+				// 计数失败时放行，不影响业务
+				log.Errorf("err:%v", err)
+				return handler(ctx)
+			}
+		}
+
+		remaining := c.Limit - cnt
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		ctx.SetHeader(HeaderRateLimitLimit, strconv.FormatInt(c.Limit, 10))
+		ctx.SetHeader(HeaderRateLimitRemaining, strconv.FormatInt(remaining, 10))
+
+		if cnt <= c.Limit {
+			return handler(ctx)
+		}
+
+		retryAfter := window.Add(c.Window).Sub(now)
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+
+		log.Warnf("rate limited, method:%s, path:%s, key:%s", ctx.Method(), ctx.Path(), id)
+		return xerror.NewRateLimited(retryAfter)
+	}
+}
+
+func (p *App) rateLimitConfig(r *Route) *RateLimitConfig {
+	c := r.RateLimit
+	if c == nil {
+		c = p.c.RateLimit
+	}
+
+	if c == nil || c.Disable || c.Limit <= 0 {
+		return nil
+	}
+
+	// 全局配置会被多个路由共用，复制一份避免 apply 时并发修改
+	cc := *c
+	return &cc
+}
+
+// RouteWithRateLimit 单个路由的限流
+func RouteWithRateLimit(c *RateLimitConfig) RouteOption {
+	return func(r *Route) {
+		r.RateLimit = c
+	}
+}
+
+// RouteWithoutRateLimit 关闭单个路由的限流
+func RouteWithoutRateLimit() RouteOption {
+	return func(r *Route) {
+		r.RateLimit = &RateLimitConfig{
+			Disable: true,
+		}
+	}
+}
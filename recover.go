@@ -0,0 +1,97 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/valyala/fasthttp"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const maxCrashBodySize = 1024
+
+type CrashReport struct {
+	Method  string
+	Path    string
+	TraceId string
+
+	// 请求体，只保留文本类型并截断
+	Body string
+
+	Panic any
+	Stack string
+
+	CreatedAt time.Time
+}
+
+// CrashSink 用于将 panic 的现场上报到外部系统，例如 sentry
+type CrashSink interface {
+	Report(report *CrashReport)
+}
+
+func sanitizeCrashBody(ctx *Ctx) string {
+	contentType := ctx.Header(HeaderContentType)
+	if contentType != "" && !strings.Contains(contentType, "json") && !strings.HasPrefix(contentType, "text/") {
+		return ""
+	}
+
+	body := ctx.Body()
+	if len(body) > maxCrashBodySize {
+		return string(body[:maxCrashBodySize]) + "..."
+	}
+
+	return string(body)
+}
+
+// CrashCount 返回服务启动以来处理过的 panic 次数
+func (p *App) CrashCount() int64 {
+	return p.crashCount.Load()
+}
+
+// Run、ListenAndServe 期间，后台任务通过 xerror.Recover、xerror.Go 捕获到的 panic 同样计数并上报
+func (p *App) reportPanic(err *xerror.Error) {
+	p.crashCount.Add(1)
+
+	if p.c.CrashSink == nil {
+		return
+	}
+
+	p.c.CrashSink.Report(&CrashReport{
+		TraceId:   log.GetTrace(),
+		Panic:     err,
+		Stack:     strings.Join(err.StackTrace(), "\n"),
+		CreatedAt: time.Now(),
+	})
+}
+
+func (p *App) recover(ctx *Ctx) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	p.crashCount.Add(1)
+
+	report := &CrashReport{
+		Method:    ctx.Method(),
+		Path:      ctx.Path(),
+		TraceId:   ctx.TranceId(),
+		Body:      sanitizeCrashBody(ctx),
+		Panic:     r,
+		Stack:     string(debug.Stack()),
+		CreatedAt: time.Now(),
+	}
+
+	log.Errorf("panic:%v, method:%s, path:%s", r, report.Method, report.Path)
+	for _, line := range strings.Split(report.Stack, "\n") {
+		log.Error("  ", line)
+	}
+
+	if p.c.CrashSink != nil {
+		p.c.CrashSink.Report(report)
+	}
+
+	ctx.SendStatus(fasthttp.StatusInternalServerError)
+	p.onError(ctx, xerror.New(xerror.ErrSystemError))
+}
@@ -0,0 +1,82 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/log"
+)
+
+type RequestIdConfig struct {
+	// 默认 X-Request-ID
+	Header string
+
+	// 请求中没有携带或者不合法时用于生成，默认 log.GenTraceId
+	Generator func() string
+}
+
+func (c *RequestIdConfig) apply() {
+	if c.Header == "" {
+		c.Header = HeaderRequestId
+	}
+
+	if c.Generator == nil {
+		c.Generator = log.GenTraceId
+	}
+}
+
+// 请求 ID 会写入日志与响应头，只接受长度与字符集受限的值
+const maxRequestIdLen = 128
+
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLen {
+		return false
+	}
+
+	for i := 0; i < len(requestId); i++ {
+		switch b := requestId[i]; {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '.', b == '_', b == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Ctx) RequestId() string {
+	return p.requestId
+}
+
+// RequestId 读取或生成请求 ID 并写回响应头，需要通过 App.Use 注册
+// 上游传递的 ID 超过 128 个字符或者包含 [A-Za-z0-9._-] 以外的字符时重新生成
+// 上游没有传递 X-Trance 时直接作为 trace 使用，日志中的每一行都会带上；
+// 否则沿用上游的 trace，并输出一次两者的对应关系
+func RequestId(configs ...*RequestIdConfig) HandlerFunc {
+	c := &RequestIdConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	return func(ctx *Ctx) error {
+		requestId := ctx.Header(c.Header)
+		if !validRequestId(requestId) {
+			if requestId != "" {
+				log.Warnf("invalid request id, length:%d", len(requestId))
+			}
+			requestId = c.Generator()
+		}
+
+		ctx.requestId = requestId
+		ctx.logger = nil
+		ctx.SetHeader(c.Header, requestId)
+
+		if ctx.Header(HeaderTrance) == "" {
+			ctx.SetTranceId(requestId)
+			log.SetTrace(requestId)
+			ctx.SetHeader(HeaderTrance, requestId)
+		} else {
+			log.Infof("request id:%s", requestId)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,247 @@
+package lrpc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/utils/json"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	HeaderXCache = "X-Cache"
+
+	responseCachePrefix = "lrpc:resp:"
+)
+
+type ResponseCacheConfig struct {
+	// 缓存时间，默认 1 分钟
+	TTL time.Duration
+
+	// 参与计算 key 的 query 参数，为空时使用全部参数
+	VaryQuery []string
+
+	// 参与计算 key 的请求头
+	VaryHeaders []string
+
+	// 是否按 Ctx.UserId 区分，需要在鉴权之后执行，已登录但是无法获取用户 ID 时不使用缓存
+	VaryUser bool
+
+	// 需要缓存的响应头，默认 Content-Type、Content-Encoding、ETag、Last-Modified
+	Headers []string
+
+	// 缓存的存储，默认使用进程内缓存
+	Cache cache.Cache
+}
+
+func (c *ResponseCacheConfig) apply() {
+	if c.TTL == 0 {
+		c.TTL = time.Minute
+	}
+
+	if len(c.Headers) == 0 {
+		c.Headers = []string{HeaderContentType, HeaderContentEncoding, HeaderETag, HeaderLastModified}
+	}
+
+	if c.Cache == nil {
+		c.Cache = getResponseCacheFallback()
+	}
+}
+
+var (
+	responseCacheFallbackOnce sync.Once
+	responseCacheFallback     cache.Cache
+)
+
+func getResponseCacheFallback() cache.Cache {
+	responseCacheFallbackOnce.Do(func() {
+		responseCacheFallback = cache.NewMem()
+	})
+	return responseCacheFallback
+}
+
+type cachedResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    []byte            `json:"body,omitempty"`
+}
+
+// 同一个路由的所有缓存共用前缀，用于整体失效
+func responseCacheRoutePrefix(method, path string) string {
+	return responseCachePrefix + method + " " + path + ":"
+}
+
+// 每一部分带上长度，避免值中的分隔符拼出与其他请求相同的内容
+func writeKeyPart(h hash.Hash, b []byte) {
+	var n [binary.MaxVarintLen64]byte
+	h.Write(n[:binary.PutUvarint(n[:], uint64(len(b)))])
+	h.Write(b)
+}
+
+// 使用 sha256，不同用户的请求不能被构造为相同的 key，无法区分用户时返回 false
+func (c *ResponseCacheConfig) key(ctx *Ctx) (string, bool) {
+	h := sha256.New()
+	writeKeyPart(h, []byte(ctx.Path()))
+
+	args := ctx.Context().QueryArgs()
+	if len(c.VaryQuery) == 0 {
+		// 参数顺序不同视为同一个请求
+		var pairs [][2]string
+		args.VisitAll(func(key, value []byte) {
+			pairs = append(pairs, [2]string{string(key), string(value)})
+		})
+		sort.Slice(pairs, func(i, j int) bool {
+			if pairs[i][0] != pairs[j][0] {
+				return pairs[i][0] < pairs[j][0]
+			}
+			return pairs[i][1] < pairs[j][1]
+		})
+		for _, pair := range pairs {
+			writeKeyPart(h, []byte(pair[0]))
+			writeKeyPart(h, []byte(pair[1]))
+		}
+	} else {
+		for _, name := range c.VaryQuery {
+			writeKeyPart(h, []byte(name))
+			writeKeyPart(h, args.Peek(name))
+		}
+	}
+
+	// 响应的编码取决于请求的 Content-Type 和 Accept
+	for _, header := range append([]string{HeaderContentType, HeaderAccept}, c.VaryHeaders...) {
+		writeKeyPart(h, []byte(ctx.Header(header)))
+	}
+
+	if c.VaryUser {
+		user := ctx.UserId()
+		if user == "" && ctx.User() != nil {
+			return "", false
+		}
+		writeKeyPart(h, []byte(user))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+// 只缓存 200 的响应，缓存读写失败时直接执行 handler
+func (p *App) responseCacheHandler(handler HandlerFunc, r *Route, c *ResponseCacheConfig) HandlerFunc {
+	c.apply()
+
+	prefix := responseCacheRoutePrefix(r.Method, r.Path)
+	p.responseCaches.Store(r.Method+" "+r.Path, c)
+
+	return func(ctx *Ctx) error {
+		key, ok := c.key(ctx)
+		if !ok {
+			return handler(ctx)
+		}
+		key = prefix + key
+
+		value, err := c.Cache.Get(key)
+		if err == nil {
+			var resp cachedResponse
+			err = json.UnmarshalString(value, &resp)
+			if err == nil {
+				for k, v := range resp.Headers {
+					ctx.SetHeader(k, v)
+				}
+				ctx.SetHeader(HeaderXCache, "HIT")
+				ctx.Context().SetStatusCode(resp.Status)
+				ctx.Send(resp.Body)
+				return nil
+			}
+			log.Errorf("err:%v", err)
+		} else if err != cache.NotFound {
+			log.Errorf("err:%v", err)
+		}
+
+		err = handler(ctx)
+		if err != nil {
+			return err
+		}
+
+		ctx.SetHeader(HeaderXCache, "MISS")
+
+		rc := ctx.Context()
+		if rc.Response.StatusCode() != fasthttp.StatusOK || rc.IsBodyStream() {
+			return nil
+		}
+
+		resp := &cachedResponse{
+			Status:  rc.Response.StatusCode(),
+			Headers: make(map[string]string, len(c.Headers)),
+			Body:    rc.Response.Body(),
+		}
+		for _, header := range c.Headers {
+			if v := rc.Response.Header.Peek(header); len(v) > 0 {
+				resp.Headers[header] = string(v)
+			}
+		}
+
+		buf, err := json.MarshalString(resp)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil
+		}
+
+		err = c.Cache.SetEx(key, buf, c.TTL)
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+
+		return nil
+	}
+}
+
+func (p *App) responseCacheConfig(r *Route) *ResponseCacheConfig {
+	if r.ResponseCache == nil {
+		return nil
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		log.Warnf("response cache only support GET and HEAD, method:%s, path:%s", r.Method, r.Path)
+		return nil
+	}
+
+	cc := *r.ResponseCache
+	return &cc
+}
+
+// InvalidateResponseCache 删除路由的全部缓存，path 为注册时的路由，例如 /user/:id，一般在写接口中调用
+func (p *App) InvalidateResponseCache(paths ...string) error {
+	for _, path := range paths {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			v, ok := p.responseCaches.Load(method + " " + path)
+			if !ok {
+				continue
+			}
+
+			_, err := v.(*ResponseCacheConfig).Cache.DelPrefix(responseCacheRoutePrefix(method, path))
+			if err != nil {
+				log.Errorf("err:%v", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RouteWithResponseCache 缓存单个 GET 路由的完整响应，只用于幂等的接口
+func RouteWithResponseCache(c ...*ResponseCacheConfig) RouteOption {
+	return func(r *Route) {
+		if len(c) > 0 {
+			r.ResponseCache = c[0]
+		} else {
+			r.ResponseCache = &ResponseCacheConfig{}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package lrpc
+
+import (
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 带有 Retry-After 的错误返回对应的状态码，其他错误码只设置 Retry-After
+func setRetryAfter(ctx *Ctx, err error) {
+	retryAfter, ok := xerror.GetRetryAfter(err)
+	if !ok {
+		return
+	}
+
+	// 向上取整，避免客户端提前重试
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	ctx.SetHeader(HeaderRetryAfter, strconv.FormatInt(seconds, 10))
+
+	code, _ := xerror.GetCode(err)
+	switch code {
+	case xerror.ErrTooManyReq:
+		ctx.SendStatus(fasthttp.StatusTooManyRequests)
+	case xerror.ErrUnavailable:
+		ctx.SendStatus(fasthttp.StatusServiceUnavailable)
+	}
+}
+
+// 支持秒数与 HTTP 时间两种格式，无法解析时返回 0
+func parseRetryAfter(value []byte) time.Duration {
+	if len(value) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(string(value)); err == nil {
+		return time.Until(t)
+	}
+
+	return 0
+}
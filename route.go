@@ -1,5 +1,7 @@
 package lrpc
 
+import "time"
+
 type Route struct {
 	Method string
 	Path   string
@@ -10,6 +12,36 @@ type Route struct {
 
 	// 可以存储一些类似于权限等信息，会在调用前写入到 local 中
 	Extra map[string]any
+
+	// 为空时该路由不单独处理跨域，对全部路由生效需要通过 App.Use(Cors()) 注册
+	Cors *CorsConfig
+
+	// 处理的超时时间，为 0 时使用 Config.HandlerTimeout
+	Timeout time.Duration
+
+	// 请求体的最大大小，为 0 时不限制(依旧受 Config.MaxRequestBodySize 限制)
+	MaxBodySize int
+
+	// 合并并发的相同 GET 请求，为空时使用 Config.Dedup
+	Dedup *DedupConfig
+
+	// 限流，为空时使用 Config.RateLimit
+	RateLimit *RateLimitConfig
+
+	// 并发限制，为空时使用 Config.Concurrency
+	Concurrency *ConcurrencyConfig
+
+	// 缓存完整的响应，只能通过 RouteWithResponseCache 对单个 GET 路由开启
+	ResponseCache *ResponseCacheConfig
+
+	// 要求客户端证书，只能通过 RouteWithClientCert 设置
+	ClientCert *ClientCertConfig
+
+	// 以流的方式读取请求体，不受 Config.MaxRequestBodySize 与 MaxBodySize 的限制，用于 SaveUploads 上传大文件
+	StreamBody bool
+
+	// 由跨域配置自动注册的预检路由
+	preflight bool
 }
 
 type RouteOption func(r *Route)
@@ -49,3 +81,22 @@ func RouteWithMergeExtra(merge map[string]any) RouteOption {
 		}
 	}
 }
+
+func RouteWithTimeout(timeout time.Duration) RouteOption {
+	return func(r *Route) {
+		r.Timeout = timeout
+	}
+}
+
+func RouteWithMaxBodySize(size int) RouteOption {
+	return func(r *Route) {
+		r.MaxBodySize = size
+	}
+}
+
+// RouteWithStreamBody 请求体不会完整读入内存，全局中间件读取 Body 时依旧会读取全部内容
+func RouteWithStreamBody() RouteOption {
+	return func(r *Route) {
+		r.StreamBody = true
+	}
+}
@@ -1,8 +1,10 @@
 package lrpc
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
 	"github.com/lazygophers/utils/network"
 	"github.com/lazygophers/utils/runtime"
 	"github.com/valyala/fasthttp"
@@ -23,31 +25,73 @@ func (p *App) Handler(c *fasthttp.RequestCtx) {
 
 	ctx.SetHeader(HeaderTrance, log.GetTrace())
 
+	defer p.recover(ctx)
+
 	log.Infof("%s %s", ctx.Method(), ctx.Path())
 
+	err := p.handle(ctx)
+	if ctx.detached {
+		// 已经超时返回，handler 还在后台修改响应
+		return
+	}
+
+	if err != nil {
+		log.Errorf("err:%v", err)
+		p.onError(ctx, err)
+	}
+
+	if len(p.after) > 0 {
+		ctx.aborted = false
+		err = MergeHandler(p.after...)(ctx)
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+	}
+
+	return
+}
+
+func (p *App) handle(ctx *Ctx) error {
+	err := p.readBody(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = p.checkMaintenance(ctx)
+	if err != nil {
+		return err
+	}
+
+	p.bindFeatures(ctx)
+
+	if len(p.before) > 0 {
+		err = MergeHandler(p.before...)(ctx)
+		if err != nil {
+			return err
+		}
+
+		if ctx.IsAborted() {
+			return nil
+		}
+	}
+
 	route := p.routes[ctx.Method()]
 	if route == nil {
 		log.Errorf("not found route, method:%s, path:%s", ctx.Method(), ctx.Path())
-		c.SetStatusCode(fasthttp.StatusNotFound)
-		return
+		ctx.SendStatus(fasthttp.StatusNotFound)
+		return nil
 	}
 
 	res, ok := route.Search(ctx.Path())
 	if !ok {
 		log.Errorf("not found route, method:%s, path:%s", ctx.Method(), ctx.Path())
-		c.SetStatusCode(fasthttp.StatusNotFound)
-		return
+		ctx.SendStatus(fasthttp.StatusNotFound)
+		return nil
 	}
 
 	ctx.setParam(res.Params)
 
-	err := res.Item(ctx)
-	if err != nil {
-		log.Errorf("err:%v", err)
-		p.onError(ctx, err)
-	}
-
-	return
+	return res.Item(ctx)
 }
 
 func (p *App) ErrorHandler(c *fasthttp.RequestCtx, err error) {
@@ -67,20 +111,20 @@ func (p *App) initServer() {
 		Concurrency:                        0,
 		ReadBufferSize:                     0,
 		WriteBufferSize:                    0,
-		ReadTimeout:                        0,
-		WriteTimeout:                       0,
-		IdleTimeout:                        0,
-		MaxConnsPerIP:                      0,
+		ReadTimeout:                        p.c.ReadTimeout,
+		WriteTimeout:                       p.c.WriteTimeout,
+		IdleTimeout:                        p.c.IdleTimeout,
+		MaxConnsPerIP:                      p.c.MaxConnsPerIP,
 		MaxRequestsPerConn:                 0,
 		MaxKeepaliveDuration:               0,
 		MaxIdleWorkerDuration:              0,
 		TCPKeepalivePeriod:                 0,
-		MaxRequestBodySize:                 0,
+		MaxRequestBodySize:                 p.c.MaxRequestBodySize,
 		DisableKeepalive:                   false,
 		TCPKeepalive:                       false,
 		ReduceMemoryUsage:                  false,
 		GetOnly:                            false,
-		DisablePreParseMultipartForm:       false,
+		DisablePreParseMultipartForm:       true,
 		LogAllErrors:                       true,
 		SecureErrorLogMessage:              false,
 		DisableHeaderNamesNormalizing:      false,
@@ -90,7 +134,7 @@ func (p *App) initServer() {
 		NoDefaultContentType:               false,
 		KeepHijackedConns:                  true,
 		CloseOnShutdown:                    true,
-		StreamRequestBody:                  false,
+		StreamRequestBody:                  true,
 		ConnState:                          nil,
 		Logger:                             log.Clone(),
 		TLSConfig:                          nil,
@@ -140,7 +184,13 @@ func ListenWithLan(prev6 ...bool) ListenHandler {
 	return EmptyListenHandler
 }
 
-func (p *App) ListenAndServe(port int, handlers ...ListenHandler) (err error) {
+// ListenAndServe 运行期间后台任务的 panic 同样上报到该 App 的 CrashSink
+func (p *App) ListenAndServe(port int, handlers ...ListenHandler) error {
+	defer xerror.OnPanic(p.reportPanic)()
+	return p.listenAndServe(port, handlers...)
+}
+
+func (p *App) listenAndServe(port int, handlers ...ListenHandler) (err error) {
 	c := &listenConfig{
 		port: port,
 	}
@@ -159,6 +209,18 @@ func (p *App) ListenAndServe(port int, handlers ...ListenHandler) (err error) {
 	}
 	defer conn.Close()
 
+	if p.c.TLS != nil {
+		tc, closer, err := p.newTLSConfig(p.c.TLS)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		defer closer()
+
+		p.server.TLSConfig = tc
+		conn = tls.NewListener(conn, tc)
+	}
+
 	run := make(chan struct{}, 1)
 	go func() {
 		defer func() {
@@ -1,8 +1,27 @@
 package lrpc_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/lazygophers/lrpc"
+	"github.com/lazygophers/lrpc/middleware/core"
+	"github.com/lazygophers/lrpc/middleware/storage/cache"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/lazygophers/utils/json"
+	"github.com/valyala/fasthttp"
+	"io"
+	"mime/multipart"
 	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestInnerIp(t *testing.T) {
@@ -29,3 +48,1516 @@ func TestInnerIp(t *testing.T) {
 		}
 	}
 }
+
+func TestRecover(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Get("/panic", func(ctx *lrpc.Ctx) error {
+		panic("boom")
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/panic")
+
+	app.Handler(&c)
+
+	if c.Response.StatusCode() != fasthttp.StatusInternalServerError {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	if app.CrashCount() != 1 {
+		t.Errorf("crash count:%d", app.CrashCount())
+	}
+}
+
+type crashSink struct {
+	reports []*lrpc.CrashReport
+}
+
+func (p *crashSink) Report(report *lrpc.CrashReport) {
+	p.reports = append(p.reports, report)
+}
+
+func TestRecoverTimeout(t *testing.T) {
+	sink := &crashSink{}
+	app := lrpc.NewApp(&lrpc.Config{
+		HandlerTimeout: time.Second,
+		CrashSink:      sink,
+	})
+	app.Get("/panic", func(ctx *lrpc.Ctx) error {
+		panic("boom")
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/panic")
+
+	app.Handler(&c)
+
+	// 在超时的 goroutine 中 panic 同样上报
+	if app.CrashCount() != 1 || len(sink.reports) != 1 {
+		t.Errorf("crash count:%d, reports:%d", app.CrashCount(), len(sink.reports))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Get("/limit", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithRateLimit(&lrpc.RateLimitConfig{
+		Limit: 2,
+		Key:   lrpc.RateLimitByHeader("X-Api-Key"),
+	}))
+
+	call := func(key string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/limit")
+		c.Request.Header.Set("X-Api-Key", key)
+		app.Handler(&c)
+		return &c
+	}
+
+	for i := 0; i < 2; i++ {
+		if c := call("a"); c.Response.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("status code:%d", c.Response.StatusCode())
+		}
+	}
+
+	c := call("a")
+	if c.Response.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("status code:%d", c.Response.StatusCode())
+	}
+	if len(c.Response.Header.Peek(lrpc.HeaderRetryAfter)) == 0 {
+		t.Errorf("missing retry after")
+	}
+
+	if c := call("b"); c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+}
+
+func TestETag(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Get("/etag", func(ctx *lrpc.Ctx) error {
+		ctx.SendString("hello")
+		return nil
+	}, lrpc.RouteWithAfter(lrpc.ETag()))
+
+	call := func(inm string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/etag")
+		if inm != "" {
+			c.Request.Header.Set(lrpc.HeaderIfNoneMatch, inm)
+		}
+		app.Handler(&c)
+		return &c
+	}
+
+	c := call("")
+	etag := string(c.Response.Header.Peek(lrpc.HeaderETag))
+	if c.Response.StatusCode() != fasthttp.StatusOK || etag == "" {
+		t.Fatalf("status code:%d, etag:%s", c.Response.StatusCode(), etag)
+	}
+
+	c = call(etag)
+	if c.Response.StatusCode() != fasthttp.StatusNotModified || len(c.Response.Body()) != 0 {
+		t.Errorf("status code:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+
+	c = call(`"other"`)
+	if c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	app := lrpc.NewApp(&lrpc.Config{
+		Maintenance: &lrpc.MaintenanceConfig{
+			Allow:      []string{"/health"},
+			RetryAfter: time.Minute,
+		},
+	})
+	app.Get("/hello", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+	app.Get("/health", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+	app.Get("/healthz", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+
+	call := func(path string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(path)
+		app.Handler(&c)
+		return &c
+	}
+
+	if c := call("/hello"); c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("status code:%d", c.Response.StatusCode())
+	}
+
+	app.SetMaintenance(true)
+
+	c := call("/hello")
+	if c.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("status code:%d", c.Response.StatusCode())
+	}
+	if string(c.Response.Header.Peek(lrpc.HeaderRetryAfter)) != "60" {
+		t.Errorf("retry after:%s", c.Response.Header.Peek(lrpc.HeaderRetryAfter))
+	}
+
+	if c := call("/health"); c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	// 只匹配完整的路径分段
+	if c := call("/healthz"); c.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	app.SetMaintenance(false)
+
+	if c := call("/hello"); c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+}
+
+func TestMaintenanceIsolation(t *testing.T) {
+	c := &lrpc.Config{
+		Maintenance: &lrpc.MaintenanceConfig{
+			Allow: []string{"/health"},
+		},
+	}
+	lrpc.NewApp(c).AllowInMaintenance("/admin")
+
+	// 不修改调用方的配置
+	if len(c.Maintenance.Allow) != 1 {
+		t.Errorf("config modified:%v", c.Maintenance.Allow)
+	}
+
+	// 使用默认配置的 App 之间互不影响
+	lrpc.NewApp().AllowInMaintenance("/open")
+
+	app := lrpc.NewApp()
+	app.Get("/open", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+	app.SetMaintenance(true)
+
+	var ctx fasthttp.RequestCtx
+	ctx.Request.Header.SetMethod(fasthttp.MethodGet)
+	ctx.Request.SetRequestURI("/open")
+	app.Handler(&ctx)
+	if ctx.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("status code:%d", ctx.Response.StatusCode())
+	}
+}
+
+func TestBind(t *testing.T) {
+	type req struct {
+		Id     uint64   `path:"id" validate:"required"`
+		Page   int      `query:"page" default:"1" validate:"min=1"`
+		Status string   `query:"status" validate:"omitempty,oneof=open closed"`
+		Tags   []string `query:"tag"`
+	}
+
+	var got req
+	var bindErr error
+	app := lrpc.NewApp()
+	app.Get("/user/:id", func(ctx *lrpc.Ctx) error {
+		got = req{}
+		bindErr = ctx.BindPath(&got)
+		if bindErr == nil {
+			bindErr = ctx.BindQuery(&got)
+		}
+		return nil
+	})
+
+	call := func(uri string) {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(uri)
+		app.Handler(&c)
+	}
+
+	call("/user/12?status=open&tag=a,b&tag=c")
+	if bindErr != nil {
+		t.Fatalf("err:%v", bindErr)
+	}
+	if got.Id != 12 || got.Page != 1 || got.Status != "open" || len(got.Tags) != 3 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+
+	call("/user/12?page=x&status=unknown")
+	x, ok := bindErr.(*xerror.Error)
+	if !ok || x.Code != xerror.ErrInvalidParam {
+		t.Fatalf("err:%v", bindErr)
+	}
+	if x.Fields()["page"] == nil {
+		t.Errorf("missing page error: %v", x)
+	}
+
+	call("/user/12?status=unknown")
+	x, ok = bindErr.(*xerror.Error)
+	if !ok || x.Fields()["status"] != "oneof=open closed" {
+		t.Errorf("err:%v", bindErr)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	var hits int
+	app := lrpc.NewApp()
+	app.Get("/user/:id", func(ctx *lrpc.Ctx) error {
+		hits++
+		ctx.SendString(ctx.Parame("id") + ":" + ctx.Query("lang"))
+		return nil
+	}, lrpc.RouteWithResponseCache(&lrpc.ResponseCacheConfig{
+		VaryQuery: []string{"lang"},
+	}))
+
+	call := func(uri string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(uri)
+		app.Handler(&c)
+		return &c
+	}
+
+	call("/user/1?lang=en")
+	c := call("/user/1?lang=en&ignored=1")
+	if hits != 1 || string(c.Response.Body()) != "1:en" || string(c.Response.Header.Peek(lrpc.HeaderXCache)) != "HIT" {
+		t.Fatalf("hits:%d, body:%s", hits, c.Response.Body())
+	}
+
+	call("/user/1?lang=zh")
+	call("/user/2?lang=en")
+	if hits != 3 {
+		t.Fatalf("hits:%d", hits)
+	}
+
+	err := app.InvalidateResponseCache("/user/:id")
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+
+	call("/user/1?lang=en")
+	if hits != 4 {
+		t.Errorf("hits:%d", hits)
+	}
+
+	// 参数值中的分隔符不能拼出与其他请求相同的 key
+	app.Get("/search", func(ctx *lrpc.Ctx) error {
+		hits++
+		return nil
+	}, lrpc.RouteWithResponseCache(&lrpc.ResponseCacheConfig{}))
+
+	call("/search?a=1&b=2")
+	call("/search?a=1%26b%3D2")
+	if hits != 6 {
+		t.Errorf("hits:%d", hits)
+	}
+
+	// 不同编码的请求不能共用缓存
+	for _, accept := range []string{lrpc.MIMEJson, lrpc.MIMEJson, lrpc.MIMEMsgpack} {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/user/1?lang=en")
+		c.Request.Header.Set(lrpc.HeaderAccept, accept)
+		app.Handler(&c)
+	}
+	if hits != 8 {
+		t.Errorf("hits:%d", hits)
+	}
+
+	// 按用户 ID 区分，无法获取用户 ID 的登录用户不使用缓存
+	app.Use(func(ctx *lrpc.Ctx) error {
+		switch ctx.Header("X-User") {
+		case "1":
+			ctx.SetUser(&identifiedUser{profileUser{Id: 1}})
+		case "2":
+			ctx.SetUser(&identifiedUser{profileUser{Id: 2}})
+		case "profile":
+			ctx.SetUser(&profileUser{Id: 3})
+		}
+		return nil
+	})
+	app.Get("/me", func(ctx *lrpc.Ctx) error {
+		hits++
+		ctx.SendString(ctx.UserId())
+		return nil
+	}, lrpc.RouteWithResponseCache(&lrpc.ResponseCacheConfig{
+		VaryUser: true,
+	}))
+
+	for _, user := range []string{"1", "1", "2", "profile", "profile"} {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/me")
+		c.Request.Header.Set("X-User", user)
+		app.Handler(&c)
+	}
+	if hits != 12 {
+		t.Errorf("hits:%d", hits)
+	}
+}
+
+func TestAdmin(t *testing.T) {
+	mem := cache.NewMem()
+	allow := false
+
+	app := lrpc.NewApp()
+
+	// 管理接口必须显式设置鉴权
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic without auth")
+			}
+		}()
+		app.EnableAdmin()
+	}()
+
+	app.EnableAdmin(&lrpc.AdminConfig{
+		Auth: func(ctx *lrpc.Ctx) bool {
+			return allow
+		},
+		HealthChecks: map[string]func() error{
+			"db": func() error {
+				return errors.New("connection refused")
+			},
+		},
+		Config: map[string]any{
+			"name":     "demo",
+			"password": "123456",
+		},
+		Caches: map[string]cache.Cache{
+			"mem": mem,
+		},
+		Stats: map[string]func() (any, error){
+			"db": func() (any, error) {
+				return map[string]int64{"user": 10}, nil
+			},
+		},
+	})
+
+	call := func(method, uri string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(method)
+		c.Request.SetRequestURI(uri)
+		app.Handler(&c)
+		return &c
+	}
+
+	if c := call(fasthttp.MethodGet, "/admin/health"); c.Response.StatusCode() != fasthttp.StatusForbidden {
+		t.Fatalf("status code:%d", c.Response.StatusCode())
+	}
+	allow = true
+
+	if c := call(fasthttp.MethodGet, "/admin/health"); c.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	c := call(fasthttp.MethodGet, "/admin/config")
+	if body := string(c.Response.Body()); strings.Contains(body, "123456") || !strings.Contains(body, "demo") {
+		t.Errorf("config:%s", body)
+	}
+
+	c = call(fasthttp.MethodGet, "/admin/stats")
+	if body := string(c.Response.Body()); body != `{"db":{"user":10}}` {
+		t.Errorf("stats:%s", body)
+	}
+
+	_ = mem.Set("user:1", "a")
+	_ = mem.Set("order:1", "b")
+	c = call(fasthttp.MethodPost, "/admin/cache/invalidate?cache=mem&namespace=user:")
+	if c.Response.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("status code:%d", c.Response.StatusCode())
+	}
+	if ok, _ := mem.Exists("user:1"); ok {
+		t.Errorf("user:1 not invalidated")
+	}
+	if ok, _ := mem.Exists("order:1"); !ok {
+		t.Errorf("order:1 invalidated")
+	}
+
+	if c := call(fasthttp.MethodPost, "/admin/cache/invalidate?cache=mem"); c.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	if c := call(fasthttp.MethodPost, "/admin/log/level?level=verbose"); c.Response.StatusCode() != fasthttp.StatusBadRequest {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+
+	call(fasthttp.MethodPost, "/admin/maintenance?enable=true")
+	if !app.IsMaintenance() {
+		t.Errorf("maintenance not enabled")
+	}
+	if c := call(fasthttp.MethodGet, "/admin/health"); c.Response.StatusCode() == fasthttp.StatusForbidden {
+		t.Errorf("admin blocked in maintenance")
+	}
+}
+
+func TestFeature(t *testing.T) {
+	app := lrpc.NewApp(&lrpc.Config{
+		Features: &lrpc.FeatureConfig{
+			Flags: []*lrpc.FeatureFlag{
+				{Name: "all", Enable: true, Percent: 100},
+				{Name: "beta", Enable: true, Users: []string{"1"}},
+				{Name: "off", Enable: false, Percent: 100},
+			},
+		},
+	})
+	app.Use(func(ctx *lrpc.Ctx) error {
+		if user := ctx.Header("X-User"); user != "" {
+			ctx.SetUser(user)
+		}
+		if ctx.Header("X-Profile") != "" {
+			ctx.SetUser(&identifiedUser{profileUser{Id: 1, Email: "alice@example.com"}})
+		}
+		return nil
+	})
+	app.Get("/", func(ctx *lrpc.Ctx) error {
+		features := ctx.Features()
+		ctx.SendString(fmt.Sprintf("%v,%v,%v", features["all"], features["beta"], ctx.Feature("off")))
+		return nil
+	})
+
+	call := func(user string) string {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/")
+		c.Request.Header.Set("X-User", user)
+		app.Handler(&c)
+		return string(c.Response.Body())
+	}
+
+	if body := call("1"); body != "true,true,false" {
+		t.Errorf("body:%s", body)
+	}
+	if body := call("2"); body != "true,false,false" {
+		t.Errorf("body:%s", body)
+	}
+
+	// 结构体用户按 UserIdentifier 返回的 ID 定向
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/")
+	c.Request.Header.Set("X-Profile", "1")
+	app.Handler(&c)
+	if body := string(c.Response.Body()); body != "true,true,false" {
+		t.Errorf("body:%s", body)
+	}
+
+	app.SetFeatures(&lrpc.FeatureConfig{
+		Flags: []*lrpc.FeatureFlag{
+			{Name: "beta", Enable: true, Percent: 100},
+		},
+	})
+	if body := call("2"); body != "false,true,false" {
+		t.Errorf("body:%s", body)
+	}
+}
+
+type envelopeList struct {
+	Items    []string       `json:"items"`
+	Paginate *core.Paginate `json:"-"`
+}
+
+func (p *envelopeList) GetPaginate() *core.Paginate {
+	return p.Paginate
+}
+
+func TestEnvelope(t *testing.T) {
+	app := lrpc.NewApp(&lrpc.Config{
+		Envelope: true,
+	})
+	app.Get("/list", app.ToHandlerFunc(func(ctx *lrpc.Ctx) (*envelopeList, error) {
+		return &envelopeList{
+			Items:    []string{"a"},
+			Paginate: &core.Paginate{Limit: 1, Total: 3},
+		}, nil
+	}))
+	app.Get("/fail", func(ctx *lrpc.Ctx) error {
+		return xerror.New(xerror.ErrNoData)
+	})
+	app.Get("/raw", app.ToHandlerFunc(func(ctx *lrpc.Ctx) (*envelopeList, error) {
+		return &envelopeList{Items: []string{"b"}}, nil
+	}), lrpc.RouteWithoutEnvelope())
+
+	call := func(path string) string {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(path)
+		app.Handler(&c)
+		return string(c.Response.Body())
+	}
+
+	var rsp struct {
+		Code       int32          `json:"code"`
+		Message    string         `json:"message"`
+		Data       *envelopeList  `json:"data"`
+		RequestId  string         `json:"request_id"`
+		Pagination *core.Paginate `json:"pagination"`
+	}
+
+	err := json.Unmarshal([]byte(call("/list")), &rsp)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if rsp.Code != 0 || rsp.Data == nil || len(rsp.Data.Items) != 1 || rsp.Pagination == nil || rsp.Pagination.Total != 3 || rsp.RequestId == "" {
+		t.Errorf("unexpected response: %+v", rsp)
+	}
+
+	rsp.Data = nil
+	err = json.Unmarshal([]byte(call("/fail")), &rsp)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if rsp.Code != xerror.ErrNoData || rsp.Message == "" || rsp.Data != nil {
+		t.Errorf("unexpected response: %+v", rsp)
+	}
+
+	if body := call("/raw"); body != `{"items":["b"]}` {
+		t.Errorf("body:%s", body)
+	}
+}
+
+func TestClientCert(t *testing.T) {
+	// 没有 CA 时无法校验客户端证书，启动时报错
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected panic without client ca")
+			}
+		}()
+		lrpc.NewApp().Get("/internal", func(ctx *lrpc.Ctx) error {
+			return nil
+		}, lrpc.RouteWithClientCert())
+	}()
+
+	app := lrpc.NewApp(&lrpc.Config{
+		TLS: &lrpc.TLSConfig{
+			ClientCAFile: "ca.pem",
+		},
+	})
+	app.Get("/public", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+	app.Get("/internal", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithClientCert())
+
+	call := func(path string) int {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(path)
+		app.Handler(&c)
+		return c.Response.StatusCode()
+	}
+
+	if code := call("/public"); code != fasthttp.StatusOK {
+		t.Errorf("status code:%d", code)
+	}
+
+	// 非 TLS 的请求没有客户端证书
+	if code := call("/internal"); code != fasthttp.StatusForbidden {
+		t.Errorf("status code:%d", code)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Get("/busy", func(ctx *lrpc.Ctx) error {
+		return xerror.NewUnavailable(time.Millisecond * 1500)
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/busy")
+	app.Handler(&c)
+
+	if c.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+	if v := string(c.Response.Header.Peek(lrpc.HeaderRetryAfter)); v != "2" {
+		t.Errorf("retry after:%s", v)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	app := lrpc.NewApp()
+	app.Get("/report", func(ctx *lrpc.Ctx) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}, lrpc.RouteWithConcurrency(&lrpc.ConcurrencyConfig{
+		Limit: 1,
+	}))
+
+	call := func() *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/report")
+		app.Handler(&c)
+		return &c
+	}
+
+	done := make(chan int)
+	go func() {
+		done <- call().Response.StatusCode()
+	}()
+	<-started
+
+	c := call()
+	if c.Response.StatusCode() != fasthttp.StatusServiceUnavailable {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+	if len(c.Response.Header.Peek(lrpc.HeaderRetryAfter)) == 0 {
+		t.Errorf("missing retry after")
+	}
+
+	close(release)
+	if code := <-done; code != fasthttp.StatusOK {
+		t.Errorf("status code:%d", code)
+	}
+}
+
+func TestMemo(t *testing.T) {
+	var loads int
+	loadUser := func(ctx *lrpc.Ctx) (string, error) {
+		return lrpc.CtxMemo(ctx, "user", func() (string, error) {
+			loads++
+			return "alice", nil
+		})
+	}
+
+	app := lrpc.NewApp()
+	app.Use(func(ctx *lrpc.Ctx) error {
+		_, err := loadUser(ctx)
+		return err
+	})
+	app.Get("/profile", func(ctx *lrpc.Ctx) error {
+		user, err := loadUser(ctx)
+		if err != nil {
+			return err
+		}
+		ctx.SendString(user)
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/profile")
+		app.Handler(&c)
+
+		if string(c.Response.Body()) != "alice" {
+			t.Errorf("body:%s", c.Response.Body())
+		}
+	}
+
+	// 每个请求只加载一次，请求结束后丢弃
+	if loads != 2 {
+		t.Errorf("loads:%d", loads)
+	}
+
+	// loader panic 后不会阻塞之后的调用
+	app.Get("/panic", func(ctx *lrpc.Ctx) error {
+		func() {
+			defer func() {
+				_ = recover()
+			}()
+			_, _ = ctx.Memo("k", func() (any, error) {
+				panic("boom")
+			})
+		}()
+
+		value, err := ctx.Memo("k", func() (any, error) {
+			return "ok", nil
+		})
+		if err != nil {
+			return err
+		}
+		ctx.SendString(value.(string))
+		return nil
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/panic")
+	app.Handler(&c)
+	if string(c.Response.Body()) != "ok" {
+		t.Errorf("body:%s", c.Response.Body())
+	}
+}
+
+func TestCtxLogger(t *testing.T) {
+	var prefix string
+	var same bool
+
+	app := lrpc.NewApp()
+	app.Use(lrpc.RequestId())
+	app.Get("/orders", func(ctx *lrpc.Ctx) error {
+		ctx.SetUser("u1")
+		ctx.SetTenantId("t1")
+
+		l := ctx.Logger()
+		prefix = string(l.PrefixMsg)
+		same = core.LoggerFromContext(ctx.Context()) == l
+		return nil
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/orders")
+	c.Request.Header.Set(lrpc.HeaderRequestId, "req-1")
+	app.Handler(&c)
+
+	for _, want := range []string{"request_id=req-1", "route=GET /orders", "user=u1", "tenant=t1"} {
+		if !strings.Contains(prefix, want) {
+			t.Errorf("prefix %q should contain %q", prefix, want)
+		}
+	}
+	if !same {
+		t.Error("logger from context should be the request logger")
+	}
+
+	// 没有实现 UserIdentifier 的结构体用户不写入日志
+	app.Get("/profile", func(ctx *lrpc.Ctx) error {
+		ctx.SetUser(&profileUser{Id: 1, Email: "alice@example.com"})
+		prefix = string(ctx.Logger().PrefixMsg)
+		return nil
+	})
+
+	var pc fasthttp.RequestCtx
+	pc.Request.Header.SetMethod(fasthttp.MethodGet)
+	pc.Request.SetRequestURI("/profile")
+	app.Handler(&pc)
+	if strings.Contains(prefix, "user=") || strings.Contains(prefix, "alice") {
+		t.Errorf("prefix:%s", prefix)
+	}
+}
+
+func TestRequestId(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Use(lrpc.RequestId(&lrpc.RequestIdConfig{
+		Generator: func() string {
+			return "generated"
+		},
+	}))
+	app.Get("/", func(ctx *lrpc.Ctx) error {
+		ctx.SendString(ctx.RequestId())
+		return nil
+	})
+
+	for requestId, want := range map[string]string{
+		"":                          "generated",
+		"req-1.a_B":                 "req-1.a_B",
+		strings.Repeat("a", 128):    strings.Repeat("a", 128),
+		strings.Repeat("a", 129):    "generated",
+		"req 1":                     "generated",
+		"<script>alert(1)</script>": "generated",
+		"req\x00":                   "generated",
+	} {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/")
+		c.Request.Header.Set(lrpc.HeaderRequestId, requestId)
+		app.Handler(&c)
+
+		if body := string(c.Response.Body()); body != want || string(c.Response.Header.Peek(lrpc.HeaderRequestId)) != want {
+			t.Errorf("request id:%q, got:%s", requestId, body)
+		}
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	var events []string
+	component := func(name string, startErr, readyErr error) *lrpc.Component {
+		return &lrpc.Component{
+			Name: name,
+			Start: func() error {
+				events = append(events, "start "+name)
+				return startErr
+			},
+			Ready: func() error {
+				return readyErr
+			},
+			Stop: func() error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		}
+	}
+
+	app := lrpc.NewApp()
+	app.Register(component("db", nil, nil), component("cache", nil, nil), component("queue", errors.New("refused"), nil))
+
+	if err := app.Run(0); err == nil || !strings.Contains(err.Error(), "queue") {
+		t.Fatalf("err:%v", err)
+	}
+	if got := strings.Join(events, ","); got != "start db,start cache,start queue,stop cache,stop db" {
+		t.Errorf("events:%s", got)
+	}
+	if app.IsReady() {
+		t.Error("app should not be ready")
+	}
+
+	events = nil
+	app = lrpc.NewApp(&lrpc.Config{
+		ReadyTimeout: time.Millisecond,
+	})
+	app.Register(component("db", nil, errors.New("not connected")), lrpc.CloserComponent("cache", cache.NewMem()))
+
+	if err := app.Run(0); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Fatalf("err:%v", err)
+	}
+	if got := strings.Join(events, ","); got != "start db,stop db" {
+		t.Errorf("events:%s", got)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	c := &lrpc.CompressConfig{MinSize: 64}
+	body := strings.Repeat("hello lrpc ", 100)
+
+	app := lrpc.NewApp()
+	app.UseAfter(lrpc.Compress(c))
+	app.Get("/big", func(ctx *lrpc.Ctx) error {
+		ctx.SendString(body)
+		return nil
+	})
+	app.Get("/small", func(ctx *lrpc.Ctx) error {
+		ctx.SendString("hello")
+		return nil
+	})
+	app.Get("/stream", func(ctx *lrpc.Ctx) error {
+		ctx.Context().SetBodyStream(strings.NewReader(body), -1)
+		return nil
+	})
+
+	// 不修改调用方的配置
+	if c.Level != 0 || c.Encodings != nil || c.ContentTypes != nil {
+		t.Errorf("config changed:%+v", c)
+	}
+
+	call := func(path, accept string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(path)
+		if accept != "" {
+			c.Request.Header.Set(lrpc.HeaderAcceptEncoding, accept)
+		}
+		app.Handler(&c)
+		return &c
+	}
+
+	decode := func(encoding string, b []byte) string {
+		switch encoding {
+		case lrpc.EncodingGzip:
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+			b, err = io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+		case lrpc.EncodingZstd:
+			d, err := zstd.NewReader(nil)
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+			defer d.Close()
+			b, err = d.DecodeAll(b, nil)
+			if err != nil {
+				t.Fatalf("err:%v", err)
+			}
+		}
+		return string(b)
+	}
+
+	for accept, want := range map[string]string{
+		"":                   "",
+		"br":                 "",
+		"gzip":               lrpc.EncodingGzip,
+		"gzip, zstd":         lrpc.EncodingZstd,
+		"zstd;q=0, gzip":     lrpc.EncodingGzip,
+		"GZIP;q=0.5, br;q=1": lrpc.EncodingGzip,
+	} {
+		rsp := call("/big", accept)
+		encoding := string(rsp.Response.Header.Peek(lrpc.HeaderContentEncoding))
+		if encoding != want || decode(encoding, rsp.Response.Body()) != body {
+			t.Errorf("accept:%q, encoding:%q", accept, encoding)
+		}
+		if string(rsp.Response.Header.Peek(lrpc.HeaderVary)) != lrpc.HeaderAcceptEncoding {
+			t.Errorf("accept:%q, vary:%q", accept, rsp.Response.Header.Peek(lrpc.HeaderVary))
+		}
+	}
+
+	// 小于 MinSize 以及流式的响应不压缩
+	for _, path := range []string{"/small", "/stream"} {
+		rsp := call(path, "gzip")
+		if encoding := rsp.Response.Header.Peek(lrpc.HeaderContentEncoding); len(encoding) != 0 {
+			t.Errorf("path:%s, encoding:%s", path, encoding)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var got int
+	app := lrpc.NewApp()
+	app.Use(lrpc.Decompress(&lrpc.CompressConfig{
+		MaxDecompressSize: 1024,
+	}))
+	app.Post("/upload", func(ctx *lrpc.Ctx) error {
+		got = len(ctx.Body())
+		return nil
+	})
+
+	call := func(encoding string, size int) *fasthttp.RequestCtx {
+		body := bytes.Repeat([]byte("a"), size)
+
+		var buf bytes.Buffer
+		switch encoding {
+		case lrpc.EncodingGzip:
+			w := gzip.NewWriter(&buf)
+			_, _ = w.Write(body)
+			_ = w.Close()
+		case lrpc.EncodingZstd:
+			w, _ := zstd.NewWriter(&buf)
+			_, _ = w.Write(body)
+			_ = w.Close()
+		}
+
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodPost)
+		c.Request.SetRequestURI("/upload")
+		c.Request.Header.Set(lrpc.HeaderContentEncoding, encoding)
+		c.Request.SetBody(buf.Bytes())
+		app.Handler(&c)
+		return &c
+	}
+
+	for _, encoding := range []string{lrpc.EncodingGzip, lrpc.EncodingZstd} {
+		got = 0
+		if c := call(encoding, 1000); c.Response.StatusCode() != fasthttp.StatusOK || got != 1000 {
+			t.Errorf("%s: status code:%d, body:%d", encoding, c.Response.StatusCode(), got)
+		}
+
+		got = 0
+		if c := call(encoding, 1<<20); c.Response.StatusCode() != fasthttp.StatusRequestEntityTooLarge || got != 0 {
+			t.Errorf("%s: status code:%d, body:%d", encoding, c.Response.StatusCode(), got)
+		}
+	}
+}
+
+func TestDebugAuth(t *testing.T) {
+	app := lrpc.NewApp()
+	app.EnableDebug()
+
+	call := func(ip string) int {
+		var req fasthttp.Request
+		req.Header.SetMethod(fasthttp.MethodGet)
+		req.SetRequestURI("/debug/build")
+
+		var c fasthttp.RequestCtx
+		c.Init(&req, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+		app.Handler(&c)
+		return c.Response.StatusCode()
+	}
+
+	if code := call("127.0.0.1"); code != fasthttp.StatusOK {
+		t.Errorf("status code:%d", code)
+	}
+
+	// 默认不允许内网的其他地址
+	if code := call("10.0.0.1"); code != fasthttp.StatusForbidden {
+		t.Errorf("status code:%d", code)
+	}
+}
+
+func TestDebugPprof(t *testing.T) {
+	app := lrpc.NewApp()
+	app.EnableDebug(&lrpc.DebugConfig{
+		EnablePprof: true,
+	})
+
+	call := func(uri string) *fasthttp.RequestCtx {
+		var req fasthttp.Request
+		req.Header.SetMethod(fasthttp.MethodGet)
+		req.SetRequestURI(uri)
+
+		var c fasthttp.RequestCtx
+		c.Init(&req, &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}, nil)
+		app.Handler(&c)
+		return &c
+	}
+
+	c := call("/debug/pprof")
+	if c.Response.StatusCode() != fasthttp.StatusMovedPermanently || !strings.HasSuffix(string(c.Response.Header.Peek("Location")), "/debug/pprof/") {
+		t.Errorf("status code:%d, location:%s", c.Response.StatusCode(), c.Response.Header.Peek("Location"))
+	}
+
+	// 首页的相对链接需要能够访问
+	c = call("/debug/pprof/")
+	if c.Response.StatusCode() != fasthttp.StatusOK || !strings.Contains(string(c.Response.Body()), `href='goroutine?debug=1'`) {
+		t.Fatalf("status code:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+
+	c = call("/debug/pprof/goroutine?debug=1")
+	if c.Response.StatusCode() != fasthttp.StatusOK || !strings.Contains(string(c.Response.Body()), "goroutine profile") {
+		t.Errorf("status code:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	app := lrpc.NewApp()
+	app.Get("/items", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithCors(&lrpc.CorsConfig{AllowMethods: []string{fasthttp.MethodGet}}))
+	app.Post("/items", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithCors(&lrpc.CorsConfig{AllowMethods: []string{fasthttp.MethodPost}}))
+
+	app.Options("/custom", func(ctx *lrpc.Ctx) error {
+		ctx.SendString("custom")
+		return nil
+	})
+	app.Get("/custom", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithCors(&lrpc.CorsConfig{}))
+
+	call := func(path string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodOptions)
+		c.Request.SetRequestURI(path)
+		c.Request.Header.Set(lrpc.HeaderOrigin, "https://example.com")
+		c.Request.Header.Set(lrpc.HeaderAccessControlRequestMethod, fasthttp.MethodPost)
+		app.Handler(&c)
+		return &c
+	}
+
+	// 同一路径下的方法合并
+	c := call("/items")
+	if c.Response.StatusCode() != fasthttp.StatusNoContent {
+		t.Errorf("status code:%d", c.Response.StatusCode())
+	}
+	if methods := string(c.Response.Header.Peek(lrpc.HeaderAccessControlAllowMethods)); methods != "GET,POST" {
+		t.Errorf("allow methods:%s", methods)
+	}
+
+	// 不覆盖用户注册的 OPTIONS 路由
+	if c := call("/custom"); string(c.Response.Body()) != "custom" {
+		t.Errorf("body:%s", c.Response.Body())
+	}
+}
+
+func TestSaveUploads(t *testing.T) {
+	dir := t.TempDir()
+
+	app := lrpc.NewApp()
+	app.Post("/upload", func(ctx *lrpc.Ctx) error {
+		_, err := ctx.SaveUploads(&lrpc.UploadConfig{
+			MaxSize: 4,
+			Storage: &lrpc.LocalStorage{Dir: dir},
+		})
+		return err
+	})
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, content := range []string{"ok", "too large"} {
+		fw, err := w.CreateFormFile("file", "a.txt")
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = w.Close()
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodPost)
+	c.Request.SetRequestURI("/upload")
+	c.Request.Header.SetContentType(w.FormDataContentType())
+	c.Request.SetBody(body.Bytes())
+	app.Handler(&c)
+
+	// 第二个文件失败时删除已经保存的文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("files left:%d", len(entries))
+	}
+}
+
+type profileUser struct {
+	Id    int64
+	Email string
+}
+
+type identifiedUser struct {
+	profileUser
+}
+
+func (p *identifiedUser) UserId() string {
+	return fmt.Sprint(p.Id)
+}
+
+type stringerUser struct {
+	profileUser
+}
+
+func (p *stringerUser) String() string {
+	return p.Email
+}
+
+func TestCtxValue(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+
+	app := lrpc.NewApp()
+	app.Get("/set", func(ctx *lrpc.Ctx) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("deadline should not be set")
+		}
+		if user, ok := lrpc.CtxUser[*profileUser](ctx); ok || user != nil {
+			t.Errorf("user:%v", user)
+		}
+
+		ctx.SetUser(&profileUser{Id: 1})
+		ctx.SetTenantId("t1")
+		ctx.SetLocale("en-US")
+		ctx.SetDeadline(deadline)
+
+		user, ok := lrpc.CtxUser[*profileUser](ctx)
+		if !ok || user.Id != 1 {
+			t.Errorf("user:%v, ok:%v", user, ok)
+		}
+		// 类型不匹配时返回零值
+		if other, ok := lrpc.CtxUser[*identifiedUser](ctx); ok || other != nil {
+			t.Errorf("user:%v", other)
+		}
+		if id, ok := lrpc.CtxUser[string](ctx); ok || id != "" {
+			t.Errorf("user:%v", id)
+		}
+
+		if ctx.TenantId() != "t1" || ctx.Locale() != "en-US" {
+			t.Errorf("tenant:%s, locale:%s", ctx.TenantId(), ctx.Locale())
+		}
+		if got, ok := ctx.Deadline(); !ok || !got.Equal(deadline) {
+			t.Errorf("deadline:%v", got)
+		}
+		return nil
+	})
+	app.Get("/locale", func(ctx *lrpc.Ctx) error {
+		ctx.SendString(ctx.Locale())
+		return nil
+	})
+
+	var c fasthttp.RequestCtx
+	c.Request.Header.SetMethod(fasthttp.MethodGet)
+	c.Request.SetRequestURI("/set")
+	c.Request.Header.Set(lrpc.HeaderAcceptLanguage, "zh-CN")
+	app.Handler(&c)
+
+	// 未设置时使用 Accept-Language 中的第一个语言
+	for header, want := range map[string]string{
+		"":                            "",
+		"zh-CN":                       "zh-CN",
+		"zh-CN;q=0.9, en;q=0.8":       "zh-CN",
+		" fr-CH , fr;q=0.9, en;q=0.8": "fr-CH",
+	} {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/locale")
+		c.Request.Header.Set(lrpc.HeaderAcceptLanguage, header)
+		app.Handler(&c)
+		if got := string(c.Response.Body()); got != want {
+			t.Errorf("accept language:%q, locale:%q", header, got)
+		}
+	}
+}
+
+func TestUserId(t *testing.T) {
+	var keys []string
+	var ids []string
+
+	app := lrpc.NewApp()
+	app.Get("/user", func(ctx *lrpc.Ctx) error {
+		switch ctx.Query("type") {
+		case "profile":
+			ctx.SetUser(&profileUser{Id: 1, Email: "alice@example.com"})
+		case "identified":
+			ctx.SetUser(&identifiedUser{profileUser{Id: 1, Email: "alice@example.com"}})
+		case "stringer":
+			ctx.SetUser(&stringerUser{profileUser{Id: 1, Email: "alice@example.com"}})
+		}
+		keys = append(keys, lrpc.RateLimitByUser(ctx))
+		ids = append(ids, ctx.UserId())
+		return nil
+	})
+
+	for _, typ := range []string{"profile", "identified", "stringer"} {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/user?type=" + typ)
+		app.Handler(&c)
+	}
+
+	// 没有实现 UserIdentifier 的结构体不会作为 key 或者写入日志
+	if ids[0] != "" || strings.Contains(keys[0], "alice") {
+		t.Errorf("id:%s, key:%s", ids[0], keys[0])
+	}
+	if ids[1] != "1" || keys[1] != "user:1" {
+		t.Errorf("id:%s, key:%s", ids[1], keys[1])
+	}
+	// String 通常用于展示，可能包含用户信息，不作为用户 ID
+	if ids[2] != "" || strings.Contains(keys[2], "alice") {
+		t.Errorf("id:%s, key:%s", ids[2], keys[2])
+	}
+}
+
+// 同时有多个 Write 时报错，用于检查访问日志的并发写入
+type serialWriter struct {
+	writing    atomic.Bool
+	overlapped atomic.Bool
+	lines      atomic.Int32
+}
+
+func (p *serialWriter) Write(b []byte) (int, error) {
+	if !p.writing.CompareAndSwap(false, true) {
+		p.overlapped.Store(true)
+	}
+	time.Sleep(time.Millisecond)
+	p.writing.Store(false)
+	p.lines.Add(1)
+	return len(b), nil
+}
+
+// 记录是否被读取过
+type trackedReader struct {
+	read bool
+}
+
+func (p *trackedReader) Read(b []byte) (int, error) {
+	p.read = true
+	return 0, io.EOF
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	app := lrpc.NewApp()
+	app.UseAfter(lrpc.AccessLogger(&lrpc.AccessLogConfig{
+		Writer:  &buf,
+		LogBody: true,
+	}))
+
+	body := &trackedReader{}
+	app.Post("/login", func(ctx *lrpc.Ctx) error {
+		ctx.SetUser(&identifiedUser{profileUser{Id: 7}})
+		ctx.SetTenantId("t1")
+		return nil
+	})
+	app.Get("/file", func(ctx *lrpc.Ctx) error {
+		ctx.Context().SetBodyStream(body, 1234)
+		return nil
+	})
+	app.Get("/quiet", func(ctx *lrpc.Ctx) error {
+		return nil
+	}, lrpc.RouteWithoutAccessLog())
+
+	call := func(method, path, body string) {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(method)
+		c.Request.SetRequestURI(path)
+		if body != "" {
+			c.Request.Header.SetContentType("application/json")
+			c.Request.SetBodyString(body)
+		}
+		app.Handler(&c)
+	}
+
+	call(fasthttp.MethodPost, "/login", `[{"name":"a","password":"p1"},{"access_token":"t1"}]`)
+
+	var l lrpc.AccessLog
+	err := json.Unmarshal(buf.Bytes(), &l)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if l.User != "7" || l.Tenant != "t1" {
+		t.Errorf("user:%s, tenant:%s", l.User, l.Tenant)
+	}
+	// 与其他地方一样通过 middleware/redact 脱敏，顶层为数组时同样生效
+	if strings.Contains(l.Body, "p1") || strings.Contains(l.Body, `"t1"`) || !strings.Contains(l.Body, `"name":"a"`) {
+		t.Errorf("body:%s", l.Body)
+	}
+
+	// 流式的响应不能为了记录大小被读取
+	buf.Reset()
+	call(fasthttp.MethodGet, "/file", "")
+	err = json.Unmarshal(buf.Bytes(), &l)
+	if err != nil {
+		t.Fatalf("err:%v", err)
+	}
+	if l.Bytes != 1234 || body.read {
+		t.Errorf("bytes:%d, read:%v", l.Bytes, body.read)
+	}
+
+	buf.Reset()
+	call(fasthttp.MethodGet, "/quiet", "")
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log:%s", buf.String())
+	}
+}
+
+func TestAccessLogConcurrentWrite(t *testing.T) {
+	w := &serialWriter{}
+	app := lrpc.NewApp()
+	app.UseAfter(lrpc.AccessLogger(&lrpc.AccessLogConfig{
+		Writer: w,
+		Format: lrpc.AccessLogFormatCombined,
+	}))
+	app.Get("/", func(ctx *lrpc.Ctx) error {
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var c fasthttp.RequestCtx
+			c.Request.Header.SetMethod(fasthttp.MethodGet)
+			c.Request.SetRequestURI("/")
+			app.Handler(&c)
+		}()
+	}
+	wg.Wait()
+
+	if w.overlapped.Load() || w.lines.Load() != 8 {
+		t.Errorf("overlapped:%v, lines:%d", w.overlapped.Load(), w.lines.Load())
+	}
+}
+
+func TestDedup(t *testing.T) {
+	started := make(chan string, 4)
+	release := make(chan struct{})
+
+	app := lrpc.NewApp(&lrpc.Config{
+		Dedup: &lrpc.DedupConfig{},
+	})
+	app.Get("/items", func(ctx *lrpc.Ctx) error {
+		started <- ctx.Header(lrpc.HeaderContentType)
+		<-release
+		return ctx.SendJson(map[string]string{"type": ctx.Header(lrpc.HeaderContentType)})
+	})
+
+	call := func(contentType string, done chan<- string) {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI("/items")
+		c.Request.Header.SetContentType(contentType)
+		app.Handler(&c)
+		done <- string(c.Response.Header.ContentType())
+	}
+
+	done := make(chan string, 4)
+	go call(lrpc.MIMEJson, done)
+	<-started
+
+	// 相同的请求等待第一个请求的结果，不再执行 handler
+	go call(lrpc.MIMEJson, done)
+
+	// 编码不同的请求不能共用响应
+	go call(lrpc.MIMEMsgpack, done)
+	select {
+	case contentType := <-started:
+		if contentType != lrpc.MIMEMsgpack {
+			t.Errorf("content type:%s", contentType)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request with different encoding merged")
+	}
+
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+
+	types := map[string]int{}
+	for i := 0; i < 3; i++ {
+		types[<-done]++
+	}
+
+	if len(started) != 0 {
+		t.Errorf("handler called %d more times", len(started))
+	}
+	if types[lrpc.MIMEJson] != 2 || types[lrpc.MIMEMsgpack] != 1 {
+		t.Errorf("content types:%v", types)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	newApp := func(js string) *lrpc.App {
+		app := lrpc.NewApp()
+		app.Static("/app", &lrpc.StaticConfig{
+			FS: fstest.MapFS{
+				"index.html": {Data: []byte("<html></html>")},
+				"app.js":     {Data: []byte(js)},
+			},
+			SPA: true,
+		})
+		return app
+	}
+
+	call := func(app *lrpc.App, uri string, headers ...string) *fasthttp.RequestCtx {
+		var c fasthttp.RequestCtx
+		c.Request.Header.SetMethod(fasthttp.MethodGet)
+		c.Request.SetRequestURI(uri)
+		for i := 0; i+1 < len(headers); i += 2 {
+			c.Request.Header.Set(headers[i], headers[i+1])
+		}
+		app.Handler(&c)
+		return &c
+	}
+
+	app := newApp("0123456789")
+
+	// 前端路由返回 index.html，有后缀的路径不回退
+	c := call(app, "/app/settings/profile")
+	if string(c.Response.Body()) != "<html></html>" || string(c.Response.Header.Peek(lrpc.HeaderCacheControl)) != "no-cache" {
+		t.Errorf("body:%s", c.Response.Body())
+	}
+	c = call(app, "/app/missing.js")
+	if string(c.Response.Body()) == "<html></html>" {
+		t.Error("missing file should not fall back to index")
+	}
+
+	c = call(app, "/app/app.js", "Range", "bytes=2-4")
+	if c.Response.StatusCode() != fasthttp.StatusPartialContent || string(c.Response.Body()) != "234" {
+		t.Errorf("status:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+
+	// 没有修改时间时按内容计算 ETag
+	etag := string(call(app, "/app/app.js").Response.Header.Peek(lrpc.HeaderETag))
+	if etag == "" {
+		t.Fatal("missing etag")
+	}
+	c = call(app, "/app/app.js", lrpc.HeaderIfNoneMatch, etag)
+	if c.Response.StatusCode() != fasthttp.StatusNotModified || len(c.Response.Body()) != 0 {
+		t.Errorf("status:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+
+	c = call(newApp("9876543210"), "/app/app.js", lrpc.HeaderIfNoneMatch, etag)
+	if c.Response.StatusCode() != fasthttp.StatusOK || string(c.Response.Body()) != "9876543210" {
+		t.Errorf("status:%d, body:%s", c.Response.StatusCode(), c.Response.Body())
+	}
+}
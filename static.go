@@ -0,0 +1,241 @@
+package lrpc
+
+import (
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/valyala/fasthttp"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderCacheControl = "Cache-Control"
+	HeaderETag         = "ETag"
+	HeaderIfNoneMatch  = "If-None-Match"
+)
+
+const staticPathKey = "lrpc_static_path"
+
+type StaticConfig struct {
+	// 本地目录，FS 为空时使用
+	Root string
+
+	// 例如 embed.FS，优先于 Root
+	FS fs.FS
+
+	// 目录的默认文件，默认 index.html
+	Index string
+
+	// 找不到文件且路径没有后缀时返回 Index，用于前端路由
+	SPA bool
+
+	// Cache-Control 的 max-age，为 0 时不设置，Index 始终为 no-cache
+	MaxAge time.Duration
+
+	DisableByteRange bool
+
+	// 按 Accept-Encoding 返回压缩后的文件
+	Compress bool
+}
+
+func (c *StaticConfig) apply() {
+	if c.Index == "" {
+		c.Index = "index.html"
+	}
+
+	if c.FS == nil {
+		root := c.Root
+		if root == "" {
+			root = "."
+		}
+		c.FS = os.DirFS(root)
+	}
+}
+
+type static struct {
+	c *StaticConfig
+
+	prefix       string
+	cacheControl string
+	handler      fasthttp.RequestHandler
+
+	// 按内容计算的 ETag，key 为文件名
+	etags sync.Map
+}
+
+func (p *static) lookup(name string) (string, fs.FileInfo) {
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(p.c.FS, name)
+	if err != nil {
+		return "", nil
+	}
+
+	if info.IsDir() {
+		name = path.Join(name, p.c.Index)
+		info, err = fs.Stat(p.c.FS, name)
+		if err != nil || info.IsDir() {
+			return "", nil
+		}
+	}
+
+	return name, info
+}
+
+// embed.FS 没有修改时间，只按大小无法区分内容不同的版本，改为按内容计算
+// 没有修改时间的文件视为不会变化，只计算一次，计算失败时返回空
+func (p *static) etag(name string, info fs.FileInfo) string {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`W/"%x-%x"`, info.ModTime().Unix(), info.Size())
+	}
+
+	if v, ok := p.etags.Load(name); ok {
+		return v.(string)
+	}
+
+	file, err := p.c.FS.Open(name)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+	defer file.Close()
+
+	h := fnv.New64a()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return ""
+	}
+
+	etag := fmt.Sprintf(`W/"%x-%x"`, h.Sum64(), info.Size())
+	p.etags.Store(name, etag)
+	return etag
+}
+
+func matchETag(header, etag string) bool {
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// 返回 false 表示没有对应的文件，交给后续的路由处理
+func (p *static) serve(ctx *Ctx) bool {
+	reqPath := ctx.Path()
+	if reqPath+"/" == p.prefix {
+		reqPath = p.prefix
+	}
+
+	if !strings.HasPrefix(reqPath, p.prefix) {
+		return false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+reqPath[len(p.prefix):]), "/")
+
+	name, info := p.lookup(name)
+	if info == nil {
+		if !p.c.SPA || path.Ext(ctx.Path()) != "" {
+			return false
+		}
+
+		name, info = p.lookup(p.c.Index)
+		if info == nil {
+			return false
+		}
+	}
+
+	etag := p.etag(name, info)
+	if etag != "" {
+		ctx.SetHeader(HeaderETag, etag)
+	}
+
+	if path.Base(name) == p.c.Index {
+		ctx.SetHeader(HeaderCacheControl, "no-cache")
+	} else if p.cacheControl != "" {
+		ctx.SetHeader(HeaderCacheControl, p.cacheControl)
+	}
+
+	if h := ctx.Header(HeaderIfNoneMatch); h != "" && etag != "" && matchETag(h, etag) {
+		ctx.SendStatus(fasthttp.StatusNotModified)
+		return true
+	}
+
+	ctx.Context().SetUserValue(staticPathKey, "/"+name)
+	p.handler(ctx.Context())
+
+	return true
+}
+
+// Static 将 prefix 下的 GET/HEAD 请求映射到文件，已注册的路由优先
+// 通过 Use 注册，与其他全局中间件按注册顺序在路由之前执行，命中文件后不再执行之后的中间件
+// 需要对静态文件生效的中间件（例如鉴权）要在 Static 之前 Use
+func (p *App) Static(prefix string, configs ...*StaticConfig) {
+	c := &StaticConfig{}
+	if len(configs) > 0 {
+		c = configs[0]
+	}
+	c.apply()
+
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	s := &static{
+		c:      c,
+		prefix: prefix,
+	}
+
+	if c.MaxAge > 0 {
+		s.cacheControl = "public, max-age=" + strconv.FormatInt(int64(c.MaxAge.Seconds()), 10)
+	}
+
+	s.handler = (&fasthttp.FS{
+		FS:              c.FS,
+		AcceptByteRange: !c.DisableByteRange,
+		Compress:        c.Compress,
+		CompressBrotli:  c.Compress,
+		PathRewrite: func(ctx *fasthttp.RequestCtx) []byte {
+			name, _ := ctx.UserValue(staticPathKey).(string)
+			return []byte(name)
+		},
+		PathNotFound: func(ctx *fasthttp.RequestCtx) {
+			log.Warnf("static file not found, path:%s", ctx.Path())
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		},
+	}).NewRequestHandler()
+
+	p.Use(func(ctx *Ctx) error {
+		switch ctx.Method() {
+		case fasthttp.MethodGet, fasthttp.MethodHead:
+		default:
+			return nil
+		}
+
+		if route := p.routes[ctx.Method()]; route != nil {
+			if _, ok := route.Search(ctx.Path()); ok {
+				return nil
+			}
+		}
+
+		if s.serve(ctx) {
+			ctx.Abort()
+		}
+
+		return nil
+	})
+}
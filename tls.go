@@ -0,0 +1,277 @@
+package lrpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+type TLSConfig struct {
+	// 证书与私钥文件，文件变化时自动重新加载，加载失败时继续使用旧的证书
+	CertFile string
+	KeyFile  string
+
+	// 通过 ACME 自动申请与续期证书，设置后忽略 CertFile/KeyFile
+	AutoCert *AutoCertConfig
+
+	// 用于校验客户端证书的 CA，设置后客户端可以提供证书，RouteWithClientCert 的路由要求必须提供
+	ClientCAFile string
+	// 全部请求都必须提供客户端证书
+	RequireClientCert bool
+
+	// 默认 tls.VersionTLS12
+	MinVersion uint16
+}
+
+type AutoCertConfig struct {
+	// 允许申请证书的域名，不能为空
+	Domains []string
+
+	// 证书的缓存目录，默认 ./autocert
+	CacheDir string
+
+	// 用于接收证书到期等通知
+	Email string
+
+	// 默认为 Let's Encrypt
+	DirectoryURL string
+}
+
+func (p *TLSConfig) apply() {
+	if p.MinVersion == 0 {
+		p.MinVersion = tls.VersionTLS12
+	}
+
+	if p.AutoCert != nil && p.AutoCert.CacheDir == "" {
+		p.AutoCert.CacheDir = "autocert"
+	}
+}
+
+// certReloader 在证书文件变化时重新加载，k8s 的 secret 通过替换软链更新，所以监听所在的目录
+type certReloader struct {
+	certFile, keyFile string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	watcher *fsnotify.Watcher
+	once    sync.Once
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	p := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	err := p.reload()
+	if err != nil {
+		return nil, err
+	}
+
+	p.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	dirs := map[string]bool{
+		filepath.Dir(certFile): true,
+		filepath.Dir(keyFile):  true,
+	}
+	for dir := range dirs {
+		err = p.watcher.Add(dir)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			_ = p.watcher.Close()
+			return nil, err
+		}
+	}
+
+	go p.watch()
+
+	return p, nil
+}
+
+func (p *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
+
+	p.cert.Store(&cert)
+
+	return nil
+}
+
+func (p *certReloader) watch() {
+	for {
+		select {
+		case event, ok := <-p.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			// 写入证书与私钥之间可能不匹配，失败时等待下一次变化
+			if p.reload() == nil {
+				log.Infof("tls certificate reloaded, file:%s", event.Name)
+			}
+
+		case err, ok := <-p.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Errorf("err:%v", err)
+		}
+	}
+}
+
+func (p *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return p.cert.Load(), nil
+}
+
+func (p *certReloader) Close() error {
+	var err error
+	p.once.Do(func() {
+		err = p.watcher.Close()
+	})
+	return err
+}
+
+// 生成监听使用的 tls 配置，返回的 closer 用于停止监听证书文件
+func (p *App) newTLSConfig(c *TLSConfig) (*tls.Config, func(), error) {
+	c.apply()
+
+	tc := &tls.Config{
+		MinVersion: c.MinVersion,
+		// fasthttp 只支持 HTTP/1.1
+		NextProtos: []string{"http/1.1"},
+	}
+
+	closer := func() {}
+	switch {
+	case c.AutoCert != nil:
+		if len(c.AutoCert.Domains) == 0 {
+			return nil, nil, errors.New("autocert domains is empty")
+		}
+
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.AutoCert.Domains...),
+			Cache:      autocert.DirCache(c.AutoCert.CacheDir),
+			Email:      c.AutoCert.Email,
+		}
+		if c.AutoCert.DirectoryURL != "" {
+			m.Client = &acme.Client{
+				DirectoryURL: c.AutoCert.DirectoryURL,
+			}
+		}
+
+		// 使用 tls-alpn-01 验证，需要通过 443 端口访问
+		tc.GetCertificate = m.GetCertificate
+		tc.NextProtos = append(tc.NextProtos, acme.ALPNProto)
+
+	case c.CertFile != "" && c.KeyFile != "":
+		reloader, err := newCertReloader(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tc.GetCertificate = reloader.GetCertificate
+		closer = func() {
+			_ = reloader.Close()
+		}
+
+	default:
+		return nil, nil, errors.New("tls cert file or autocert is required")
+	}
+
+	if c.ClientCAFile != "" {
+		buf, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			closer()
+			return nil, nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			closer()
+			return nil, nil, errors.New("invalid client ca file")
+		}
+
+		tc.ClientCAs = pool
+		if c.RequireClientCert {
+			tc.ClientAuth = tls.RequireAndVerifyClientCert
+		} else {
+			// 是否必须提供由路由决定
+			tc.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return tc, closer, nil
+}
+
+// ClientCert 返回已经通过校验的客户端证书，非 TLS 或者客户端没有提供时返回 nil
+func (p *Ctx) ClientCert() *x509.Certificate {
+	state := p.ctx.TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// RouteWithClientCert 要求客户端提供通过 TLSConfig.ClientCAFile 校验的证书，verify 用于额外的校验，例如检查证书的 CN
+// 没有设置 ClientCAFile 时注册路由会 panic，否则该路由总是返回 403
+func RouteWithClientCert(verify ...func(cert *x509.Certificate) error) RouteOption {
+	return func(r *Route) {
+		r.ClientCert = &ClientCertConfig{}
+		if len(verify) > 0 {
+			r.ClientCert.Verify = verify[0]
+		}
+	}
+}
+
+type ClientCertConfig struct {
+	Verify func(cert *x509.Certificate) error
+}
+
+func (p *App) clientCertHandler(c *ClientCertConfig) HandlerFunc {
+	if p.c.TLS == nil || p.c.TLS.ClientCAFile == "" {
+		panic("client cert requires TLSConfig.ClientCAFile")
+	}
+
+	return func(ctx *Ctx) error {
+		cert := ctx.ClientCert()
+		if cert == nil {
+			log.Warnf("client certificate required, path:%s", ctx.Path())
+			ctx.SendStatus(fasthttp.StatusForbidden)
+			return xerror.New(xerror.ErrNoAuth)
+		}
+
+		if c.Verify != nil {
+			err := c.Verify(cert)
+			if err != nil {
+				log.Warnf("client certificate rejected, path:%s, subject:%s, err:%v", ctx.Path(), cert.Subject, err)
+				ctx.SendStatus(fasthttp.StatusForbidden)
+				return xerror.New(xerror.ErrNoAuth)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,231 @@
+package lrpc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/lazygophers/log"
+	"github.com/lazygophers/lrpc/middleware/xerror"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadStorage 上传文件的存储后端，例如本地磁盘、S3、GridFS，返回文件的访问位置
+type UploadStorage interface {
+	Save(file *UploadFile, r io.Reader) (string, error)
+
+	// Remove 删除 Save 返回的文件，同一个请求中后续的文件保存失败时调用
+	Remove(location string) error
+}
+
+type UploadFile struct {
+	Field       string `json:"field,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+
+	// 由存储后端返回
+	Location string `json:"location,omitempty"`
+}
+
+type UploadConfig struct {
+	// 表单字段，为空时处理全部文件
+	Field string
+
+	// 单个文件的最大大小，为 0 时不限制
+	MaxSize int64
+
+	// 允许的 Content-Type 前缀或文件后缀，例如 image/ 或 .pdf，为空时不限制
+	AllowTypes []string
+
+	Storage UploadStorage
+
+	// 每写入一块数据回调一次
+	OnProgress func(file *UploadFile, written int64)
+}
+
+// LocalStorage 将文件保存到本地目录，文件名随机生成以避免覆盖
+type LocalStorage struct {
+	Dir string
+}
+
+func (p *LocalStorage) Save(file *UploadFile, r io.Reader) (string, error) {
+	err := os.MkdirAll(p.Dir, 0755)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	buf := make([]byte, 16)
+	_, err = rand.Read(buf)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+
+	name := filepath.Join(p.Dir, hex.EncodeToString(buf)+strings.ToLower(filepath.Ext(file.Filename)))
+
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		_ = os.Remove(name)
+		return "", err
+	}
+
+	return name, nil
+}
+
+func (p *LocalStorage) Remove(location string) error {
+	err := os.Remove(location)
+	if err != nil && !os.IsNotExist(err) {
+		log.Errorf("err:%v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *UploadConfig) allowType(file *UploadFile) bool {
+	if len(c.AllowTypes) == 0 {
+		return true
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	for _, t := range c.AllowTypes {
+		if strings.HasPrefix(t, ".") {
+			if strings.EqualFold(t, ext) {
+				return true
+			}
+		} else if strings.HasPrefix(file.ContentType, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type uploadReader struct {
+	r       io.Reader
+	file    *UploadFile
+	written int64
+	limit   int64
+
+	onProgress func(file *UploadFile, written int64)
+}
+
+func (p *uploadReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.written += int64(n)
+
+	if p.limit > 0 && p.written > p.limit {
+		return n, xerror.NewInvalidParam(fmt.Sprintf("file %s too large, limit %d", p.file.Filename, p.limit))
+	}
+
+	if n > 0 && p.onProgress != nil {
+		p.onProgress(p.file, p.written)
+	}
+
+	return n, err
+}
+
+func (p *Ctx) saveUpload(c *UploadConfig, part *multipart.Part) (*UploadFile, error) {
+	file := &UploadFile{
+		Field:       part.FormName(),
+		Filename:    filepath.Base(part.FileName()),
+		ContentType: part.Header.Get(HeaderContentType),
+	}
+
+	if !c.allowType(file) {
+		return nil, xerror.NewInvalidParam("file type not allowed ", file.ContentType)
+	}
+
+	r := &uploadReader{
+		r:          part,
+		file:       file,
+		limit:      c.MaxSize,
+		onProgress: c.OnProgress,
+	}
+
+	var err error
+	file.Location, err = c.Storage.Save(file, r)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+	file.Size = r.written
+
+	return file, nil
+}
+
+func removeUploads(c *UploadConfig, files []*UploadFile) {
+	for _, file := range files {
+		_ = c.Storage.Remove(file.Location)
+	}
+}
+
+// 路由通过 RouteWithStreamBody 开启时直接读取请求流，否则读取已经在内存中的请求体
+func (p *Ctx) multipartReader() (*multipart.Reader, error) {
+	boundary := string(p.ctx.Request.Header.MultipartFormBoundary())
+	if boundary == "" {
+		return nil, xerror.NewInvalidParam("invalid multipart form")
+	}
+
+	body := p.ctx.RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(p.ctx.Request.Body())
+	}
+
+	return multipart.NewReader(body, boundary), nil
+}
+
+// SaveUploads 逐个读取 multipart 请求中的文件并写入存储后端，任意一个文件失败时删除已经保存的文件
+// 文件不会完整读入内存，大小与类型在读取的过程中检查，需要配合 RouteWithStreamBody 使用
+func (p *Ctx) SaveUploads(c *UploadConfig) ([]*UploadFile, error) {
+	if c.Storage == nil {
+		return nil, xerror.NewInvalidParam("upload storage is required")
+	}
+
+	mr, err := p.multipartReader()
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	var files []*UploadFile
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Errorf("err:%v", err)
+			removeUploads(c, files)
+			return nil, xerror.NewInvalidParam("invalid multipart form")
+		}
+
+		if part.FileName() == "" || (c.Field != "" && part.FormName() != c.Field) {
+			continue
+		}
+
+		file, err := p.saveUpload(c, part)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			removeUploads(c, files)
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,136 @@
+package lrpc
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+	"mime/multipart"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func serveInmemory(t *testing.T, app *App) *fasthttp.Client {
+	ln := fasthttputil.NewInmemoryListener()
+	go func() {
+		_ = app.server.Serve(ln)
+	}()
+	t.Cleanup(func() {
+		_ = app.server.Shutdown()
+	})
+
+	return &fasthttp.Client{
+		Dial: func(addr string) (net.Conn, error) {
+			return ln.Dial()
+		},
+	}
+}
+
+func multipartBody(t *testing.T, files map[string]string) (string, []byte) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, content := range files {
+		fw, err := w.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("err:%v", err)
+		}
+		_, _ = fw.Write([]byte(content))
+	}
+	_ = w.Close()
+
+	return w.FormDataContentType(), body.Bytes()
+}
+
+func TestStreamUpload(t *testing.T) {
+	dir := t.TempDir()
+
+	var saved []*UploadFile
+	app := NewApp(&Config{
+		MaxRequestBodySize: 16 * 1024,
+	})
+	app.Post("/upload", func(ctx *Ctx) error {
+		files, err := ctx.SaveUploads(&UploadConfig{
+			MaxSize:    512 * 1024,
+			AllowTypes: []string{".bin"},
+			Storage:    &LocalStorage{Dir: dir},
+		})
+		saved = files
+		return err
+	}, RouteWithStreamBody())
+	app.Post("/echo", func(ctx *Ctx) error {
+		return nil
+	})
+
+	client := serveInmemory(t, app)
+
+	post := func(path, contentType string, body []byte, chunked bool) int {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		rsp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(rsp)
+
+		req.SetRequestURI("http://lrpc" + path)
+		req.Header.SetMethod(fasthttp.MethodPost)
+		req.Header.SetContentType(contentType)
+		if chunked {
+			req.SetBodyStream(bytes.NewReader(body), -1)
+		} else {
+			req.SetBody(body)
+		}
+
+		// 服务端拒绝后不再读取剩余的请求体并关闭连接，客户端可能在写入时就收到错误
+		err := client.Do(req, rsp)
+		if err != nil {
+			t.Logf("%s err:%v", path, err)
+			return 0
+		}
+		return rsp.StatusCode()
+	}
+
+	// 超过 MaxRequestBodySize 的文件以流的方式写入
+	large := strings.Repeat("x", 256*1024)
+	contentType, body := multipartBody(t, map[string]string{"a.bin": large})
+
+	if code := post("/upload", contentType, body, false); code != fasthttp.StatusOK {
+		t.Fatalf("status code:%d", code)
+	}
+	if len(saved) != 1 || saved[0].Size != int64(len(large)) {
+		t.Fatalf("saved:%+v", saved)
+	}
+	buf, err := os.ReadFile(saved[0].Location)
+	if err != nil || string(buf) != large {
+		t.Errorf("content mismatch, err:%v", err)
+	}
+
+	// 其他路由依旧受 MaxRequestBodySize 限制，包括分块传输
+	if code := post("/echo", contentType, body, false); code == fasthttp.StatusOK {
+		t.Errorf("status code:%d", code)
+	}
+	if code := post("/echo", contentType, body, true); code == fasthttp.StatusOK {
+		t.Errorf("chunked status code:%d", code)
+	}
+	if code := post("/echo", "text/plain", []byte("small"), true); code != fasthttp.StatusOK {
+		t.Errorf("small chunked status code:%d", code)
+	}
+
+	// 读取过程中检查大小与类型，失败时删除已经保存的文件
+	for _, files := range []map[string]string{
+		{"b.bin": strings.Repeat("x", 600*1024)},
+		{"c.exe": "x"},
+	} {
+		_ = os.RemoveAll(dir)
+
+		saved = nil
+		contentType, body = multipartBody(t, files)
+		post("/upload", contentType, body, false)
+		if saved != nil {
+			t.Errorf("saved:%+v", saved)
+		}
+
+		entries, _ := os.ReadDir(dir)
+		if len(entries) != 0 {
+			t.Errorf("files left:%d", len(entries))
+		}
+	}
+}